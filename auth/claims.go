@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"google.golang.org/grpc/metadata"
+)
+
+// Claims are the claims of a validated token.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	Tenant    string
+	Groups    []string
+	// Raw holds every claim of the token.
+	Raw map[string]interface{}
+}
+
+func (v *Validator) claims(raw map[string]interface{}) Claims {
+	groupsClaim := v.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	tenantClaim := v.TenantClaim
+	if tenantClaim == "" {
+		tenantClaim = "tenant"
+	}
+
+	c := Claims{
+		Subject:   str(raw["sub"]),
+		Issuer:    str(raw["iss"]),
+		Audience:  strs(raw["aud"]),
+		ExpiresAt: unix(raw["exp"]),
+		NotBefore: unix(raw["nbf"]),
+		Tenant:    str(claim(raw, tenantClaim)),
+		Groups:    strs(claim(raw, groupsClaim)),
+		Raw:       raw,
+	}
+
+	return c
+}
+
+// claim returns the value of a claim, following dots into nested objects.
+func claim(raw map[string]interface{}, name string) interface{} {
+	if v, ok := raw[name]; ok {
+		return v
+	}
+
+	head, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil
+	}
+
+	nested, _ := raw[head].(map[string]interface{})
+
+	return claim(nested, rest)
+}
+
+func str(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	default:
+		return ""
+	}
+}
+
+// strs accepts a single string, a list of strings or a comma separated string.
+func strs(v interface{}) []string {
+	switch s := v.(type) {
+	case string:
+		var out []string
+		for _, e := range strings.Split(s, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				out = append(out, e)
+			}
+		}
+		return out
+	case []interface{}:
+		var out = make([]string, 0, len(s))
+		for _, e := range s {
+			if e := str(e); e != "" {
+				out = append(out, e)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func unix(v interface{}) time.Time {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(f), 0)
+}
+
+// FromRequest validates the bearer token of the Authorization header of r.
+func (v *Validator) FromRequest(r *http.Request) (Claims, error) {
+	return v.fromAuthorization(r.Header.Get("Authorization"))
+}
+
+// FromMetadata validates the bearer token of the authorization metadata of an
+// incoming gRPC call.
+func (v *Validator) FromMetadata(ctx context.Context) (Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var value string
+	if values := md.Get("authorization"); len(values) != 0 {
+		value = values[0]
+	}
+
+	return v.fromAuthorization(value)
+}
+
+func (v *Validator) fromAuthorization(value string) (Claims, error) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return Claims{}, fmt.Errorf("%w: missing bearer token", ErrInvalidToken)
+	}
+
+	return v.Validate(strings.TrimSpace(token))
+}
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx carrying c. Searches and mutations executed with it
+// are audited as done by the subject of c within its tenant.
+func NewContext(ctx context.Context, c Claims) context.Context {
+	ctx = opensearch.WithAuditActor(ctx, opensearch.AuditActor{User: c.Subject, Tenant: c.Tenant})
+
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// FromContext returns the claims stored in ctx by NewContext.
+func FromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(Claims)
+
+	return c, ok
+}
+
+// Scope returns a copy of q restricted to the tenant of c, when it has one, and to the
+// documents whose groupsField contains one of the groups of c. A claims without groups
+// can't see any document.
+func (c Claims) Scope(q opensearch.SearchRequest, groupsField string) opensearch.SearchRequest {
+	if c.Tenant != "" {
+		q = q.TenantScope(c.Tenant)
+	}
+
+	groups := make([]interface{}, len(c.Groups))
+	for i, g := range c.Groups {
+		groups[i] = g
+	}
+
+	visible := opensearch.Query{Terms: map[string][]interface{}{groupsField: groups}}
+
+	var query = opensearch.Query{Bool: &opensearch.Bool{Filter: []opensearch.Query{visible}}}
+	if q.Query != nil {
+		query.Bool.Must = []opensearch.Query{*q.Query}
+	}
+
+	q.Query = &query
+
+	return q
+}
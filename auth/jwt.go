@@ -0,0 +1,251 @@
+// Package auth validates the JWTs presented to API services, from HTTP headers or gRPC
+// metadata, and extracts the claims used to scope searches: the subject, the tenant
+// and the visibility groups.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens and invalid signatures.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned for tokens used outside their validity period.
+	ErrExpiredToken = errors.New("token expired or not valid yet")
+)
+
+// Validator validates JWTs signed with HS256/384/512, RS256/384/512 or ES256/384/512.
+type Validator struct {
+	mu sync.RWMutex
+	// keys by key ID: []byte for HMAC, *rsa.PublicKey or *ecdsa.PublicKey.
+	keys map[string]interface{}
+
+	// Issuer and Audience, if set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// GroupsClaim is the claim holding the visibility groups, "groups" by default.
+	// Nested claims are separated by dots, e.g. "realm_access.roles".
+	GroupsClaim string
+	// TenantClaim is the claim holding the tenant ID, "tenant" by default.
+	TenantClaim string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// NewValidator returns a validator without keys.
+func NewValidator() *Validator {
+	return &Validator{keys: make(map[string]interface{})}
+}
+
+// SetKey sets the key verifying the tokens whose header has the given kid. Tokens
+// without kid are verified with the key set for the empty ID. Keys can be replaced
+// while the validator is in use, to rotate them.
+func (v *Validator) SetKey(kid string, key interface{}) error {
+	switch key.(type) {
+	case []byte, *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.keys == nil {
+		v.keys = make(map[string]interface{})
+	}
+
+	v.keys[kid] = key
+
+	return nil
+}
+
+// RemoveKey removes the key with the given ID.
+func (v *Validator) RemoveKey(kid string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.keys, kid)
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Validate checks the signature and validity of token and returns its claims.
+func (v *Validator) Validate(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var h header
+
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	v.mu.RLock()
+	key, ok := v.keys[h.Kid]
+	v.mu.RUnlock()
+
+	if !ok {
+		return Claims{}, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, h.Kid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	err = verify(h.Alg, key, []byte(parts[0]+"."+parts[1]), sig)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	var raw map[string]interface{}
+
+	err = decodeSegment(parts[1], &raw)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	c := v.claims(raw)
+
+	err = v.check(c, time.Now())
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return c, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	j, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(j)))
+	dec.UseNumber()
+
+	err = dec.Decode(v)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+func verify(alg string, key interface{}, signed, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+
+	var hf crypto.Hash
+	var newHash func() hash.Hash
+
+	switch alg[2:] {
+	case "256":
+		hf, newHash = crypto.SHA256, sha256.New
+	case "384":
+		hf, newHash = crypto.SHA384, sha512.New384
+	case "512":
+		hf, newHash = crypto.SHA512, sha512.New
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+
+	h := newHash()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	// The key type must match the algorithm, so that public keys are never used as
+	// HMAC secrets.
+	switch k := key.(type) {
+	case []byte:
+		if alg[:2] != "HS" {
+			break
+		}
+
+		mac := hmac.New(newHash, k)
+		mac.Write(signed)
+
+		if hmac.Equal(mac.Sum(nil), sig) {
+			return nil
+		}
+
+		return ErrInvalidToken
+	case *rsa.PublicKey:
+		if alg[:2] != "RS" {
+			break
+		}
+
+		if rsa.VerifyPKCS1v15(k, hf, digest, sig) == nil {
+			return nil
+		}
+
+		return ErrInvalidToken
+	case *ecdsa.PublicKey:
+		if alg[:2] != "ES" {
+			break
+		}
+
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrInvalidToken
+		}
+
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+
+		if ecdsa.Verify(k, digest, r, s) {
+			return nil
+		}
+
+		return ErrInvalidToken
+	}
+
+	return fmt.Errorf("%w: algorithm %s does not match the key", ErrInvalidToken, alg)
+}
+
+func (v *Validator) check(c Claims, now time.Time) error {
+	if !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt.Add(v.Leeway)) {
+		return ErrExpiredToken
+	}
+
+	if !c.NotBefore.IsZero() && now.Add(v.Leeway).Before(c.NotBefore) {
+		return ErrExpiredToken
+	}
+
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, c.Issuer)
+	}
+
+	if v.Audience != "" {
+		for _, aud := range c.Audience {
+			if aud == v.Audience {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: audience %q not allowed", ErrInvalidToken, v.Audience)
+	}
+
+	return nil
+}
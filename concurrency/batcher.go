@@ -0,0 +1,154 @@
+package concurrency
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed is returned by Batcher.Add after Close.
+var ErrBatcherClosed = errors.New("batcher closed")
+
+// BatcherConfig configures a Batcher. A batch is flushed when it reaches MaxItems or
+// MaxBytes, or when its first value is MaxAge old; zero disables each limit.
+type BatcherConfig[T any] struct {
+	MaxItems int
+	MaxBytes int
+	MaxAge   time.Duration
+	// Size returns the size in bytes of a value, required when MaxBytes is set.
+	Size func(v T) int
+	// Flush receives every batch, one at a time and in order. Batches are never empty.
+	Flush func(batch []T)
+}
+
+// Batcher groups values into batches, e.g. documents of bulk requests, messages of a
+// producer or events of a gRPC stream.
+type Batcher[T any] struct {
+	cfg BatcherConfig[T]
+
+	mu     sync.Mutex
+	batch  []T
+	bytes  int
+	timer  *time.Timer
+	gen    uint64
+	closed bool
+
+	// flushMu serializes the calls to cfg.Flush, keeping the batches in order.
+	flushMu sync.Mutex
+}
+
+// NewBatcher returns a Batcher with the given configuration.
+func NewBatcher[T any](cfg BatcherConfig[T]) *Batcher[T] {
+	if cfg.MaxBytes > 0 && cfg.Size == nil {
+		panic("concurrency: BatcherConfig.Size is required with MaxBytes")
+	}
+
+	return &Batcher[T]{cfg: cfg}
+}
+
+// Add appends v to the current batch, flushing it in the calling goroutine if it
+// reaches a limit. A value larger than MaxBytes is flushed alone.
+func (b *Batcher[T]) Add(v T) error {
+	var size int
+	if b.cfg.Size != nil {
+		size = b.cfg.Size(v)
+	}
+
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBatcherClosed
+	}
+
+	var full []T
+
+	// Flush the current batch first when v would make it exceed MaxBytes.
+	if b.cfg.MaxBytes > 0 && len(b.batch) != 0 && b.bytes+size > b.cfg.MaxBytes {
+		full = b.take()
+	}
+
+	b.batch = append(b.batch, v)
+	b.bytes += size
+
+	if len(b.batch) == 1 && b.cfg.MaxAge > 0 {
+		gen := b.gen
+		b.timer = time.AfterFunc(b.cfg.MaxAge, func() { b.expire(gen) })
+	}
+
+	var next []T
+
+	if (b.cfg.MaxItems > 0 && len(b.batch) >= b.cfg.MaxItems) ||
+		(b.cfg.MaxBytes > 0 && b.bytes >= b.cfg.MaxBytes) {
+		next = b.take()
+	}
+
+	// Take the flush lock before releasing mu so batches are flushed in order.
+	b.flushMu.Lock()
+	b.mu.Unlock()
+	defer b.flushMu.Unlock()
+
+	b.flush(full)
+	b.flush(next)
+
+	return nil
+}
+
+// Flush flushes the current batch, if not empty.
+func (b *Batcher[T]) Flush() {
+	b.mu.Lock()
+	batch := b.take()
+	b.flushMu.Lock()
+	b.mu.Unlock()
+	defer b.flushMu.Unlock()
+
+	b.flush(batch)
+}
+
+// Close flushes the current batch and rejects the values added afterwards.
+func (b *Batcher[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	b.Flush()
+}
+
+func (b *Batcher[T]) expire(gen uint64) {
+	b.mu.Lock()
+
+	// The batch the timer was started for was already flushed.
+	if gen != b.gen {
+		b.mu.Unlock()
+		return
+	}
+
+	batch := b.take()
+	b.flushMu.Lock()
+	b.mu.Unlock()
+	defer b.flushMu.Unlock()
+
+	b.flush(batch)
+}
+
+// take returns the current batch and starts a new one. The caller must hold b.mu.
+func (b *Batcher[T]) take() []T {
+	batch := b.batch
+
+	b.batch = nil
+	b.bytes = 0
+	b.gen++
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	return batch
+}
+
+func (b *Batcher[T]) flush(batch []T) {
+	if len(batch) != 0 {
+		b.cfg.Flush(batch)
+	}
+}
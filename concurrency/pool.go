@@ -0,0 +1,147 @@
+// Package concurrency provides bounded worker pools, fan-in/fan-out helpers and
+// pipeline stages to process events concurrently without spawning unbounded
+// goroutines, e.g.:
+//
+//	parse := &concurrency.Stage[[]byte, *plugins.Event]{Name: "parse", Workers: 4, Fn: parseEvent}
+//	enrich := &concurrency.Stage[*plugins.Event, *plugins.Event]{Name: "enrich", Workers: 16, Ordered: true, Fn: enrichEvent}
+//	index := &concurrency.Stage[*plugins.Event, struct{}]{Name: "index", Workers: 8, Fn: indexEvent}
+//
+//	done := index.Run(ctx, enrich.Run(ctx, parse.Run(ctx, raw)))
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool runs tasks on a bounded number of goroutines.
+type Pool struct {
+	tasks chan func()
+	wg    sync.WaitGroup
+	once  sync.Once
+}
+
+// NewPool starts a pool of workers goroutines. Submit blocks while all of them are
+// busy and queue tasks are waiting.
+func NewPool(workers, queue int) *Pool {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	p := &Pool{tasks: make(chan func(), queue)}
+
+	p.wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+
+	return p
+}
+
+// Submit queues task, waiting for room in the queue until ctx is done. It must not be
+// called after Close.
+func (p *Pool) Submit(ctx context.Context, task func()) error {
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting tasks and waits for the queued ones to finish.
+func (p *Pool) Close() {
+	p.once.Do(func() {
+		close(p.tasks)
+	})
+
+	p.wg.Wait()
+}
+
+// FanOut distributes the values of in over n channels, each value going to a single
+// one of them. The channels are closed when in is closed or ctx is done.
+func FanOut[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	var outs = make([]<-chan T, n)
+
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = out
+
+		go func() {
+			defer close(out)
+
+			for {
+				var v T
+
+				select {
+				case value, open := <-in:
+					if !open {
+						return
+					}
+
+					v = value
+				case <-ctx.Done():
+					return
+				}
+
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	return outs
+}
+
+// FanIn merges the values of several channels into one, closed when all of them are
+// closed or ctx is done. Values of different channels are not ordered.
+func FanIn[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	var out = make(chan T)
+	var wg sync.WaitGroup
+
+	wg.Add(len(ins))
+
+	for _, in := range ins {
+		go func(in <-chan T) {
+			defer wg.Done()
+
+			for {
+				var v T
+
+				select {
+				case value, open := <-in:
+					if !open {
+						return
+					}
+
+					v = value
+				case <-ctx.Done():
+					return
+				}
+
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(in)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
@@ -0,0 +1,233 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSkip can be returned by a stage function to drop a value without counting it as
+// failed, e.g. to filter events out.
+var ErrSkip = errors.New("skip")
+
+// Stage processes the values of a channel with a bounded number of workers.
+type Stage[In, Out any] struct {
+	Name    string
+	Workers int
+	// Ordered makes the stage emit its results in the order of its input. A slow
+	// value then holds back the results of the following ones.
+	Ordered bool
+	Fn      func(ctx context.Context, v In) (Out, error)
+	// OnError, if set, is called with the values whose Fn failed. They are dropped.
+	OnError func(v In, err error)
+
+	metrics stageMetrics
+}
+
+type stageMetrics struct {
+	processed atomic.Int64
+	failed    atomic.Int64
+	skipped   atomic.Int64
+	busy      atomic.Int64
+	inFlight  atomic.Int64
+}
+
+// Metrics are the counters of a stage since it started.
+type Metrics struct {
+	Name      string
+	Processed int64
+	Failed    int64
+	Skipped   int64
+	// InFlight is the number of values being processed.
+	InFlight int64
+	// Busy is the accumulated processing time of the workers.
+	Busy time.Duration
+}
+
+// Metrics returns the current counters of the stage.
+func (s *Stage[In, Out]) Metrics() Metrics {
+	return Metrics{
+		Name:      s.Name,
+		Processed: s.metrics.processed.Load(),
+		Failed:    s.metrics.failed.Load(),
+		Skipped:   s.metrics.skipped.Load(),
+		InFlight:  s.metrics.inFlight.Load(),
+		Busy:      time.Duration(s.metrics.busy.Load()),
+	}
+}
+
+// Run starts the workers of the stage on in and returns the channel of results,
+// closed once in is closed and every value is processed, or ctx is done. A stage
+// must be run only once.
+func (s *Stage[In, Out]) Run(ctx context.Context, in <-chan In) <-chan Out {
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	if s.Ordered {
+		return s.runOrdered(ctx, in, workers)
+	}
+
+	var out = make(chan Out, workers)
+	var wg sync.WaitGroup
+
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				var v In
+
+				select {
+				case value, open := <-in:
+					if !open {
+						return
+					}
+
+					v = value
+				case <-ctx.Done():
+					return
+				}
+
+				r, ok := s.process(ctx, v)
+				if !ok {
+					continue
+				}
+
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+type result[Out any] struct {
+	value Out
+	ok    bool
+}
+
+// runOrdered queues a result channel per value, in input order, which the emitter
+// reads in turn while the workers fill them.
+func (s *Stage[In, Out]) runOrdered(ctx context.Context, in <-chan In, workers int) <-chan Out {
+	var out = make(chan Out, workers)
+	var pending = make(chan chan result[Out], workers)
+	var sem = make(chan struct{}, workers)
+
+	go func() {
+		defer close(pending)
+
+		for {
+			var v In
+
+			select {
+			case value, open := <-in:
+				if !open {
+					return
+				}
+
+				v = value
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			r := make(chan result[Out], 1)
+
+			select {
+			case pending <- r:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(v In) {
+				defer func() { <-sem }()
+
+				value, ok := s.process(ctx, v)
+				r <- result[Out]{value: value, ok: ok}
+			}(v)
+		}
+	}()
+
+	go func() {
+		defer close(out)
+
+		for r := range pending {
+			var res result[Out]
+
+			select {
+			case res = <-r:
+			case <-ctx.Done():
+				return
+			}
+
+			if !res.ok {
+				continue
+			}
+
+			select {
+			case out <- res.value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+func (s *Stage[In, Out]) process(ctx context.Context, v In) (Out, bool) {
+	s.metrics.inFlight.Add(1)
+	start := time.Now()
+
+	r, err := s.Fn(ctx, v)
+
+	s.metrics.busy.Add(int64(time.Since(start)))
+	s.metrics.inFlight.Add(-1)
+
+	switch {
+	case errors.Is(err, ErrSkip):
+		s.metrics.skipped.Add(1)
+		return r, false
+	case err != nil:
+		s.metrics.failed.Add(1)
+		if s.OnError != nil {
+			s.OnError(v, err)
+		}
+		return r, false
+	}
+
+	s.metrics.processed.Add(1)
+
+	return r, true
+}
+
+// Drain consumes in until it is closed, e.g. the output of the last stage of a
+// pipeline, and returns the number of values read.
+func Drain[T any](in <-chan T) int {
+	var n int
+	for range in {
+		n++
+	}
+
+	return n
+}
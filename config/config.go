@@ -0,0 +1,314 @@
+// Package config loads the configuration of services into structs, layering, from
+// lowest to highest precedence, the defaults in the struct tags, YAML or JSON files,
+// environment variables and command line flags.
+//
+// Fields are named by their yaml tag, or their lowercase name, and nested structs
+// form dotted paths, e.g. "opensearch.nodes". The environment variable of a field is
+// its path in uppercase with underscores, e.g. OPENSEARCH_NODES, after the prefix of
+// the Options, unless set with an env tag. Tags also set defaults and requirements:
+//
+//	type Settings struct {
+//		OpenSearch config.OpenSearch `yaml:"opensearch"`
+//		Workers    int               `yaml:"workers" default:"4"`
+//		Feed       string            `yaml:"feed" env:"FEED_URL" required:"true"`
+//	}
+//
+// Structs implementing Validator are validated after loading. SchemaOf describes the
+// fields, to validate configurations before loading them or to document them.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validator is implemented by configuration structs checking their values.
+type Validator interface {
+	Validate() error
+}
+
+// Options of Load.
+type Options struct {
+	// Files are read in order, later files overriding earlier ones. Files ending in
+	// .json are decoded as JSON, others as YAML.
+	Files []string
+	// Optional ignores the files that don't exist.
+	Optional bool
+	// EnvPrefix is prepended to the derived environment variable names, e.g. "APP_".
+	EnvPrefix string
+	// Flags, parsed, set the fields bound with BindFlags.
+	Flags *flag.FlagSet
+}
+
+// field is a settable leaf field of a configuration struct.
+type field struct {
+	path  string
+	env   string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// Load fills dst, a pointer to a struct, from the defaults, files, environment and
+// flags.
+func Load(dst interface{}, opts Options) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config destination must be a pointer to a struct")
+	}
+
+	fields := collect(v.Elem(), "", opts.EnvPrefix)
+
+	for _, f := range fields {
+		if def, ok := f.tag.Lookup("default"); ok && f.value.IsZero() {
+			err := set(f.value, def)
+			if err != nil {
+				return fmt.Errorf("default of %s: %w", f.path, err)
+			}
+		}
+	}
+
+	for _, file := range opts.Files {
+		err := decodeFile(file, dst)
+		if errors.Is(err, os.ErrNotExist) && opts.Optional {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", file, err)
+		}
+	}
+
+	for _, f := range fields {
+		if value, ok := os.LookupEnv(f.env); ok {
+			err := set(f.value, value)
+			if err != nil {
+				return fmt.Errorf("environment variable %s: %w", f.env, err)
+			}
+		}
+	}
+
+	if opts.Flags != nil {
+		var err error
+
+		opts.Flags.Visit(func(fl *flag.Flag) {
+			for _, f := range fields {
+				if f.path == fl.Name && err == nil {
+					err = set(f.value, fl.Value.String())
+					if err != nil {
+						err = fmt.Errorf("flag -%s: %w", fl.Name, err)
+					}
+				}
+			}
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	var missing []string
+
+	for _, f := range fields {
+		if f.tag.Get("required") == "true" && f.value.IsZero() {
+			missing = append(missing, fmt.Sprintf("%s (%s)", f.path, f.env))
+		}
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("configuration required: %s", strings.Join(missing, ", "))
+	}
+
+	for _, f := range fields {
+		if opts := options(f.tag); len(opts) != 0 && !f.value.IsZero() {
+			err := (Field{Options: opts}).check(fmt.Sprint(f.value.Interface()))
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.path, err)
+			}
+		}
+	}
+
+	return validate(v)
+}
+
+// BindFlags defines a flag for each field of dst, named by its path, to pass to Load
+// in Options.Flags once parsed. Flags only override the other sources when set.
+func BindFlags(fs *flag.FlagSet, dst interface{}) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+
+	for _, f := range collect(v.Elem(), "", "") {
+		if fs.Lookup(f.path) == nil {
+			fs.String(f.path, "", "sets "+f.path)
+		}
+	}
+}
+
+func decodeFile(file string, dst interface{}) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		return json.Unmarshal(data, dst)
+	}
+
+	return yaml.Unmarshal(data, dst)
+}
+
+// collect returns the leaf fields of the struct v, recursing into nested structs.
+func collect(v reflect.Value, path, env string) []field {
+	var fields []field
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		fieldEnv := env + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+		if explicit, ok := sf.Tag.Lookup("env"); ok {
+			fieldEnv = explicit
+		}
+
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			fields = append(fields, collect(fv, fieldPath, fieldEnv+"_")...)
+			continue
+		}
+
+		fields = append(fields, field{path: fieldPath, env: fieldEnv, value: fv, tag: sf.Tag})
+	}
+
+	return fields
+}
+
+// set parses s into v. Slices take comma separated values.
+func set(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		var items []string
+		if strings.TrimSpace(s) != "" {
+			items = strings.Split(s, ",")
+		}
+
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			err := set(slice.Index(i), strings.TrimSpace(item))
+			if err != nil {
+				return err
+			}
+		}
+
+		v.Set(slice)
+
+		return nil
+	}
+
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	case time.Time:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+
+		v.Set(reflect.ValueOf(t))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// validate calls Validate on v and its nested structs, innermost first.
+func validate(v reflect.Value) error {
+	elem := v.Elem()
+
+	for i := 0; i < elem.NumField(); i++ {
+		fv := elem.Field(i)
+		if fv.Kind() == reflect.Struct && fv.CanAddr() && elem.Type().Field(i).IsExported() {
+			err := validate(fv.Addr())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if validator, ok := v.Interface().(Validator); ok {
+		return validator.Validate()
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	osgo "github.com/opensearch-project/opensearch-go/v2"
+)
+
+// OpenSearch is the configuration of the connection to the cluster, to embed in the
+// configuration of services, e.g. as the "opensearch" field read from the
+// OPENSEARCH_NODES, OPENSEARCH_USER and OPENSEARCH_PASSWORD variables.
+type OpenSearch struct {
+	Nodes    []string `yaml:"nodes" json:"nodes" required:"true"`
+	User     string   `yaml:"user" json:"user"`
+	Password string   `yaml:"password" json:"password"`
+	// CAFile verifies the certificates of the nodes. Without it, certificates are
+	// not verified, as with opensearch.Connect.
+	CAFile string `yaml:"caFile" json:"caFile"`
+}
+
+func (o *OpenSearch) Validate() error {
+	if (o.User == "") != (o.Password == "") {
+		return fmt.Errorf("opensearch user and password must be set together")
+	}
+
+	return nil
+}
+
+// Config returns the client configuration, to pass to opensearch.ConnectWithConfig.
+func (o OpenSearch) Config() (osgo.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return osgo.Config{}, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return osgo.Config{}, fmt.Errorf("no certificates in %s", o.CAFile)
+		}
+
+		tlsConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return osgo.Config{
+		Addresses: o.Nodes,
+		Username:  o.User,
+		Password:  o.Password,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
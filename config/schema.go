@@ -0,0 +1,380 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Field describes a configuration key.
+type Field struct {
+	// Key is the dotted path of the field, e.g. "opensearch.nodes".
+	Key string `json:"key"`
+	// Type is "string", "integer", "number", "boolean", "duration", "time", "list" or
+	// "object".
+	Type string `json:"type"`
+	// Items is the type of the elements of lists.
+	Items       string `json:"items,omitempty"`
+	Env         string `json:"env,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Options are the allowed values, when restricted.
+	Options []string `json:"options,omitempty"`
+}
+
+// Schema describes the configuration of a service or plugin, to validate the values
+// provided to it and render its documentation or configuration forms.
+type Schema struct {
+	Fields []Field `json:"fields"`
+}
+
+// SchemaOf returns the schema of the configuration struct dst, or a pointer to it,
+// with the keys, environment variables, defaults and requirements Load uses, and the
+// descriptions and options of the description and options tags:
+//
+//	Mode string `yaml:"mode" default:"fast" options:"fast,safe" description:"Processing mode."`
+func SchemaOf(dst interface{}, envPrefix string) Schema {
+	v := reflect.ValueOf(dst)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return Schema{}
+	}
+
+	var schema Schema
+
+	for _, f := range collect(reflect.New(v.Type()).Elem(), "", envPrefix) {
+		field := Field{
+			Key:         f.path,
+			Type:        typeName(f.value.Type()),
+			Env:         f.env,
+			Default:     f.tag.Get("default"),
+			Description: f.tag.Get("description"),
+			Required:    f.tag.Get("required") == "true",
+			Options:     options(f.tag),
+		}
+
+		if field.Type == "list" {
+			field.Items = typeName(f.value.Type().Elem())
+		}
+
+		schema.Fields = append(schema.Fields, field)
+	}
+
+	return schema
+}
+
+func options(tag reflect.StructTag) []string {
+	list, ok := tag.Lookup("options")
+	if !ok {
+		return nil
+	}
+
+	var opts []string
+	for _, o := range strings.Split(list, ",") {
+		opts = append(opts, strings.TrimSpace(o))
+	}
+
+	return opts
+}
+
+func typeName(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return "duration"
+	case reflect.TypeOf(time.Time{}):
+		return "time"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+
+		return "list"
+	case reflect.Map, reflect.Struct, reflect.Interface:
+		return "object"
+	}
+
+	return "string"
+}
+
+// Validate checks configuration values, as decoded from YAML or JSON, against the
+// schema: unknown keys, missing required keys, values of the wrong type and values
+// out of the options are reported together.
+func (s Schema) Validate(values map[string]interface{}) error {
+	var fields = make(map[string]Field, len(s.Fields))
+	for _, f := range s.Fields {
+		fields[f.Key] = f
+	}
+
+	var problems []string
+
+	flat := make(map[string]interface{})
+	flatten(values, "", fields, flat)
+
+	var keys = make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		f, ok := fields[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown key", key))
+			continue
+		}
+
+		err := f.check(flat[key])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+
+	for _, f := range s.Fields {
+		if _, ok := flat[f.Key]; !ok && f.Required && f.Default == "" {
+			problems = append(problems, fmt.Sprintf("%s: required", f.Key))
+		}
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// flatten sets in flat the values of the nested maps of values keyed by their dotted
+// path, keeping maps whole for the keys of object fields.
+func flatten(values map[string]interface{}, path string, fields map[string]Field, flat map[string]interface{}) {
+	for k, v := range values {
+		key := k
+		if path != "" {
+			key = path + "." + k
+		}
+
+		if m, ok := v.(map[string]interface{}); ok && fields[key].Type != "object" {
+			flatten(m, key, fields, flat)
+			continue
+		}
+
+		flat[key] = v
+	}
+}
+
+// check verifies the type and options of a value of the field.
+func (f Field) check(v interface{}) error {
+	err := checkType(f.Type, f.Items, v)
+	if err != nil {
+		return err
+	}
+
+	if len(f.Options) == 0 {
+		return nil
+	}
+
+	value := fmt.Sprint(v)
+	for _, o := range f.Options {
+		if value == o {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%q is not one of %s", value, strings.Join(f.Options, ", "))
+}
+
+func checkType(typ, items string, v interface{}) error {
+	var ok bool
+
+	switch typ {
+	case "string":
+		_, ok = v.(string)
+	case "boolean":
+		_, ok = v.(bool)
+	case "integer":
+		switch n := v.(type) {
+		case int, int64, uint64:
+			ok = true
+		case float64:
+			ok = n == float64(int64(n))
+		}
+	case "number":
+		switch v.(type) {
+		case int, int64, uint64, float64:
+			ok = true
+		}
+	case "duration":
+		if s, isString := v.(string); isString {
+			_, err := time.ParseDuration(s)
+			ok = err == nil
+		}
+	case "time":
+		switch t := v.(type) {
+		case time.Time:
+			ok = true
+		case string:
+			_, err := time.Parse(time.RFC3339, t)
+			ok = err == nil
+		}
+	case "list":
+		list, isList := v.([]interface{})
+		if !isList {
+			// Lists are also given as comma separated values, like in variables.
+			_, ok = v.(string)
+			break
+		}
+
+		for i, item := range list {
+			err := checkType(items, "", item)
+			if err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+
+		ok = true
+	default:
+		ok = true
+	}
+
+	if !ok {
+		return fmt.Errorf("%v is not a valid %s", v, typ)
+	}
+
+	return nil
+}
+
+// Markdown renders the schema as a Markdown table documenting each key.
+func (s Schema) Markdown() string {
+	var b strings.Builder
+
+	b.WriteString("| Key | Type | Default | Environment | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+
+	for _, f := range s.Fields {
+		typ := f.Type
+		if f.Items != "" {
+			typ += " of " + f.Items
+		}
+
+		description := f.Description
+		if f.Required {
+			description = strings.TrimSpace("Required. " + description)
+		}
+
+		if len(f.Options) != 0 {
+			description = strings.TrimSpace(description + " One of: " + strings.Join(f.Options, ", ") + ".")
+		}
+
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n",
+			f.Key, typ, code(f.Default), code(f.Env), strings.ReplaceAll(description, "|", "\\|"))
+	}
+
+	return b.String()
+}
+
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "`" + s + "`"
+}
+
+// JSONSchema returns the schema as a JSON Schema object, nesting dotted keys, e.g. to
+// generate configuration forms.
+func (s Schema) JSONSchema() map[string]interface{} {
+	root := jsonObject()
+
+	for _, f := range s.Fields {
+		parent := root
+		parts := strings.Split(f.Key, ".")
+
+		for _, part := range parts[:len(parts)-1] {
+			props := parent["properties"].(map[string]interface{})
+
+			child, ok := props[part].(map[string]interface{})
+			if !ok {
+				child = jsonObject()
+				props[part] = child
+			}
+
+			parent = child
+		}
+
+		prop := jsonType(f.Type, f.Items)
+
+		if f.Description != "" {
+			prop["description"] = f.Description
+		}
+
+		if f.Default != "" {
+			prop["default"] = jsonDefault(f)
+		}
+
+		if len(f.Options) != 0 {
+			prop["enum"] = f.Options
+		}
+
+		name := parts[len(parts)-1]
+		parent["properties"].(map[string]interface{})[name] = prop
+
+		if f.Required && f.Default == "" {
+			parent["required"] = append(parent["required"].([]string), name)
+		}
+	}
+
+	return root
+}
+
+// jsonDefault returns the default of the field as a value of its JSON type.
+func jsonDefault(f Field) interface{} {
+	switch f.Type {
+	case "integer", "number", "boolean":
+		var v interface{}
+		if json.Unmarshal([]byte(f.Default), &v) == nil {
+			return v
+		}
+	case "list":
+		var items = make([]interface{}, 0)
+		for _, item := range strings.Split(f.Default, ",") {
+			items = append(items, jsonDefault(Field{Type: f.Items, Default: strings.TrimSpace(item)}))
+		}
+
+		return items
+	}
+
+	return f.Default
+}
+
+func jsonObject() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}, "required": []string{}}
+}
+
+func jsonType(typ, items string) map[string]interface{} {
+	switch typ {
+	case "duration":
+		return map[string]interface{}{"type": "string", "format": "duration"}
+	case "time":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "list":
+		return map[string]interface{}{"type": "array", "items": jsonType(items, "")}
+	}
+
+	return map[string]interface{}{"type": typ}
+}
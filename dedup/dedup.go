@@ -0,0 +1,208 @@
+// Package dedup suppresses duplicate events, e.g. from replayed syslog streams, by
+// comparing stable fingerprints of their identifying fields within a time window.
+package dedup
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/threatwinds/go-sdk/plugins"
+	"github.com/tidwall/gjson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultFields identify an event when no fields are configured: the same raw log,
+// received from the same source, is a duplicate.
+var DefaultFields = []string{"dataSource", "dataType", "raw"}
+
+// Fingerprint returns a stable fingerprint of the given fields of e. Fields are paths
+// of the JSON representation of the event, e.g. "remote.ip" or "log.eventId"; missing
+// fields take part in the fingerprint as empty.
+func Fingerprint(e *plugins.Event, fields []string) (string, error) {
+	j, err := protojson.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+
+	for _, field := range fields {
+		value := gjson.GetBytes(j, field)
+
+		// protojson varies its whitespace on purpose, so objects and arrays are
+		// compacted before hashing.
+		raw := []byte(value.Raw)
+		if value.IsObject() || value.IsArray() {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return "", err
+			}
+
+			raw = compact.Bytes()
+		}
+
+		// Lengths delimit the values, so that moving characters between adjacent
+		// fields changes the fingerprint.
+		fmt.Fprintf(h, "%d:%s=%d:%s;", len(field), field, len(raw), raw)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Deduplicator remembers the fingerprints of the events seen within Window.
+type Deduplicator struct {
+	// Fields identifying an event, DefaultFields when empty.
+	Fields []string
+	Window time.Duration
+
+	// Index, if set, is searched for the fingerprints missing from memory, e.g. after
+	// a restart or on another replica, in the documents whose FingerprintField holds
+	// them and whose TimeField is within the window. The fingerprint must be stored
+	// in the indexed events, see Stamp.
+	Index            string
+	FingerprintField string
+	TimeField        string
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	max     int
+}
+
+type entry struct {
+	fingerprint string
+	seen        time.Time
+}
+
+// New returns a Deduplicator remembering up to size fingerprints for window.
+func New(window time.Duration, size int) *Deduplicator {
+	return &Deduplicator{
+		Window:           window,
+		FingerprintField: "log.fingerprint",
+		TimeField:        "@timestamp",
+		entries:          make(map[string]*list.Element),
+		order:            list.New(),
+		max:              size,
+	}
+}
+
+// Duplicate reports whether an event with the same fingerprint as e was seen within
+// the window, and remembers e otherwise. It also returns the fingerprint of e.
+func (d *Deduplicator) Duplicate(ctx context.Context, e *plugins.Event) (bool, string, error) {
+	fields := d.Fields
+	if len(fields) == 0 {
+		fields = DefaultFields
+	}
+
+	fp, err := Fingerprint(e, fields)
+	if err != nil {
+		return false, "", err
+	}
+
+	now := time.Now()
+
+	if d.seen(fp, now) {
+		return true, fp, nil
+	}
+
+	if d.Index != "" {
+		found, err := d.lookup(ctx, fp, now)
+		if err != nil {
+			d.forget(fp)
+			return false, fp, err
+		}
+
+		if found {
+			return true, fp, nil
+		}
+	}
+
+	return false, fp, nil
+}
+
+// Stamp sets the fingerprint in the log of e, under FingerprintField without its
+// "log." prefix, for the lookups of other deduplicators.
+func (d *Deduplicator) Stamp(e *plugins.Event, fingerprint string) {
+	field := strings.TrimPrefix(d.FingerprintField, "log.")
+
+	if e.Log == nil {
+		e.Log = make(map[string]*structpb.Value)
+	}
+
+	e.Log[field] = structpb.NewStringValue(fingerprint)
+}
+
+// seen reports whether fp was remembered within the window, and remembers it
+// otherwise, evicting the least recently stored fingerprint when full. The window of
+// a fingerprint starts at its first occurrence, duplicates don't extend it.
+func (d *Deduplicator) seen(fp string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.entries[fp]; ok {
+		if now.Sub(el.Value.(*entry).seen) <= d.Window {
+			return true
+		}
+
+		el.Value.(*entry).seen = now
+		d.order.MoveToFront(el)
+
+		return false
+	}
+
+	d.entries[fp] = d.order.PushFront(&entry{fingerprint: fp, seen: now})
+
+	for d.max > 0 && d.order.Len() > d.max {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.entries, oldest.Value.(*entry).fingerprint)
+	}
+
+	return false
+}
+
+// forget removes fp, so an event whose lookup failed isn't taken as duplicate later.
+func (d *Deduplicator) forget(fp string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.entries[fp]; ok {
+		d.order.Remove(el)
+		delete(d.entries, fp)
+	}
+}
+
+func (d *Deduplicator) lookup(ctx context.Context, fp string, now time.Time) (bool, error) {
+	index, err := opensearch.CheckIndexAccess(d.Index)
+	if err != nil {
+		return false, err
+	}
+
+	query := opensearch.Query{Bool: &opensearch.Bool{Filter: []opensearch.Query{
+		{Term: map[string]map[string]interface{}{d.FingerprintField: {"value": fp}}},
+		{Range: map[string]map[string]interface{}{
+			d.TimeField: {"gte": now.Add(-d.Window).UTC().Format(time.RFC3339Nano)},
+		}},
+	}}}
+
+	path := "/" + url.PathEscape(strings.Join(index, ",")) + "/_count"
+
+	resp, err := opensearch.Do(ctx, http.MethodPost, path, nil, map[string]interface{}{"query": query})
+	if err != nil {
+		return false, err
+	}
+
+	return gjson.GetBytes(resp, "count").Int() > 0, nil
+}
@@ -0,0 +1,63 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/plugins"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFingerprint(t *testing.T) {
+	nested, err := structpb.NewValue(map[string]interface{}{
+		"id":   "4624",
+		"tags": []interface{}{"logon", "success"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := &plugins.Event{
+		DataType: "wineventlog",
+		Raw:      "An account was successfully logged on.",
+		Log:      map[string]*structpb.Value{"event": nested},
+		Remote:   &plugins.Side{Ip: "10.0.0.1"},
+	}
+
+	want := func(parts ...string) string {
+		h := sha256.New()
+		for i := 0; i < len(parts); i += 2 {
+			fmt.Fprintf(h, "%d:%s=%d:%s;", len(parts[i]), parts[i], len(parts[i+1]), parts[i+1])
+		}
+
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{name: "string field", fields: []string{"dataType"}, want: want("dataType", `"wineventlog"`)},
+		{name: "missing field", fields: []string{"dataSource"}, want: want("dataSource", "")},
+		{name: "nested field", fields: []string{"log.event"}, want: want("log.event", `{"id":"4624","tags":["logon","success"]}`)},
+		{name: "nested array", fields: []string{"log.event.tags"}, want: want("log.event.tags", `["logon","success"]`)},
+		{name: "nested message", fields: []string{"remote"}, want: want("remote", `{"ip":"10.0.0.1"}`)},
+		{name: "several fields", fields: []string{"dataType", "remote.ip"}, want: want("dataType", `"wineventlog"`, "remote.ip", `"10.0.0.1"`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fingerprint(event, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Fingerprint() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
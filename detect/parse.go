@@ -0,0 +1,376 @@
+// Package detect implements a small detection language, compiled both to a matcher of
+// events, applied while streaming, and to a search query, to hunt the same rule in the
+// indexed events.
+//
+// Rules compare fields, the paths of the JSON representation of the events like
+// "remote.ip" or "log.eventCode", with values:
+//
+//	dataType == "wineventlog" and log.eventCode in [4624, 4625]
+//	and not (remote.ip startswith "10." or log.user =~ "svc_.*")
+//
+// The operators are ==, !=, <, <=, >, >=, =~ and !~ (regular expressions matching the
+// whole value), in and not in (lists), contains, startswith and endswith, and
+// "exists field". Conditions combine with and, or, not and parentheses. A field with
+// several values, like an array of addresses, matches when any value matches, and a
+// negated condition when none does.
+package detect
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+var operators = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "=~": true, "!~": true}
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(src); {
+		r := rune(src[i])
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != src[i] {
+				if src[end] == '\\' {
+					end++
+				}
+
+				end++
+			}
+
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+
+			value, err := unquote(src[i+1 : end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %w", i, err)
+			}
+
+			tokens = append(tokens, token{tokenString, value, i})
+			i = end + 1
+		case unicode.IsDigit(r) || r == '-' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1])):
+			end := i + 1
+			for end < len(src) && strings.IndexByte("0123456789.eE+-", src[end]) >= 0 {
+				end++
+			}
+
+			tokens = append(tokens, token{tokenNumber, src[i:end], i})
+			i = end
+		case unicode.IsLetter(r) || r == '_' || r == '@':
+			end := i + 1
+			for end < len(src) && (unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end])) || strings.IndexByte("_@.", src[end]) >= 0) {
+				end++
+			}
+
+			tokens = append(tokens, token{tokenIdent, src[i:end], i})
+			i = end
+		default:
+			op := src[i : i+1]
+			if i+1 < len(src) && operators[src[i:i+2]] {
+				op = src[i : i+2]
+			}
+
+			if !strings.Contains("()[],<>", op) && len(op) == 1 {
+				return nil, fmt.Errorf("unexpected %q at %d", op, i)
+			}
+
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "", len(src)}), nil
+}
+
+// unquote resolves the backslash escapes of a string literal.
+func unquote(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// keyword reports whether the next token is the keyword k, consuming it if so.
+func (p *parser) keyword(k string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.value, k) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) op(op string) bool {
+	t := p.peek()
+	if t.kind == tokenOp && t.value == op {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes = []node{n}
+
+	for p.keyword("or") {
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	return or(nodes), nil
+}
+
+func (p *parser) and() (node, error) {
+	n, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes = []node{n}
+
+	for p.keyword("and") {
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	return and(nodes), nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.keyword("not") {
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		return not{n}, nil
+	}
+
+	if p.op("(") {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.op(")") {
+			return nil, p.unexpected()
+		}
+
+		return n, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	if p.keyword("exists") {
+		field := p.next()
+		if field.kind != tokenIdent {
+			return nil, fmt.Errorf("expected field after exists at %d", field.pos)
+		}
+
+		return &comparison{field: field.value, op: "exists"}, nil
+	}
+
+	field := p.next()
+	if field.kind != tokenIdent {
+		return nil, fmt.Errorf("expected field at %d", field.pos)
+	}
+
+	c := &comparison{field: field.value}
+
+	var negate bool
+
+	switch t := p.next(); {
+	case t.kind == tokenOp && operators[t.value]:
+		c.op = t.value
+	case t.kind == tokenIdent && strings.EqualFold(t.value, "not"):
+		if !p.keyword("in") {
+			return nil, p.unexpected()
+		}
+
+		c.op, negate = "in", true
+	case t.kind == tokenIdent && (strings.EqualFold(t.value, "in") || strings.EqualFold(t.value, "contains") ||
+		strings.EqualFold(t.value, "startswith") || strings.EqualFold(t.value, "endswith")):
+		c.op = strings.ToLower(t.value)
+	default:
+		return nil, fmt.Errorf("expected operator after %s at %d", field.value, t.pos)
+	}
+
+	// != and !~ are the negations of == and =~.
+	switch c.op {
+	case "!=":
+		c.op, negate = "==", true
+	case "!~":
+		c.op, negate = "=~", true
+	}
+
+	if c.op == "in" {
+		if !p.op("[") {
+			return nil, p.unexpected()
+		}
+
+		for !p.op("]") {
+			if len(c.values) != 0 && !p.op(",") {
+				return nil, p.unexpected()
+			}
+
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+
+			c.values = append(c.values, v)
+		}
+
+		if len(c.values) == 0 {
+			return nil, fmt.Errorf("empty list after %s in", field.value)
+		}
+	} else {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		c.values = []interface{}{v}
+	}
+
+	if c.op == "=~" {
+		s, ok := c.values[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("regular expression of %s is not a string", field.value)
+		}
+
+		re, err := regexp.Compile("^(?:" + s + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression of %s: %w", field.value, err)
+		}
+
+		c.re = re
+	}
+
+	if negate {
+		return not{c}, nil
+	}
+
+	return c, nil
+}
+
+func (p *parser) value() (interface{}, error) {
+	t := p.next()
+
+	switch {
+	case t.kind == tokenString:
+		return t.value, nil
+	case t.kind == tokenNumber:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at %d", t.value, t.pos)
+		}
+
+		return f, nil
+	case t.kind == tokenIdent && (t.value == "true" || t.value == "false"):
+		return t.value == "true", nil
+	default:
+		return nil, fmt.Errorf("expected value at %d", t.pos)
+	}
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of rule")
+	}
+
+	return fmt.Errorf("unexpected %q at %d", t.value, t.pos)
+}
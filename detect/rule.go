@@ -0,0 +1,292 @@
+package detect
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/threatwinds/go-sdk/plugins"
+	"github.com/tidwall/gjson"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Rule is a compiled detection rule.
+type Rule struct {
+	src  string
+	expr node
+}
+
+// Compile parses a rule.
+func Compile(src string) (*Rule, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
+
+	p := parser{tokens: tokens}
+
+	expr, err := p.or()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.unexpected()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
+
+	return &Rule{src: src, expr: expr}, nil
+}
+
+// MustCompile is like Compile but panics if the rule is invalid.
+func MustCompile(src string) *Rule {
+	r, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+func (r *Rule) String() string {
+	return r.src
+}
+
+// Match reports whether e matches the rule.
+func (r *Rule) Match(e *plugins.Event) (bool, error) {
+	j, err := protojson.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+
+	return r.MatchJSON(j), nil
+}
+
+// MatchJSON reports whether the JSON document matches the rule, e.g. the source of a
+// search hit.
+func (r *Rule) MatchJSON(doc []byte) bool {
+	return r.expr.match(doc)
+}
+
+// Query returns the query matching the indexed documents that match the rule. Fields
+// are queried with term-level queries, so text fields should be compared through
+// their keyword sub-fields, and regular expressions are limited to the syntax shared
+// by Go and OpenSearch.
+func (r *Rule) Query() opensearch.Query {
+	return r.expr.query()
+}
+
+type node interface {
+	match(doc []byte) bool
+	query() opensearch.Query
+}
+
+type and []node
+
+func (n and) match(doc []byte) bool {
+	for _, c := range n {
+		if !c.match(doc) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (n and) query() opensearch.Query {
+	var clauses = make([]opensearch.Query, 0, len(n))
+	for _, c := range n {
+		clauses = append(clauses, c.query())
+	}
+
+	return opensearch.Query{Bool: &opensearch.Bool{Filter: clauses}}
+}
+
+type or []node
+
+func (n or) match(doc []byte) bool {
+	for _, c := range n {
+		if c.match(doc) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n or) query() opensearch.Query {
+	var clauses = make([]opensearch.Query, 0, len(n))
+	for _, c := range n {
+		clauses = append(clauses, c.query())
+	}
+
+	return opensearch.Query{Bool: &opensearch.Bool{Should: clauses, MinimumShouldMatch: 1}}
+}
+
+type not struct {
+	node
+}
+
+func (n not) match(doc []byte) bool {
+	return !n.node.match(doc)
+}
+
+func (n not) query() opensearch.Query {
+	return opensearch.Query{Bool: &opensearch.Bool{MustNot: []opensearch.Query{n.node.query()}}}
+}
+
+type comparison struct {
+	field  string
+	op     string
+	values []interface{}
+	re     *regexp.Regexp
+}
+
+func (c *comparison) match(doc []byte) bool {
+	result := gjson.GetBytes(doc, c.field)
+	if !result.Exists() || result.Type == gjson.Null {
+		return false
+	}
+
+	if c.op == "exists" {
+		return true
+	}
+
+	var found bool
+
+	each(result, func(v gjson.Result) bool {
+		found = c.matchValue(v)
+		return !found
+	})
+
+	return found
+}
+
+// each calls fn with every value of an array, or with result itself, until fn
+// returns false.
+func each(result gjson.Result, fn func(gjson.Result) bool) {
+	if !result.IsArray() {
+		fn(result)
+		return
+	}
+
+	result.ForEach(func(_, v gjson.Result) bool {
+		return fn(v)
+	})
+}
+
+func (c *comparison) matchValue(v gjson.Result) bool {
+	switch c.op {
+	case "==", "in":
+		for _, want := range c.values {
+			if compare(v, want) == 0 {
+				return true
+			}
+		}
+
+		return false
+	case "<", "<=", ">", ">=":
+		cmp := compare(v, c.values[0])
+		if cmp == incomparable {
+			return false
+		}
+
+		switch c.op {
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	case "=~":
+		return c.re.MatchString(v.String())
+	case "contains":
+		return strings.Contains(v.String(), fmt.Sprint(c.values[0]))
+	case "startswith":
+		return strings.HasPrefix(v.String(), fmt.Sprint(c.values[0]))
+	case "endswith":
+		return strings.HasSuffix(v.String(), fmt.Sprint(c.values[0]))
+	default:
+		return false
+	}
+}
+
+// incomparable is returned by compare for values of different kinds.
+const incomparable = -2
+
+// compare compares v with want: numerically when want is a number, as text when it is
+// a string, and as booleans otherwise.
+func compare(v gjson.Result, want interface{}) int {
+	switch w := want.(type) {
+	case float64:
+		var f float64
+
+		switch v.Type {
+		case gjson.Number:
+			f = v.Num
+		case gjson.String:
+			parsed, err := strconv.ParseFloat(v.Str, 64)
+			if err != nil {
+				return incomparable
+			}
+
+			f = parsed
+		default:
+			return incomparable
+		}
+
+		switch {
+		case f < w:
+			return -1
+		case f > w:
+			return 1
+		default:
+			return 0
+		}
+	case string:
+		if v.Type != gjson.String && v.Type != gjson.Number {
+			return incomparable
+		}
+
+		return strings.Compare(v.String(), w)
+	case bool:
+		if (v.Type == gjson.True || v.Type == gjson.False) && v.Bool() == w {
+			return 0
+		}
+
+		return incomparable
+	default:
+		return incomparable
+	}
+}
+
+func (c *comparison) query() opensearch.Query {
+	switch c.op {
+	case "exists":
+		return opensearch.Query{Exists: map[string]string{"field": c.field}}
+	case "==":
+		return opensearch.TermQuery(c.field, c.values[0], false)
+	case "in":
+		return opensearch.Query{Terms: map[string][]interface{}{c.field: c.values}}
+	case "<", "<=", ">", ">=":
+		op := map[string]string{"<": "lt", "<=": "lte", ">": "gt", ">=": "gte"}[c.op]
+		return opensearch.Query{Range: map[string]map[string]interface{}{c.field: {op: c.values[0]}}}
+	case "=~":
+		return opensearch.RegexpQuery(c.field, c.values[0].(string), false)
+	case "contains":
+		return opensearch.WildcardQuery(c.field, "*"+escapeWildcard(fmt.Sprint(c.values[0]))+"*", false)
+	case "startswith":
+		return opensearch.PrefixQuery(c.field, fmt.Sprint(c.values[0]), false)
+	default:
+		return opensearch.WildcardQuery(c.field, "*"+escapeWildcard(fmt.Sprint(c.values[0])), false)
+	}
+}
+
+// escapeWildcard escapes the special characters of a wildcard query pattern.
+func escapeWildcard(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`).Replace(s)
+}
@@ -0,0 +1,138 @@
+package detect
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRule(t *testing.T) {
+	doc := []byte(`{
+		"dataType": "wineventlog",
+		"log": {"eventCode": 4625, "user": "svc_backup"},
+		"remote": {"ip": ["10.0.0.5", "8.8.8.8"]},
+		"ok": true,
+		"n": "12"
+	}`)
+
+	tests := []struct {
+		src   string
+		match bool
+		query string
+	}{
+		{
+			src:   `dataType == "wineventlog" and log.eventCode in [4624, 4625]`,
+			match: true,
+			query: `{"bool":{"filter":[{"term":{"dataType":{"value":"wineventlog"}}},{"terms":{"log.eventCode":[4624,4625]}}]}}`,
+		},
+		{
+			src:   `not (remote.ip startswith "10." or log.user =~ "svc_.*")`,
+			query: `{"bool":{"must_not":[{"bool":{"should":[{"prefix":{"remote.ip":"10."}},{"regexp":{"log.user":"svc_.*"}}],"minimum_should_match":1}}]}}`,
+		},
+		{
+			src:   `remote.ip != "10.0.0.5"`,
+			query: `{"bool":{"must_not":[{"term":{"remote.ip":{"value":"10.0.0.5"}}}]}}`,
+		},
+		{
+			src:   `remote.ip == "8.8.8.8"`,
+			match: true,
+			query: `{"term":{"remote.ip":{"value":"8.8.8.8"}}}`,
+		},
+		{
+			src:   `log.user !~ "svc_.*"`,
+			query: `{"bool":{"must_not":[{"regexp":{"log.user":"svc_.*"}}]}}`,
+		},
+		{
+			src:   `log.user =~ "svc"`,
+			query: `{"regexp":{"log.user":"svc"}}`,
+		},
+		{
+			src:   `log.eventCode not in [1, 2]`,
+			match: true,
+			query: `{"bool":{"must_not":[{"terms":{"log.eventCode":[1,2]}}]}}`,
+		},
+		{
+			src:   `log.eventCode >= 4625 and log.eventCode < 5000`,
+			match: true,
+			query: `{"bool":{"filter":[{"range":{"log.eventCode":{"gte":4625}}},{"range":{"log.eventCode":{"lt":5000}}}]}}`,
+		},
+		{
+			src:   `n > 10`,
+			match: true,
+			query: `{"range":{"n":{"gt":10}}}`,
+		},
+		{
+			src:   `ok == true`,
+			match: true,
+			query: `{"term":{"ok":{"value":true}}}`,
+		},
+		{
+			src:   `exists log.user`,
+			match: true,
+			query: `{"exists":{"field":"log.user"}}`,
+		},
+		{
+			src:   `exists missing or missing == "x"`,
+			query: `{"bool":{"should":[{"exists":{"field":"missing"}},{"term":{"missing":{"value":"x"}}}],"minimum_should_match":1}}`,
+		},
+		{
+			src:   `log.user contains "back" and log.user endswith "up"`,
+			match: true,
+			query: `{"bool":{"filter":[{"wildcard":{"log.user":{"value":"*back*"}}},{"wildcard":{"log.user":{"value":"*up"}}}]}}`,
+		},
+		{
+			src:   `log.user contains "a*b"`,
+			query: `{"wildcard":{"log.user":{"value":"*a\\*b*"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			r, err := Compile(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := r.MatchJSON(doc); got != tt.match {
+				t.Errorf("MatchJSON() = %v, want %v", got, tt.match)
+			}
+
+			q, err := json.Marshal(r.Query())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(q) != tt.query {
+				t.Errorf("Query() = %s\nwant      %s", q, tt.query)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: `dataType ==`, want: "invalid rule: expected value at 11"},
+		{src: `(dataType == "x"`, want: "invalid rule: unexpected end of rule"},
+		{src: `dataType == "x" )`, want: `invalid rule: unexpected ")" at 16`},
+		{src: `dataType = "x"`, want: `invalid rule: unexpected "=" at 9`},
+		{src: `dataType == "unterminated`, want: "invalid rule: unterminated string at 12"},
+		{src: `'a' == 'a'`, want: "invalid rule: expected field at 0"},
+		{src: `a in [1, 2`, want: "invalid rule: unexpected end of rule"},
+		{src: `log.user =~ "("`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			if err == nil {
+				t.Fatal("Compile() succeeded")
+			}
+
+			if tt.want != "" && err.Error() != tt.want {
+				t.Errorf("Compile() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// OpenSearch checks that the cluster answers and its health is not red.
+func OpenSearch() Check {
+	return func(ctx context.Context) error {
+		resp, err := opensearch.Do(ctx, http.MethodGet, "/_cluster/health", nil, nil)
+		if err != nil {
+			return err
+		}
+
+		var health struct {
+			Status string `json:"status"`
+		}
+
+		err = json.Unmarshal(resp, &health)
+		if err != nil {
+			return err
+		}
+
+		if health.Status == "red" {
+			return fmt.Errorf("cluster health is red")
+		}
+
+		return nil
+	}
+}
+
+// MappingWatch checks that w polled the mapping of its pattern successfully within
+// maxAge, i.e. that the mapping changes it watches for are still noticed.
+func MappingWatch(w *opensearch.MappingWatcher, maxAge time.Duration) Check {
+	return func(context.Context) error {
+		last := w.LastPoll()
+		if last.IsZero() {
+			last = w.Started()
+		}
+
+		if age := time.Since(last); age > maxAge {
+			if err := w.Err(); err != nil {
+				return fmt.Errorf("mapping last polled %s ago: %w", age.Round(time.Second), err)
+			}
+
+			return fmt.Errorf("mapping last polled %s ago", age.Round(time.Second))
+		}
+
+		return nil
+	}
+}
+
+// GRPC checks a gRPC backend with the standard health checking protocol. An empty
+// service checks the whole server.
+func GRPC(conn grpc.ClientConnInterface, service string) Check {
+	client := healthpb.NewHealthClient(conn)
+
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("backend status %s", resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// Heartbeat checks that a background worker, e.g. a plugin supervisor, beat within
+// maxAge. last returns the time of its latest beat.
+func Heartbeat(last func() time.Time, maxAge time.Duration) Check {
+	return func(context.Context) error {
+		if age := time.Since(last()); age > maxAge {
+			return fmt.Errorf("last heartbeat %s ago", age.Round(time.Second))
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+// Package health serves the liveness and readiness probes of services using the SDK,
+// aggregating checks of their dependencies into a detailed JSON report:
+//
+//	h := health.New()
+//	h.AddReadiness("opensearch", health.OpenSearch(), true)
+//	h.AddReadiness("mappings", health.MappingWatch(watcher, 10*time.Minute), false)
+//	h.AddReadiness("enrichment", health.GRPC(conn, ""), true)
+//	http.Handle("/healthz", h.LivenessHandler())
+//	http.Handle("/readyz", h.ReadinessHandler())
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a check or of a whole probe.
+type Status string
+
+const (
+	StatusUp Status = "up"
+	// StatusDegraded is reported when only non-critical checks fail. The probe
+	// still succeeds.
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Check returns nil when the checked dependency is healthy.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status   Status        `json:"status"`
+	Critical bool          `json:"critical"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Report is the outcome of a probe.
+type Report struct {
+	Status Status            `json:"status"`
+	Time   time.Time         `json:"time"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name     string
+	check    Check
+	critical bool
+}
+
+// Health holds the checks of the liveness and readiness probes.
+type Health struct {
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+
+	// Timeout bounds the duration of every check, 5 seconds by default.
+	Timeout time.Duration
+}
+
+// New returns a Health without checks, whose probes always succeed.
+func New() *Health {
+	return &Health{Timeout: 5 * time.Second}
+}
+
+// AddLiveness adds a check to the liveness probe. Liveness checks should only cover the
+// state of the process itself, e.g. a deadlocked worker, since failing them restarts
+// it; they are always critical.
+func (h *Health) AddLiveness(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.liveness = append(h.liveness, namedCheck{name: name, check: check, critical: true})
+}
+
+// AddReadiness adds a check to the readiness probe. When a critical check fails the
+// probe fails, and the service stops receiving traffic; other failures only degrade it.
+func (h *Health) AddReadiness(name string, check Check, critical bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.readiness = append(h.readiness, namedCheck{name: name, check: check, critical: critical})
+}
+
+// Liveness runs the liveness checks.
+func (h *Health) Liveness(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := h.liveness
+	h.mu.RUnlock()
+
+	return h.run(ctx, checks)
+}
+
+// Readiness runs the readiness checks.
+func (h *Health) Readiness(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := h.readiness
+	h.mu.RUnlock()
+
+	return h.run(ctx, checks)
+}
+
+// LivenessHandler serves the liveness report, with status 503 when it is down.
+func (h *Health) LivenessHandler() http.Handler {
+	return handler(h.Liveness)
+}
+
+// ReadinessHandler serves the readiness report, with status 503 when it is down.
+func (h *Health) ReadinessHandler() http.Handler {
+	return handler(h.Readiness)
+}
+
+func handler(probe func(context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := probe(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		if report.Status == StatusDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// run runs the checks concurrently.
+func (h *Health) run(ctx context.Context, checks []namedCheck) Report {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	var report = Report{Status: StatusUp, Time: time.Now().UTC(), Checks: make(map[string]Result, len(checks))}
+	var results = make([]Result, len(checks))
+	var wg sync.WaitGroup
+
+	for i, c := range checks {
+		wg.Add(1)
+
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+
+			results[i] = runCheck(ctx, c, timeout)
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	for i, c := range checks {
+		r := results[i]
+		report.Checks[c.name] = r
+
+		switch {
+		case r.Status == StatusUp:
+		case r.Critical:
+			report.Status = StatusDown
+		case report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, c namedCheck, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	var done = make(chan error, 1)
+
+	// A check ignoring its context still can't block the probe beyond the timeout.
+	go func() {
+		done <- c.check(ctx)
+	}()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	var r = Result{Status: StatusUp, Critical: c.critical, Duration: time.Since(start)}
+
+	if err != nil {
+		r.Status = StatusDown
+		r.Error = err.Error()
+	}
+
+	return r
+}
@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying id, which identifies the request being
+// served across services and in the search engine logs. A new ID is generated when
+// id is empty.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx by WithRequestID, or an
+// empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
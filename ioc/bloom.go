@@ -0,0 +1,65 @@
+package ioc
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloom is a Bloom filter, answering whether a value may be in a set with no false
+// negatives and a bounded rate of false positives.
+type bloom struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// newBloom returns a filter sized for n values with a false positive rate of p.
+func newBloom(n int, p float64) *bloom {
+	if n < 1 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// hashes returns the two base hashes combined to derive the k positions of value.
+func hashes(value string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(value))
+	h1 := h.Sum64()
+
+	// The second hash must be odd to visit distinct positions.
+	h2 := (h1>>33 | h1<<31) ^ 0x9e3779b97f4a7c15
+
+	return h1, h2 | 1
+}
+
+func (b *bloom) add(value string) {
+	h1, h2 := hashes(value)
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (b *bloom) mayContain(value string) bool {
+	h1, h2 := hashes(value)
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
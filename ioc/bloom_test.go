@@ -0,0 +1,53 @@
+package ioc
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloom(t *testing.T) {
+	tests := []struct {
+		n int
+		p float64
+	}{
+		{n: 0, p: 0.01},
+		{n: 1, p: 0.01},
+		{n: 1000, p: 0.01},
+		{n: 10000, p: 0.001},
+		{n: 50000, p: 0.05},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("n=%d p=%g", tt.n, tt.p), func(t *testing.T) {
+			b := newBloom(tt.n, tt.p)
+
+			if b.m < 64 || uint64(len(b.bits))*64 < b.m || b.k < 1 {
+				t.Fatalf("invalid filter: %d bits in %d words, %d hashes", b.m, len(b.bits), b.k)
+			}
+
+			for i := 0; i < tt.n; i++ {
+				b.add(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+			}
+
+			for i := 0; i < tt.n; i++ {
+				if v := fmt.Sprintf("10.0.%d.%d", i/256, i%256); !b.mayContain(v) {
+					t.Fatalf("false negative for %s", v)
+				}
+			}
+
+			const probes = 100000
+
+			var positives int
+			for i := 0; i < probes; i++ {
+				if b.mayContain(fmt.Sprintf("absent-%d.example.com", i)) {
+					positives++
+				}
+			}
+
+			// Leave room for the variance of the measured rate.
+			if rate := float64(positives) / probes; rate > 2*tt.p {
+				t.Errorf("false positive rate %g, want at most about %g", rate, tt.p)
+			}
+		})
+	}
+}
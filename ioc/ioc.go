@@ -0,0 +1,272 @@
+// Package ioc matches events against large lists of indicators of compromise, kept in
+// memory as a Bloom filter, to discard most values cheaply, backed by sorted lists of
+// the indicators to verify the candidates exactly. Lists refresh periodically from
+// indices or URLs, replacing the indicators atomically.
+package ioc
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/plugins"
+)
+
+// Types of indicators matched in events.
+const (
+	IP     = "ip"
+	Domain = "domain"
+	URL    = "url"
+	Email  = "email"
+	MD5    = "md5"
+	SHA1   = "sha1"
+	SHA256 = "sha256"
+)
+
+// Indicator is an indicator of compromise.
+type Indicator struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Normalize returns the canonical form of a value of the given type, the form stored
+// and matched: lowercase domains, emails and hashes, domains without trailing dot,
+// and addresses in their standard notation.
+func Normalize(typ, value string) string {
+	value = strings.TrimSpace(value)
+
+	switch typ {
+	case IP:
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+
+		return value
+	case Domain:
+		return strings.TrimSuffix(strings.ToLower(value), ".")
+	case Email, MD5, SHA1, SHA256:
+		return strings.ToLower(value)
+	default:
+		return value
+	}
+}
+
+// List holds the indicators matched in events.
+type List struct {
+	// FalsePositiveRate of the Bloom filter, 0.001 by default. Lower rates take more
+	// memory and reduce the exact verifications of values that don't match.
+	FalsePositiveRate float64
+	// Subdomains makes domain indicators match their subdomains as well.
+	Subdomains bool
+
+	mu       sync.RWMutex
+	snapshot *snapshot
+	loaded   time.Time
+}
+
+type snapshot struct {
+	filter *bloom
+	// values holds the sorted "type:value" keys of the indicators.
+	values []string
+}
+
+func key(typ, value string) string {
+	return typ + ":" + value
+}
+
+// NewList returns an empty list.
+func NewList() *List {
+	return &List{FalsePositiveRate: 0.001}
+}
+
+// Load replaces the indicators of the list.
+func (l *List) Load(indicators []Indicator) {
+	var values = make([]string, 0, len(indicators))
+	for _, ind := range indicators {
+		if ind.Value == "" {
+			continue
+		}
+
+		values = append(values, key(ind.Type, Normalize(ind.Type, ind.Value)))
+	}
+
+	sort.Strings(values)
+	values = compact(values)
+
+	rate := l.FalsePositiveRate
+	if rate <= 0 || rate >= 1 {
+		rate = 0.001
+	}
+
+	filter := newBloom(len(values), rate)
+	for _, v := range values {
+		filter.add(v)
+	}
+
+	l.mu.Lock()
+	l.snapshot = &snapshot{filter: filter, values: values}
+	l.loaded = time.Now()
+	l.mu.Unlock()
+}
+
+func compact(sorted []string) []string {
+	var out = sorted[:0]
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// Len returns the number of indicators in the list.
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.snapshot == nil {
+		return 0
+	}
+
+	return len(l.snapshot.values)
+}
+
+// Loaded returns when the indicators were last loaded.
+func (l *List) Loaded() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.loaded
+}
+
+// Contains reports whether the list has the indicator.
+func (l *List) Contains(typ, value string) bool {
+	l.mu.RLock()
+	s := l.snapshot
+	l.mu.RUnlock()
+
+	if s == nil {
+		return false
+	}
+
+	value = Normalize(typ, value)
+
+	if s.contains(key(typ, value)) {
+		return true
+	}
+
+	if typ == Domain && l.Subdomains {
+		for i := strings.IndexByte(value, '.'); i >= 0; i = strings.IndexByte(value, '.') {
+			value = value[i+1:]
+			if strings.Contains(value, ".") && s.contains(key(typ, value)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *snapshot) contains(k string) bool {
+	if !s.filter.mayContain(k) {
+		return false
+	}
+
+	i := sort.SearchStrings(s.values, k)
+
+	return i < len(s.values) && s.values[i] == k
+}
+
+// Match returns the indicators of the list found in the addresses, domains, URLs,
+// emails and hashes of the sides of e.
+func (l *List) Match(e *plugins.Event) []Indicator {
+	var matches []Indicator
+	var seen = make(map[string]bool)
+
+	check := func(typ, single string, values ...[]string) {
+		for _, v := range append([]string{single}, concat(values...)...) {
+			if v == "" || seen[key(typ, v)] {
+				continue
+			}
+
+			seen[key(typ, v)] = true
+
+			if l.Contains(typ, v) {
+				matches = append(matches, Indicator{Type: typ, Value: v})
+			}
+		}
+	}
+
+	for _, side := range []*plugins.Side{e.GetRemote(), e.GetLocal(), e.GetFrom(), e.GetTo()} {
+		if side == nil {
+			continue
+		}
+
+		check(IP, side.Ip, side.Ips)
+		check(Domain, side.Domain, side.Domains, []string{side.Fqdn}, side.Fqdns)
+		check(URL, side.Url, side.Urls)
+		check(Email, side.Email, side.Emails)
+		check(MD5, side.Md5, side.Md5S)
+		check(SHA1, side.Sha1, side.Sha1S)
+		check(SHA256, side.Sha256, side.Sha256S)
+	}
+
+	return matches
+}
+
+func concat(lists ...[]string) []string {
+	var out []string
+	for _, l := range lists {
+		out = append(out, l...)
+	}
+
+	return out
+}
+
+// Source provides the indicators of a list.
+type Source interface {
+	Fetch(ctx context.Context) ([]Indicator, error)
+}
+
+// Refresh loads the indicators of all sources. The list keeps its indicators if any
+// source fails.
+func (l *List) Refresh(ctx context.Context, sources ...Source) (int, error) {
+	var all []Indicator
+
+	for _, s := range sources {
+		indicators, err := s.Fetch(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		all = append(all, indicators...)
+	}
+
+	l.Load(all)
+
+	return l.Len(), nil
+}
+
+// Run refreshes the list every interval until ctx is done, passing each outcome to
+// report, which may be nil.
+func (l *List) Run(ctx context.Context, interval time.Duration, report func(int, error), sources ...Source) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := l.Refresh(ctx, sources...)
+		if report != nil {
+			report(n, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
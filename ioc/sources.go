@@ -0,0 +1,163 @@
+package ioc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/tidwall/gjson"
+)
+
+// IndexSource reads the indicators stored as documents of an index.
+type IndexSource struct {
+	Index string
+	// Query selects the documents, all of them when nil.
+	Query *opensearch.Query
+	// TypeField and ValueField are the paths of the type and value of the indicators
+	// in the documents, "type" and "value" by default. Type, when set, is the type of
+	// every indicator, ignoring TypeField.
+	TypeField  string
+	ValueField string
+	Type       string
+}
+
+// Fetch pages through the documents of the index.
+func (s IndexSource) Fetch(ctx context.Context) ([]Indicator, error) {
+	typeField, valueField := s.TypeField, s.ValueField
+	if typeField == "" {
+		typeField = "type"
+	}
+
+	if valueField == "" {
+		valueField = "value"
+	}
+
+	includes := []string{valueField}
+	if s.Type == "" {
+		includes = append(includes, typeField)
+	}
+
+	q := opensearch.SearchRequest{
+		Size:   1000,
+		Query:  s.Query,
+		Source: &opensearch.Source{Includes: includes},
+	}
+
+	var indicators []Indicator
+
+	p := opensearch.NewPaginator(q, []string{s.Index})
+
+	for {
+		page, err := p.NextPage(ctx)
+		if errors.Is(err, opensearch.ErrNoMorePages) {
+			return indicators, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("indicators of %s: %w", s.Index, err)
+		}
+
+		for _, hit := range page.Hits.Hits {
+			j, err := json.Marshal(hit.Source)
+			if err != nil {
+				return nil, err
+			}
+
+			typ := s.Type
+			if typ == "" {
+				typ = gjson.GetBytes(j, typeField).String()
+			}
+
+			value := gjson.GetBytes(j, valueField)
+			if value.IsArray() {
+				for _, v := range value.Array() {
+					indicators = append(indicators, Indicator{Type: typ, Value: v.String()})
+				}
+
+				continue
+			}
+
+			indicators = append(indicators, Indicator{Type: typ, Value: value.String()})
+		}
+
+		if len(page.Hits.Hits) == 0 {
+			return indicators, nil
+		}
+	}
+}
+
+// URLSource reads a plain text list of indicators, one per line. Empty lines and
+// lines starting with # are ignored.
+type URLSource struct {
+	URL string
+	// Type of the indicators. When empty, lines are "type,value" pairs.
+	Type string
+	// Header is added to the request, e.g. for authentication.
+	Header http.Header
+	// HTTPClient fetches the list, with a 60 seconds timeout by default.
+	HTTPClient *http.Client
+}
+
+// Fetch downloads the list.
+func (s URLSource) Fetch(ctx context.Context) ([]Indicator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("indicators of %s: status %d", s.URL, resp.StatusCode)
+	}
+
+	var indicators []Indicator
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if s.Type != "" {
+			indicators = append(indicators, Indicator{Type: s.Type, Value: text})
+			continue
+		}
+
+		typ, value, ok := strings.Cut(text, ",")
+		if !ok {
+			return nil, fmt.Errorf("indicators of %s: line %d is not a type,value pair", s.URL, line)
+		}
+
+		indicators = append(indicators, Indicator{Type: strings.TrimSpace(typ), Value: strings.TrimSpace(value)})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("indicators of %s: %w", s.URL, err)
+	}
+
+	return indicators, nil
+}
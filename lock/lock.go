@@ -0,0 +1,324 @@
+// Package lock implements lease-based locks on documents of an index, so that the
+// replicas of a service coordinate exclusive work, like rollovers. Leases expire after
+// a TTL unless renewed, and carry a fencing token, increasing with every acquisition,
+// that the protected resources can check to reject stale holders.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+var (
+	// ErrLocked is returned when the lock is held by another owner.
+	ErrLocked = errors.New("lock held by another owner")
+	// ErrLeaseLost is returned when a lease expired or was taken over.
+	ErrLeaseLost = errors.New("lease lost")
+)
+
+// Locker acquires locks stored in Index.
+type Locker struct {
+	Index string
+	// TTL of the leases. Leases must be renewed before they expire, and the TTL must
+	// exceed the clock skew between replicas.
+	TTL time.Duration
+	// Owner identifies the holder, unique per Locker by default.
+	Owner string
+}
+
+// New returns a Locker with a unique owner.
+func New(index string, ttl time.Duration) *Locker {
+	host, _ := os.Hostname()
+
+	return &Locker{Index: index, TTL: ttl, Owner: host + "/" + uuid.NewString()}
+}
+
+type document struct {
+	Owner   string    `json:"owner"`
+	Token   int64     `json:"token"`
+	Expires time.Time `json:"expires"`
+	Updated time.Time `json:"@timestamp"`
+}
+
+type version struct {
+	SeqNo       int64 `json:"_seq_no"`
+	PrimaryTerm int64 `json:"_primary_term"`
+}
+
+func (l *Locker) path(name string) string {
+	return "/" + l.Index + "/_doc/" + url.PathEscape(name)
+}
+
+func (l *Locker) read(ctx context.Context, name string) (document, version, bool, error) {
+	resp, err := opensearch.Do(ctx, http.MethodGet, l.path(name), nil, nil)
+
+	var statusErr *opensearch.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return document{}, version{}, false, nil
+	}
+
+	if err != nil {
+		return document{}, version{}, false, err
+	}
+
+	var doc struct {
+		version
+		Found  bool     `json:"found"`
+		Source document `json:"_source"`
+	}
+
+	err = json.Unmarshal(resp, &doc)
+	if err != nil {
+		return document{}, version{}, false, err
+	}
+
+	return doc.Source, doc.version, doc.Found, nil
+}
+
+// write stores doc if the lock document is still at v, or creates it when v is nil.
+// It returns ErrLocked on conflicts.
+func (l *Locker) write(ctx context.Context, name string, doc document, v *version) (version, error) {
+	path := l.path(name)
+	params := url.Values{}
+
+	if v == nil {
+		params.Set("op_type", "create")
+	} else {
+		params.Set("if_seq_no", strconv.FormatInt(v.SeqNo, 10))
+		params.Set("if_primary_term", strconv.FormatInt(v.PrimaryTerm, 10))
+	}
+
+	doc.Updated = time.Now().UTC()
+
+	resp, err := opensearch.Do(ctx, http.MethodPut, path, params, doc)
+
+	var statusErr *opensearch.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict {
+		return version{}, ErrLocked
+	}
+
+	if err != nil {
+		return version{}, err
+	}
+
+	var written version
+
+	return written, json.Unmarshal(resp, &written)
+}
+
+// Acquire acquires the lock name, if free or expired, or returns ErrLocked.
+func (l *Locker) Acquire(ctx context.Context, name string) (*Lease, error) {
+	current, v, found, err := l.read(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if found && current.Owner != l.Owner && now.Before(current.Expires) {
+		return nil, ErrLocked
+	}
+
+	doc := document{Owner: l.Owner, Token: current.Token + 1, Expires: now.Add(l.TTL).UTC()}
+
+	var expected *version
+	if found {
+		expected = &v
+	}
+
+	written, err := l.write(ctx, name, doc, expected)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Lease{locker: l, name: name, token: doc.Token, expires: doc.Expires, version: written}, nil
+}
+
+// AcquireWait retries Acquire every interval until it succeeds or ctx is done.
+func (l *Locker) AcquireWait(ctx context.Context, name string, interval time.Duration) (*Lease, error) {
+	for {
+		lease, err := l.Acquire(ctx, name)
+		if !errors.Is(err, ErrLocked) {
+			return lease, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Lease is a held lock.
+type Lease struct {
+	locker *Locker
+	name   string
+	token  int64
+
+	mu      sync.Mutex
+	expires time.Time
+	version version
+}
+
+// Name returns the name of the lock.
+func (l *Lease) Name() string {
+	return l.name
+}
+
+// Token returns the fencing token of the lease, greater than the tokens of all the
+// previous leases of the lock.
+func (l *Lease) Token() int64 {
+	return l.token
+}
+
+// Expires returns when the lease expires unless renewed.
+func (l *Lease) Expires() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.expires
+}
+
+// Renew extends the lease by the TTL of the Locker. It returns ErrLeaseLost if the
+// lease expired or the lock was acquired by another owner.
+func (l *Lease) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !time.Now().Before(l.expires) {
+		return ErrLeaseLost
+	}
+
+	expires := time.Now().Add(l.locker.TTL).UTC()
+
+	written, err := l.locker.write(ctx, l.name, document{Owner: l.locker.Owner, Token: l.token, Expires: expires}, &l.version)
+	if errors.Is(err, ErrLocked) {
+		return ErrLeaseLost
+	}
+
+	if err != nil {
+		return err
+	}
+
+	l.expires, l.version = expires, written
+
+	return nil
+}
+
+// Release frees the lock. The document is kept, expired, to preserve the fencing
+// token sequence.
+func (l *Lease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	_, err := l.locker.write(ctx, l.name, document{Owner: l.locker.Owner, Token: l.token, Expires: now}, &l.version)
+	if errors.Is(err, ErrLocked) {
+		return ErrLeaseLost
+	}
+
+	if err != nil {
+		return err
+	}
+
+	l.expires = now
+
+	return nil
+}
+
+// KeepAlive renews the lease every third of the TTL until ctx is done, then releases
+// it. The returned channel is closed when the lease is lost or released.
+func (l *Lease) KeepAlive(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(l.locker.TTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				release, cancel := context.WithTimeout(context.Background(), l.locker.TTL/3)
+				_ = l.Release(release)
+				cancel()
+
+				return
+			case <-ticker.C:
+				// Renewals aren't cancelled with ctx, which would leave the version of
+				// the lease unknown if the write went through, failing the release.
+				renew, cancel := context.WithTimeout(context.Background(), l.locker.TTL/3)
+				err := l.Renew(renew)
+				cancel()
+
+				if errors.Is(err, ErrLeaseLost) {
+					return
+				}
+
+				// Transient errors are retried on the next tick, while the lease
+				// is still valid.
+				if err != nil && !time.Now().Before(l.Expires()) {
+					return
+				}
+			}
+		}
+	}()
+
+	return done
+}
+
+// Lead runs fn while holding the lock name, acquiring it as soon as it is free. The
+// context of fn is cancelled when the lease is lost, after which Lead tries to
+// acquire the lock again, until ctx is done. Lead releases the lock and returns when
+// fn returns on its own.
+func (l *Locker) Lead(ctx context.Context, name string, fn func(ctx context.Context)) error {
+	for {
+		lease, err := l.AcquireWait(ctx, name, l.TTL/3)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("lock %s: %w", name, err)
+		}
+
+		leadCtx, cancel := context.WithCancel(ctx)
+		lost := lease.KeepAlive(leadCtx)
+
+		finished := make(chan struct{})
+
+		go func() {
+			defer close(finished)
+			fn(leadCtx)
+		}()
+
+		select {
+		case <-lost:
+			cancel()
+			<-finished
+		case <-finished:
+			cancel()
+			<-lost
+
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package misp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/threatwinds/go-sdk/plugins"
+)
+
+// DefaultVisibility maps the TLP levels to the visibility groups of the indicators:
+// clear and white indicators are public, green ones are shared with the community,
+// and amber and red ones are restricted to the importing tenant.
+var DefaultVisibility = map[string][]string{
+	"clear":        {"public"},
+	"white":        {"public"},
+	"green":        {"community"},
+	"amber":        {"tenant"},
+	"amber+strict": {"tenant"},
+	"red":          {"tenant"},
+}
+
+// Importer maps MISP events to indicators and sends them as logs of type "misp".
+type Importer struct {
+	// DataSource and TenantID are set in the emitted logs.
+	DataSource string
+	TenantID   string
+	// IDSOnly skips the attributes not flagged for detection.
+	IDSOnly bool
+	// Visibility maps TLP levels to visibility groups, DefaultVisibility when nil.
+	// Indicators without TLP tag are treated as DefaultTLP.
+	Visibility map[string][]string
+	DefaultTLP string
+	// Send emits a log, e.g. StreamSender of an Engine input stream.
+	Send func(*plugins.Log) error
+	// HTTPClient fetches feeds, with a 60 seconds timeout by default.
+	HTTPClient *http.Client
+}
+
+// StreamSender returns a Send function for an input stream of the engine.
+func StreamSender(stream plugins.Engine_InputClient) func(*plugins.Log) error {
+	return stream.Send
+}
+
+// Indicators returns the indicators of the attributes of e, including those grouped
+// in objects. The TLP of an attribute is its own TLP tag, or the one of the event.
+func (im *Importer) Indicators(e Event) []Indicator {
+	eventTags := tagNames(e.Tags)
+	eventTLP := tlp(eventTags)
+
+	var indicators []Indicator
+
+	attributes := e.Attributes
+	for _, o := range e.Objects {
+		attributes = append(attributes, o.Attributes...)
+	}
+
+	for _, a := range attributes {
+		if im.IDSOnly && !a.ToIDS {
+			continue
+		}
+
+		tags := append(tagNames(a.Tags), eventTags...)
+
+		level := tlp(tagNames(a.Tags))
+		if level == "" {
+			level = eventTLP
+		}
+
+		if level == "" {
+			level = im.DefaultTLP
+		}
+
+		updated := time.Time(a.Timestamp)
+		if updated.IsZero() {
+			updated = time.Time(e.Timestamp)
+		}
+
+		for _, pair := range split(a) {
+			indicators = append(indicators, Indicator{
+				Type:      pair[0],
+				Value:     pair[1],
+				MISPType:  a.Type,
+				Category:  a.Category,
+				Comment:   a.Comment,
+				ToIDS:     a.ToIDS,
+				Tags:      dedupe(tags),
+				TLP:       level,
+				VisibleBy: im.visibleBy(level),
+				EventUUID: e.UUID,
+				EventInfo: e.Info,
+				Org:       e.Orgc.Name,
+				Timestamp: updated,
+			})
+		}
+	}
+
+	return indicators
+}
+
+func (im *Importer) visibleBy(level string) []string {
+	visibility := im.Visibility
+	if visibility == nil {
+		visibility = DefaultVisibility
+	}
+
+	return visibility[level]
+}
+
+func tagNames(tags []Tag) []string {
+	var names = make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+
+	return names
+}
+
+// tlp returns the TLP level among tags, the most restrictive if there are several.
+func tlp(tags []string) string {
+	var rank = map[string]int{"clear": 1, "white": 1, "green": 2, "amber": 3, "amber+strict": 4, "red": 5}
+
+	var level string
+
+	for _, tag := range tags {
+		name := strings.ToLower(strings.TrimSpace(tag))
+		if !strings.HasPrefix(name, "tlp:") {
+			continue
+		}
+
+		name = strings.TrimPrefix(name, "tlp:")
+		if rank[name] > rank[level] {
+			level = name
+		}
+	}
+
+	return level
+}
+
+func dedupe(values []string) []string {
+	var seen = make(map[string]bool, len(values))
+	var out []string
+
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// Emit sends the indicators of e and returns how many were sent.
+func (im *Importer) Emit(e Event) (int, error) {
+	var sent int
+
+	for _, ind := range im.Indicators(e) {
+		raw, err := json.Marshal(ind)
+		if err != nil {
+			return sent, err
+		}
+
+		err = im.Send(&plugins.Log{
+			Id:         uuid.NewSHA1(uuid.NameSpaceURL, []byte(ind.EventUUID+"|"+ind.Type+"|"+ind.Value)).String(),
+			DataType:   "misp",
+			DataSource: im.DataSource,
+			Timestamp:  ind.Timestamp.Format(time.RFC3339Nano),
+			TenantId:   im.TenantID,
+			Raw:        string(raw),
+		})
+		if err != nil {
+			return sent, err
+		}
+
+		sent++
+	}
+
+	return sent, nil
+}
+
+// ManifestEntry describes an event of a feed manifest.
+type ManifestEntry struct {
+	UUID      string    `json:"-"`
+	Info      string    `json:"info"`
+	Timestamp Timestamp `json:"timestamp"`
+}
+
+// Manifest returns the events listed in the manifest.json of a MISP feed, sorted by
+// timestamp.
+func (im *Importer) Manifest(ctx context.Context, feedURL string) ([]ManifestEntry, error) {
+	var manifest map[string]ManifestEntry
+
+	err := im.get(ctx, strings.TrimSuffix(feedURL, "/")+"/manifest.json", &manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries = make([]ManifestEntry, 0, len(manifest))
+	for id, entry := range manifest {
+		entry.UUID = id
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return time.Time(entries[i].Timestamp).Before(time.Time(entries[j].Timestamp))
+	})
+
+	return entries, nil
+}
+
+// ImportFeed emits the indicators of the events of a feed updated after since, in
+// update order, and returns the timestamp of the last imported event, to pass as
+// since on the next import, and the number of indicators sent.
+func (im *Importer) ImportFeed(ctx context.Context, feedURL string, since time.Time) (time.Time, int, error) {
+	entries, err := im.Manifest(ctx, feedURL)
+	if err != nil {
+		return since, 0, err
+	}
+
+	var sent int
+
+	for _, entry := range entries {
+		updated := time.Time(entry.Timestamp)
+		if !updated.After(since) {
+			continue
+		}
+
+		var raw json.RawMessage
+
+		err = im.get(ctx, strings.TrimSuffix(feedURL, "/")+"/"+entry.UUID+".json", &raw)
+		if err != nil {
+			return since, sent, fmt.Errorf("event %s: %w", entry.UUID, err)
+		}
+
+		e, err := ParseEvent(raw)
+		if err != nil {
+			return since, sent, fmt.Errorf("event %s: %w", entry.UUID, err)
+		}
+
+		n, err := im.Emit(e)
+		sent += n
+
+		if err != nil {
+			return since, sent, err
+		}
+
+		since = updated
+	}
+
+	return since, sent, nil
+}
+
+func (im *Importer) get(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	client := im.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("feed status %d, response: %s", resp.StatusCode, body)
+	}
+
+	return json.Unmarshal(body, out)
+}
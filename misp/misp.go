@@ -0,0 +1,168 @@
+// Package misp imports MISP JSON feeds and events, mapping their attributes to
+// normalized indicators emitted as logs through the input channel of the engine.
+package misp
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a MISP event.
+type Event struct {
+	UUID          string      `json:"uuid"`
+	Info          string      `json:"info"`
+	Date          string      `json:"date"`
+	Timestamp     Timestamp   `json:"timestamp"`
+	ThreatLevelID string      `json:"threat_level_id"`
+	Analysis      string      `json:"analysis"`
+	Orgc          Org         `json:"Orgc"`
+	Tags          []Tag       `json:"Tag,omitempty"`
+	Attributes    []Attribute `json:"Attribute,omitempty"`
+	Objects       []Object    `json:"Object,omitempty"`
+}
+
+// Org is the organisation that created an event.
+type Org struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// Tag is a MISP tag, e.g. "tlp:amber".
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// Attribute is an observable of an event.
+type Attribute struct {
+	UUID      string    `json:"uuid"`
+	Type      string    `json:"type"`
+	Category  string    `json:"category"`
+	Value     string    `json:"value"`
+	Comment   string    `json:"comment,omitempty"`
+	ToIDS     bool      `json:"to_ids"`
+	Timestamp Timestamp `json:"timestamp"`
+	Tags      []Tag     `json:"Tag,omitempty"`
+}
+
+// Object groups related attributes of an event, e.g. the hashes and name of a file.
+type Object struct {
+	Name       string      `json:"name"`
+	Attributes []Attribute `json:"Attribute,omitempty"`
+}
+
+// Timestamp is a Unix time in seconds, encoded by MISP as a string.
+type Timestamp time.Time
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	seconds, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*t = Timestamp(time.Unix(seconds, 0).UTC())
+
+	return nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(time.Time(t).Unix(), 10))
+}
+
+// ParseEvent decodes a MISP event, either bare or wrapped as {"Event": {...}} like in
+// feeds and API responses.
+func ParseEvent(data []byte) (Event, error) {
+	var wrapped struct {
+		Event *Event `json:"Event"`
+	}
+
+	err := json.Unmarshal(data, &wrapped)
+	if err != nil {
+		return Event{}, err
+	}
+
+	if wrapped.Event != nil {
+		return *wrapped.Event, nil
+	}
+
+	var e Event
+
+	err = json.Unmarshal(data, &e)
+
+	return e, err
+}
+
+// Indicator is a normalized indicator from a MISP attribute.
+type Indicator struct {
+	// Type is the normalized type: ip, domain, url, email, md5, sha1, sha256, sha512,
+	// filename, port, or the MISP type when it has no normalized equivalent.
+	Type      string    `json:"type"`
+	Value     string    `json:"value"`
+	MISPType  string    `json:"mispType"`
+	Category  string    `json:"category"`
+	Comment   string    `json:"comment,omitempty"`
+	ToIDS     bool      `json:"toIds"`
+	Tags      []string  `json:"tags,omitempty"`
+	TLP       string    `json:"tlp,omitempty"`
+	VisibleBy []string  `json:"visibleBy,omitempty"`
+	EventUUID string    `json:"eventUuid"`
+	EventInfo string    `json:"eventInfo"`
+	Org       string    `json:"org,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// normalizedTypes maps MISP attribute types to normalized indicator types.
+var normalizedTypes = map[string]string{
+	"ip-src":              "ip",
+	"ip-dst":              "ip",
+	"domain":              "domain",
+	"hostname":            "domain",
+	"url":                 "url",
+	"link":                "url",
+	"uri":                 "url",
+	"email":               "email",
+	"email-src":           "email",
+	"email-dst":           "email",
+	"md5":                 "md5",
+	"sha1":                "sha1",
+	"sha256":              "sha256",
+	"sha512":              "sha512",
+	"filename":            "filename",
+	"port":                "port",
+	"ja3-fingerprint-md5": "ja3",
+}
+
+// split returns the normalized type and value pairs of an attribute. Composite MISP
+// types, like "domain|ip" or "filename|md5", yield an indicator per component.
+func split(a Attribute) [][2]string {
+	types := strings.Split(a.Type, "|")
+	values := strings.SplitN(a.Value, "|", len(types))
+
+	if len(types) != len(values) {
+		return [][2]string{{a.Type, a.Value}}
+	}
+
+	var pairs [][2]string
+
+	for i, t := range types {
+		// The port of "ip-src|port" qualifies the address, it isn't an indicator.
+		if t == "port" && len(types) > 1 {
+			continue
+		}
+
+		normalized, ok := normalizedTypes[t]
+		if !ok {
+			normalized = t
+		}
+
+		pairs = append(pairs, [2]string{normalized, values[i]})
+	}
+
+	return pairs
+}
@@ -0,0 +1,315 @@
+package opensearch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// IndexAccessError is returned when a request targets an index the process is not
+// allowed to access by SetIndexACL.
+type IndexAccessError struct {
+	Index string
+}
+
+func (e *IndexAccessError) Error() string {
+	return fmt.Sprintf("access to index %s is not allowed", e.Index)
+}
+
+var indexACL struct {
+	sync.RWMutex
+	allow []string
+	deny  []string
+}
+
+// SetIndexACL restricts the indices this process can search, read and write. Patterns
+// may contain "*" wildcards. When allow is not empty, every targeted index expression
+// must be covered by one of its patterns, and concrete index names must not match a
+// deny pattern. Searches with wildcard expressions reaching denied indices are not
+// rejected, the deny patterns are excluded from them instead. Calling SetIndexACL with
+// no patterns removes the restrictions.
+func SetIndexACL(allow, deny []string) {
+	indexACL.Lock()
+	defer indexACL.Unlock()
+
+	indexACL.allow = append([]string(nil), allow...)
+	indexACL.deny = append([]string(nil), deny...)
+}
+
+// CheckIndexAccess verifies index expressions against the ACL set by SetIndexACL as the
+// search and document functions, and Do, do. It returns the expressions to use, with
+// the exclusions of the denied indices their wildcards may reach, or an
+// *IndexAccessError.
+func CheckIndexAccess(index ...string) ([]string, error) {
+	return checkIndexAccess(index...)
+}
+
+// checkIndexAccess verifies the index expressions of a request against the ACL and
+// returns them with the exclusions of the deny patterns their wildcards may reach.
+func checkIndexAccess(index ...string) ([]string, error) {
+	indexACL.RLock()
+	defer indexACL.RUnlock()
+
+	if len(indexACL.allow) == 0 && len(indexACL.deny) == 0 {
+		return index, nil
+	}
+
+	var names []string
+	for _, expr := range index {
+		names = append(names, strings.Split(expr, ",")...)
+	}
+
+	if len(names) == 0 {
+		names = []string{"*"}
+	}
+
+	var excluded = make(map[string]bool)
+	var exclusions []string
+
+	// Expressions already checked hold their exclusions.
+	for _, name := range names {
+		if strings.HasPrefix(name, "-") {
+			excluded[name[1:]] = true
+		}
+	}
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "-") {
+			continue
+		}
+
+		if name == "_all" || name == "" {
+			name = "*"
+		}
+
+		if len(indexACL.allow) != 0 && !allowed(name) {
+			return nil, &IndexAccessError{Index: name}
+		}
+
+		for _, pattern := range indexACL.deny {
+			if !globOverlap(pattern, name) {
+				continue
+			}
+
+			if !strings.Contains(name, "*") {
+				return nil, &IndexAccessError{Index: name}
+			}
+
+			if !excluded[pattern] {
+				excluded[pattern] = true
+				exclusions = append(exclusions, "-"+pattern)
+			}
+		}
+	}
+
+	if len(exclusions) == 0 {
+		return index, nil
+	}
+
+	return append(names, exclusions...), nil
+}
+
+// allIndicesEndpoints are the cluster-level endpoints reaching every index, checked as
+// the "*" expression.
+var allIndicesEndpoints = map[string]bool{
+	"_search":        true,
+	"_count":         true,
+	"_mapping":       true,
+	"_settings":      true,
+	"_field_caps":    true,
+	"_validate":      true,
+	"_alias":         true,
+	"_refresh":       true,
+	"_flush":         true,
+	"_forcemerge":    true,
+	"_cache":         true,
+	"_stats":         true,
+	"_segments":      true,
+	"_search_shards": true,
+}
+
+// clusterSearchEndpoints are the endpoints under _search not targeting indices.
+var clusterSearchEndpoints = map[string]bool{
+	"pipeline":      true,
+	"scroll":        true,
+	"point_in_time": true,
+}
+
+// bodyTargetEndpoints are the endpoints whose indices are given in the body, or in a
+// query, where they can't be checked. They are only allowed when the ACL allows every
+// index; the functions of this package sending them check the indices beforehand.
+var bodyTargetEndpoints = map[string]bool{
+	"_bulk":                         true,
+	"_mget":                         true,
+	"_msearch":                      true,
+	"_reindex":                      true,
+	"_aliases":                      true,
+	"_sql":                          true,
+	"_plugins/_sql":                 true,
+	"_plugins/_ppl":                 true,
+	"_plugins/_transform":           true,
+	"_plugins/_rollup":              true,
+	"_plugins/_ism":                 true,
+	"_plugins/_anomaly_detection":   true,
+	"_plugins/_asynchronous_search": true,
+	"_opendistro/_sql":              true,
+	"_opendistro/_ppl":              true,
+}
+
+// checkPathAccess verifies the indices targeted by the path of a request sent with Do
+// against the ACL, and returns the path with the exclusions of the denied indices its
+// wildcards may reach. Cluster-level paths, starting with "_", are allowed, except
+// those reaching every index or naming their indices in the body.
+func checkPathAccess(path string) (string, error) {
+	indexACL.RLock()
+	restricted := len(indexACL.allow) != 0 || len(indexACL.deny) != 0
+	indexACL.RUnlock()
+
+	if !restricted {
+		return path, nil
+	}
+
+	rest, query, _ := strings.Cut(strings.TrimPrefix(path, "/"), "?")
+	segments := strings.Split(rest, "/")
+
+	first, err := url.PathUnescape(segments[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid path %s: %w", path, err)
+	}
+
+	var second string
+	if len(segments) > 1 {
+		second = segments[1]
+	}
+
+	switch {
+	case first == "":
+		return path, nil
+	case bodyTargetEndpoints[first] || bodyTargetEndpoints[first+"/"+second]:
+		checked, err := checkIndexAccess("*")
+		if err != nil || len(checked) != 1 {
+			return "", &IndexAccessError{Index: "*"}
+		}
+
+		return path, nil
+	case first == "_search" && clusterSearchEndpoints[second]:
+		return path, nil
+	case allIndicesEndpoints[first]:
+		checked, err := checkIndexAccess("*")
+		if err != nil {
+			return "", err
+		}
+
+		if len(checked) == 1 {
+			return path, nil
+		}
+
+		return "/" + strings.Join(checked, ",") + "/" + rest + queryPart(query), nil
+	case strings.HasPrefix(first, "_") && first != "_all":
+		return path, nil
+	}
+
+	index := strings.Split(first, ",")
+
+	checked, err := checkIndexAccess(index...)
+	if err != nil {
+		return "", err
+	}
+
+	if len(checked) == len(index) {
+		return path, nil
+	}
+
+	segments[0] = strings.Join(checked, ",")
+
+	return "/" + strings.Join(segments, "/") + queryPart(query), nil
+}
+
+func queryPart(query string) string {
+	if query == "" {
+		return ""
+	}
+
+	return "?" + query
+}
+
+// allowed reports whether name is covered by an allow pattern. Wildcards in name are
+// matched literally, so "logs-*" is covered by "logs-*" or "*" but not by "logs-2024*".
+// The caller must hold indexACL.
+func allowed(name string) bool {
+	for _, pattern := range indexACL.allow {
+		if globMatch(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// globOverlap reports whether some index name matches both patterns, where "*"
+// matches any sequence of characters.
+func globOverlap(a, b string) bool {
+	// seen[i][j] is set once a[i:] and b[j:] were found not to overlap.
+	var seen = make([][]bool, len(a)+1)
+	for i := range seen {
+		seen[i] = make([]bool, len(b)+1)
+	}
+
+	var overlap func(i, j int) bool
+	overlap = func(i, j int) bool {
+		if seen[i][j] {
+			return false
+		}
+
+		var ok bool
+
+		switch {
+		case i == len(a) && j == len(b):
+			ok = true
+		case i < len(a) && a[i] == '*':
+			ok = overlap(i+1, j) || (j < len(b) && overlap(i, j+1))
+		case j < len(b) && b[j] == '*':
+			ok = overlap(i, j+1) || (i < len(a) && overlap(i+1, j))
+		case i < len(a) && j < len(b):
+			ok = a[i] == b[j] && overlap(i+1, j+1)
+		}
+
+		if !ok {
+			seen[i][j] = true
+		}
+
+		return ok
+	}
+
+	return overlap(0, 0)
+}
+
+// globMatch reports whether name matches pattern, where "*" in pattern matches any
+// sequence of characters and "*" in name is a literal character.
+func globMatch(pattern, name string) bool {
+	var p, n, star, mark = 0, 0, -1, 0
+
+	for n < len(name) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, n
+			p++
+		case p < len(pattern) && pattern[p] == name[n]:
+			p++
+			n++
+		case star != -1:
+			p = star + 1
+			mark++
+			n = mark
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
@@ -0,0 +1,220 @@
+package opensearch_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/threatwinds/go-sdk/opensearch/ostest"
+)
+
+var srv *ostest.Server
+
+func TestMain(m *testing.M) {
+	srv = ostest.NewServer()
+	if err := srv.Connect(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	srv.Close()
+	os.Exit(code)
+}
+
+func seed(t *testing.T) {
+	t.Helper()
+
+	srv.Reset()
+
+	docs := []struct {
+		index, id, tenant string
+	}{
+		{"logs-a", "1", "t1"},
+		{"logs-a", "2", "t2"},
+		{"logs-secret", "3", "t1"},
+		{"other", "4", "t1"},
+	}
+
+	for _, doc := range docs {
+		if err := srv.Put(doc.index, doc.id, map[string]interface{}{"tenantId": doc.tenant}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func setACL(t *testing.T, allow, deny []string) {
+	t.Helper()
+
+	opensearch.SetIndexACL(allow, deny)
+	t.Cleanup(func() { opensearch.SetIndexACL(nil, nil) })
+}
+
+func hitIDs(r opensearch.SearchResult) string {
+	var ids []string
+	for _, hit := range r.Hits.Hits {
+		ids = append(ids, hit.ID)
+	}
+
+	return strings.Join(ids, ",")
+}
+
+func isAccessError(err error) bool {
+	var accessErr *opensearch.IndexAccessError
+
+	return errors.As(err, &accessErr)
+}
+
+func TestCheckIndexAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		index   []string
+		want    string
+		wantErr bool
+	}{
+		{name: "no ACL", index: []string{"anything"}, want: "anything"},
+		{name: "allowed name", allow: []string{"logs-*"}, index: []string{"logs-a"}, want: "logs-a"},
+		{name: "name not allowed", allow: []string{"logs-*"}, index: []string{"other"}, wantErr: true},
+		{name: "one of several not allowed", allow: []string{"logs-*"}, index: []string{"logs-a,other"}, wantErr: true},
+		{name: "wildcard beyond allow", allow: []string{"logs-*"}, index: []string{"*"}, wantErr: true},
+		{name: "no index means all", allow: []string{"logs-*"}, wantErr: true},
+		{name: "denied name", allow: []string{"logs-*"}, deny: []string{"logs-secret"}, index: []string{"logs-secret"}, wantErr: true},
+		{name: "wildcard excludes denied", allow: []string{"logs-*"}, deny: []string{"logs-secret"}, index: []string{"logs-*"}, want: "logs-*,-logs-secret"},
+		{name: "deny only", deny: []string{"logs-secret"}, index: []string{"_all"}, want: "_all,-logs-secret"},
+		{name: "wildcard not reaching denied", deny: []string{"logs-secret"}, index: []string{"other*"}, want: "other*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setACL(t, tt.allow, tt.deny)
+
+			got, err := opensearch.CheckIndexAccess(tt.index...)
+			if tt.wantErr {
+				if !isAccessError(err) {
+					t.Fatalf("CheckIndexAccess() error = %v, want an *IndexAccessError", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("CheckIndexAccess() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchAccess(t *testing.T) {
+	seed(t)
+	setACL(t, []string{"logs-*"}, []string{"logs-secret"})
+
+	tests := []struct {
+		name    string
+		index   []string
+		want    string
+		wantErr bool
+	}{
+		{name: "allowed index", index: []string{"logs-a"}, want: "1,2"},
+		{name: "wildcard skips denied", index: []string{"logs-*"}, want: "1,2"},
+		{name: "denied index", index: []string{"logs-secret"}, wantErr: true},
+		{name: "index not allowed", index: []string{"other"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := opensearch.SearchRequest{Size: 10}.SearchIn(context.Background(), tt.index)
+			if tt.wantErr {
+				if !isAccessError(err) {
+					t.Fatalf("SearchIn() error = %v, want an *IndexAccessError", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := hitIDs(result); got != tt.want {
+				t.Errorf("SearchIn() hits = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentAccess(t *testing.T) {
+	seed(t)
+	setACL(t, []string{"logs-*"}, []string{"logs-secret"})
+
+	ctx := context.Background()
+
+	get := func(index, id string) func() error {
+		return func() error {
+			_, err := opensearch.GetDoc(ctx, index, id)
+			return err
+		}
+	}
+
+	put := func(index, id string) func() error {
+		return func() error {
+			return opensearch.IndexDoc(ctx, map[string]interface{}{"n": 1}, index, id)
+		}
+	}
+
+	do := func(method, path string) func() error {
+		return func() error {
+			_, err := opensearch.Do(ctx, method, path, nil, nil)
+			return err
+		}
+	}
+
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr bool
+	}{
+		{name: "get allowed", call: get("logs-a", "1")},
+		{name: "get denied", call: get("logs-secret", "3"), wantErr: true},
+		{name: "index allowed", call: put("logs-a", "5")},
+		{name: "index not allowed", call: put("other", "5"), wantErr: true},
+		{name: "bulk create not allowed", call: func() error {
+			return opensearch.BulkCreate(ctx, "other", []interface{}{map[string]interface{}{"n": 1}})
+		}, wantErr: true},
+		{name: "bulk update not allowed", call: func() error {
+			return opensearch.BulkUpdate(ctx, "other", []opensearch.DocUpdate{{ID: "4", Body: map[string]interface{}{"doc": map[string]interface{}{"n": 1}}}})
+		}, wantErr: true},
+		{name: "raw request allowed", call: do(http.MethodGet, "/logs-a/_doc/1")},
+		{name: "raw request denied", call: do(http.MethodGet, "/logs-secret/_doc/3"), wantErr: true},
+		{name: "raw request on every index", call: do(http.MethodPost, "/_search"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if tt.wantErr {
+				if !isAccessError(err) {
+					t.Fatalf("error = %v, want an *IndexAccessError", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	if _, ok := srv.Get("other", "5"); ok {
+		t.Error("a document was indexed in an index not allowed")
+	}
+}
@@ -0,0 +1,15 @@
+package opensearch
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateIndex creates an index. Body holds its settings, mappings and aliases, e.g.
+// {"settings": {...}, "mappings": {"properties": {...}}}, and may be nil.
+func CreateIndex(ctx context.Context, name string, body interface{}) error {
+	_, err := Do(ctx, http.MethodPut, "/"+url.PathEscape(name), nil, body)
+
+	return err
+}
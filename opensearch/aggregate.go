@@ -0,0 +1,202 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNoAggregations is returned by AggregateIn for requests without aggregations.
+var ErrNoAggregations = errors.New("search request has no aggregations")
+
+// Aggregations are the aggregations of a response, keyed by name.
+type Aggregations map[string]interface{}
+
+// AggregationResult is the response of AggregateIn.
+type AggregationResult struct {
+	Took         int64
+	TimedOut     bool
+	Shards       Shards
+	Total        Total
+	Aggregations Aggregations
+}
+
+// AggregateIn runs the aggregations of the request without returning hits, whatever
+// its size, e.g. for dashboards only needing buckets. It returns ErrNoAggregations
+// when the request has none.
+func (q SearchRequest) AggregateIn(ctx context.Context, index []string) (AggregationResult, error) {
+	if len(q.Aggs) == 0 {
+		return AggregationResult{}, ErrNoAggregations
+	}
+
+	q.Size = 0
+	q.From = 0
+	q.SearchAfter = nil
+	q.SearchAfterValues = nil
+
+	result, err := q.SearchIn(ctx, index)
+	if err != nil {
+		return AggregationResult{}, err
+	}
+
+	return AggregationResult{
+		Took:         result.Took,
+		TimedOut:     result.TimedOut,
+		Shards:       result.Shards,
+		Total:        result.Hits.Total,
+		Aggregations: result.Aggregations,
+	}, nil
+}
+
+// Bucket is a bucket of a multi-bucket aggregation, like terms or date_histogram.
+type Bucket struct {
+	Key         interface{}
+	KeyAsString string
+	DocCount    int64
+	// Score and BgCount are the significance score and the background document count
+	// of the buckets of significant terms.
+	Score   float64
+	BgCount int64
+	// DocCountErrorUpperBound is the maximum number of documents of the term the
+	// count may miss, returned by terms aggregations with ShowTermDocCountError.
+	DocCountErrorUpperBound int64
+	// Aggregations are the sub-aggregations of the bucket.
+	Aggregations Aggregations
+}
+
+// Get returns the aggregation at path, whose ">" separated names select nested
+// aggregations, e.g. "errors>per_hour".
+func (a Aggregations) Get(path string) (map[string]interface{}, error) {
+	var agg interface{} = map[string]interface{}(a)
+
+	for _, part := range strings.Split(path, ">") {
+		m, ok := agg.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s not found in the response", path)
+		}
+
+		agg, ok = m[part]
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s not found in the response", path)
+		}
+	}
+
+	m, ok := agg.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("aggregation %s not found in the response", path)
+	}
+
+	return m, nil
+}
+
+// Value returns the value of the single-value metric aggregation at path, like a sum
+// or a cardinality. Metrics of empty buckets have no value.
+func (a Aggregations) Value(path string) (float64, bool) {
+	m, err := a.Get(path)
+	if err != nil {
+		return 0, false
+	}
+
+	v, ok := m["value"].(float64)
+
+	return v, ok
+}
+
+// Buckets returns the buckets of the multi-bucket aggregation at path. Keyed buckets,
+// like those of filters aggregations, are returned with their key.
+func (a Aggregations) Buckets(path string) ([]Bucket, error) {
+	m, err := a.Get(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []interface{}
+
+	// Keyed buckets, sorted by key.
+	var keys []string
+
+	switch b := m["buckets"].(type) {
+	case []interface{}:
+		raw = b
+	case map[string]interface{}:
+		for key := range b {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			raw = append(raw, b[key])
+		}
+	default:
+		return nil, fmt.Errorf("aggregation %s has no buckets", path)
+	}
+
+	var buckets = make([]Bucket, 0, len(raw))
+
+	for i, r := range raw {
+		bm, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s has an invalid bucket", path)
+		}
+
+		bucket := Bucket{Key: bm["key"], Aggregations: make(Aggregations)}
+		if keys != nil {
+			bucket.Key = keys[i]
+		}
+
+		bucket.KeyAsString, _ = bm["key_as_string"].(string)
+
+		count, _ := bm["doc_count"].(float64)
+		bucket.DocCount = int64(count)
+
+		bucket.Score, _ = bm["score"].(float64)
+
+		bgCount, _ := bm["bg_count"].(float64)
+		bucket.BgCount = int64(bgCount)
+
+		countError, _ := bm["doc_count_error_upper_bound"].(float64)
+		bucket.DocCountErrorUpperBound = int64(countError)
+
+		for k, v := range bm {
+			switch k {
+			case "key", "key_as_string", "doc_count", "score", "bg_count", "doc_count_error_upper_bound":
+			default:
+				bucket.Aggregations[k] = v
+			}
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
+
+// TermsCounts are the counts of the documents of a terms aggregation not in its
+// buckets, telling how approximate the top terms are.
+type TermsCounts struct {
+	// DocCountErrorUpperBound is the maximum number of documents of a term missing
+	// from the buckets, the counts of terms being approximate when it isn't zero.
+	DocCountErrorUpperBound int64
+	// SumOtherDocCount is the number of documents of the terms not returned.
+	SumOtherDocCount int64
+}
+
+// TermsCounts returns the counts of the terms aggregation at path.
+func (a Aggregations) TermsCounts(path string) (TermsCounts, error) {
+	m, err := a.Get(path)
+	if err != nil {
+		return TermsCounts{}, err
+	}
+
+	if _, ok := m["buckets"]; !ok {
+		return TermsCounts{}, fmt.Errorf("aggregation %s has no buckets", path)
+	}
+
+	countError, _ := m["doc_count_error_upper_bound"].(float64)
+	other, _ := m["sum_other_doc_count"].(float64)
+
+	return TermsCounts{DocCountErrorUpperBound: int64(countError), SumOtherDocCount: int64(other)}, nil
+}
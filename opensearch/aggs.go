@@ -0,0 +1,37 @@
+package opensearch
+
+import "encoding/json"
+
+// FilterAgg returns a filter aggregation computing the given sub-aggregations only on
+// the documents matching filter. Combined with SearchRequest.PostFilter, it allows
+// faceted searches where each facet is narrowed independently of the returned hits.
+func FilterAgg(filter Query, aggs map[string]Aggs) (Aggs, error) {
+	j, err := json.Marshal(filter)
+	if err != nil {
+		return Aggs{}, err
+	}
+
+	var f map[string]interface{}
+
+	err = json.Unmarshal(j, &f)
+	if err != nil {
+		return Aggs{}, err
+	}
+
+	return Aggs{Filter: f, Aggs: aggs}, nil
+}
+
+// MarshalJSON encodes the terms aggregation, with a min_doc_count of zero when
+// ZeroMinDocCount is set.
+func (t Terms) MarshalJSON() ([]byte, error) {
+	type plain Terms
+
+	if !t.ZeroMinDocCount {
+		return json.Marshal(plain(t))
+	}
+
+	return json.Marshal(struct {
+		plain
+		MinDocCount int64 `json:"min_doc_count"`
+	}{plain: plain(t)})
+}
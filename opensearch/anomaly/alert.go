@@ -0,0 +1,106 @@
+package anomaly
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/threatwinds/go-sdk/plugins"
+)
+
+// AlertOptions complete the alerts converted from results.
+type AlertOptions struct {
+	// DetectorName names the alerts, the detector ID by default.
+	DetectorName string
+	DataSource   string
+	TenantID     string
+	TenantName   string
+	// Category of the alerts, "Anomaly" by default.
+	Category string
+	// EntityFields maps the category fields of the detector to the attributes of the
+	// target of the alerts: "ip", "host", "user", "group", "domain", "fqdn", "mac",
+	// "process", "file" or "path". Fields not mapped use the last segment of their
+	// name, e.g. "origin.ip" sets the IP, and are ignored when it isn't an attribute.
+	EntityFields map[string]string
+}
+
+// Alert converts the result into an alert, whose ID is derived from the detector, the
+// bucket and the entity, so that converting a result twice yields the same alert.
+func (r Result) Alert(o AlertOptions) *plugins.Alert {
+	name := o.DetectorName
+	if name == "" {
+		name = r.DetectorID
+	}
+
+	category := o.Category
+	if category == "" {
+		category = "Anomaly"
+	}
+
+	key := []string{r.DetectorID, r.Start().Format(time.RFC3339Nano)}
+	for _, e := range r.Entity {
+		key = append(key, e.Name+"="+e.Value)
+	}
+
+	target := &plugins.Side{}
+	for _, e := range r.Entity {
+		attribute, ok := o.EntityFields[e.Name]
+		if !ok {
+			attribute = e.Name[strings.LastIndex(e.Name, ".")+1:]
+		}
+
+		setAttribute(target, strings.ToLower(attribute), e.Value)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	return &plugins.Alert{
+		Id:          uuid.NewSHA1(uuid.NameSpaceURL, []byte("anomaly:"+strings.Join(key, "\x00"))).String(),
+		Timestamp:   r.End().Format(time.RFC3339Nano),
+		LastUpdate:  now,
+		Name:        "Anomaly detected by " + name,
+		TenantId:    o.TenantID,
+		TenantName:  o.TenantName,
+		DataSource:  o.DataSource,
+		DataType:    "anomaly",
+		Category:    category,
+		Description: r.describe(name),
+		Severity:    r.Severity(),
+		Target:      target,
+	}
+}
+
+func setAttribute(side *plugins.Side, attribute, value string) {
+	switch attribute {
+	case "ip":
+		side.Ip = value
+	case "host":
+		side.Host = value
+	case "user":
+		side.User = value
+	case "group":
+		side.Group = value
+	case "domain":
+		side.Domain = value
+	case "fqdn":
+		side.Fqdn = value
+	case "mac":
+		side.Mac = value
+	case "process":
+		side.Process = value
+	case "file":
+		side.File = value
+	case "path":
+		side.Path = value
+	}
+}
+
+// Alerts converts the results into alerts.
+func Alerts(results []Result, o AlertOptions) []*plugins.Alert {
+	var alerts = make([]*plugins.Alert, len(results))
+	for i, r := range results {
+		alerts[i] = r.Alert(o)
+	}
+
+	return alerts
+}
@@ -0,0 +1,315 @@
+// Package anomaly is a client of the Anomaly Detection plugin. It creates and runs
+// detectors, reads their results, and converts anomalies into plugin alerts for
+// correlation with the rest of the alerts.
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+const detectorsPath = "/_plugins/_anomaly_detection/detectors"
+
+// Feature is a metric a detector models, computed by a single-value aggregation, e.g.
+// a sum or a cardinality.
+type Feature struct {
+	Name        string
+	Aggregation opensearch.Aggs
+	// Disabled features are kept in the detector without being modeled.
+	Disabled bool
+}
+
+// Detector is an anomaly detector.
+type Detector struct {
+	Name        string
+	Description string
+	Indices     []string
+	// TimeField is the date field of the documents, "@timestamp" by default.
+	TimeField string
+	Features  []Feature
+	// Filter selects the documents modeled, all by default.
+	Filter *opensearch.Query
+	// Interval is the size of the buckets modeled, ten minutes by default, in whole
+	// minutes.
+	Interval time.Duration
+	// WindowDelay waits for late documents before modeling a bucket.
+	WindowDelay time.Duration
+	// CategoryFields split the detector into one model per entity, e.g. per host.
+	CategoryFields []string
+	// ShingleSize is the number of buckets modeled together, 8 by default.
+	ShingleSize int
+	// ResultIndex stores the results in a custom index, whose name must start with
+	// "opensearch-ad-plugin-result-", instead of the default one.
+	ResultIndex string
+}
+
+func period(d time.Duration, def int64) map[string]interface{} {
+	minutes := int64(d / time.Minute)
+	if d <= 0 {
+		minutes = def
+	} else if minutes < 1 {
+		minutes = 1
+	}
+
+	return map[string]interface{}{"period": map[string]interface{}{"interval": minutes, "unit": "Minutes"}}
+}
+
+// Body returns the detector as expected by the Anomaly Detection API.
+func Body(d Detector) map[string]interface{} {
+	timeField := d.TimeField
+	if timeField == "" {
+		timeField = "@timestamp"
+	}
+
+	var features = make([]map[string]interface{}, len(d.Features))
+	for i, f := range d.Features {
+		features[i] = map[string]interface{}{
+			"feature_name":      f.Name,
+			"feature_enabled":   !f.Disabled,
+			"aggregation_query": map[string]opensearch.Aggs{f.Name: f.Aggregation},
+		}
+	}
+
+	var filter interface{} = map[string]interface{}{"match_all": map[string]interface{}{}}
+	if d.Filter != nil {
+		filter = d.Filter
+	}
+
+	body := map[string]interface{}{
+		"name":               d.Name,
+		"description":        d.Description,
+		"time_field":         timeField,
+		"indices":            d.Indices,
+		"feature_attributes": features,
+		"filter_query":       filter,
+		"detection_interval": period(d.Interval, 10),
+		"window_delay":       period(d.WindowDelay, 0),
+	}
+
+	if len(d.CategoryFields) != 0 {
+		body["category_field"] = d.CategoryFields
+	}
+
+	if d.ShingleSize != 0 {
+		body["shingle_size"] = d.ShingleSize
+	}
+
+	if d.ResultIndex != "" {
+		body["result_index"] = d.ResultIndex
+	}
+
+	return body
+}
+
+// Create creates the detector and returns its ID.
+func Create(ctx context.Context, d Detector) (string, error) {
+	if d.Name == "" || len(d.Indices) == 0 || len(d.Features) == 0 {
+		return "", fmt.Errorf("detector requires name, indices and features")
+	}
+
+	resp, err := opensearch.Do(ctx, http.MethodPost, detectorsPath, nil, Body(d))
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		ID string `json:"_id"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return "", err
+	}
+
+	return result.ID, nil
+}
+
+// Start starts the real-time detection of the detector.
+func Start(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodPost, detectorsPath+"/"+url.PathEscape(id)+"/_start", nil, nil)
+
+	return err
+}
+
+// Stop stops the real-time detection of the detector.
+func Stop(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodPost, detectorsPath+"/"+url.PathEscape(id)+"/_stop", nil, nil)
+
+	return err
+}
+
+// Delete deletes the detector, which must be stopped. Its results are kept.
+func Delete(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodDelete, detectorsPath+"/"+url.PathEscape(id), nil, nil)
+
+	return err
+}
+
+// FeatureValue is the value of a feature in a result.
+type FeatureValue struct {
+	ID    string  `json:"feature_id"`
+	Name  string  `json:"feature_name"`
+	Value float64 `json:"data"`
+}
+
+// EntityValue is the value of a category field of a result.
+type EntityValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Result is the outcome of the detection of a bucket. Anomalies have a grade above
+// zero.
+type Result struct {
+	ID         string         `json:"-"`
+	DetectorID string         `json:"detector_id"`
+	Grade      float64        `json:"anomaly_grade"`
+	Confidence float64        `json:"confidence"`
+	Score      float64        `json:"anomaly_score"`
+	DataStart  int64          `json:"data_start_time"`
+	DataEnd    int64          `json:"data_end_time"`
+	Features   []FeatureValue `json:"feature_data"`
+	Entity     []EntityValue  `json:"entity"`
+	Error      string         `json:"error"`
+}
+
+// Start returns the start of the bucket of the result.
+func (r Result) Start() time.Time {
+	return time.UnixMilli(r.DataStart).UTC()
+}
+
+// End returns the end of the bucket of the result.
+func (r Result) End() time.Time {
+	return time.UnixMilli(r.DataEnd).UTC()
+}
+
+// ResultsQuery selects the results of Results.
+type ResultsQuery struct {
+	DetectorID string
+	// Since and Until bound the end of the buckets of the results, unbounded when
+	// zero.
+	Since time.Time
+	Until time.Time
+	// MinGrade filters out results graded lower, 0 returning every anomaly. Results
+	// without anomalies are never returned.
+	MinGrade float64
+	// Size is the maximum number of results, 100 by default, the latest first.
+	Size int64
+	// ResultIndex is the custom result index of the detector, if any.
+	ResultIndex string
+}
+
+// Results returns the anomalies of a detector.
+func Results(ctx context.Context, q ResultsQuery) ([]Result, error) {
+	size := q.Size
+	if size <= 0 {
+		size = 100
+	}
+
+	grade := map[string]interface{}{"gt": q.MinGrade}
+	if q.MinGrade > 0 {
+		grade = map[string]interface{}{"gte": q.MinGrade}
+	}
+
+	var filter = []opensearch.Query{
+		opensearch.TermQuery("detector_id", q.DetectorID, false),
+		{Range: map[string]map[string]interface{}{"anomaly_grade": grade}},
+	}
+
+	end := map[string]interface{}{}
+	if !q.Since.IsZero() {
+		end["gte"] = q.Since.UnixMilli()
+	}
+
+	if !q.Until.IsZero() {
+		end["lt"] = q.Until.UnixMilli()
+	}
+
+	if len(end) != 0 {
+		filter = append(filter, opensearch.Query{Range: map[string]map[string]interface{}{"data_end_time": end}})
+	}
+
+	req := opensearch.SearchRequest{
+		Size:  size,
+		Sort:  []map[string]map[string]interface{}{{"data_end_time": {"order": "desc"}}},
+		Query: &opensearch.Query{Bool: &opensearch.Bool{Filter: filter}},
+	}
+
+	path := detectorsPath + "/results/_search"
+	if q.ResultIndex != "" {
+		path += "/" + url.PathEscape(q.ResultIndex)
+	}
+
+	resp, err := opensearch.Do(ctx, http.MethodPost, path, nil, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID     string `json:"_id"`
+				Source Result `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	var results = make([]Result, len(result.Hits.Hits))
+	for i, hit := range result.Hits.Hits {
+		results[i] = hit.Source
+		results[i].ID = hit.ID
+	}
+
+	return results, nil
+}
+
+// Severity returns "high" for anomalies graded 0.7 or more, "medium" for 0.4 or more,
+// and "low" otherwise.
+func (r Result) Severity() string {
+	switch {
+	case r.Grade >= 0.7:
+		return "high"
+	case r.Grade >= 0.4:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// describe lists the feature values of the result.
+func (r Result) describe(detector string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Anomaly graded %.2f with confidence %.2f detected by %s between %s and %s",
+		r.Grade, r.Confidence, detector, r.Start().Format(time.RFC3339), r.End().Format(time.RFC3339))
+
+	for i, e := range r.Entity {
+		if i == 0 {
+			b.WriteString(" for ")
+		} else {
+			b.WriteString(", ")
+		}
+
+		fmt.Fprintf(&b, "%s %s", e.Name, e.Value)
+	}
+
+	b.WriteString(".")
+
+	for _, f := range r.Features {
+		fmt.Fprintf(&b, " %s: %g.", f.Name, f.Value)
+	}
+
+	return b.String()
+}
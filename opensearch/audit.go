@@ -0,0 +1,197 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditEntry records a search or a mutation executed through this package.
+type AuditEntry struct {
+	Time    time.Time `json:"@timestamp"`
+	Process string    `json:"process"`
+	Host    string    `json:"host,omitempty"`
+	Tenant  string    `json:"tenant,omitempty"`
+	User    string    `json:"user,omitempty"`
+	Action  string    `json:"action"`
+	Index   []string  `json:"index"`
+	DocID   string    `json:"docId,omitempty"`
+	Query   *Query    `json:"query,omitempty"`
+	// Statement is the query of SQL and PPL queries.
+	Statement string `json:"statement,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AuditActor identifies who executes the operations of a context.
+type AuditActor struct {
+	// User is the user claim of the caller, e.g. the subject of its token.
+	User   string
+	Tenant string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a copy of ctx whose searches and mutations are recorded as
+// executed by actor. The tenant of searches defaults to their tenant scope.
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditConfig configures the audit log enabled by EnableAudit.
+type AuditConfig struct {
+	// Index is the index receiving the audit entries.
+	Index string
+	// Buffer is the number of entries waiting to be written before new ones are
+	// dropped, 1000 by default.
+	Buffer int
+	// OnError, if set, is called when entries can't be written or are dropped.
+	OnError func(err error, entries []AuditEntry)
+}
+
+const auditBatch = 500
+
+var audit = struct {
+	sync.RWMutex
+	cfg     AuditConfig
+	entries chan AuditEntry
+	done    chan struct{}
+}{}
+
+var auditProcess, auditHost = func() (string, string) {
+	host, _ := os.Hostname()
+	return filepath.Base(os.Args[0]), host
+}()
+
+// EnableAudit records every search, multi-search, SQL and PPL query, index, update,
+// upsert and delete executed through this package in cfg.Index. Entries are written asynchronously in
+// bulk, so auditing doesn't slow the operations down; when the buffer is full new
+// entries are dropped and reported to cfg.OnError. Calling it again replaces the
+// previous configuration after flushing its entries.
+func EnableAudit(cfg AuditConfig) {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 1000
+	}
+
+	DisableAudit()
+
+	audit.Lock()
+	defer audit.Unlock()
+
+	audit.cfg = cfg
+	audit.entries = make(chan AuditEntry, cfg.Buffer)
+	audit.done = make(chan struct{})
+
+	go writeAudit(cfg, audit.entries, audit.done)
+}
+
+// DisableAudit stops recording operations and waits for the buffered entries to be
+// written.
+func DisableAudit() {
+	audit.Lock()
+	entries, done := audit.entries, audit.done
+	audit.entries, audit.done = nil, nil
+	audit.Unlock()
+
+	if entries == nil {
+		return
+	}
+
+	close(entries)
+	<-done
+}
+
+// recordAudit queues an audit entry for an operation if auditing is enabled.
+func recordAudit(ctx context.Context, action string, index []string, id string, query *Query, tenant string, opErr error) {
+	queueAudit(ctx, AuditEntry{Action: action, Index: index, DocID: id, Query: query, Tenant: tenant}, opErr)
+}
+
+// recordStatementAudit queues an audit entry for a SQL or PPL query if auditing is
+// enabled.
+func recordStatementAudit(ctx context.Context, action string, index []string, statement string, opErr error) {
+	queueAudit(ctx, AuditEntry{Action: action, Index: index, Statement: statement}, opErr)
+}
+
+// queueAudit completes the entry with the time, the process and the actor of ctx,
+// and queues it.
+func queueAudit(ctx context.Context, entry AuditEntry, opErr error) {
+	audit.RLock()
+	defer audit.RUnlock()
+
+	if audit.entries == nil {
+		return
+	}
+
+	actor, _ := ctx.Value(auditActorKey{}).(AuditActor)
+	if actor.Tenant != "" {
+		entry.Tenant = actor.Tenant
+	}
+
+	entry.Time = time.Now().UTC()
+	entry.Process = auditProcess
+	entry.Host = auditHost
+	entry.User = actor.User
+
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	}
+
+	select {
+	case audit.entries <- entry:
+	default:
+		if audit.cfg.OnError != nil {
+			audit.cfg.OnError(ErrAuditBufferFull, []AuditEntry{entry})
+		}
+	}
+}
+
+// ErrAuditBufferFull is passed to AuditConfig.OnError with the entries dropped because
+// the audit buffer was full.
+var ErrAuditBufferFull = errors.New("audit buffer full, entry dropped")
+
+// writeAudit writes the entries in bulk requests, as many as are queued at once.
+func writeAudit(cfg AuditConfig, entries <-chan AuditEntry, done chan<- struct{}) {
+	defer close(done)
+
+	for entry := range entries {
+		var batch = []AuditEntry{entry}
+
+	fill:
+		for len(batch) < auditBatch {
+			select {
+			case e, ok := <-entries:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, e)
+			default:
+				break fill
+			}
+		}
+
+		err := bulkAudit(cfg.Index, batch)
+		if err != nil && cfg.OnError != nil {
+			cfg.OnError(err, batch)
+		}
+	}
+}
+
+func bulkAudit(index string, batch []AuditEntry) error {
+	var docs = make([]interface{}, len(batch))
+	for i, entry := range batch {
+		docs[i] = entry
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := bulkCreate(ctx, index, docs)
+	if err != nil {
+		return fmt.Errorf("audit entries: %w", err)
+	}
+
+	return nil
+}
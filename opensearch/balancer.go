@@ -0,0 +1,216 @@
+package opensearch
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchtransport"
+)
+
+// NodeHealth is the health of a node as seen by a LoadBalancer.
+type NodeHealth struct {
+	Node      string
+	Latency   time.Duration
+	ErrorRate float64
+	InFlight  int
+	Requests  int64
+	// EjectedUntil is set while the node is ejected.
+	EjectedUntil time.Time
+}
+
+// LoadBalancer selects the nodes of the requests by their load, tracking the latency,
+// error rate and in-flight requests of each node. Nodes failing more than
+// ErrorThreshold of their recent requests are ejected for EjectFor, unless all are.
+// Set it in ClientOptions to use it.
+type LoadBalancer struct {
+	// ErrorThreshold is the recent error rate ejecting a node, 0.5 by default.
+	// Connection errors, and 429, 502, 503 and 504 responses, count as errors.
+	ErrorThreshold float64
+	// EjectFor is how long nodes are ejected, 30 seconds by default.
+	EjectFor time.Duration
+
+	mu    sync.Mutex
+	nodes map[string]*nodeState
+}
+
+type nodeState struct {
+	latency   float64
+	errorRate float64
+	inFlight  int
+	requests  int64
+	ejected   time.Time
+}
+
+// decay weighs the last request in the moving averages of latency and error rate.
+const decay = 0.2
+
+// NewLoadBalancer returns a LoadBalancer with the default settings.
+func NewLoadBalancer() *LoadBalancer {
+	return &LoadBalancer{ErrorThreshold: 0.5, EjectFor: 30 * time.Second}
+}
+
+func (b *LoadBalancer) state(node string) *nodeState {
+	if b.nodes == nil {
+		b.nodes = make(map[string]*nodeState)
+	}
+
+	s, ok := b.nodes[node]
+	if !ok {
+		s = &nodeState{}
+		b.nodes[node] = s
+	}
+
+	return s
+}
+
+// score is lower for the nodes to prefer. Nodes without requests yet score zero, so
+// that they are tried.
+func (s *nodeState) score() float64 {
+	return s.latency * float64(s.inFlight+1) * (1 + 4*s.errorRate)
+}
+
+// Select picks the least loaded of two random nodes that aren't ejected, or of all
+// nodes when every node is ejected.
+func (b *LoadBalancer) Select(conns []*opensearchtransport.Connection) (*opensearchtransport.Connection, error) {
+	if len(conns) == 0 {
+		return nil, errors.New("no connection available")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	var candidates = make([]*opensearchtransport.Connection, 0, len(conns))
+	for _, c := range conns {
+		if !now.Before(b.state(c.URL.Host).ejected) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = conns
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	i := rand.Intn(len(candidates))
+	j := rand.Intn(len(candidates) - 1)
+
+	if j >= i {
+		j++
+	}
+
+	if b.state(candidates[j].URL.Host).score() < b.state(candidates[i].URL.Host).score() {
+		return candidates[j], nil
+	}
+
+	return candidates[i], nil
+}
+
+// Transport wraps next, recording the outcome of the requests to each node.
+func (b *LoadBalancer) Transport(next http.RoundTripper) http.RoundTripper {
+	return balancerTransport{balancer: b, next: next}
+}
+
+type balancerTransport struct {
+	balancer *LoadBalancer
+	next     http.RoundTripper
+}
+
+func (t balancerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	node := req.URL.Host
+
+	t.balancer.mu.Lock()
+	t.balancer.state(node).inFlight++
+	t.balancer.mu.Unlock()
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	failed := err != nil
+	if resp != nil {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			failed = true
+		}
+	}
+
+	t.balancer.record(node, time.Since(start), failed)
+
+	return resp, err
+}
+
+func (b *LoadBalancer) record(node string, latency time.Duration, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.state(node)
+	s.inFlight--
+	s.requests++
+
+	var errorSample float64
+	if failed {
+		errorSample = 1
+	}
+
+	if s.requests == 1 {
+		s.latency = float64(latency)
+	} else {
+		s.latency += decay * (float64(latency) - s.latency)
+	}
+
+	s.errorRate += decay * (errorSample - s.errorRate)
+
+	threshold := b.ErrorThreshold
+	if threshold <= 0 {
+		threshold = 0.5
+	}
+
+	// A few requests are needed before the error rate is meaningful.
+	if s.requests >= 5 && s.errorRate > threshold && !time.Now().Before(s.ejected) {
+		ejectFor := b.EjectFor
+		if ejectFor <= 0 {
+			ejectFor = 30 * time.Second
+		}
+
+		s.ejected = time.Now().Add(ejectFor)
+		// Back on probation, the node is ejected again on the first errors.
+		s.errorRate = threshold / 2
+	}
+}
+
+// Nodes returns the health of the nodes, sorted by node.
+func (b *LoadBalancer) Nodes() []NodeHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	var nodes = make([]NodeHealth, 0, len(b.nodes))
+	for node, s := range b.nodes {
+		h := NodeHealth{
+			Node:      node,
+			Latency:   time.Duration(s.latency),
+			ErrorRate: s.errorRate,
+			InFlight:  s.inFlight,
+			Requests:  s.requests,
+		}
+
+		if now.Before(s.ejected) {
+			h.EjectedUntil = s.ejected
+		}
+
+		nodes = append(nodes, h)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+
+	return nodes
+}
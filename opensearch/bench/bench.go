@@ -0,0 +1,449 @@
+// Package bench measures the search latency of a cluster and of the query builders of
+// the SDK. It indexes synthetic documents generated from a seed and runs a weighted mix
+// of term, bool, aggregation and k-NN searches against them, reporting the latency
+// percentiles of each kind, so that runs with the same configuration are comparable
+// across cluster sizes and SDK versions.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Kind is a kind of query of the mix.
+type Kind string
+
+const (
+	Term Kind = "term"
+	Bool Kind = "bool"
+	Agg  Kind = "agg"
+	KNN  Kind = "knn"
+)
+
+// Config configures a benchmark. The zero value of each field selects its default.
+type Config struct {
+	// Index is created by Setup and deleted by Teardown, "bench" by default.
+	Index string
+	// Docs is the number of documents indexed, 100000 by default.
+	Docs int
+	// Seed makes the documents and the queries reproducible, 1 by default.
+	Seed int64
+	// Dim is the dimension of the document vectors, 16 by default.
+	Dim int
+	// BatchSize is the number of documents per bulk request, 1000 by default.
+	BatchSize int
+	// Mix weighs the kinds of queries run, all four equally by default. Kinds with a
+	// zero weight aren't run.
+	Mix map[Kind]int
+	// Concurrency is the number of concurrent searches, 8 by default.
+	Concurrency int
+	// Duration bounds the run, one minute by default, unless Requests is set, in which
+	// case the run ends after that many searches.
+	Duration time.Duration
+	Requests int
+	// Warmup searches are run before measuring, to load caches and compile queries.
+	Warmup int
+}
+
+func (c Config) withDefaults() Config {
+	if c.Index == "" {
+		c.Index = "bench"
+	}
+
+	if c.Docs <= 0 {
+		c.Docs = 100000
+	}
+
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+
+	if c.Dim <= 0 {
+		c.Dim = 16
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1000
+	}
+
+	if len(c.Mix) == 0 {
+		c.Mix = map[Kind]int{Term: 1, Bool: 1, Agg: 1, KNN: 1}
+	}
+
+	if c.Concurrency <= 0 {
+		c.Concurrency = 8
+	}
+
+	if c.Duration <= 0 && c.Requests <= 0 {
+		c.Duration = time.Minute
+	}
+
+	return c
+}
+
+var (
+	hosts      = 200
+	users      = 1000
+	actions    = []string{"login", "logout", "read", "write", "delete", "exec", "connect", "deny"}
+	severities = 5
+)
+
+// Document returns the i-th synthetic document of the seed. Documents hold keywords of
+// varying cardinality, numbers, a timestamp within the last 30 days of start, and a
+// vector of dim dimensions.
+func Document(seed int64, i int, dim int, start time.Time) map[string]interface{} {
+	r := rand.New(rand.NewSource(seed*1000003 + int64(i)))
+
+	var vector = make([]float32, dim)
+	for j := range vector {
+		vector[j] = float32(r.NormFloat64())
+	}
+
+	return map[string]interface{}{
+		"@timestamp": start.Add(-time.Duration(r.Int63n(int64(30 * 24 * time.Hour)))).UTC().Format(time.RFC3339Nano),
+		"host":       fmt.Sprintf("host-%d", zipf(r, hosts)),
+		"user":       fmt.Sprintf("user-%d", zipf(r, users)),
+		"action":     actions[r.Intn(len(actions))],
+		"severity":   1 + r.Intn(severities),
+		"bytes":      int64(r.ExpFloat64() * 10000),
+		"vector":     vector,
+	}
+}
+
+// zipf returns a skewed value below n, as keyword values of real events are.
+func zipf(r *rand.Rand, n int) int {
+	return int(math.Floor(math.Pow(r.Float64(), 3) * float64(n)))
+}
+
+// Setup creates the index of cfg and indexes its documents.
+func Setup(ctx context.Context, cfg Config) error {
+	cfg = cfg.withDefaults()
+
+	err := opensearch.CreateKNNIndex(ctx, cfg.Index, cfg.Dim, "l2", "lucene", opensearch.KNNParams{
+		Properties: map[string]interface{}{
+			"@timestamp": map[string]interface{}{"type": "date"},
+			"host":       map[string]interface{}{"type": "keyword"},
+			"user":       map[string]interface{}{"type": "keyword"},
+			"action":     map[string]interface{}{"type": "keyword"},
+			"severity":   map[string]interface{}{"type": "integer"},
+			"bytes":      map[string]interface{}{"type": "long"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("creating index %s: %w", cfg.Index, err)
+	}
+
+	start := time.Now()
+
+	for i := 0; i < cfg.Docs; i += cfg.BatchSize {
+		n := cfg.BatchSize
+		if i+n > cfg.Docs {
+			n = cfg.Docs - i
+		}
+
+		var docs = make([]interface{}, n)
+		for j := range docs {
+			docs[j] = Document(cfg.Seed, i+j, cfg.Dim, start)
+		}
+
+		err := opensearch.BulkCreate(ctx, cfg.Index, docs)
+		if err != nil {
+			return fmt.Errorf("indexing documents %d to %d: %w", i, i+n, err)
+		}
+	}
+
+	return opensearch.RefreshIndex(ctx, cfg.Index)
+}
+
+// Teardown deletes the index of cfg.
+func Teardown(ctx context.Context, cfg Config) error {
+	cfg = cfg.withDefaults()
+
+	_, err := opensearch.Do(ctx, http.MethodDelete, "/"+url.PathEscape(cfg.Index), nil, nil)
+
+	return err
+}
+
+// Query returns a random search of the kind, drawn from r.
+func Query(kind Kind, r *rand.Rand, dim int) opensearch.SearchRequest {
+	switch kind {
+	case Term:
+		q := opensearch.TermQuery("host", fmt.Sprintf("host-%d", zipf(r, hosts)), false)
+
+		return opensearch.SearchRequest{Size: 10, Query: &q}
+	case Bool:
+		hours := 1 + r.Intn(30*24)
+
+		return opensearch.SearchRequest{
+			Size: 10,
+			Sort: []map[string]map[string]interface{}{{"@timestamp": {"order": "desc"}}},
+			Query: &opensearch.Query{Bool: &opensearch.Bool{
+				Filter: []opensearch.Query{
+					{Range: map[string]map[string]interface{}{"@timestamp": {"gte": fmt.Sprintf("now-%dh", hours)}}},
+					{Terms: map[string][]interface{}{"action": {actions[r.Intn(len(actions))], actions[r.Intn(len(actions))]}}},
+				},
+				MustNot: []opensearch.Query{
+					opensearch.TermQuery("user", fmt.Sprintf("user-%d", zipf(r, users)), false),
+				},
+				Should: []opensearch.Query{
+					{Range: map[string]map[string]interface{}{"severity": {"gte": 1 + r.Intn(severities)}}},
+				},
+			}},
+		}
+	case Agg:
+		q := opensearch.TermQuery("action", actions[r.Intn(len(actions))], false)
+
+		return opensearch.SearchRequest{
+			Query: &q,
+			Aggs: map[string]opensearch.Aggs{
+				"hosts": {
+					Terms: &opensearch.Terms{Field: "host", Size: 10},
+					Aggs:  map[string]opensearch.Aggs{"bytes": {Sum: &opensearch.Agg{Field: "bytes"}}},
+				},
+				"timeline": {DateHistogram: &opensearch.Histogram{Field: "@timestamp", FixedInterval: "1d"}},
+			},
+		}
+	case KNN:
+		var vector = make([]float32, dim)
+		for j := range vector {
+			vector[j] = float32(r.NormFloat64())
+		}
+
+		q := opensearch.KNNQuery("vector", vector, 10, nil)
+
+		return opensearch.SearchRequest{Size: 10, Query: &q}
+	}
+
+	return opensearch.SearchRequest{}
+}
+
+// Stats are the latencies of the searches of a kind.
+type Stats struct {
+	Kind     Kind
+	Requests int
+	Errors   int
+	Mean     time.Duration
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Duration time.Duration
+	Requests int
+	Errors   int
+	// Throughput is the number of searches per second.
+	Throughput float64
+	// Kinds holds the stats of each kind run, sorted by kind.
+	Kinds []Stats
+	// FirstError is the first search error, to diagnose failing runs.
+	FirstError error
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d searches in %s, %.1f/s, %d errors\n", r.Requests, r.Duration.Round(time.Millisecond), r.Throughput, r.Errors)
+	fmt.Fprintf(&b, "%-6s %8s %6s %10s %10s %10s %10s %10s\n", "kind", "count", "errors", "mean", "p50", "p90", "p99", "max")
+
+	for _, s := range r.Kinds {
+		fmt.Fprintf(&b, "%-6s %8d %6d %10s %10s %10s %10s %10s\n", s.Kind, s.Requests, s.Errors,
+			ms(s.Mean), ms(s.P50), ms(s.P90), ms(s.P99), ms(s.Max))
+	}
+
+	return b.String()
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d.Microseconds())/1000)
+}
+
+type sample struct {
+	kind    Kind
+	latency time.Duration
+	err     error
+}
+
+// Run runs the query mix of cfg against its index, which Setup must have populated.
+func Run(ctx context.Context, cfg Config) (Report, error) {
+	cfg = cfg.withDefaults()
+
+	var kinds []Kind
+	var total int
+
+	for kind, weight := range cfg.Mix {
+		if weight > 0 {
+			kinds = append(kinds, kind)
+			total += weight
+		}
+	}
+
+	if len(kinds) == 0 {
+		return Report{}, errors.New("empty query mix")
+	}
+
+	// Sorted so that the same seed draws the same queries.
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	pick := func(r *rand.Rand) Kind {
+		n := r.Intn(total)
+		for _, kind := range kinds {
+			n -= cfg.Mix[kind]
+			if n < 0 {
+				return kind
+			}
+		}
+
+		return kinds[len(kinds)-1]
+	}
+
+	warm := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < cfg.Warmup; i++ {
+		_, _ = Query(pick(warm), warm, cfg.Dim).SearchIn(ctx, []string{cfg.Index})
+	}
+
+	runCtx := ctx
+	if cfg.Requests <= 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	var (
+		mu       sync.Mutex
+		samples  []sample
+		issued   int
+		wg       sync.WaitGroup
+		start    = time.Now()
+		budgeted = cfg.Requests > 0
+	)
+
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+
+		go func(worker int) {
+			defer wg.Done()
+
+			r := rand.New(rand.NewSource(cfg.Seed + int64(worker) + 1))
+
+			for runCtx.Err() == nil {
+				if budgeted {
+					mu.Lock()
+					if issued >= cfg.Requests {
+						mu.Unlock()
+						return
+					}
+					issued++
+					mu.Unlock()
+				}
+
+				kind := pick(r)
+				q := Query(kind, r, cfg.Dim)
+
+				begin := time.Now()
+				_, err := q.SearchIn(runCtx, []string{cfg.Index})
+				latency := time.Since(begin)
+
+				// Searches cut by the end of the run aren't measured.
+				if err != nil && runCtx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				samples = append(samples, sample{kind: kind, latency: latency, err: err})
+				mu.Unlock()
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(start)
+
+	if ctx.Err() != nil {
+		return Report{}, ctx.Err()
+	}
+
+	return report(samples, elapsed), nil
+}
+
+func report(samples []sample, elapsed time.Duration) Report {
+	rep := Report{Duration: elapsed, Requests: len(samples)}
+
+	if elapsed > 0 {
+		rep.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+
+	var byKind = make(map[Kind][]time.Duration)
+	var errs = make(map[Kind]int)
+
+	for _, s := range samples {
+		if s.err != nil {
+			rep.Errors++
+			errs[s.kind]++
+
+			if rep.FirstError == nil {
+				rep.FirstError = s.err
+			}
+
+			continue
+		}
+
+		byKind[s.kind] = append(byKind[s.kind], s.latency)
+	}
+
+	// Kinds of which every search failed are reported too.
+	for kind := range errs {
+		if _, ok := byKind[kind]; !ok {
+			byKind[kind] = nil
+		}
+	}
+
+	for kind, latencies := range byKind {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		s := Stats{Kind: kind, Requests: len(latencies) + errs[kind], Errors: errs[kind]}
+
+		if len(latencies) != 0 {
+			var sum time.Duration
+			for _, l := range latencies {
+				sum += l
+			}
+
+			s.Mean = sum / time.Duration(len(latencies))
+			s.P50 = percentile(latencies, 0.50)
+			s.P90 = percentile(latencies, 0.90)
+			s.P99 = percentile(latencies, 0.99)
+			s.Max = latencies[len(latencies)-1]
+		}
+
+		rep.Kinds = append(rep.Kinds, s)
+	}
+
+	sort.Slice(rep.Kinds, func(i, j int) bool { return rep.Kinds[i].Kind < rep.Kinds[j].Kind })
+
+	return rep
+}
+
+// percentile returns the nearest-rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
@@ -0,0 +1,123 @@
+// Command osbench runs the benchmark of package bench against a cluster, e.g.
+//
+//	OPENSEARCH_NODES=https://localhost:9200 OPENSEARCH_USER=admin OPENSEARCH_PASSWORD=admin \
+//		osbench -docs 1000000 -concurrency 16 -duration 5m -mix term=4,bool=2,agg=1,knn=1
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/config"
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/threatwinds/go-sdk/opensearch/bench"
+)
+
+func main() {
+	var cfg bench.Config
+
+	flag.StringVar(&cfg.Index, "index", "bench", "benchmark index")
+	flag.IntVar(&cfg.Docs, "docs", 100000, "documents indexed by setup")
+	flag.Int64Var(&cfg.Seed, "seed", 1, "seed of the documents and queries")
+	flag.IntVar(&cfg.Dim, "dim", 16, "dimension of the document vectors")
+	flag.IntVar(&cfg.BatchSize, "batch", 1000, "documents per bulk request")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 8, "concurrent searches")
+	flag.DurationVar(&cfg.Duration, "duration", time.Minute, "duration of the run")
+	flag.IntVar(&cfg.Requests, "requests", 0, "searches of the run, instead of a duration")
+	flag.IntVar(&cfg.Warmup, "warmup", 100, "searches run before measuring")
+	mix := flag.String("mix", "term=1,bool=1,agg=1,knn=1", "weights of the kinds of queries")
+	setup := flag.Bool("setup", true, "create and populate the index before the run")
+	teardown := flag.Bool("teardown", true, "delete the index after the run")
+	flag.Parse()
+
+	var err error
+
+	cfg.Mix, err = parseMix(*mix)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var conn config.OpenSearch
+
+	err = config.Load(&conn, config.Options{EnvPrefix: "OPENSEARCH_"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	osConfig, err := conn.Config()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = opensearch.ConnectWithConfig(osConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *setup {
+		start := time.Now()
+
+		err = bench.Setup(ctx, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("indexed %d documents in %s", cfg.Docs, time.Since(start).Round(time.Millisecond))
+	}
+
+	report, err := bench.Run(ctx, cfg)
+
+	if *teardown {
+		// The index is deleted even when the run is interrupted.
+		tdErr := bench.Teardown(context.Background(), cfg)
+		if tdErr != nil {
+			log.Print(tdErr)
+		}
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(report)
+
+	if report.FirstError != nil {
+		log.Printf("first error: %v", report.FirstError)
+	}
+}
+
+func parseMix(s string) (map[bench.Kind]int, error) {
+	var mix = make(map[bench.Kind]int)
+
+	for _, part := range strings.Split(s, ",") {
+		kind, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q, expected kind=weight", part)
+		}
+
+		switch bench.Kind(kind) {
+		case bench.Term, bench.Bool, bench.Agg, bench.KNN:
+		default:
+			return nil, fmt.Errorf("unknown query kind %q", kind)
+		}
+
+		n, err := strconv.Atoi(weight)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid weight %q of %s", weight, kind)
+		}
+
+		mix[bench.Kind(kind)] = n
+	}
+
+	return mix, nil
+}
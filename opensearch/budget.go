@@ -0,0 +1,199 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryBudget bounds the total time of a logical operation, like a search and the
+// requests it retries, rather than each of its requests.
+type RetryBudget struct {
+	// Budget is the time the operation may take, across attempts and backoffs.
+	Budget time.Duration
+	// MaxAttempts of each request, 3 by default.
+	MaxAttempts int
+	// Backoff is the wait before the second attempt, 100 milliseconds by default,
+	// doubled before each next attempt up to MaxBackoff, 2 seconds by default.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Attempt is an attempt of a request under a time budget.
+type Attempt struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// Status is the status code of the response, zero when none was received.
+	Status int    `json:"status,omitempty"`
+	Err    string `json:"error,omitempty"`
+}
+
+// DeadlineExceededError is returned when an operation runs out of its time budget. It
+// matches context.DeadlineExceeded and the error of the last attempt with errors.Is
+// and errors.As.
+type DeadlineExceededError struct {
+	Budget  time.Duration
+	Elapsed time.Duration
+	// Attempts are the attempts of every request of the operation.
+	Attempts []Attempt
+	// Last is the error of the last attempt, nil if the budget ran out before it.
+	Last error
+}
+
+func (e *DeadlineExceededError) Error() string {
+	if e.Last == nil {
+		return fmt.Sprintf("time budget of %s exceeded after %s and %d attempts", e.Budget, e.Elapsed.Round(time.Millisecond), len(e.Attempts))
+	}
+
+	return fmt.Sprintf("time budget of %s exceeded after %s and %d attempts, last: %v", e.Budget, e.Elapsed.Round(time.Millisecond), len(e.Attempts), e.Last)
+}
+
+func (e *DeadlineExceededError) Unwrap() []error {
+	if e.Last == nil {
+		return []error{context.DeadlineExceeded}
+	}
+
+	return []error{context.DeadlineExceeded, e.Last}
+}
+
+// timeBudget is the budget of an operation, shared by its requests.
+type timeBudget struct {
+	RetryBudget
+	start    time.Time
+	deadline time.Time
+
+	mu       sync.Mutex
+	attempts []Attempt
+}
+
+type budgetKey struct{}
+
+// WithTimeBudget returns a context bounding the operation run with it to the budget.
+// Requests failing on connection errors or with 429, 502, 503 and 504 responses are
+// retried with exponential backoff, as long as the budget allows the wait. Once the
+// budget runs out, requests fail with a *DeadlineExceededError recording every
+// attempt, so that retries can't exceed the latency expected from the operation.
+func WithTimeBudget(ctx context.Context, b RetryBudget) (context.Context, context.CancelFunc) {
+	if b.MaxAttempts <= 0 {
+		b.MaxAttempts = 3
+	}
+
+	if b.Backoff <= 0 {
+		b.Backoff = 100 * time.Millisecond
+	}
+
+	if b.MaxBackoff <= 0 {
+		b.MaxBackoff = 2 * time.Second
+	}
+
+	now := time.Now()
+	budget := &timeBudget{RetryBudget: b, start: now, deadline: now.Add(b.Budget)}
+
+	ctx, cancel := context.WithDeadline(ctx, budget.deadline)
+
+	return context.WithValue(ctx, budgetKey{}, budget), cancel
+}
+
+func budgetFrom(ctx context.Context) *timeBudget {
+	budget, _ := ctx.Value(budgetKey{}).(*timeBudget)
+
+	return budget
+}
+
+// do runs attempt until it succeeds, fails with an error not worth retrying, or the
+// attempts or the budget run out.
+func (b *timeBudget) do(ctx context.Context, attempt func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	backoff := b.Backoff
+
+	for i := 1; ; i++ {
+		start := time.Now()
+
+		body, err := attempt(ctx)
+
+		b.record(start, err)
+
+		if err == nil {
+			return body, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, b.exceeded(ctx, err)
+		}
+
+		if !retryable(err) || i >= b.MaxAttempts {
+			return nil, err
+		}
+
+		// Waiting past the deadline only delays the failure.
+		if time.Now().Add(backoff).After(b.deadline) {
+			return nil, b.exceeded(ctx, err)
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, b.exceeded(ctx, err)
+		case <-timer.C:
+		}
+
+		backoff = min(2*backoff, b.MaxBackoff)
+	}
+}
+
+func (b *timeBudget) record(start time.Time, err error) {
+	a := Attempt{Start: start, Duration: time.Since(start)}
+
+	if err != nil {
+		a.Err = err.Error()
+
+		var status *StatusError
+		if errors.As(err, &status) {
+			a.Status = status.StatusCode
+		}
+	}
+
+	b.mu.Lock()
+	b.attempts = append(b.attempts, a)
+	b.mu.Unlock()
+}
+
+// exceeded returns the error of the operation once ctx is done or the budget doesn't
+// allow another attempt: a *DeadlineExceededError, unless ctx was cancelled by its
+// parent for another reason.
+func (b *timeBudget) exceeded(ctx context.Context, last error) error {
+	if err := ctx.Err(); err != nil && (!errors.Is(err, context.DeadlineExceeded) || time.Now().Before(b.deadline)) {
+		return last
+	}
+
+	b.mu.Lock()
+	attempts := append([]Attempt(nil), b.attempts...)
+	b.mu.Unlock()
+
+	// The last error is only the deadline when the budget interrupted the attempt.
+	if errors.Is(last, context.DeadlineExceeded) {
+		last = nil
+	}
+
+	return &DeadlineExceededError{Budget: b.Budget, Elapsed: time.Since(b.start), Attempts: attempts, Last: last}
+}
+
+// retryable reports whether a failed request may succeed if sent again.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch status.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+
+		return false
+	}
+
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,136 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// BulkCreate creates the documents in index with a single bulk request, letting the
+// cluster assign their IDs. It fails with the first rejection when any document is
+// rejected; the others are created nonetheless.
+func BulkCreate(ctx context.Context, index string, docs []interface{}) error {
+	return checkedBulk(ctx, "bulk_create", index, func() error {
+		return bulkCreate(ctx, index, docs)
+	})
+}
+
+// checkedBulk checks the access to index before sending a bulk request with send, and
+// records it in the audit log. bulkCreate and bulkUpdate don't check the access, so
+// that the audit log can write its own index.
+func checkedBulk(ctx context.Context, action, index string, send func() error) error {
+	_, err := checkIndexAccess(index)
+	if err == nil {
+		err = send()
+	}
+
+	recordAudit(ctx, action, []string{index}, "", nil, "", err)
+
+	return err
+}
+
+func bulkCreate(ctx context.Context, index string, docs []interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+
+	enc := json.NewEncoder(&body)
+
+	for _, doc := range docs {
+		err := enc.Encode(map[string]interface{}{"create": map[string]string{"_index": index}})
+		if err != nil {
+			return err
+		}
+
+		err = enc.Encode(doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return sendBulk(ctx, body.Bytes())
+}
+
+// DocUpdate is an update of a document of a bulk request. Body is the body of the
+// update API: a partial "doc", with "doc_as_upsert" to create missing documents, or a
+// "script" with its "upsert" document.
+type DocUpdate struct {
+	ID   string
+	Body interface{}
+}
+
+// BulkUpdate updates the documents of index with a single bulk request. It fails with
+// the first rejection when any update is rejected; the others are applied nonetheless.
+func BulkUpdate(ctx context.Context, index string, updates []DocUpdate) error {
+	return checkedBulk(ctx, "bulk_update", index, func() error {
+		return bulkUpdate(ctx, index, updates)
+	})
+}
+
+func bulkUpdate(ctx context.Context, index string, updates []DocUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+
+	enc := json.NewEncoder(&body)
+
+	for _, u := range updates {
+		err := enc.Encode(map[string]interface{}{"update": map[string]string{"_index": index, "_id": u.ID}})
+		if err != nil {
+			return err
+		}
+
+		err = enc.Encode(u.Body)
+		if err != nil {
+			return err
+		}
+	}
+
+	return sendBulk(ctx, body.Bytes())
+}
+
+// sendBulk sends a bulk request, returning the first rejection of its items.
+func sendBulk(ctx context.Context, body []byte) error {
+	resp, err := do(ctx, http.MethodPost, "/_bulk", nil, body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return err
+	}
+
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, r := range item {
+				if len(r.Error) != 0 {
+					return fmt.Errorf("documents rejected: %s", r.Error)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// RefreshIndex makes the recent changes of the index visible to searches.
+func RefreshIndex(ctx context.Context, index string) error {
+	_, err := Do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_refresh", nil, nil)
+
+	return err
+}
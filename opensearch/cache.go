@@ -0,0 +1,382 @@
+package opensearch
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cache stores search results. Entries are tagged with the index expressions of their
+// search, to invalidate them when the indices change.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	// Invalidate removes the entries tagged with any of the tags.
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// SearchCache caches the results of searches for TTL, e.g. for dashboards repeating
+// the same expensive aggregations every few seconds. Results are keyed by the query,
+// the indices, the tenant scope and the visibility groups of the caller, so that they
+// are only served to callers allowed to see them. Partial results aren't cached.
+type SearchCache struct {
+	Backend Cache
+	// TTL is how long results are served from the cache, 30 seconds by default.
+	TTL time.Duration
+	// Prefix namespaces the keys, e.g. to share a Redis instance between services.
+	Prefix string
+	// OnError is called when the backend fails, and may be nil. Searches fall back to
+	// the cluster on backend errors.
+	OnError func(error)
+}
+
+// SearchIn searches like SearchRequest.SearchIn, serving the result from the cache when
+// an identical search with the same groups was cached less than TTL ago.
+func (c *SearchCache) SearchIn(ctx context.Context, q SearchRequest, index []string, groups []string) (SearchResult, error) {
+	// The key is computed from the request as searched, so that access, tenant scope
+	// and every rewrite of its query are checked on hits too.
+	prepared := q
+	if prepared.Source == nil {
+		prepared.Source = new(Source)
+	}
+
+	expanded, err := prepared.prepare(ctx, index)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	key, err := c.key(prepared, expanded, groups)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	cached, ok, err := c.Backend.Get(ctx, key)
+	if err != nil {
+		c.fail(err)
+	}
+
+	if ok {
+		var result SearchResult
+
+		if json.Unmarshal(cached, &result) == nil {
+			// The page isn't encoded with the result.
+			result.page = prepared.pageInfo(result)
+
+			recordAudit(ctx, "search", index, "", q.Query, q.TenantID, nil)
+
+			return result, nil
+		}
+	}
+
+	result, err := q.SearchIn(ctx, index)
+	if err != nil || result.Partial() || len(result.SkippedIndices) != 0 {
+		return result, err
+	}
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		return result, nil
+	}
+
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+
+	err = c.Backend.Set(ctx, key, value, ttl, c.tags(index))
+	if err != nil {
+		c.fail(err)
+	}
+
+	return result, nil
+}
+
+// Invalidate removes the cached results of the searches in the index expressions,
+// which must be written as they were searched, e.g. "logs-*" rather than an index
+// matching it.
+func (c *SearchCache) Invalidate(ctx context.Context, index ...string) error {
+	return c.Backend.Invalidate(ctx, c.tags(index)...)
+}
+
+func (c *SearchCache) tags(index []string) []string {
+	var tags = make([]string, len(index))
+	for i, idx := range index {
+		tags[i] = c.Prefix + "index:" + idx
+	}
+
+	return tags
+}
+
+func (c *SearchCache) fail(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// key hashes the prepared request, whose maps marshal with sorted keys, with the
+// sorted indices and groups, and the options not marshalled.
+func (c *SearchCache) key(q SearchRequest, index, groups []string) (string, error) {
+	body, err := json.Marshal(q)
+	if err != nil {
+		return "", err
+	}
+
+	index = sortedCopy(index)
+	groups = sortedCopy(groups)
+
+	h := sha256.New()
+	h.Write(body)
+
+	for _, part := range [][]string{index, groups, {q.params().Encode(), q.DedupeBy}} {
+		h.Write([]byte{0})
+		for _, s := range part {
+			h.Write([]byte(strconv.Itoa(len(s)) + ":" + s))
+		}
+	}
+
+	h.Write([]byte(fmt.Sprint(q.FailOnPartialResults, q.RetryWithoutFailedIndices, q.KeepIndices, q.IncludeSoftDeleted, q.ConstantScoring)))
+
+	return c.Prefix + "search:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func sortedCopy(s []string) []string {
+	var c = append([]string(nil), s...)
+	sort.Strings(c)
+
+	return c
+}
+
+// MemoryCache is an in-memory Cache evicting the least recently used entries beyond
+// MaxEntries, 1000 by default.
+type MemoryCache struct {
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	tagged  map[string]map[string]struct{}
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+// NewMemoryCache returns a MemoryCache holding up to maxEntries entries.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{MaxEntries: maxEntries}
+}
+
+func (m *MemoryCache) init() {
+	if m.entries == nil {
+		m.entries = make(map[string]*list.Element)
+		m.order = list.New()
+		m.tagged = make(map[string]map[string]struct{})
+	}
+}
+
+func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.init()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		m.remove(el)
+
+		return nil, false, nil
+	}
+
+	m.order.MoveToFront(el)
+
+	return entry.value, true, nil
+}
+
+func (m *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.init()
+
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+
+	entry := &memoryEntry{key: key, value: value, expires: time.Now().Add(ttl), tags: tags}
+	m.entries[key] = m.order.PushFront(entry)
+
+	for _, tag := range tags {
+		if m.tagged[tag] == nil {
+			m.tagged[tag] = make(map[string]struct{})
+		}
+
+		m.tagged[tag][key] = struct{}{}
+	}
+
+	maxEntries := m.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+
+	for m.order.Len() > maxEntries {
+		m.remove(m.order.Back())
+	}
+
+	return nil
+}
+
+func (m *MemoryCache) Invalidate(_ context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.init()
+
+	for _, tag := range tags {
+		for key := range m.tagged[tag] {
+			if el, ok := m.entries[key]; ok {
+				m.remove(el)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryCache) remove(el *list.Element) {
+	entry := el.Value.(*memoryEntry)
+
+	m.order.Remove(el)
+	delete(m.entries, entry.key)
+
+	for _, tag := range entry.tags {
+		delete(m.tagged[tag], entry.key)
+
+		if len(m.tagged[tag]) == 0 {
+			delete(m.tagged, tag)
+		}
+	}
+}
+
+// RedisDo sends a command to Redis and returns its reply, e.g. with go-redis:
+//
+//	func(ctx context.Context, args ...interface{}) (interface{}, error) {
+//		return rdb.Do(ctx, args...).Result()
+//	}
+//
+// Missing keys may be reported as a nil reply, ErrRedisNil, or an error recognized by
+// RedisCache.IsNil.
+type RedisDo func(ctx context.Context, args ...interface{}) (interface{}, error)
+
+// ErrRedisNil is the nil reply of Redis, for RedisDo implementations without their own.
+var ErrRedisNil = errors.New("redis: nil")
+
+// RedisCache is a Cache stored in Redis, shared by the replicas of a service. Tags are
+// sets of the keys of their entries, expiring with the last of them, which requires
+// Redis 7.
+type RedisCache struct {
+	Do RedisDo
+	// IsNil reports whether an error is the nil reply of the client, e.g. redis.Nil
+	// for go-redis. Only ErrRedisNil is recognized when unset.
+	IsNil func(error) bool
+}
+
+func (r RedisCache) isNil(err error) bool {
+	if errors.Is(err, ErrRedisNil) {
+		return true
+	}
+
+	return r.IsNil != nil && r.IsNil(err)
+}
+
+func (r RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.Do(ctx, "GET", key)
+	if err != nil {
+		if r.isNil(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+
+	switch v := reply.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		return []byte(v), true, nil
+	case []byte:
+		return v, true, nil
+	}
+
+	return nil, false, fmt.Errorf("unexpected redis reply %T", reply)
+}
+
+func (r RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+
+	_, err := r.Do(ctx, "SET", key, value, "PX", ms)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = r.Do(ctx, "SADD", tag, key)
+		if err != nil {
+			return err
+		}
+
+		// GT keeps the expiry of the tag when it is already later.
+		_, err = r.Do(ctx, "PEXPIRE", tag, ms, "GT")
+		if err != nil {
+			return err
+		}
+
+		_, err = r.Do(ctx, "PEXPIRE", tag, ms, "NX")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r RedisCache) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		reply, err := r.Do(ctx, "SMEMBERS", tag)
+		if err != nil && !r.isNil(err) {
+			return err
+		}
+
+		var args = []interface{}{"DEL", tag}
+
+		members, _ := reply.([]interface{})
+		for _, m := range members {
+			args = append(args, m)
+		}
+
+		_, err = r.Do(ctx, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package opensearch
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	osgo "github.com/opensearch-project/opensearch-go/v2"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchtransport"
+)
+
+// ClientOptions tunes the connection to the cluster, e.g. for bulk-heavy indexers
+// limited by the default transport.
+type ClientOptions struct {
+	Nodes []string
+	// User and Password authenticate the requests, unless Credentials is set, which
+	// is read again every CredentialsRefresh and on 401 responses.
+	User               string
+	Password           string
+	Credentials        Credentials
+	CredentialsRefresh time.Duration
+	// TLSConfig defaults to not verifying certificates, as with Connect.
+	TLSConfig *tls.Config
+
+	// CompressRequests gzips request bodies. Responses are requested gzipped unless
+	// DisableResponseCompression is set, e.g. when the cluster is local and CPU bound.
+	CompressRequests           bool
+	DisableResponseCompression bool
+
+	// MaxIdleConnsPerHost is the number of connections kept open per node, 32 by
+	// default; Go defaults to 2, which concurrent bulk requests quickly exhaust.
+	// MaxConnsPerHost limits the connections per node, unlimited when zero.
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	// IdleConnTimeout closes idle connections, after 90 seconds by default.
+	IdleConnTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for the response headers once the
+	// request is sent, unlimited when zero; contexts bound whole requests.
+	ResponseHeaderTimeout time.Duration
+	// DialTimeout bounds connection establishment, 30 seconds by default.
+	DialTimeout time.Duration
+
+	// DiscoverNodesOnStart and DiscoverNodesInterval sniff the nodes of the cluster,
+	// to spread the requests over all of them rather than over Nodes only.
+	DiscoverNodesOnStart  bool
+	DiscoverNodesInterval time.Duration
+	// LoadBalancer selects the nodes by their load instead of in turn.
+	LoadBalancer *LoadBalancer
+
+	// MaxRetries of requests failing on connection errors or 502, 503 and 504
+	// responses, 3 by default. RetryOnTimeout retries timed out requests as well.
+	MaxRetries     int
+	DisableRetry   bool
+	RetryOnTimeout bool
+
+	// Middleware wraps the transport, the first middleware being the outermost. They
+	// see the requests before they are authenticated and balanced.
+	Middleware []Middleware
+}
+
+// Transport returns the HTTP transport configured by the options.
+func (o ClientOptions) Transport() *http.Transport {
+	tlsConfig := o.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	idle := o.MaxIdleConnsPerHost
+	if idle <= 0 {
+		idle = 32
+	}
+
+	idleTimeout := o.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = 90 * time.Second
+	}
+
+	dialTimeout := o.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 30 * time.Second
+	}
+
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          idle * 4,
+		MaxIdleConnsPerHost:   idle,
+		MaxConnsPerHost:       o.MaxConnsPerHost,
+		IdleConnTimeout:       idleTimeout,
+		ResponseHeaderTimeout: o.ResponseHeaderTimeout,
+		DisableCompression:    o.DisableResponseCompression,
+	}
+}
+
+// ConnectWithOptions connects with a tuned transport. As with Connect, only the first
+// call in the process takes effect; later ones return ErrAlreadyConnected.
+func ConnectWithOptions(ctx context.Context, o ClientOptions) error {
+	var transport http.RoundTripper = o.Transport()
+
+	var selector opensearchtransport.Selector
+
+	if o.LoadBalancer != nil {
+		transport = o.LoadBalancer.Transport(transport)
+		selector = o.LoadBalancer
+	}
+
+	var creds *credentialsTransport
+
+	if o.Credentials != nil {
+		creds = &credentialsTransport{next: transport, source: o.Credentials, refresh: o.CredentialsRefresh}
+
+		// Invalid credentials sources fail at connection rather than on the first
+		// request.
+		_, _, err := creds.credentials(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		transport = creds
+	}
+
+	transport = chain(transport, o.Middleware)
+
+	created, err := connect(osgo.Config{
+		Addresses:             o.Nodes,
+		Username:              o.User,
+		Password:              o.Password,
+		Transport:             transport,
+		CompressRequestBody:   o.CompressRequests,
+		DiscoverNodesOnStart:  o.DiscoverNodesOnStart,
+		DiscoverNodesInterval: o.DiscoverNodesInterval,
+		Selector:              selector,
+		MaxRetries:            o.MaxRetries,
+		DisableRetry:          o.DisableRetry,
+		EnableRetryOnTimeout:  o.RetryOnTimeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		return ErrAlreadyConnected
+	}
+
+	if creds != nil {
+		rotating.Store(creds)
+	}
+
+	return nil
+}
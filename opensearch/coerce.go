@@ -0,0 +1,252 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMissingField is wrapped by the errors of the HitSource accessors when the field
+// is missing or null.
+var ErrMissingField = errors.New("missing field")
+
+// FieldTypeError is returned by the HitSource accessors when the value of a field
+// can't be converted to the type asked.
+type FieldTypeError struct {
+	Field string
+	Value interface{}
+	Type  string
+	// Err is the conversion error, if any.
+	Err error
+}
+
+func (e *FieldTypeError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("field %s: cannot convert %#v (%T) to %s: %v", e.Field, e.Value, e.Value, e.Type, e.Err)
+	}
+
+	return fmt.Sprintf("field %s: cannot convert %#v (%T) to %s", e.Field, e.Value, e.Value, e.Type)
+}
+
+func (e *FieldTypeError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultTimeLayouts are the layouts GetTime parses strings with when given none.
+var DefaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// value returns the scalar value of the dot-notation field, the element of slices
+// holding a single one.
+func (h HitSource) value(field string) (interface{}, error) {
+	v, ok := sourceValue(h, field)
+	if !ok {
+		return nil, fmt.Errorf("field %s: %w", field, ErrMissingField)
+	}
+
+	if list, ok := v.([]interface{}); ok && len(list) == 1 {
+		v = list[0]
+	}
+
+	return v, nil
+}
+
+// GetString returns the dot-notation field as a string. Numbers and booleans are
+// formatted as in JSON.
+func (h HitSource) GetString(field string) (string, error) {
+	v, err := h.value(field)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := coerceString(v)
+	if !ok {
+		return "", &FieldTypeError{Field: field, Value: v, Type: "string"}
+	}
+
+	return s, nil
+}
+
+func coerceString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case json.Number:
+		return x.String(), true
+	case bool:
+		return strconv.FormatBool(x), true
+	case int:
+		return strconv.Itoa(x), true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	}
+
+	return "", false
+}
+
+// GetInt64 returns the dot-notation field as an integer. Strings holding integers,
+// like "42" or "42.0", are parsed, as numeric fields sometimes arrive as strings.
+// Numbers with a fractional part fail rather than being truncated.
+func (h HitSource) GetInt64(field string) (int64, error) {
+	v, err := h.value(field)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := coerceInt64(v)
+	if err != nil {
+		return 0, &FieldTypeError{Field: field, Value: v, Type: "int64", Err: err}
+	}
+
+	return i, nil
+}
+
+func coerceInt64(v interface{}) (int64, error) {
+	var f float64
+
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case float64:
+		f = x
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+
+		var err error
+
+		f, err = x.Float64()
+		if err != nil {
+			return 0, errors.Unwrap(err)
+		}
+	case string:
+		s := strings.TrimSpace(x)
+
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+
+		var err error
+
+		f, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, errors.Unwrap(err)
+		}
+	default:
+		return 0, errors.New("not a number")
+	}
+
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, errors.New("not an integer in range")
+	}
+
+	return int64(f), nil
+}
+
+// GetTime returns the dot-notation field as a time. Strings are parsed with the
+// layouts, DefaultTimeLayouts by default, and those without a zone are in UTC.
+// Numbers, and strings of digits no layout matches, are milliseconds since the
+// epoch, as the search engine stores dates.
+func (h HitSource) GetTime(field string, layouts ...string) (time.Time, error) {
+	v, err := h.value(field)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(layouts) == 0 {
+		layouts = DefaultTimeLayouts
+	}
+
+	switch x := v.(type) {
+	case string:
+		s := strings.TrimSpace(x)
+
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+
+		if millis, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.UnixMilli(millis).UTC(), nil
+		}
+
+		return time.Time{}, &FieldTypeError{Field: field, Value: v, Type: "time", Err: fmt.Errorf("matches none of the layouts %q", layouts)}
+	case float64, json.Number, int, int64:
+		millis, err := coerceInt64(v)
+		if err != nil {
+			return time.Time{}, &FieldTypeError{Field: field, Value: v, Type: "time", Err: err}
+		}
+
+		return time.UnixMilli(millis).UTC(), nil
+	}
+
+	return time.Time{}, &FieldTypeError{Field: field, Value: v, Type: "time"}
+}
+
+// GetIPAddr returns the dot-notation field as an IP address, with IPv4-mapped IPv6
+// addresses unmapped.
+func (h HitSource) GetIPAddr(field string) (netip.Addr, error) {
+	v, err := h.value(field)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return netip.Addr{}, &FieldTypeError{Field: field, Value: v, Type: "IP address"}
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(strings.TrimSpace(s), "[]"))
+	if err != nil {
+		return netip.Addr{}, &FieldTypeError{Field: field, Value: v, Type: "IP address", Err: err}
+	}
+
+	return addr.Unmap(), nil
+}
+
+// GetStringSlice returns the dot-notation field as a slice of strings, converting its
+// elements as GetString does. A single value is returned as a slice of one.
+func (h HitSource) GetStringSlice(field string) ([]string, error) {
+	v, ok := sourceValue(h, field)
+	if !ok {
+		return nil, fmt.Errorf("field %s: %w", field, ErrMissingField)
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+
+	var out = make([]string, 0, len(list))
+
+	for i, item := range list {
+		if item == nil {
+			continue
+		}
+
+		s, ok := coerceString(item)
+		if !ok {
+			return nil, &FieldTypeError{Field: fmt.Sprintf("%s[%d]", field, i), Value: item, Type: "string"}
+		}
+
+		out = append(out, s)
+	}
+
+	return out, nil
+}
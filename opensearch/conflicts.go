@@ -0,0 +1,277 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MappingConflictReport is the report of the fields mapped with different types
+// across the indices of a pattern, meant to be stored and read by tools, like
+// data-quality dashboards.
+type MappingConflictReport struct {
+	Index       []string  `json:"index"`
+	GeneratedAt time.Time `json:"@timestamp"`
+	// Fields are the conflicting fields grouped by their base field, sorted.
+	Fields []FieldConflicts `json:"fields"`
+}
+
+// FieldConflicts are the conflicts of a base field: the field itself and its
+// multi-fields, e.g. "message" and "message.keyword".
+type FieldConflicts struct {
+	BaseField string            `json:"baseField"`
+	Conflicts []MappingConflict `json:"conflicts"`
+}
+
+// MappingConflict is a field mapped with different types.
+type MappingConflict struct {
+	Field string `json:"field"`
+	// Types are the types of the field, sorted, with their indices.
+	Types      []ConflictType     `json:"types"`
+	Resolution ConflictResolution `json:"resolution"`
+}
+
+// ConflictType is a type of a conflicting field and the sorted indices mapping it so.
+type ConflictType struct {
+	Type    string   `json:"type"`
+	Indices []string `json:"indices"`
+}
+
+// ConflictResolution is the suggested resolution of a conflict.
+type ConflictResolution struct {
+	// Type is the type to map the field as in every index.
+	Type string `json:"type"`
+	// Reindex are the indices of the other types, to reindex or let expire once the
+	// index template maps the field as Type.
+	Reindex []string `json:"reindex"`
+	Reason  string   `json:"reason"`
+}
+
+// Count returns the number of conflicting fields of the report.
+func (r MappingConflictReport) Count() int {
+	var n int
+	for _, f := range r.Fields {
+		n += len(f.Conflicts)
+	}
+
+	return n
+}
+
+// GetMappingConflicts returns the report of the fields mapped with different types
+// across the indices matching the patterns, with a suggested resolution for each.
+func GetMappingConflicts(ctx context.Context, index []string) (MappingConflictReport, error) {
+	merged, err := GetMergedMapping(ctx, index)
+	if err != nil {
+		return MappingConflictReport{}, err
+	}
+
+	report := MappingConflictReport{Index: index, GeneratedAt: time.Now().UTC(), Fields: []FieldConflicts{}}
+
+	var groups = make(map[string][]MappingConflict)
+
+	for field, byType := range merged.Conflicts {
+		base := baseField(merged, field)
+		groups[base] = append(groups[base], newMappingConflict(field, byType))
+	}
+
+	for base, conflicts := range groups {
+		sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Field < conflicts[j].Field })
+
+		report.Fields = append(report.Fields, FieldConflicts{BaseField: base, Conflicts: conflicts})
+	}
+
+	sort.Slice(report.Fields, func(i, j int) bool { return report.Fields[i].BaseField < report.Fields[j].BaseField })
+
+	return report, nil
+}
+
+// baseField returns the field a multi-field belongs to, the nearest parent mapped as
+// a leaf, or the field itself.
+func baseField(merged MergedMapping, field string) string {
+	base := field
+
+	for i := strings.LastIndexByte(field, '.'); i > 0; i = strings.LastIndexByte(field[:i], '.') {
+		if _, ok := merged.Fields[field[:i]]; ok {
+			base = field[:i]
+		}
+	}
+
+	return base
+}
+
+func newMappingConflict(field string, byType map[string][]string) MappingConflict {
+	c := MappingConflict{Field: field}
+
+	for t, indices := range byType {
+		c.Types = append(c.Types, ConflictType{Type: t, Indices: indices})
+	}
+
+	sort.Slice(c.Types, func(i, j int) bool { return c.Types[i].Type < c.Types[j].Type })
+
+	c.Resolution = resolveConflict(byType)
+
+	for _, t := range c.Types {
+		if t.Type != c.Resolution.Type {
+			c.Resolution.Reindex = append(c.Resolution.Reindex, t.Indices...)
+		}
+	}
+
+	sort.Strings(c.Resolution.Reindex)
+
+	return c
+}
+
+// numericWidth ranks the numeric types by the values they hold.
+var numericWidth = map[string]int{
+	"byte":          1,
+	"short":         2,
+	"integer":       3,
+	"long":          4,
+	"unsigned_long": 5,
+	"half_float":    6,
+	"float":         7,
+	"scaled_float":  8,
+	"double":        9,
+}
+
+var stringTypes = map[string]bool{
+	"keyword":          true,
+	"constant_keyword": true,
+	"wildcard":         true,
+	"text":             true,
+	"match_only_text":  true,
+}
+
+// resolveConflict suggests the type to map a field as, the one every value of the
+// others can be reindexed into when there is one, otherwise the most used.
+func resolveConflict(byType map[string][]string) ConflictResolution {
+	var numeric, texts, dates int
+	var widest string
+
+	for t := range byType {
+		switch {
+		case numericWidth[t] != 0:
+			numeric++
+
+			if numericWidth[t] > numericWidth[widest] {
+				widest = t
+			}
+		case stringTypes[t]:
+			texts++
+		case t == "date" || t == "date_nanos":
+			dates++
+		}
+	}
+
+	switch {
+	case numeric == len(byType):
+		if numericWidth[widest] > numericWidth["unsigned_long"] {
+			widest = "double"
+		}
+
+		return ConflictResolution{Type: widest, Reason: "numeric types differ, the widest holds the values of all"}
+	case dates == len(byType):
+		return ConflictResolution{Type: "date_nanos", Reason: "date precisions differ, date_nanos holds the dates of both"}
+	case texts == len(byType):
+		if _, ok := byType["keyword"]; ok {
+			return ConflictResolution{Type: "keyword", Reason: "string types differ, keyword supports exact matches, sorting and aggregations; add a text multi-field for full-text search"}
+		}
+	case dates != 0 && dates+texts == len(byType):
+		return ConflictResolution{Type: "date", Reason: "dates are indexed as strings in some indices, so range queries and date histograms miss them"}
+	}
+
+	var most string
+	for t, indices := range byType {
+		if most == "" || len(indices) > len(byType[most]) || (len(indices) == len(byType[most]) && t < most) {
+			most = t
+		}
+	}
+
+	return ConflictResolution{Type: most, Reason: "types are incompatible, the most used is kept; fix the producers of the others"}
+}
+
+// ConflictSink stores a mapping conflict report.
+type ConflictSink func(ctx context.Context, report MappingConflictReport) error
+
+// ConflictFileSink writes the reports as JSON to path, replacing the previous one.
+func ConflictFileSink(path string) ConflictSink {
+	return func(ctx context.Context, report MappingConflictReport) error {
+		j, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		// Readers never see a partially written report.
+		tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+		if err != nil {
+			return err
+		}
+
+		defer os.Remove(tmp.Name())
+
+		_, err = tmp.Write(append(j, '\n'))
+		if err == nil {
+			err = tmp.Close()
+		} else {
+			_ = tmp.Close()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return os.Rename(tmp.Name(), path)
+	}
+}
+
+// ConflictIndexSink indexes the reports in index as a document per conflicting
+// field, with the time, the patterns and the base field of the report, so that
+// dashboards can aggregate them.
+func ConflictIndexSink(index string) ConflictSink {
+	return func(ctx context.Context, report MappingConflictReport) error {
+		var docs []interface{}
+
+		for _, f := range report.Fields {
+			for _, c := range f.Conflicts {
+				docs = append(docs, map[string]interface{}{
+					"@timestamp": report.GeneratedAt,
+					"index":      report.Index,
+					"baseField":  f.BaseField,
+					"field":      c.Field,
+					"types":      c.Types,
+					"resolution": c.Resolution,
+				})
+			}
+		}
+
+		return BulkCreate(ctx, index, docs)
+	}
+}
+
+// MappingConflictsTask returns a task building the mapping conflict report of the
+// patterns and storing it in the sinks, e.g. to run on a schedule as the Task of a
+// schedule.Job.
+func MappingConflictsTask(index []string, sinks ...ConflictSink) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		report, err := GetMappingConflicts(ctx, index)
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+
+		for _, sink := range sinks {
+			err = sink(ctx, report)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+}
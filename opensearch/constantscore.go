@@ -0,0 +1,75 @@
+package opensearch
+
+type ConstantScore struct {
+	Filter Query   `json:"filter"`
+	Boost  float64 `json:"boost,omitempty"`
+}
+
+// ConstantScoreQuery returns a query matching the documents q matches, in filter
+// context, all scored boost, 1 when zero. Filters skip scoring and are cached by the
+// cluster, so searches only filtering, like those of detections and exports, are
+// cheaper this way.
+func ConstantScoreQuery(q Query, boost float64) Query {
+	return Query{ConstantScore: &ConstantScore{Filter: q, Boost: boost}}
+}
+
+// ConstantScoreMode returns a copy of the request whose query SearchIn and
+// MultiSearch wrap in a constant_score query when it only filters, i.e. when it holds
+// no full-text, vector or scoring clause, so that the cluster doesn't score hits
+// nobody ranks.
+func (q SearchRequest) ConstantScoreMode() SearchRequest {
+	q.ConstantScoring = true
+
+	return q
+}
+
+// constantScore wraps the query in a constant_score query when the request asks so
+// and the query only filters.
+func (q *SearchRequest) constantScore() {
+	if !q.ConstantScoring || q.Query == nil || q.Query.ConstantScore != nil || !q.Query.filterOnly() {
+		return
+	}
+
+	wrapped := ConstantScoreQuery(*q.Query, 0)
+	q.Query = &wrapped
+}
+
+// filterOnly reports whether the query only has clauses whose scores mean nothing to
+// filtering, like term, range or exists clauses, possibly combined in bool or dis_max
+// queries.
+func (q Query) filterOnly() bool {
+	if len(q.Match) != 0 || q.MultiMatch != nil || len(q.MatchBoolPrefix) != 0 || len(q.MatchPhrase) != 0 ||
+		len(q.MatchPhrasePrefix) != 0 || q.QueryString != nil || q.SimpleQueryString != nil || len(q.Fuzzy) != 0 ||
+		len(q.KNN) != 0 || len(q.Intervals) != 0 || q.MoreLikeThis != nil || q.FunctionScore != nil {
+		return false
+	}
+
+	if q.HasChild != nil && q.HasChild.ScoreMode != "" && q.HasChild.ScoreMode != "none" {
+		return false
+	}
+
+	if q.HasParent != nil && q.HasParent.Score {
+		return false
+	}
+
+	if q.DisMax != nil {
+		for _, clause := range q.DisMax.Queries {
+			if !clause.filterOnly() {
+				return false
+			}
+		}
+	}
+
+	// Filter and must_not clauses are in filter context already.
+	if q.Bool != nil {
+		for _, list := range [][]Query{q.Bool.Must, q.Bool.Should} {
+			for _, clause := range list {
+				if !clause.filterOnly() {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,139 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/threatwinds/go-sdk/secrets"
+)
+
+// Credentials returns the current user and password of the cluster.
+type Credentials func(ctx context.Context) (user, password string, err error)
+
+// SecretCredentials reads the credentials from p under the given secret names.
+func SecretCredentials(p secrets.Provider, userSecret, passwordSecret string) Credentials {
+	return func(ctx context.Context) (string, string, error) {
+		user, err := p.Get(ctx, userSecret)
+		if err != nil {
+			return "", "", fmt.Errorf("opensearch user: %w", err)
+		}
+
+		password, err := p.Get(ctx, passwordSecret)
+		if err != nil {
+			return "", "", fmt.Errorf("opensearch password: %w", err)
+		}
+
+		return user.Reveal(), password.Reveal(), nil
+	}
+}
+
+// ConnectWithSecrets connects like Connect, authenticating with the user and password
+// read from p under the given secret names. They are read again every hour, and when
+// the cluster rejects them, to follow rotations.
+func ConnectWithSecrets(ctx context.Context, nodes []string, p secrets.Provider, userSecret, passwordSecret string) error {
+	return ConnectWithCredentials(ctx, nodes, SecretCredentials(p, userSecret, passwordSecret), time.Hour)
+}
+
+// credentialsTransport authenticates requests with credentials refreshed every
+// refresh interval, and right away when a request is rejected with 401, which is then
+// retried once.
+type credentialsTransport struct {
+	next    http.RoundTripper
+	source  Credentials
+	refresh time.Duration
+
+	mu       sync.Mutex
+	user     string
+	password string
+	fetched  time.Time
+}
+
+// rotating is the transport of the credentials refreshed by RefreshCredentials.
+var rotating atomic.Pointer[credentialsTransport]
+
+// ConnectWithCredentials connects like Connect, authenticating with the credentials
+// returned by source, which are read again every refresh interval, and whenever the
+// cluster rejects them, so that rotated passwords apply without restarting.
+func ConnectWithCredentials(ctx context.Context, nodes []string, source Credentials, refresh time.Duration) error {
+	return ConnectWithOptions(ctx, ClientOptions{Nodes: nodes, Credentials: source, CredentialsRefresh: refresh})
+}
+
+// RefreshCredentials makes the next request read the credentials again, e.g. when
+// notified of a rotation by the secrets store. It has no effect unless connected with
+// ConnectWithCredentials or ConnectWithSecrets.
+func RefreshCredentials() {
+	if t := rotating.Load(); t != nil {
+		t.mu.Lock()
+		t.fetched = time.Time{}
+		t.mu.Unlock()
+	}
+}
+
+func (t *credentialsTransport) credentials(ctx context.Context, force bool) (string, string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !force && !t.fetched.IsZero() && (t.refresh <= 0 || time.Since(t.fetched) < t.refresh) {
+		return t.user, t.password, nil
+	}
+
+	user, password, err := t.source(ctx)
+	if err != nil {
+		// Keep using the previous credentials if there are some, they may still be
+		// valid.
+		if t.fetched.IsZero() {
+			return "", "", err
+		}
+
+		return t.user, t.password, nil
+	}
+
+	t.user, t.password, t.fetched = user, password, time.Now()
+
+	return user, password, nil
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	user, password, err := t.credentials(req.Context(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.send(req, user, password)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The body must be replayable to retry.
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	refreshedUser, refreshedPassword, err := t.credentials(req.Context(), true)
+	if err != nil || (refreshedUser == user && refreshedPassword == password) {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+	}
+
+	resp.Body.Close()
+
+	return t.send(retry, refreshedUser, refreshedPassword)
+}
+
+func (t *credentialsTransport) send(req *http.Request, user, password string) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(user, password)
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,424 @@
+// Package dashboard describes the data sources of charts, what to search, how to
+// aggregate it and what to project, as JSON descriptors saved with the dashboards,
+// and runs them into chart-ready series and tables, so that the frontends and the
+// report generator share one implementation.
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Kind is the shape of the data of a chart.
+type Kind string
+
+const (
+	// TimeSeries aggregates the documents per time interval, e.g. for line charts.
+	TimeSeries Kind = "timeseries"
+	// Terms aggregates the documents per value of GroupBy, e.g. for bar or pie
+	// charts.
+	Terms Kind = "terms"
+	// Metric aggregates all the documents, e.g. for counters.
+	Metric Kind = "metric"
+	// Table lists the Columns of the latest documents.
+	Table Kind = "table"
+)
+
+// MetricSpec is a value computed per bucket.
+type MetricSpec struct {
+	// Name names the series of the metric, its type by default.
+	Name string `json:"name,omitempty"`
+	// Type is "count", the default, "sum", "avg", "min", "max" or "cardinality".
+	Type  string `json:"type,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+func (m MetricSpec) name() string {
+	if m.Name != "" {
+		return m.Name
+	}
+
+	if m.Type == "" {
+		return "count"
+	}
+
+	return m.Type
+}
+
+func (m MetricSpec) agg() (opensearch.Aggs, error) {
+	field := &opensearch.Agg{Field: m.Field}
+
+	switch m.Type {
+	case "sum":
+		return opensearch.Aggs{Sum: field}, nil
+	case "avg":
+		return opensearch.Aggs{Avg: field}, nil
+	case "min":
+		return opensearch.Aggs{Min: field}, nil
+	case "max":
+		return opensearch.Aggs{Max: field}, nil
+	case "cardinality":
+		return opensearch.Aggs{Cardinality: &opensearch.Cardinality{Field: m.Field}}, nil
+	}
+
+	return opensearch.Aggs{}, fmt.Errorf("unknown metric type %q", m.Type)
+}
+
+// DataSource is the saved descriptor of the data of a chart.
+type DataSource struct {
+	Name  string   `json:"name"`
+	Title string   `json:"title,omitempty"`
+	Kind  Kind     `json:"kind"`
+	Index []string `json:"index"`
+	// Query filters the documents, all by default.
+	Query *opensearch.Query `json:"query,omitempty"`
+	// TimeField is the date field of the documents, "@timestamp" by default, and From
+	// and To bound it, "now-24h" and "now" by default.
+	TimeField string `json:"timeField,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	// Interval is the fixed interval of time series, like "1h", one hour by default.
+	Interval string `json:"interval,omitempty"`
+	// GroupBy splits time series, and groups terms charts, by the values of a keyword
+	// field, keeping the Size most frequent, 10 by default.
+	GroupBy string `json:"groupBy,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	// Metrics are the values of each bucket, its document count by default.
+	Metrics []MetricSpec `json:"metrics,omitempty"`
+	// Columns are the fields of the documents of tables.
+	Columns []string `json:"columns,omitempty"`
+}
+
+func (ds DataSource) timeField() string {
+	if ds.TimeField == "" {
+		return "@timestamp"
+	}
+
+	return ds.TimeField
+}
+
+func (ds DataSource) metrics() []MetricSpec {
+	if len(ds.Metrics) == 0 {
+		return []MetricSpec{{Type: "count"}}
+	}
+
+	return ds.Metrics
+}
+
+func (ds DataSource) size() int64 {
+	if ds.Size <= 0 {
+		return 10
+	}
+
+	return ds.Size
+}
+
+// Request returns the search request of the data source.
+func (ds DataSource) Request() (opensearch.SearchRequest, error) {
+	from, to := ds.From, ds.To
+	if from == "" {
+		from = "now-24h"
+	}
+
+	if to == "" {
+		to = "now"
+	}
+
+	filter := []opensearch.Query{{Range: map[string]map[string]interface{}{
+		ds.timeField(): {"gte": from, "lte": to},
+	}}}
+
+	if ds.Query != nil {
+		filter = append(filter, *ds.Query)
+	}
+
+	q := opensearch.SearchRequest{Query: &opensearch.Query{Bool: &opensearch.Bool{Filter: filter}}}
+
+	metrics := make(map[string]opensearch.Aggs)
+
+	for i, m := range ds.metrics() {
+		if m.Type == "" || m.Type == "count" {
+			continue
+		}
+
+		agg, err := m.agg()
+		if err != nil {
+			return q, fmt.Errorf("data source %s: %w", ds.Name, err)
+		}
+
+		metrics[fmt.Sprintf("m%d", i)] = agg
+	}
+
+	var split map[string]opensearch.Aggs
+	if ds.GroupBy != "" {
+		split = map[string]opensearch.Aggs{"group": {Terms: &opensearch.Terms{Field: ds.GroupBy, Size: ds.size()}, Aggs: metrics}}
+	}
+
+	switch ds.Kind {
+	case TimeSeries:
+		interval := ds.Interval
+		if interval == "" {
+			interval = "1h"
+		}
+
+		var zero int64
+
+		sub := metrics
+		if split != nil {
+			sub = split
+		}
+
+		q.Aggs = map[string]opensearch.Aggs{"time": {
+			DateHistogram: &opensearch.Histogram{
+				Field:          ds.timeField(),
+				FixedInterval:  interval,
+				MinDocCount:    &zero,
+				ExtendedBounds: map[string]interface{}{"min": from, "max": to},
+			},
+			Aggs: sub,
+		}}
+	case Terms:
+		if split == nil {
+			return q, fmt.Errorf("data source %s: terms charts require groupBy", ds.Name)
+		}
+
+		q.Aggs = split
+	case Metric:
+		q.Aggs = metrics
+		// Counts are read from the total, tracked accurately.
+		q.TrackTotalHits = true
+	case Table:
+		if len(ds.Columns) == 0 {
+			return q, fmt.Errorf("data source %s: tables require columns", ds.Name)
+		}
+
+		q.Size = ds.size()
+		q.Source = &opensearch.Source{Includes: ds.Columns}
+		q.Sort = []map[string]map[string]interface{}{{ds.timeField(): {"order": "desc"}}}
+	default:
+		return q, fmt.Errorf("data source %s: unknown kind %q", ds.Name, ds.Kind)
+	}
+
+	return q, nil
+}
+
+// Point is a value of a series, at a time for time series or for a category.
+type Point struct {
+	Time     *time.Time `json:"time,omitempty"`
+	Category string     `json:"category,omitempty"`
+	Value    float64    `json:"value"`
+}
+
+// Series is a line, or a set of bars, of a chart.
+type Series struct {
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+// Chart is the data of a data source.
+type Chart struct {
+	Name   string   `json:"name"`
+	Title  string   `json:"title,omitempty"`
+	Kind   Kind     `json:"kind"`
+	Series []Series `json:"series,omitempty"`
+	// Columns and Rows hold the documents of tables.
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows,omitempty"`
+}
+
+// Options of Run.
+type Options struct {
+	// TenantID scopes the search as SearchRequest.TenantScope does.
+	TenantID string
+}
+
+// Run runs the data source and returns its chart. Time series are split into one
+// series per metric, or per group and metric, named "group" or "group metric" when
+// grouped and the data source has more than one metric.
+func Run(ctx context.Context, ds DataSource, o Options) (Chart, error) {
+	q, err := ds.Request()
+	if err != nil {
+		return Chart{}, err
+	}
+
+	if o.TenantID != "" {
+		q = q.TenantScope(o.TenantID)
+	}
+
+	chart := Chart{Name: ds.Name, Title: ds.Title, Kind: ds.Kind}
+
+	if ds.Kind == Table {
+		result, err := q.SearchIn(ctx, ds.Index)
+		if err != nil {
+			return Chart{}, err
+		}
+
+		chart.Columns = ds.Columns
+
+		for _, hit := range result.Hits.Hits {
+			row := make([]interface{}, len(ds.Columns))
+			for i, column := range ds.Columns {
+				row[i], _ = hit.Value(column)
+			}
+
+			chart.Rows = append(chart.Rows, row)
+		}
+
+		return chart, nil
+	}
+
+	var result opensearch.AggregationResult
+
+	if len(q.Aggs) == 0 {
+		// Metric data sources only counting documents.
+		q.Size = 0
+
+		search, err := q.SearchIn(ctx, ds.Index)
+		if err != nil {
+			return Chart{}, err
+		}
+
+		result = opensearch.AggregationResult{Total: search.Hits.Total, Aggregations: search.Aggregations}
+	} else {
+		result, err = q.AggregateIn(ctx, ds.Index)
+		if err != nil {
+			return Chart{}, err
+		}
+	}
+
+	series := newSeriesSet(ds)
+
+	switch ds.Kind {
+	case TimeSeries:
+		buckets, err := result.Aggregations.Buckets("time")
+		if err != nil {
+			return Chart{}, err
+		}
+
+		for _, b := range buckets {
+			key, _ := b.Key.(float64)
+			at := time.UnixMilli(int64(key)).UTC()
+
+			if ds.GroupBy == "" {
+				series.add("", Point{Time: &at}, b)
+				continue
+			}
+
+			groups, err := b.Aggregations.Buckets("group")
+			if err != nil {
+				return Chart{}, err
+			}
+
+			for _, g := range groups {
+				series.add(bucketKey(g), Point{Time: &at}, g)
+			}
+		}
+	case Terms:
+		groups, err := result.Aggregations.Buckets("group")
+		if err != nil {
+			return Chart{}, err
+		}
+
+		for _, g := range groups {
+			series.add("", Point{Category: bucketKey(g)}, g)
+		}
+	case Metric:
+		series.add("", Point{}, opensearch.Bucket{DocCount: result.Total.Value, Aggregations: result.Aggregations})
+	}
+
+	chart.Series = series.list()
+
+	return chart, nil
+}
+
+func bucketKey(b opensearch.Bucket) string {
+	if b.KeyAsString != "" {
+		return b.KeyAsString
+	}
+
+	return fmt.Sprint(b.Key)
+}
+
+// seriesSet collects the series of a chart in the order they appear.
+type seriesSet struct {
+	ds     DataSource
+	series map[string]*Series
+	order  []string
+}
+
+func newSeriesSet(ds DataSource) *seriesSet {
+	return &seriesSet{ds: ds, series: make(map[string]*Series)}
+}
+
+// add adds a point per metric of the bucket to the series of group.
+func (s *seriesSet) add(group string, p Point, b opensearch.Bucket) {
+	metrics := s.ds.metrics()
+
+	for i, m := range metrics {
+		name := m.name()
+
+		switch {
+		case group != "" && len(metrics) > 1:
+			name = group + " " + name
+		case group != "":
+			name = group
+		}
+
+		if m.Type == "" || m.Type == "count" {
+			p.Value = float64(b.DocCount)
+		} else {
+			p.Value, _ = b.Aggregations.Value(fmt.Sprintf("m%d", i))
+		}
+
+		series, ok := s.series[name]
+		if !ok {
+			series = &Series{Name: name}
+			s.series[name] = series
+			s.order = append(s.order, name)
+		}
+
+		series.Points = append(series.Points, p)
+	}
+}
+
+// list returns the series, those of time series sorted by name, since groups appear
+// in the buckets of each interval in a different order.
+func (s *seriesSet) list() []Series {
+	if s.ds.Kind == TimeSeries && s.ds.GroupBy != "" {
+		sort.Strings(s.order)
+	}
+
+	var list = make([]Series, len(s.order))
+	for i, name := range s.order {
+		list[i] = *s.series[name]
+	}
+
+	return list
+}
+
+// Dashboard is a saved set of data sources.
+type Dashboard struct {
+	Name    string       `json:"name"`
+	Title   string       `json:"title,omitempty"`
+	Sources []DataSource `json:"sources"`
+}
+
+// Run runs the data sources of the dashboard, in order.
+func (d Dashboard) Run(ctx context.Context, o Options) ([]Chart, error) {
+	var charts = make([]Chart, 0, len(d.Sources))
+
+	for _, ds := range d.Sources {
+		chart, err := Run(ctx, ds, o)
+		if err != nil {
+			return nil, fmt.Errorf("dashboard %s: %w", d.Name, err)
+		}
+
+		charts = append(charts, chart)
+	}
+
+	return charts, nil
+}
@@ -0,0 +1,107 @@
+package opensearch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dedupe removes the hits sharing the same value of field, keeping the best scoring
+// one of each value in its original position. It is meant for combining results
+// across indices when collapse cannot be used, e.g. together with k-NN queries. The
+// field is read from the source, or from the returned fields when not in the source.
+// Hits without the field are kept.
+func (r *SearchResult) Dedupe(field string) {
+	var best = make(map[string]int)
+	var keep = make([]bool, len(r.Hits.Hits))
+
+	for i, hit := range r.Hits.Hits {
+		value, ok := hitValue(hit, field)
+		if !ok {
+			keep[i] = true
+			continue
+		}
+
+		key := fmt.Sprint(value)
+
+		j, seen := best[key]
+		if seen && hitScore(r.Hits.Hits[j]) >= hitScore(hit) {
+			continue
+		}
+
+		if seen {
+			keep[j] = false
+		}
+
+		best[key] = i
+		keep[i] = true
+	}
+
+	var hits = make([]Hit, 0, len(best))
+	for i, hit := range r.Hits.Hits {
+		if keep[i] {
+			hits = append(hits, hit)
+		}
+	}
+
+	r.Hits.Hits = hits
+}
+
+// Value returns the value of the dot-notation field of the hit, from its source, or
+// the first value of its fields, e.g. for docvalue or script fields.
+func (h Hit) Value(field string) (interface{}, bool) {
+	return hitValue(h, field)
+}
+
+// hitValue returns the first value of the dot-notation field of the hit.
+func hitValue(hit Hit, field string) (interface{}, bool) {
+	if v, ok := sourceValue(hit.Source, field); ok {
+		return v, true
+	}
+
+	switch v := hit.Fields[field].(type) {
+	case nil:
+		return nil, false
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, false
+		}
+		return v[0], true
+	default:
+		return v, true
+	}
+}
+
+// sourceValue returns the value at the dot-notation field of src, whether it is
+// stored as nested objects or under a dotted key.
+func sourceValue(src map[string]interface{}, field string) (interface{}, bool) {
+	if v, ok := src[field]; ok && v != nil {
+		return v, true
+	}
+
+	for i := strings.Index(field, "."); i != -1; i = nextDot(field, i) {
+		if m, ok := src[field[:i]].(map[string]interface{}); ok {
+			if v, ok := sourceValue(m, field[i+1:]); ok {
+				return v, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func nextDot(field string, i int) int {
+	j := strings.Index(field[i+1:], ".")
+	if j == -1 {
+		return -1
+	}
+
+	return i + 1 + j
+}
+
+func hitScore(hit Hit) float64 {
+	if score, ok := hit.Score.(float64); ok {
+		return score
+	}
+
+	return 0
+}
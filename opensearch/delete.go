@@ -2,17 +2,36 @@ package opensearch
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
 )
 
 // Delete deletes the document from the OpenSearch index.
 func (h Hit) Delete(ctx context.Context) error {
+	err := h.delete(ctx)
+
+	recordAudit(ctx, "delete", []string{h.Index}, h.ID, nil, "", err)
+
+	return err
+}
+
+func (h Hit) delete(ctx context.Context) error {
+	_, err := checkIndexAccess(h.Index)
+	if err != nil {
+		return err
+	}
+
 	req := opensearchapi.DeleteRequest{
 		Index:      h.Index,
 		DocumentID: h.ID,
+		Routing:    h.Routing,
 	}
 
 	resp, err := req.Do(ctx, client)
@@ -28,8 +47,61 @@ func (h Hit) Delete(ctx context.Context) error {
 			return err
 		}
 
-		return fmt.Errorf("search engine status %d, response: %s", resp.StatusCode, body)
+		return statusError(ctx, resp.StatusCode, body)
 	}
 
 	return nil
 }
+
+// DeleteByQueryResult is the result of DeleteByQuery.
+type DeleteByQueryResult struct {
+	// Total is the number of documents matching the query.
+	Total int64 `json:"total"`
+	// Deleted is the number of documents deleted.
+	Deleted int64 `json:"deleted"`
+}
+
+// DeleteByQuery permanently deletes the documents of the indices matching query,
+// waiting for the deletion to complete, and records it in the audit log. Documents
+// changed while deleting are skipped; failures are returned as an error along with
+// the result.
+func DeleteByQuery(ctx context.Context, index []string, query Query) (DeleteByQueryResult, error) {
+	result, err := deleteByQuery(ctx, index, &query)
+
+	recordAudit(ctx, "delete_by_query", index, "", &query, "", err)
+
+	return result, err
+}
+
+func deleteByQuery(ctx context.Context, index []string, query *Query) (DeleteByQueryResult, error) {
+	index, err := checkIndexAccess(index...)
+	if err != nil {
+		return DeleteByQueryResult{}, err
+	}
+
+	if len(index) == 0 {
+		return DeleteByQueryResult{}, errors.New("deleting by query needs the indices to delete from")
+	}
+
+	resp, err := do(ctx, http.MethodPost, "/"+url.PathEscape(strings.Join(index, ","))+"/_delete_by_query",
+		url.Values{"conflicts": {"proceed"}, "wait_for_completion": {"true"}}, map[string]interface{}{"query": query})
+	if err != nil {
+		return DeleteByQueryResult{}, err
+	}
+
+	var result struct {
+		DeleteByQueryResult
+		Failures []json.RawMessage `json:"failures"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return DeleteByQueryResult{}, err
+	}
+
+	if len(result.Failures) != 0 {
+		return result.DeleteByQueryResult, fmt.Errorf("delete by query had %d failures, first: %s", len(result.Failures), result.Failures[0])
+	}
+
+	return result.DeleteByQueryResult, nil
+}
@@ -0,0 +1,220 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Describe renders the request as an indented, human-readable tree listing the clause
+// types, the fields they target (noting keyword sub-fields) and their parameters,
+// suitable for logs and support tickets. For example:
+//
+//	size: 10
+//	query:
+//	  bool
+//	    filter:
+//	      term message.keyword (keyword sub-field of message) {"value":"denied"}
+//	      range @timestamp {"gte":"now-15m"}
+//	sort:
+//	  @timestamp {"order":"desc"}
+func (q SearchRequest) Describe() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "size: %d\n", q.Size)
+
+	if q.From != 0 {
+		fmt.Fprintf(&b, "from: %d\n", q.From)
+	}
+
+	if q.Query != nil {
+		b.WriteString("query:\n")
+		describeQuery(&b, reflect.ValueOf(*q.Query), 1)
+	}
+
+	if q.PostFilter != nil {
+		b.WriteString("post_filter:\n")
+		describeQuery(&b, reflect.ValueOf(*q.PostFilter), 1)
+	}
+
+	if len(q.Sort) != 0 {
+		b.WriteString("sort:\n")
+		for _, s := range q.Sort {
+			for _, field := range sortedKeys(reflect.ValueOf(s)) {
+				writeLine(&b, 1, "%s%s %s", field, keywordNote(field), compact(s[field]))
+			}
+		}
+	}
+
+	if len(q.Aggs) != 0 {
+		b.WriteString("aggs:\n")
+		describeAggs(&b, q.Aggs, 1)
+	}
+
+	if q.Collapse != nil {
+		writeLine(&b, 0, "collapse: %s%s", q.Collapse.Field, keywordNote(q.Collapse.Field))
+	}
+
+	if after := q.searchAfter(); len(after) != 0 {
+		writeLine(&b, 0, "search_after: %s", compact(after))
+	}
+
+	return b.String()
+}
+
+// describeQuery writes one line per non-empty clause of the Query value v.
+func describeQuery(b *strings.Builder, v reflect.Value, depth int) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		if f.IsZero() {
+			continue
+		}
+
+		clause := jsonName(t.Field(i))
+
+		switch f.Kind() {
+		case reflect.Map:
+			for _, key := range sortedKeys(f) {
+				value := f.MapIndex(reflect.ValueOf(key)).Interface()
+
+				if clause == "exists" || clause == "ids" {
+					writeLine(b, depth, "%s %s%s", clause, compact(value), keywordNote(fmt.Sprint(value)))
+					continue
+				}
+
+				writeLine(b, depth, "%s %s%s %s", clause, key, keywordNote(key), compact(value))
+			}
+		default:
+			describeStruct(b, clause, f, depth)
+		}
+	}
+}
+
+// describeStruct writes a struct clause with its scalar parameters on one line,
+// followed by its nested queries.
+func describeStruct(b *strings.Builder, clause string, v reflect.Value, depth int) {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		writeLine(b, depth, "%s %s", clause, compact(v.Interface()))
+		return
+	}
+
+	t := v.Type()
+
+	var params = make(map[string]interface{})
+	var nested []int
+
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		if f.IsZero() || !t.Field(i).IsExported() {
+			continue
+		}
+
+		if containsQuery(f.Type()) {
+			nested = append(nested, i)
+			continue
+		}
+
+		params[jsonName(t.Field(i))] = f.Interface()
+	}
+
+	if len(params) == 0 {
+		writeLine(b, depth, "%s", clause)
+	} else {
+		writeLine(b, depth, "%s %s", clause, compact(params))
+	}
+
+	for _, i := range nested {
+		f := v.Field(i)
+		for f.Kind() == reflect.Ptr {
+			f = f.Elem()
+		}
+
+		writeLine(b, depth+1, "%s:", jsonName(t.Field(i)))
+
+		switch f.Kind() {
+		case reflect.Slice:
+			for j := 0; j < f.Len(); j++ {
+				describeQuery(b, f.Index(j), depth+2)
+			}
+		default:
+			describeQuery(b, f, depth+2)
+		}
+	}
+}
+
+func describeAggs(b *strings.Builder, aggs map[string]Aggs, depth int) {
+	for _, name := range sortedKeys(reflect.ValueOf(aggs)) {
+		agg := aggs[name]
+
+		v := reflect.ValueOf(agg)
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			if f.IsZero() || t.Field(i).Name == "Aggs" {
+				continue
+			}
+
+			var field string
+			if fields := structFields(f); len(fields) != 0 {
+				field = keywordNote(fields[0])
+			}
+
+			writeLine(b, depth, "%s: %s %s%s", name, jsonName(t.Field(i)), compact(f.Interface()), field)
+		}
+
+		describeAggs(b, agg.Aggs, depth+1)
+	}
+}
+
+// containsQuery reports whether values of type t are or hold queries directly.
+func containsQuery(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	return t == queryType
+}
+
+// keywordNote explains fields targeting a keyword sub-field.
+func keywordNote(field string) string {
+	if base, ok := strings.CutSuffix(field, ".keyword"); ok {
+		return fmt.Sprintf(" (keyword sub-field of %s)", base)
+	}
+
+	return ""
+}
+
+func sortedKeys(m reflect.Value) []string {
+	var keys = make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		keys = append(keys, k.String())
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func compact(v interface{}) string {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(j)
+}
+
+func writeLine(b *strings.Builder, depth int, format string, args ...interface{}) {
+	b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(b, format, args...)
+	b.WriteString("\n")
+}
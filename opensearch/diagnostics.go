@@ -0,0 +1,294 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/helpers"
+)
+
+// QueryDiagnostic is the capture of a search request.
+type QueryDiagnostic struct {
+	Time       time.Time       `json:"@timestamp"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Params     string          `json:"params,omitempty"`
+	DSL        json.RawMessage `json:"dsl,omitempty"`
+	RequestID  string          `json:"requestId,omitempty"`
+	StatusCode int             `json:"statusCode"`
+	Duration   float64         `json:"durationMs"`
+	Took       int64           `json:"tookMs"`
+	TimedOut   bool            `json:"timedOut"`
+	Shards     *Shards         `json:"shards,omitempty"`
+	Slow       bool            `json:"slow"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// DiagnosticsSink stores query diagnostics.
+type DiagnosticsSink interface {
+	Write(ctx context.Context, d QueryDiagnostic) error
+}
+
+// DiagnosticsConfig configures Diagnostics.
+type DiagnosticsConfig struct {
+	// SampleRate is the fraction of the searches captured, from 0 to 1.
+	SampleRate float64
+	// SlowThreshold, when set, captures every search lasting longer.
+	SlowThreshold time.Duration
+	Sink          DiagnosticsSink
+	// Buffer bounds the captures waiting to be written, 100 by default. Captures are
+	// dropped when it is full, so that diagnostics never slow searches down.
+	Buffer int
+	// OnError is called when a capture can't be written, and may be nil.
+	OnError func(error)
+}
+
+// Diagnostics returns a middleware capturing the DSL, timings and shard statistics of
+// a sample of the searches, counts and multi-searches, plus the slow ones, into the
+// sink of cfg. Requests of other kinds, like the ones of IndexSink, aren't captured.
+func Diagnostics(cfg DiagnosticsConfig) Middleware {
+	size := cfg.Buffer
+	if size <= 0 {
+		size = 100
+	}
+
+	queue := make(chan QueryDiagnostic, size)
+
+	go func() {
+		for d := range queue {
+			err := cfg.Sink.Write(context.Background(), d)
+			if err != nil && cfg.OnError != nil {
+				cfg.OnError(err)
+			}
+		}
+	}()
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !isSearch(req.URL.Path) {
+				return next.RoundTrip(req)
+			}
+
+			sampled := cfg.SampleRate > 0 && rand.Float64() < cfg.SampleRate
+			if !sampled && cfg.SlowThreshold <= 0 {
+				return next.RoundTrip(req)
+			}
+
+			d := QueryDiagnostic{
+				Time:      time.Now().UTC(),
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				Params:    req.URL.RawQuery,
+				RequestID: helpers.RequestIDFromContext(req.Context()),
+			}
+
+			var dsl []byte
+
+			if req.Body != nil && req.Header.Get("Content-Encoding") == "" {
+				body, err := io.ReadAll(req.Body)
+				req.Body.Close()
+
+				if err != nil {
+					return nil, err
+				}
+
+				dsl = body
+
+				req = req.Clone(req.Context())
+				req.Body = io.NopCloser(bytes.NewReader(body))
+				req.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(body)), nil
+				}
+			}
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			elapsed := time.Since(start)
+
+			d.Slow = cfg.SlowThreshold > 0 && elapsed > cfg.SlowThreshold
+			if !sampled && !d.Slow {
+				return resp, err
+			}
+
+			d.Duration = float64(elapsed.Microseconds()) / 1000
+			d.DSL = rawDSL(dsl)
+
+			if err != nil {
+				d.Error = err.Error()
+			}
+
+			if resp != nil {
+				d.StatusCode = resp.StatusCode
+
+				body, readErr := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+
+				if readErr != nil {
+					return resp, readErr
+				}
+
+				var stats struct {
+					Took     int64  `json:"took"`
+					TimedOut bool   `json:"timed_out"`
+					Shards   Shards `json:"_shards"`
+				}
+
+				if json.Unmarshal(body, &stats) == nil {
+					d.Took, d.TimedOut = stats.Took, stats.TimedOut
+					if stats.Shards.Total != 0 {
+						d.Shards = &stats.Shards
+					}
+				}
+			}
+
+			select {
+			case queue <- d:
+			default:
+			}
+
+			return resp, err
+		})
+	}
+}
+
+func isSearch(path string) bool {
+	return strings.HasSuffix(path, "/_search") || strings.HasSuffix(path, "/_msearch") ||
+		strings.HasSuffix(path, "/_count") || path == "/_search" || path == "/_msearch" || path == "/_count"
+}
+
+// rawDSL returns body as JSON, quoted when it isn't, like the NDJSON of multi-searches.
+func rawDSL(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if json.Valid(body) {
+		return body
+	}
+
+	quoted, _ := json.Marshal(string(body))
+
+	return quoted
+}
+
+// IndexSink stores the diagnostics as documents of an index.
+type IndexSink struct {
+	Index string
+}
+
+func (s IndexSink) Write(ctx context.Context, d QueryDiagnostic) error {
+	_, err := Do(ctx, http.MethodPost, "/"+s.Index+"/_doc", nil, d)
+
+	return err
+}
+
+// FileSink appends the diagnostics as JSON lines to files in Dir, starting a new file
+// when the current one exceeds MaxBytes, 10 MiB by default, and keeping the last
+// MaxFiles files, 10 by default.
+type FileSink struct {
+	Dir      string
+	MaxBytes int64
+	MaxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (s *FileSink) Write(_ context.Context, d QueryDiagnostic) error {
+	line, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	maxBytes := s.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 10 << 20
+	}
+
+	if s.file == nil || s.size+int64(len(line)) > maxBytes {
+		err = s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+
+	return err
+}
+
+// rotate starts a new file and removes the oldest ones beyond MaxFiles.
+func (s *FileSink) rotate() error {
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+
+	err := os.MkdirAll(s.Dir, 0o750)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(s.Dir, fmt.Sprintf("queries-%s.jsonl", time.Now().UTC().Format("20060102T150405.000000000")))
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+
+	s.file, s.size = f, 0
+
+	files, err := filepath.Glob(filepath.Join(s.Dir, "queries-*.jsonl"))
+	if err != nil {
+		return err
+	}
+
+	maxFiles := s.MaxFiles
+	if maxFiles <= 0 {
+		maxFiles = 10
+	}
+
+	// Names sort in creation order.
+	sort.Strings(files)
+
+	for len(files) > maxFiles {
+		_ = os.Remove(files[0])
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// Close closes the current file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+
+	return err
+}
@@ -0,0 +1,227 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind tells whether a Change added, removed or modified a value.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a difference between two search requests, or two mappings. Path locates
+// the value in the JSON, e.g. "query.bool.filter[1].term.user.value".
+type Change struct {
+	Path string      `json:"path"`
+	Kind ChangeKind  `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, compact(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, compact(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, compact(c.Old), compact(c.New))
+	}
+}
+
+// orderedLists are the request lists whose element order is meaningful.
+var orderedLists = map[string]bool{
+	"sort":         true,
+	"search_after": true,
+}
+
+// DiffRequests structurally compares two search requests and returns the added,
+// removed and changed values. Clauses of bool queries are matched regardless of
+// their order, so reordering filters is not reported, and a clause whose type and
+// field are unchanged is reported as a change of its values rather than as a
+// removal and an addition.
+func DiffRequests(from, to SearchRequest) ([]Change, error) {
+	a, err := toGeneric(from)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := toGeneric(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+
+	diffValues("", "", a, b, &changes)
+
+	return changes, nil
+}
+
+func toGeneric(v interface{}) (interface{}, error) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var g interface{}
+
+	err = json.Unmarshal(j, &g)
+
+	return g, err
+}
+
+func diffValues(path, key string, a, b interface{}, changes *[]Change) {
+	am, aIsMap := a.(map[string]interface{})
+	bm, bIsMap := b.(map[string]interface{})
+
+	if aIsMap && bIsMap {
+		var keys = make(map[string]bool)
+		for k := range am {
+			keys[k] = true
+		}
+		for k := range bm {
+			keys[k] = true
+		}
+
+		var sorted = make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			p := joinPath(path, k)
+			av, aok := am[k]
+			bv, bok := bm[k]
+
+			switch {
+			case !aok:
+				*changes = append(*changes, Change{Path: p, Kind: Added, New: bv})
+			case !bok:
+				*changes = append(*changes, Change{Path: p, Kind: Removed, Old: av})
+			default:
+				diffValues(p, k, av, bv, changes)
+			}
+		}
+
+		return
+	}
+
+	al, aIsList := a.([]interface{})
+	bl, bIsList := b.([]interface{})
+
+	if aIsList && bIsList {
+		if orderedLists[key] {
+			diffOrdered(path, al, bl, changes)
+		} else {
+			diffUnordered(path, al, bl, changes)
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Kind: Changed, Old: a, New: b})
+	}
+}
+
+func diffOrdered(path string, a, b []interface{}, changes *[]Change) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		p := fmt.Sprintf("%s[%d]", path, i)
+
+		switch {
+		case i >= len(a):
+			*changes = append(*changes, Change{Path: p, Kind: Added, New: b[i]})
+		case i >= len(b):
+			*changes = append(*changes, Change{Path: p, Kind: Removed, Old: a[i]})
+		default:
+			diffValues(p, "", a[i], b[i], changes)
+		}
+	}
+}
+
+// diffUnordered matches identical elements first, then pairs the remaining ones
+// sharing the same shape (clause type and field) to report their inner changes.
+func diffUnordered(path string, a, b []interface{}, changes *[]Change) {
+	var matchedA = make([]bool, len(a))
+	var matchedB = make([]bool, len(b))
+
+	for i := range a {
+		for j := range b {
+			if !matchedB[j] && reflect.DeepEqual(a[i], b[j]) {
+				matchedA[i], matchedB[j] = true, true
+				break
+			}
+		}
+	}
+
+	for i := range a {
+		if matchedA[i] {
+			continue
+		}
+
+		for j := range b {
+			if !matchedB[j] && shape(a[i]) != "" && shape(a[i]) == shape(b[j]) {
+				matchedA[i], matchedB[j] = true, true
+				diffValues(fmt.Sprintf("%s[%d]", path, j), "", a[i], b[j], changes)
+				break
+			}
+		}
+	}
+
+	for i := range a {
+		if !matchedA[i] {
+			*changes = append(*changes, Change{Path: fmt.Sprintf("%s[%d]", path, i), Kind: Removed, Old: a[i]})
+		}
+	}
+
+	for j := range b {
+		if !matchedB[j] {
+			*changes = append(*changes, Change{Path: fmt.Sprintf("%s[%d]", path, j), Kind: Added, New: b[j]})
+		}
+	}
+}
+
+// shape identifies a clause by its type and, for single-field clauses, its field,
+// e.g. "term.user" for {"term": {"user": {...}}}.
+func shape(v interface{}) string {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return ""
+	}
+
+	for clause, body := range m {
+		if inner, ok := body.(map[string]interface{}); ok && len(inner) == 1 && clause != "bool" {
+			for field := range inner {
+				return clause + "." + field
+			}
+		}
+
+		return clause
+	}
+
+	return ""
+}
+
+func joinPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		key = fmt.Sprintf("[%q]", key)
+		return path + key
+	}
+
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
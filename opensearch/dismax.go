@@ -0,0 +1,17 @@
+package opensearch
+
+type DisMax struct {
+	Queries    []Query `json:"queries"`
+	TieBreaker float64 `json:"tie_breaker,omitempty"`
+	Boost      float64 `json:"boost,omitempty"`
+}
+
+// DisMaxQuery returns a query matching the documents any of the queries matches,
+// scored by the best matching one plus tieBreaker, from 0 to 1, times the scores of
+// the others. Unlike a bool query adding the scores up, a document matching a query
+// on its best field ranks above one matching several alternative fields poorly, e.g.
+// with match queries on message and description. It is the best_fields mode of
+// MultiMatch for queries already built, possibly of different kinds.
+func DisMaxQuery(queries []Query, tieBreaker float64) Query {
+	return Query{DisMax: &DisMax{Queries: queries, TieBreaker: tieBreaker}}
+}
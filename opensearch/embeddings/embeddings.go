@@ -0,0 +1,30 @@
+// Package embeddings computes text embeddings for semantic search with k-NN queries.
+package embeddings
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Embedder computes the embeddings of texts. The result holds one vector per text,
+// in the same order.
+type Embedder interface {
+	EmbedText(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// KNNText embeds text with embedder and returns a query for the k nearest neighbors
+// of its embedding in field.
+func KNNText(ctx context.Context, field, text string, k int, embedder Embedder) (opensearch.Query, error) {
+	vectors, err := embedder.EmbedText(ctx, []string{text})
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	if len(vectors) != 1 {
+		return opensearch.Query{}, fmt.Errorf("embedder returned %d embeddings for 1 text", len(vectors))
+	}
+
+	return opensearch.KNNQuery(field, vectors[0], k, nil), nil
+}
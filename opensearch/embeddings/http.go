@@ -0,0 +1,103 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPEmbedder calls an inference endpoint compatible with the OpenAI embeddings API,
+// as served by most inference servers. The request is {"input": texts, "model": Model}
+// and the embeddings are read from {"data": [{"embedding": [...], "index": 0}, ...]}.
+// Responses shaped {"embeddings": [[...], ...]} are accepted too.
+type HTTPEmbedder struct {
+	URL    string
+	Model  string
+	Header http.Header
+	// Client is the HTTP client to use, http.DefaultClient if nil.
+	Client *http.Client
+}
+
+func (e HTTPEmbedder) EmbedText(ctx context.Context, texts []string) ([][]float32, error) {
+	var payload = map[string]interface{}{"input": texts}
+	if e.Model != "" {
+		payload["model"] = e.Model
+	}
+
+	j, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(j))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range e.Header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("embedding endpoint status %d, response: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		} `json:"data"`
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Data == nil {
+		return checkCount(result.Embeddings, len(texts))
+	}
+
+	var vectors = make([][]float32, len(result.Data))
+
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding endpoint returned an invalid index %d", d.Index)
+		}
+
+		vectors[d.Index] = d.Embedding
+	}
+
+	return checkCount(vectors, len(texts))
+}
+
+func checkCount(vectors [][]float32, texts int) ([][]float32, error) {
+	if len(vectors) != texts {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", texts, len(vectors))
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,56 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// MLCommonsEmbedder computes embeddings with a text embedding model deployed in the
+// OpenSearch ML Commons plugin, through the connection of the opensearch package.
+type MLCommonsEmbedder struct {
+	ModelID string
+}
+
+func (e MLCommonsEmbedder) EmbedText(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := opensearch.Do(ctx, http.MethodPost,
+		"/_plugins/_ml/_predict/text_embedding/"+url.PathEscape(e.ModelID), nil,
+		map[string]interface{}{
+			"text_docs":       texts,
+			"return_number":   true,
+			"target_response": []string{"sentence_embedding"},
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		InferenceResults []struct {
+			Output []struct {
+				Name string    `json:"name"`
+				Data []float32 `json:"data"`
+			} `json:"output"`
+		} `json:"inference_results"`
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors = make([][]float32, 0, len(result.InferenceResults))
+
+	for _, r := range result.InferenceResults {
+		if len(r.Output) == 0 {
+			return nil, fmt.Errorf("model %s returned no output", e.ModelID)
+		}
+
+		vectors = append(vectors, r.Output[0].Data)
+	}
+
+	return checkCount(vectors, len(texts))
+}
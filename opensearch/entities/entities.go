@@ -0,0 +1,284 @@
+// Package entities models the entities of investigations, like IPs, domains, hosts or
+// users, and the typed relations between them, e.g. a domain resolving to an IP. A
+// Graph stores them in two indices, upserting them in batches as they are observed,
+// and traverses them to pivot from an entity to its neighbors.
+package entities
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Ref identifies an entity by its type, like "ip", and its value.
+type Ref struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// ID returns the document ID of the entity, derived from its type and value.
+func (r Ref) ID() string {
+	sum := sha256.Sum256([]byte(strings.ToLower(r.Type) + "\x00" + r.Value))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+func (r Ref) String() string {
+	return r.Type + ":" + r.Value
+}
+
+// Entity is an observed entity.
+type Entity struct {
+	Ref
+	// Attributes are merged into those stored, e.g. the country of an IP.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	// FirstSeen and LastSeen are widened with the stored ones on upsert.
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Relation is an observed relation, directed from an entity to another, e.g.
+// "resolves_to" from a domain to an IP.
+type Relation struct {
+	Type string `json:"type"`
+	From Ref    `json:"from"`
+	To   Ref    `json:"to"`
+	// FirstSeen and LastSeen are widened with the stored ones on upsert, and Count
+	// is the number of times the relation was upserted.
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Count     int64     `json:"count"`
+}
+
+// ID returns the document ID of the relation.
+func (r Relation) ID() string {
+	sum := sha256.Sum256([]byte(r.From.ID() + "\x00" + r.Type + "\x00" + r.To.ID()))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// Graph stores entities and relations in two indices.
+type Graph struct {
+	Entities  string
+	Relations string
+}
+
+// New returns the graph stored in the prefix+"-entities" and prefix+"-relations"
+// indices.
+func New(prefix string) Graph {
+	return Graph{Entities: prefix + "-entities", Relations: prefix + "-relations"}
+}
+
+var (
+	keyword  = map[string]interface{}{"type": "keyword"}
+	date     = map[string]interface{}{"type": "date"}
+	refProps = map[string]interface{}{
+		"properties": map[string]interface{}{
+			"id":    keyword,
+			"type":  keyword,
+			"value": keyword,
+		},
+	}
+)
+
+// EnsureIndices creates the indices of the graph unless they exist.
+func (g Graph) EnsureIndices(ctx context.Context) error {
+	err := ensureIndex(ctx, g.Entities, map[string]interface{}{
+		"type":       keyword,
+		"value":      keyword,
+		"attributes": map[string]interface{}{"type": "object", "dynamic": true},
+		"first_seen": date,
+		"last_seen":  date,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ensureIndex(ctx, g.Relations, map[string]interface{}{
+		"type":       keyword,
+		"from":       refProps,
+		"to":         refProps,
+		"first_seen": date,
+		"last_seen":  date,
+		"count":      map[string]interface{}{"type": "long"},
+	})
+}
+
+func ensureIndex(ctx context.Context, index string, properties map[string]interface{}) error {
+	_, err := opensearch.Do(ctx, http.MethodHead, "/"+url.PathEscape(index), nil, nil)
+	if err == nil {
+		return nil
+	}
+
+	var status *opensearch.StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		return err
+	}
+
+	err = opensearch.CreateIndex(ctx, index, map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": properties},
+	})
+
+	// Another replica may have created it meanwhile.
+	if errors.As(err, &status) && status.StatusCode == http.StatusBadRequest {
+		return nil
+	}
+
+	return err
+}
+
+// mergeScript widens the first and last seen times of a stored document, merges its
+// attributes and counts the upserts.
+const mergeScript = `
+if (params.first_seen != null && (ctx._source.first_seen == null || params.first_seen.compareTo(ctx._source.first_seen) < 0)) { ctx._source.first_seen = params.first_seen; }
+if (params.last_seen != null && (ctx._source.last_seen == null || params.last_seen.compareTo(ctx._source.last_seen) > 0)) { ctx._source.last_seen = params.last_seen; }
+if (params.attributes != null) { if (ctx._source.attributes == null) { ctx._source.attributes = [:]; } ctx._source.attributes.putAll(params.attributes); }
+if (params.count != null) { ctx._source.count = (ctx._source.count == null ? 0 : ctx._source.count) + params.count; }
+`
+
+// seen returns the times formatted for the merge script, nil when zero.
+func seen(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	// RFC 3339 times in UTC with a fixed precision compare chronologically as strings.
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func update(upsert map[string]interface{}, params map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"script": map[string]interface{}{
+			"lang":   "painless",
+			"source": mergeScript,
+			"params": params,
+		},
+		"upsert": upsert,
+	}
+}
+
+func entityUpdate(e Entity) opensearch.DocUpdate {
+	params := map[string]interface{}{
+		"first_seen": seen(e.FirstSeen),
+		"last_seen":  seen(e.LastSeen),
+		"attributes": e.Attributes,
+	}
+
+	doc := map[string]interface{}{
+		"type":       e.Type,
+		"value":      e.Value,
+		"first_seen": seen(e.FirstSeen),
+		"last_seen":  seen(e.LastSeen),
+	}
+
+	if len(e.Attributes) != 0 {
+		doc["attributes"] = e.Attributes
+	}
+
+	return opensearch.DocUpdate{ID: e.ID(), Body: update(doc, params)}
+}
+
+func refDoc(r Ref) map[string]interface{} {
+	return map[string]interface{}{"id": r.ID(), "type": r.Type, "value": r.Value}
+}
+
+func relationUpdate(r Relation) opensearch.DocUpdate {
+	count := r.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	params := map[string]interface{}{
+		"first_seen": seen(r.FirstSeen),
+		"last_seen":  seen(r.LastSeen),
+		"count":      count,
+	}
+
+	doc := map[string]interface{}{
+		"type":       r.Type,
+		"from":       refDoc(r.From),
+		"to":         refDoc(r.To),
+		"first_seen": seen(r.FirstSeen),
+		"last_seen":  seen(r.LastSeen),
+		"count":      count,
+	}
+
+	return opensearch.DocUpdate{ID: r.ID(), Body: update(doc, params)}
+}
+
+// Upsert stores the entities and relations with a bulk request per index, merging
+// them with those stored. The entities of the relations are upserted too, with the
+// times of the relations, so that every relation leads to a stored entity.
+func (g Graph) Upsert(ctx context.Context, entities []Entity, relations []Relation) error {
+	var merged = make(map[string]*Entity, len(entities)+2*len(relations))
+	var order []string
+
+	add := func(e Entity) {
+		m, ok := merged[e.ID()]
+		if !ok {
+			merged[e.ID()] = &e
+			order = append(order, e.ID())
+
+			return
+		}
+
+		m.merge(e)
+	}
+
+	for _, e := range entities {
+		add(e)
+	}
+
+	var relationUpdates = make([]opensearch.DocUpdate, 0, len(relations))
+	for _, r := range relations {
+		relationUpdates = append(relationUpdates, relationUpdate(r))
+
+		add(Entity{Ref: r.From, FirstSeen: r.FirstSeen, LastSeen: r.LastSeen})
+		add(Entity{Ref: r.To, FirstSeen: r.FirstSeen, LastSeen: r.LastSeen})
+	}
+
+	var entityUpdates = make([]opensearch.DocUpdate, len(order))
+	for i, id := range order {
+		entityUpdates[i] = entityUpdate(*merged[id])
+	}
+
+	err := opensearch.BulkUpdate(ctx, g.Entities, entityUpdates)
+	if err != nil {
+		return err
+	}
+
+	return opensearch.BulkUpdate(ctx, g.Relations, relationUpdates)
+}
+
+// merge widens the times of e with those of o and adds its attributes.
+func (e *Entity) merge(o Entity) {
+	if !o.FirstSeen.IsZero() && (e.FirstSeen.IsZero() || o.FirstSeen.Before(e.FirstSeen)) {
+		e.FirstSeen = o.FirstSeen
+	}
+
+	if o.LastSeen.After(e.LastSeen) {
+		e.LastSeen = o.LastSeen
+	}
+
+	if len(o.Attributes) == 0 {
+		return
+	}
+
+	var attributes = make(map[string]interface{}, len(e.Attributes)+len(o.Attributes))
+	for k, v := range e.Attributes {
+		attributes[k] = v
+	}
+
+	for k, v := range o.Attributes {
+		attributes[k] = v
+	}
+
+	e.Attributes = attributes
+}
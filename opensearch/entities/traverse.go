@@ -0,0 +1,217 @@
+package entities
+
+import (
+	"context"
+	"sort"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Direction selects the relations followed by a traversal.
+type Direction int
+
+const (
+	// Both follows the relations from and to the entities.
+	Both Direction = iota
+	// Outgoing follows the relations from the entities.
+	Outgoing
+	// Incoming follows the relations to the entities.
+	Incoming
+)
+
+// TraverseOptions bound a traversal.
+type TraverseOptions struct {
+	// Depth is the number of hops from the start entity, 1 by default.
+	Depth int
+	// RelationTypes restricts the relations followed, all by default.
+	RelationTypes []string
+	Direction     Direction
+	// MaxRelations bounds the relations returned, 1000 by default.
+	MaxRelations int
+}
+
+// Subgraph is the result of a traversal.
+type Subgraph struct {
+	Entities  []Entity
+	Relations []Relation
+	// Depth maps the IDs of the entities to their distance from the start entity.
+	Depth map[string]int
+	// Truncated is set when MaxRelations was reached, leaving relations out.
+	Truncated bool
+}
+
+// Neighbors returns the entities reachable from start in up to Depth hops, closest
+// first, with the relations leading to them, running a terms query over the relations per hop.
+// Entities that are only referenced by relations, without a stored document, are
+// returned with their reference only.
+func (g Graph) Neighbors(ctx context.Context, start Ref, o TraverseOptions) (Subgraph, error) {
+	depth := o.Depth
+	if depth <= 0 {
+		depth = 1
+	}
+
+	maxRelations := o.MaxRelations
+	if maxRelations <= 0 {
+		maxRelations = 1000
+	}
+
+	sub := Subgraph{Depth: map[string]int{start.ID(): 0}}
+	refs := map[string]Ref{start.ID(): start}
+	seenRelations := make(map[string]bool)
+	frontier := []string{start.ID()}
+
+	for hop := 1; hop <= depth && len(frontier) != 0; hop++ {
+		left := maxRelations - len(sub.Relations)
+		if left <= 0 {
+			sub.Truncated = true
+			break
+		}
+
+		relations, more, err := g.relationsOf(ctx, frontier, o, left)
+		if err != nil {
+			return Subgraph{}, err
+		}
+
+		sub.Truncated = sub.Truncated || more
+
+		var next []string
+
+		for _, r := range relations {
+			if seenRelations[r.ID()] {
+				continue
+			}
+
+			seenRelations[r.ID()] = true
+			sub.Relations = append(sub.Relations, r)
+
+			for _, ref := range []Ref{r.From, r.To} {
+				if _, ok := sub.Depth[ref.ID()]; !ok {
+					sub.Depth[ref.ID()] = hop
+					refs[ref.ID()] = ref
+					next = append(next, ref.ID())
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	entities, err := g.entities(ctx, refs)
+	if err != nil {
+		return Subgraph{}, err
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		di, dj := sub.Depth[entities[i].ID()], sub.Depth[entities[j].ID()]
+		if di != dj {
+			return di < dj
+		}
+
+		return entities[i].String() < entities[j].String()
+	})
+
+	sub.Entities = entities
+
+	return sub, nil
+}
+
+// relationsOf returns up to size relations of the entities, and whether there are
+// more.
+func (g Graph) relationsOf(ctx context.Context, ids []string, o TraverseOptions, size int) ([]Relation, bool, error) {
+	var values = make([]interface{}, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+
+	var ends []opensearch.Query
+
+	if o.Direction != Incoming {
+		ends = append(ends, opensearch.Query{Terms: map[string][]interface{}{"from.id": values}})
+	}
+
+	if o.Direction != Outgoing {
+		ends = append(ends, opensearch.Query{Terms: map[string][]interface{}{"to.id": values}})
+	}
+
+	filter := []opensearch.Query{{Bool: &opensearch.Bool{Should: ends, MinimumShouldMatch: 1}}}
+
+	if len(o.RelationTypes) != 0 {
+		var types = make([]interface{}, len(o.RelationTypes))
+		for i, t := range o.RelationTypes {
+			types[i] = t
+		}
+
+		filter = append(filter, opensearch.Query{Terms: map[string][]interface{}{"type": types}})
+	}
+
+	req := opensearch.SearchRequest{
+		Size:  int64(size) + 1,
+		Query: &opensearch.Query{Bool: &opensearch.Bool{Filter: filter}},
+		Sort:  []map[string]map[string]interface{}{{"last_seen": {"order": "desc"}}},
+	}
+
+	result, err := req.SearchIn(ctx, []string{g.Relations})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var relations []Relation
+
+	for _, hit := range result.Hits.Hits {
+		var r Relation
+
+		err = hit.Source.ParseSource(&r)
+		if err != nil {
+			return nil, false, err
+		}
+
+		relations = append(relations, r)
+	}
+
+	if len(relations) > size {
+		return relations[:size], true, nil
+	}
+
+	return relations, false, nil
+}
+
+// entities returns the stored entities of refs, or their references when not stored.
+func (g Graph) entities(ctx context.Context, refs map[string]Ref) ([]Entity, error) {
+	var ids = make([]interface{}, 0, len(refs))
+	for id := range refs {
+		ids = append(ids, id)
+	}
+
+	req := opensearch.SearchRequest{
+		Size:  int64(len(ids)),
+		Query: &opensearch.Query{IDs: map[string][]interface{}{"values": ids}},
+	}
+
+	result, err := req.SearchIn(ctx, []string{g.Entities})
+	if err != nil {
+		return nil, err
+	}
+
+	var entities = make([]Entity, 0, len(refs))
+	var found = make(map[string]bool, len(refs))
+
+	for _, hit := range result.Hits.Hits {
+		var e Entity
+
+		err = hit.Source.ParseSource(&e)
+		if err != nil {
+			return nil, err
+		}
+
+		found[hit.ID] = true
+		entities = append(entities, e)
+	}
+
+	for id, ref := range refs {
+		if !found[id] {
+			entities = append(entities, Entity{Ref: ref})
+		}
+	}
+
+	return entities, nil
+}
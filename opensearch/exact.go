@@ -0,0 +1,70 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+)
+
+// ValueQuery returns the query best matching the documents where field equals value,
+// based on its mapping in the indices matching pattern:
+//
+//   - keyword and wildcard fields get a term query, with case_insensitive set when
+//     requested and the field is not already lowercased by its normalizer;
+//   - text fields get a term query on their keyword sub-field when one exists and the
+//     match must be case-sensitive, or a match_phrase query otherwise, provided the
+//     analyzer lowercases when a case-insensitive match is requested;
+//   - other fields get a plain term query.
+func (m *FieldMapper) ValueQuery(ctx context.Context, pattern, field string, value interface{}, caseInsensitive bool) (Query, error) {
+	info, ok, err := m.Field(ctx, pattern, field)
+	if err != nil {
+		return Query{}, err
+	}
+
+	if !ok {
+		return Query{}, fmt.Errorf("field %s is not mapped in %s", field, pattern)
+	}
+
+	switch {
+	case keywordTypes[info.Type]:
+		return TermQuery(field, value, caseInsensitive && !info.Lowercased()), nil
+	case textTypes[info.Type]:
+		if !caseInsensitive {
+			keyword, err := keywordSubField(ctx, m, pattern, field)
+			if err != nil {
+				return Query{}, err
+			}
+
+			if keyword != "" {
+				return TermQuery(keyword, value, false), nil
+			}
+		}
+
+		if caseInsensitive && !info.Lowercased() {
+			return Query{}, fmt.Errorf("field %s is not lowercased by its analyzer, cannot match it ignoring case", field)
+		}
+
+		return Query{MatchPhrase: map[string]MatchPhrase{field: {Query: fmt.Sprint(value)}}}, nil
+	default:
+		return TermQuery(field, value, false), nil
+	}
+}
+
+// KeywordField returns the field to use for term-level queries on field, like wildcard
+// or prefix queries, in the indices matching pattern: field itself when it is a keyword
+// or wildcard field, its keyword sub-field when it is a text field having one, or an
+// empty string otherwise.
+func (m *FieldMapper) KeywordField(ctx context.Context, pattern, field string) (string, error) {
+	info, ok, err := m.Field(ctx, pattern, field)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	switch {
+	case keywordTypes[info.Type]:
+		return field, nil
+	case textTypes[info.Type]:
+		return keywordSubField(ctx, m, pattern, field)
+	default:
+		return "", nil
+	}
+}
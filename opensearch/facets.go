@@ -0,0 +1,144 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FacetValue is a value of a facet and the number of documents having it.
+type FacetValue struct {
+	Value interface{} `json:"value"`
+	Count int64       `json:"count"`
+}
+
+// Facet is the distribution of the values of a field.
+type Facet struct {
+	// Field is the aggregated field, the keyword sub-field of text fields.
+	Field  string       `json:"field"`
+	Values []FacetValue `json:"values"`
+	// Distinct is the approximate number of distinct values, and Other the number of
+	// documents having values not returned.
+	Distinct int64 `json:"distinct"`
+	Other    int64 `json:"other"`
+}
+
+// FacetOptions configure FacetsIn.
+type FacetOptions struct {
+	// Size is the number of values per facet, 10 by default.
+	Size int64
+	// TenantID scopes the facets as TenantScope does.
+	TenantID string
+}
+
+// Facets returns the most frequent values of each field among the documents matching
+// base, all documents when nil, e.g. for the filters of a UI sidebar.
+func Facets(ctx context.Context, index []string, fields []string, base *Query) (map[string][]FacetValue, error) {
+	facets, err := FacetsIn(ctx, index, fields, base, FacetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var values = make(map[string][]FacetValue, len(facets))
+	for field, facet := range facets {
+		values[field] = facet.Values
+	}
+
+	return values, nil
+}
+
+// FacetsIn returns the facets of the fields, keyed by field, with a terms and a
+// cardinality aggregation per field in a single request. Text fields are aggregated
+// on their keyword sub-field, as resolved by Mapper, and fail when they have none.
+func FacetsIn(ctx context.Context, index []string, fields []string, base *Query, o FacetOptions) (map[string]Facet, error) {
+	size := o.Size
+	if size <= 0 {
+		size = 10
+	}
+
+	pattern := strings.Join(index, ",")
+
+	var aggs = make(map[string]Aggs, 2*len(fields))
+	var resolved = make([]string, len(fields))
+
+	for i, field := range fields {
+		name, err := facetField(ctx, pattern, field)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved[i] = name
+
+		aggs[fmt.Sprintf("facet_%d", i)] = Aggs{Terms: &Terms{Field: name, Size: size}}
+		aggs[fmt.Sprintf("distinct_%d", i)] = Aggs{Cardinality: &Cardinality{Field: name}}
+	}
+
+	q := SearchRequest{Query: base, Aggs: aggs}
+	if o.TenantID != "" {
+		q = q.TenantScope(o.TenantID)
+	}
+
+	result, err := q.AggregateIn(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+
+	var facets = make(map[string]Facet, len(fields))
+
+	for i, field := range fields {
+		facet := Facet{Field: resolved[i], Values: make([]FacetValue, 0)}
+
+		buckets, err := result.Aggregations.Buckets(fmt.Sprintf("facet_%d", i))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range buckets {
+			value := b.Key
+			if b.KeyAsString != "" {
+				value = b.KeyAsString
+			}
+
+			facet.Values = append(facet.Values, FacetValue{Value: value, Count: b.DocCount})
+		}
+
+		counts, err := result.Aggregations.TermsCounts(fmt.Sprintf("facet_%d", i))
+		if err != nil {
+			return nil, err
+		}
+
+		facet.Other = counts.SumOtherDocCount
+
+		distinct, _ := result.Aggregations.Value(fmt.Sprintf("distinct_%d", i))
+		facet.Distinct = int64(distinct)
+
+		facets[field] = facet
+	}
+
+	return facets, nil
+}
+
+// facetField returns the field to aggregate for the facet of field.
+func facetField(ctx context.Context, pattern, field string) (string, error) {
+	m := Mapper()
+
+	info, ok, err := m.Field(ctx, pattern, field)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok || !textTypes[info.Type] {
+		return field, nil
+	}
+
+	keyword, err := keywordSubField(ctx, m, pattern, field)
+	if err != nil {
+		return "", err
+	}
+
+	if keyword == "" {
+		return "", fmt.Errorf("text field %s has no keyword sub-field to aggregate", field)
+	}
+
+	return keyword, nil
+}
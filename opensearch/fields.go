@@ -0,0 +1,223 @@
+package opensearch
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldRef is a field referenced by a search request along with the clause using it,
+// e.g. {Field: "source.ip", Clause: "term"}. Sorts and collapse are reported with the
+// clauses "sort" and "collapse", and aggregations as "aggs." plus the aggregation type.
+type FieldRef struct {
+	Field  string
+	Clause string
+}
+
+// FieldRefs returns every field referenced by the query, the post filter, the sorts,
+// the aggregations and the collapse of the request, in a stable order.
+func (q SearchRequest) FieldRefs() []FieldRef {
+	var refs []FieldRef
+
+	if q.Query != nil {
+		refs = append(refs, q.Query.FieldRefs()...)
+	}
+
+	if q.PostFilter != nil {
+		refs = append(refs, q.PostFilter.FieldRefs()...)
+	}
+
+	for _, s := range q.Sort {
+		var fields = make([]string, 0, len(s))
+		for field := range s {
+			if !strings.HasPrefix(field, "_") {
+				fields = append(fields, field)
+			}
+		}
+
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			refs = append(refs, FieldRef{Field: field, Clause: "sort"})
+		}
+	}
+
+	refs = append(refs, aggsFieldRefs(q.Aggs)...)
+
+	if q.Collapse != nil && q.Collapse.Field != "" {
+		refs = append(refs, FieldRef{Field: q.Collapse.Field, Clause: "collapse"})
+	}
+
+	return refs
+}
+
+// FieldRefs returns every field referenced by the query and its nested queries.
+func (q Query) FieldRefs() []FieldRef {
+	var refs []FieldRef
+
+	walkQuery(reflect.ValueOf(q), &refs)
+
+	return refs
+}
+
+var (
+	queryType      = reflect.TypeOf(Query{})
+	querySliceType = reflect.TypeOf([]Query{})
+)
+
+// walkQuery collects the fields of a Query value. Map-keyed clauses (term, range,
+// match...) contribute their keys; struct clauses contribute their Field, Fields and
+// DefaultField members; and nested queries found anywhere are walked recursively.
+func walkQuery(v reflect.Value, refs *[]FieldRef) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		if f.IsZero() {
+			continue
+		}
+
+		clause := jsonName(t.Field(i))
+
+		switch {
+		case clause == "ids":
+		case clause == "exists":
+			if field, ok := f.Interface().(map[string]string)["field"]; ok {
+				*refs = append(*refs, FieldRef{Field: field, Clause: clause})
+			}
+		case f.Kind() == reflect.Map && f.Type().Key().Kind() == reflect.String:
+			var keys = make([]string, 0, f.Len())
+			for _, k := range f.MapKeys() {
+				keys = append(keys, k.String())
+			}
+
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				*refs = append(*refs, FieldRef{Field: key, Clause: clause})
+				walkNested(f.MapIndex(reflect.ValueOf(key)), refs)
+			}
+		default:
+			for _, field := range structFields(f) {
+				*refs = append(*refs, FieldRef{Field: field, Clause: clause})
+			}
+
+			walkNested(f, refs)
+		}
+	}
+}
+
+// walkNested walks the queries contained in v, at any depth of structs and slices.
+func walkNested(v reflect.Value, refs *[]FieldRef) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == queryType:
+		walkQuery(v, refs)
+	case v.Type() == querySliceType:
+		for i := 0; i < v.Len(); i++ {
+			walkQuery(v.Index(i), refs)
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				walkNested(v.Field(i), refs)
+			}
+		}
+	case v.Kind() == reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkNested(v.Index(i), refs)
+		}
+	}
+}
+
+// structFields returns the field names held by the Field, Fields and DefaultField
+// members of a struct clause.
+func structFields(v reflect.Value) []string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []string
+
+	for _, name := range []string{"Field", "DefaultField"} {
+		if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.String && f.String() != "" {
+			fields = append(fields, f.String())
+		}
+	}
+
+	if f := v.FieldByName("Fields"); f.IsValid() && f.Type() == reflect.TypeOf([]string{}) {
+		for i := 0; i < f.Len(); i++ {
+			// Strip per-field boosts like "message^2".
+			fields = append(fields, strings.SplitN(f.Index(i).String(), "^", 2)[0])
+		}
+	}
+
+	return fields
+}
+
+func aggsFieldRefs(aggs map[string]Aggs) []FieldRef {
+	var names = make([]string, 0, len(aggs))
+	for name := range aggs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var refs []FieldRef
+
+	for _, name := range names {
+		agg := aggs[name]
+
+		v := reflect.ValueOf(agg)
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			if f.IsZero() || t.Field(i).Name == "Aggs" {
+				continue
+			}
+
+			clause := "aggs." + jsonName(t.Field(i))
+
+			for _, field := range structFields(f) {
+				refs = append(refs, FieldRef{Field: field, Clause: clause})
+			}
+		}
+
+		if agg.MultiTerms != nil {
+			for _, term := range agg.MultiTerms.Terms {
+				refs = append(refs, FieldRef{Field: term.Field, Clause: "aggs.multi_terms"})
+			}
+		}
+
+		if agg.SignificantTerms != nil && agg.SignificantTerms.SignificantTermsParams != nil && agg.SignificantTerms.BackgroundFilter != nil {
+			refs = append(refs, agg.SignificantTerms.BackgroundFilter.FieldRefs()...)
+		}
+
+		refs = append(refs, aggsFieldRefs(agg.Aggs)...)
+	}
+
+	return refs
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+
+	return name
+}
@@ -0,0 +1,232 @@
+package opensearch
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SliceMode tells how Flatten and Unflatten handle slices.
+type SliceMode int
+
+const (
+	// KeepSlices keeps slices as the values of their field, with the objects they hold
+	// flattened on their own, e.g. {"users": [{"name": "a"}]} becomes
+	// {"users": [{"name": "a"}]} and {"host": {"ips": ["a", "b"]}} becomes
+	// {"host.ips": ["a", "b"]}.
+	KeepSlices SliceMode = iota
+	// IndexSlices flattens the elements of slices under their index, e.g.
+	// {"users": [{"name": "a"}]} becomes {"users.0.name": "a"}, so that every value
+	// has its own key, as in CSV columns.
+	IndexSlices
+	// MergeSlices flattens the objects of slices into a slice of the values of each of
+	// their fields, as the search engine indexes them, e.g.
+	// {"users": [{"name": "a"}, {"name": "b"}]} becomes {"users.name": ["a", "b"]}.
+	// Which values came from the same object is lost.
+	MergeSlices
+)
+
+// Flatten returns the values of the source keyed by their dot-notation fields, e.g.
+// {"source": {"ip": "10.0.0.1"}} becomes {"source.ip": "10.0.0.1"}, handling slices
+// as mode tells. Empty objects are kept as values, so that Unflatten restores them.
+// Keys already holding dots are kept as such.
+func (h HitSource) Flatten(mode SliceMode) map[string]interface{} {
+	var flat = make(map[string]interface{}, len(h))
+
+	flattenValue("", map[string]interface{}(h), mode, flat, false)
+
+	return flat
+}
+
+func flattenValue(prefix string, v interface{}, mode SliceMode, flat map[string]interface{}, merging bool) {
+	switch x := v.(type) {
+	case HitSource:
+		flattenValue(prefix, map[string]interface{}(x), mode, flat, merging)
+	case map[string]interface{}:
+		if len(x) == 0 && prefix != "" {
+			setFlat(flat, prefix, x, merging)
+			return
+		}
+
+		for k, child := range x {
+			flattenValue(joinField(prefix, k), child, mode, flat, merging)
+		}
+	case []interface{}:
+		switch {
+		case mode == KeepSlices:
+			setFlat(flat, prefix, keepSlice(x), merging)
+		case len(x) == 0:
+			if !merging {
+				flat[prefix] = x
+			}
+		case mode == IndexSlices:
+			for i, item := range x {
+				flattenValue(joinField(prefix, strconv.Itoa(i)), item, mode, flat, merging)
+			}
+		default:
+			for _, item := range x {
+				flattenValue(prefix, item, mode, flat, true)
+			}
+		}
+	default:
+		setFlat(flat, prefix, x, merging)
+	}
+}
+
+// keepSlice returns a copy of the slice with its objects flattened.
+func keepSlice(list []interface{}) []interface{} {
+	var out = make([]interface{}, len(list))
+
+	for i, item := range list {
+		switch x := item.(type) {
+		case HitSource:
+			out[i] = x.Flatten(KeepSlices)
+		case map[string]interface{}:
+			out[i] = HitSource(x).Flatten(KeepSlices)
+		case []interface{}:
+			out[i] = keepSlice(x)
+		default:
+			out[i] = x
+		}
+	}
+
+	return out
+}
+
+func setFlat(flat map[string]interface{}, field string, v interface{}, merging bool) {
+	if !merging {
+		flat[field] = v
+		return
+	}
+
+	values, _ := flat[field].([]interface{})
+	flat[field] = append(values, v)
+}
+
+func joinField(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+
+	return prefix + "." + field
+}
+
+// Unflatten returns the nested source of the dot-notation fields of flat, the
+// inverse of Flatten with the same mode. Under IndexSlices, objects whose keys are
+// the indices 0 to n-1 become slices. It fails when a field is both a value and the
+// parent of another, like "user" and "user.name".
+func Unflatten(flat map[string]interface{}, mode SliceMode) (HitSource, error) {
+	var fields = make([]string, 0, len(flat))
+	for field := range flat {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	var src = make(map[string]interface{}, len(flat))
+
+	for _, field := range fields {
+		v := flat[field]
+
+		if list, ok := v.([]interface{}); ok && mode != IndexSlices {
+			var err error
+
+			v, err = unflattenSlice(list, mode)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field, err)
+			}
+		}
+
+		path := strings.Split(field, ".")
+		node := src
+
+		for i, key := range path[:len(path)-1] {
+			switch child := node[key].(type) {
+			case nil:
+				m := make(map[string]interface{})
+				node[key] = m
+				node = m
+			case map[string]interface{}:
+				node = child
+			default:
+				return nil, fmt.Errorf("field %s conflicts with the value of %s", field, strings.Join(path[:i+1], "."))
+			}
+		}
+
+		leaf := path[len(path)-1]
+
+		// Parents sort before their subfields, so the leaf is only set already when an
+		// object value holds a field also given in dot notation, like {"a": {"b": 1}}
+		// and "a.b".
+		if _, ok := node[leaf]; ok {
+			return nil, fmt.Errorf("field %s is set twice", field)
+		}
+
+		node[leaf] = v
+	}
+
+	if mode == IndexSlices {
+		for k, child := range src {
+			src[k] = indexedSlices(child)
+		}
+	}
+
+	return HitSource(src), nil
+}
+
+func unflattenSlice(list []interface{}, mode SliceMode) ([]interface{}, error) {
+	var out = make([]interface{}, len(list))
+
+	for i, item := range list {
+		switch x := item.(type) {
+		case map[string]interface{}:
+			src, err := Unflatten(x, mode)
+			if err != nil {
+				return nil, err
+			}
+
+			out[i] = map[string]interface{}(src)
+		case []interface{}:
+			nested, err := unflattenSlice(x, mode)
+			if err != nil {
+				return nil, err
+			}
+
+			out[i] = nested
+		default:
+			out[i] = x
+		}
+	}
+
+	return out, nil
+}
+
+// indexedSlices turns the objects keyed by the indices 0 to n-1 into slices.
+func indexedSlices(v interface{}) interface{} {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	for k, child := range m {
+		m[k] = indexedSlices(child)
+	}
+
+	if len(m) == 0 {
+		return m
+	}
+
+	var list = make([]interface{}, len(m))
+
+	for k, child := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(m) || strconv.Itoa(i) != k {
+			return m
+		}
+
+		list[i] = child
+	}
+
+	return list
+}
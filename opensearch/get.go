@@ -0,0 +1,63 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// GetDoc retrieves a document by ID. If the document does not exist, the returned
+// Hit has Found set to false; a missing index is an error.
+func GetDoc(ctx context.Context, index, id string, opts ...DocOption) (Hit, error) {
+	_, err := checkIndexAccess(index)
+	if err != nil {
+		return Hit{}, err
+	}
+
+	o := newDocOptions(opts)
+
+	req := opensearchapi.GetRequest{
+		Index:      index,
+		DocumentID: id,
+		Routing:    o.routing,
+	}
+
+	resp, err := req.Do(ctx, client)
+	if err != nil {
+		return Hit{}, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Hit{}, err
+	}
+
+	// Missing indices are reported with a 404 too, but without "found".
+	if resp.StatusCode == http.StatusNotFound {
+		var missing struct {
+			Found *bool `json:"found"`
+		}
+
+		if json.Unmarshal(body, &missing) == nil && missing.Found != nil && !*missing.Found {
+			return Hit{Index: index, ID: id}, nil
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Hit{}, statusError(ctx, resp.StatusCode, body)
+	}
+
+	var hit Hit
+
+	err = json.Unmarshal(body, &hit)
+	if err != nil {
+		return Hit{}, err
+	}
+
+	return hit, nil
+}
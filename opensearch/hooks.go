@@ -0,0 +1,50 @@
+package opensearch
+
+import (
+	"fmt"
+	"sync"
+)
+
+// HitTransformer modifies a hit of the results of searches, e.g. to redact fields,
+// normalize scores or add computed fields. Errors fail the search.
+type HitTransformer func(*Hit) error
+
+var hitTransformers struct {
+	sync.RWMutex
+	fns []HitTransformer
+}
+
+// RegisterHitTransformer makes SearchIn and MultiSearch apply fn to every hit they
+// return, after the transformers registered before it.
+func RegisterHitTransformer(fn HitTransformer) {
+	hitTransformers.Lock()
+	defer hitTransformers.Unlock()
+
+	hitTransformers.fns = append(hitTransformers.fns, fn)
+}
+
+// ResetHitTransformers removes the registered hit transformers.
+func ResetHitTransformers() {
+	hitTransformers.Lock()
+	defer hitTransformers.Unlock()
+
+	hitTransformers.fns = nil
+}
+
+// transformHits applies the registered hit transformers to the hits of the result.
+func (r *SearchResult) transformHits() error {
+	hitTransformers.RLock()
+	fns := hitTransformers.fns
+	hitTransformers.RUnlock()
+
+	for i := range r.Hits.Hits {
+		for _, fn := range fns {
+			err := fn(&r.Hits.Hits[i])
+			if err != nil {
+				return fmt.Errorf("transforming hit %s of index %s: %w", r.Hits.Hits[i].ID, r.Hits.Hits[i].Index, err)
+			}
+		}
+	}
+
+	return nil
+}
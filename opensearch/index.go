@@ -3,7 +3,6 @@ package opensearch
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"strings"
 
@@ -15,7 +14,22 @@ import (
 // The document is marshalled to JSON and sent to OpenSearch for indexing.
 // Returns an error if there is an issue with marshalling the document to JSON,
 // if there is an issue with the request to OpenSearch, or if the response status code is not 200, 201, or 202.
-func IndexDoc(ctx context.Context, doc interface{}, index, id string) error {
+func IndexDoc(ctx context.Context, doc interface{}, index, id string, opts ...DocOption) error {
+	err := indexDoc(ctx, doc, index, id, opts)
+
+	recordAudit(ctx, "index", []string{index}, id, nil, "", err)
+
+	return err
+}
+
+func indexDoc(ctx context.Context, doc interface{}, index, id string, opts []DocOption) error {
+	_, err := checkIndexAccess(index)
+	if err != nil {
+		return err
+	}
+
+	o := newDocOptions(opts)
+
 	j, err := json.Marshal(doc)
 	if err != nil {
 		return err
@@ -28,6 +42,7 @@ func IndexDoc(ctx context.Context, doc interface{}, index, id string) error {
 		Body:       reader,
 		OpType:     "create",
 		DocumentID: id,
+		Routing:    o.routing,
 	}
 
 	resp, err := req.Do(ctx, client)
@@ -43,7 +58,7 @@ func IndexDoc(ctx context.Context, doc interface{}, index, id string) error {
 			return err
 		}
 
-		return fmt.Errorf("search engine status %d, response: %s", resp.StatusCode, body)
+		return statusError(ctx, resp.StatusCode, body)
 	}
 
 	return nil
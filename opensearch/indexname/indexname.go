@@ -0,0 +1,262 @@
+// Package indexname generates and parses the names of time-partitioned indices, like
+// "logs-2024.06.01", and narrows searches to the indices overlapping the time range of
+// their query instead of a wildcard over every partition.
+package indexname
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Granularity is the period of the partitions.
+type Granularity int
+
+const (
+	Daily Granularity = iota
+	Weekly
+	Monthly
+)
+
+// Pattern names the partitions of an index: the prefix, a dash, and the start of the
+// period, formatted "2006.01.02" for daily partitions, "2006.01" for monthly ones and
+// as the ISO week, like "2024.w05", for weekly ones.
+type Pattern struct {
+	Prefix      string
+	Granularity Granularity
+	// Layout overrides the time layout of daily and monthly partitions, e.g.
+	// "2006-01-02" for the indices of opensearch.BuildIndex.
+	Layout string
+	// Location is the time zone partitions are cut in, UTC by default.
+	Location *time.Location
+	// MaxIndices bounds the indices searched, 100 by default. Wider ranges search the
+	// wildcard of the pattern.
+	MaxIndices int
+}
+
+// New returns the pattern of the partitions of prefix.
+func New(prefix string, g Granularity) Pattern {
+	return Pattern{Prefix: prefix, Granularity: g}
+}
+
+func (p Pattern) location() *time.Location {
+	if p.Location == nil {
+		return time.UTC
+	}
+
+	return p.Location
+}
+
+func (p Pattern) layout() string {
+	if p.Layout != "" {
+		return p.Layout
+	}
+
+	if p.Granularity == Monthly {
+		return "2006.01"
+	}
+
+	return "2006.01.02"
+}
+
+// Start returns the start of the partition holding t.
+func (p Pattern) Start(t time.Time) time.Time {
+	t = t.In(p.location())
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, p.location())
+
+	switch p.Granularity {
+	case Weekly:
+		// ISO weeks start on Monday.
+		offset := (int(day.Weekday()) + 6) % 7
+
+		return day.AddDate(0, 0, -offset)
+	case Monthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, p.location())
+	}
+
+	return day
+}
+
+// next returns the start of the partition following the one starting at start.
+func (p Pattern) next(start time.Time) time.Time {
+	switch p.Granularity {
+	case Weekly:
+		return start.AddDate(0, 0, 7)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	}
+
+	return start.AddDate(0, 0, 1)
+}
+
+// Name returns the name of the partition holding t.
+func (p Pattern) Name(t time.Time) string {
+	return p.Prefix + "-" + p.suffix(p.Start(t))
+}
+
+func (p Pattern) suffix(start time.Time) string {
+	if p.Granularity == Weekly {
+		year, week := start.ISOWeek()
+
+		return fmt.Sprintf("%04d.w%02d", year, week)
+	}
+
+	return start.Format(p.layout())
+}
+
+// Wildcard returns the expression matching every partition.
+func (p Pattern) Wildcard() string {
+	return p.Prefix + "-*"
+}
+
+// Parse returns the start of the partition named name.
+func (p Pattern) Parse(name string) (time.Time, error) {
+	suffix, ok := strings.CutPrefix(name, p.Prefix+"-")
+	if !ok {
+		return time.Time{}, fmt.Errorf("index %s doesn't start with %s-", name, p.Prefix)
+	}
+
+	if p.Granularity == Weekly {
+		year, week, ok := strings.Cut(suffix, ".w")
+		if !ok {
+			return time.Time{}, fmt.Errorf("invalid weekly index %s", name)
+		}
+
+		y, err1 := strconv.Atoi(year)
+		w, err2 := strconv.Atoi(week)
+
+		if err1 != nil || err2 != nil || w < 1 || w > 53 {
+			return time.Time{}, fmt.Errorf("invalid weekly index %s", name)
+		}
+
+		// January 4th is always in the first ISO week.
+		start := p.Start(time.Date(y, time.January, 4, 0, 0, 0, 0, p.location())).AddDate(0, 0, 7*(w-1))
+		if _, got := start.ISOWeek(); got != w {
+			return time.Time{}, fmt.Errorf("invalid weekly index %s", name)
+		}
+
+		return start, nil
+	}
+
+	t, err := time.ParseInLocation(p.layout(), suffix, p.location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid index %s: %w", name, err)
+	}
+
+	return t, nil
+}
+
+// Range returns the partitions overlapping [from, to], collapsing whole months of daily
+// partitions, and whole years, into wildcards when the layout starts with the year,
+// or the wildcard of the pattern when there are more than MaxIndices.
+func (p Pattern) Range(from, to time.Time) []string {
+	if to.Before(from) {
+		return nil
+	}
+
+	maxIndices := p.MaxIndices
+	if maxIndices <= 0 {
+		maxIndices = 100
+	}
+
+	var starts []time.Time
+
+	for start := p.Start(from); !start.After(to); start = p.next(start) {
+		starts = append(starts, start)
+
+		// Ranges too wide to list are searched with the wildcard, unless they can be
+		// collapsed.
+		if len(starts) > 100000 {
+			return []string{p.Wildcard()}
+		}
+	}
+
+	var names []string
+
+	for i := 0; i < len(starts); {
+		if expr, n := p.collapse(starts[i:]); n != 0 {
+			names = append(names, expr)
+			i += n
+
+			continue
+		}
+
+		names = append(names, p.Prefix+"-"+p.suffix(starts[i]))
+		i++
+	}
+
+	if len(names) > maxIndices {
+		return []string{p.Wildcard()}
+	}
+
+	return names
+}
+
+// collapse returns a wildcard matching a whole year, or a whole month of daily
+// partitions, starting at starts[0] and entirely in starts, with the number of
+// partitions it matches.
+func (p Pattern) collapse(starts []time.Time) (string, int) {
+	layout := p.layout()
+
+	if p.Granularity == Weekly || len(layout) < 5 || !strings.HasPrefix(layout, "2006") {
+		return "", 0
+	}
+
+	first := starts[0]
+	sep := layout[4:5]
+
+	if first.YearDay() == 1 {
+		n := p.count(starts, func(t time.Time) bool { return t.Year() == first.Year() })
+		if end := p.next(starts[n-1]); end.Year() != first.Year() {
+			return fmt.Sprintf("%s-%04d%s*", p.Prefix, first.Year(), sep), n
+		}
+	}
+
+	if p.Granularity == Daily && first.Day() == 1 && strings.HasPrefix(layout, "2006"+sep+"01"+sep) {
+		n := p.count(starts, func(t time.Time) bool { return t.Month() == first.Month() && t.Year() == first.Year() })
+		if end := p.next(starts[n-1]); end.Month() != first.Month() {
+			return fmt.Sprintf("%s-%04d%s%02d%s*", p.Prefix, first.Year(), sep, int(first.Month()), sep), n
+		}
+	}
+
+	return "", 0
+}
+
+func (p Pattern) count(starts []time.Time, in func(time.Time) bool) int {
+	var n int
+	for n < len(starts) && in(starts[n]) {
+		n++
+	}
+
+	return n
+}
+
+// Indices returns the partitions overlapping the time range of q on field, taken from
+// the range clauses of its query or of the filter and must clauses of its bool
+// queries. It returns the wildcard of the pattern when the query has no lower bound.
+func (p Pattern) Indices(q opensearch.SearchRequest, field string) []string {
+	if q.Query == nil {
+		return []string{p.Wildcard()}
+	}
+
+	from, to := timeRange(*q.Query, field, time.Now())
+	if from.IsZero() {
+		return []string{p.Wildcard()}
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	return p.Range(from, to)
+}
+
+// SearchIn searches the partitions returned by Indices, ignoring the missing ones, like
+// today's index before the first document of the day.
+func (p Pattern) SearchIn(ctx context.Context, q opensearch.SearchRequest, field string) (opensearch.SearchResult, error) {
+	return q.IgnoreMissingIndices().SearchIn(ctx, p.Indices(q, field))
+}
@@ -0,0 +1,40 @@
+package indexname
+
+import (
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Pruner returns an index pruner replacing the wildcard of each pattern in the
+// indices of a search, like "logs-*", by the partitions overlapping the time range of
+// the query on field.
+func Pruner(field string, patterns ...Pattern) opensearch.IndexPruner {
+	var byWildcard = make(map[string]Pattern, len(patterns))
+	for _, p := range patterns {
+		byWildcard[p.Wildcard()] = p
+	}
+
+	return func(q opensearch.SearchRequest, index []string) []string {
+		var pruned = make([]string, 0, len(index))
+
+		for _, expr := range index {
+			p, ok := byWildcard[expr]
+			if !ok {
+				pruned = append(pruned, expr)
+
+				continue
+			}
+
+			pruned = append(pruned, p.Indices(q, field)...)
+		}
+
+		return pruned
+	}
+}
+
+// EnablePruning makes every search of the wildcard of one of the patterns search only
+// the partitions overlapping its time range on field, e.g. the last two daily indices
+// for a "now-15m" range rather than months of them. Requests with KeepIndices set
+// aren't pruned.
+func EnablePruning(field string, patterns ...Pattern) {
+	opensearch.SetIndexPruner(Pruner(field, patterns...))
+}
@@ -0,0 +1,110 @@
+package indexname
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// timeRange returns the bounds of the range clauses on field required by q, zero when
+// unbounded or not understood. Clauses of should and must_not queries don't bound the
+// matching documents and are ignored.
+func timeRange(q opensearch.Query, field string, now time.Time) (from, to time.Time) {
+	var clauses = []opensearch.Query{q}
+
+	for len(clauses) != 0 {
+		c := clauses[0]
+		clauses = clauses[1:]
+
+		if params, ok := c.Range[field]; ok {
+			for op, v := range params {
+				t, ok := parseTime(v, now)
+				if !ok {
+					continue
+				}
+
+				switch op {
+				case "gt", "gte", "from":
+					if from.IsZero() || t.After(from) {
+						from = t
+					}
+				case "lt", "lte", "to":
+					if to.IsZero() || t.Before(to) {
+						to = t
+					}
+				}
+			}
+		}
+
+		if c.Bool != nil {
+			clauses = append(clauses, c.Bool.Filter...)
+			clauses = append(clauses, c.Bool.Must...)
+		}
+
+		if c.ConstantScore != nil {
+			clauses = append(clauses, c.ConstantScore.Filter)
+		}
+	}
+
+	return from, to
+}
+
+var dateMath = regexp.MustCompile(`^now(?:([+-])(\d+)([smhdwMy]))?(?:/[smhdwMy])?$`)
+
+// parseTime parses RFC 3339 dates, epoch milliseconds and "now" date math with a single
+// offset, like "now-24h" or "now-7d/d". Rounding is ignored, which only widens ranges
+// by a partition at most.
+func parseTime(v interface{}, now time.Time) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case int64:
+		return time.UnixMilli(v), true
+	case int:
+		return time.UnixMilli(int64(v)), true
+	case float64:
+		return time.UnixMilli(int64(v)), true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			t, err := time.Parse(layout, v)
+			if err == nil {
+				return t, true
+			}
+		}
+
+		m := dateMath.FindStringSubmatch(v)
+		if m == nil {
+			return time.Time{}, false
+		}
+
+		if m[1] == "" {
+			return now, true
+		}
+
+		n, _ := strconv.Atoi(m[2])
+		if m[1] == "-" {
+			n = -n
+		}
+
+		switch m[3] {
+		case "s":
+			return now.Add(time.Duration(n) * time.Second), true
+		case "m":
+			return now.Add(time.Duration(n) * time.Minute), true
+		case "h":
+			return now.Add(time.Duration(n) * time.Hour), true
+		case "d":
+			return now.AddDate(0, 0, n), true
+		case "w":
+			return now.AddDate(0, 0, 7*n), true
+		case "M":
+			return now.AddDate(0, n, 0), true
+		case "y":
+			return now.AddDate(n, 0, 0), true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,121 @@
+package opensearch
+
+// IntervalsRule is a rule of an intervals query. Exactly one of its members is set.
+type IntervalsRule struct {
+	Match    *IntervalsMatch    `json:"match,omitempty"`
+	Prefix   *IntervalsPrefix   `json:"prefix,omitempty"`
+	Wildcard *IntervalsWildcard `json:"wildcard,omitempty"`
+	Fuzzy    *IntervalsFuzzy    `json:"fuzzy,omitempty"`
+	AllOf    *IntervalsAllOf    `json:"all_of,omitempty"`
+	AnyOf    *IntervalsAnyOf    `json:"any_of,omitempty"`
+}
+
+type IntervalsMatch struct {
+	Query    string           `json:"query"`
+	MaxGaps  *int64           `json:"max_gaps,omitempty"`
+	Ordered  bool             `json:"ordered,omitempty"`
+	Analyzer string           `json:"analyzer,omitempty"`
+	UseField string           `json:"use_field,omitempty"`
+	Filter   *IntervalsFilter `json:"filter,omitempty"`
+}
+
+type IntervalsPrefix struct {
+	Prefix   string `json:"prefix"`
+	Analyzer string `json:"analyzer,omitempty"`
+	UseField string `json:"use_field,omitempty"`
+}
+
+type IntervalsWildcard struct {
+	Pattern  string `json:"pattern"`
+	Analyzer string `json:"analyzer,omitempty"`
+	UseField string `json:"use_field,omitempty"`
+}
+
+type IntervalsFuzzy struct {
+	Term           string `json:"term"`
+	Fuzziness      string `json:"fuzziness,omitempty"`
+	PrefixLength   int64  `json:"prefix_length,omitempty"`
+	Transpositions *bool  `json:"transpositions,omitempty"`
+	Analyzer       string `json:"analyzer,omitempty"`
+	UseField       string `json:"use_field,omitempty"`
+}
+
+type IntervalsAllOf struct {
+	Intervals []IntervalsRule  `json:"intervals"`
+	MaxGaps   *int64           `json:"max_gaps,omitempty"`
+	Ordered   bool             `json:"ordered,omitempty"`
+	Filter    *IntervalsFilter `json:"filter,omitempty"`
+}
+
+type IntervalsAnyOf struct {
+	Intervals []IntervalsRule  `json:"intervals"`
+	Filter    *IntervalsFilter `json:"filter,omitempty"`
+}
+
+// IntervalsFilter keeps the intervals of a rule depending on their position relative
+// to the intervals of another rule.
+type IntervalsFilter struct {
+	After          *IntervalsRule `json:"after,omitempty"`
+	Before         *IntervalsRule `json:"before,omitempty"`
+	ContainedBy    *IntervalsRule `json:"contained_by,omitempty"`
+	Containing     *IntervalsRule `json:"containing,omitempty"`
+	NotContainedBy *IntervalsRule `json:"not_contained_by,omitempty"`
+	NotContaining  *IntervalsRule `json:"not_containing,omitempty"`
+	NotOverlapping *IntervalsRule `json:"not_overlapping,omitempty"`
+	Overlapping    *IntervalsRule `json:"overlapping,omitempty"`
+	Script         *Script        `json:"script,omitempty"`
+}
+
+// IntervalsQuery returns a query matching the documents where the terms of field
+// satisfy rule. Unlike match_phrase, it can express the order and the maximum distance
+// between several terms or phrases, e.g. "connection" followed by "refused" within
+// three words:
+//
+//	IntervalsQuery("message", MatchInterval("connection refused", 3, true))
+func IntervalsQuery(field string, rule IntervalsRule) Query {
+	return Query{Intervals: map[string]IntervalsRule{field: rule}}
+}
+
+// MatchInterval returns a rule matching the analyzed terms of query, with at most
+// maxGaps positions between them, or any number when maxGaps is negative. If ordered
+// is true, the terms must appear in the same order as in query.
+func MatchInterval(query string, maxGaps int64, ordered bool) IntervalsRule {
+	return IntervalsRule{Match: &IntervalsMatch{Query: query, MaxGaps: gaps(maxGaps), Ordered: ordered}}
+}
+
+// PrefixInterval returns a rule matching the terms starting with prefix.
+func PrefixInterval(prefix string) IntervalsRule {
+	return IntervalsRule{Prefix: &IntervalsPrefix{Prefix: prefix}}
+}
+
+// WildcardInterval returns a rule matching the terms matching pattern, where "*"
+// matches any sequence of characters and "?" a single character.
+func WildcardInterval(pattern string) IntervalsRule {
+	return IntervalsRule{Wildcard: &IntervalsWildcard{Pattern: pattern}}
+}
+
+// FuzzyInterval returns a rule matching the terms similar to term within fuzziness
+// edits, e.g. "AUTO" or "2".
+func FuzzyInterval(term, fuzziness string) IntervalsRule {
+	return IntervalsRule{Fuzzy: &IntervalsFuzzy{Term: term, Fuzziness: fuzziness}}
+}
+
+// AllOfIntervals returns a rule matching when all the rules match, with at most
+// maxGaps positions between them, or any number when maxGaps is negative. If ordered
+// is true, they must match in the given order.
+func AllOfIntervals(maxGaps int64, ordered bool, rules ...IntervalsRule) IntervalsRule {
+	return IntervalsRule{AllOf: &IntervalsAllOf{Intervals: rules, MaxGaps: gaps(maxGaps), Ordered: ordered}}
+}
+
+// AnyOfIntervals returns a rule matching when any of the rules matches.
+func AnyOfIntervals(rules ...IntervalsRule) IntervalsRule {
+	return IntervalsRule{AnyOf: &IntervalsAnyOf{Intervals: rules}}
+}
+
+func gaps(maxGaps int64) *int64 {
+	if maxGaps < 0 {
+		return nil
+	}
+
+	return &maxGaps
+}
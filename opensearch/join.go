@@ -0,0 +1,46 @@
+package opensearch
+
+// JoinRelation is the value of a join field in a document. Parent documents only set
+// Name; child documents set the ID of their parent too, and must be indexed with the
+// Routing option set to it so they are stored in the shard of their parent.
+type JoinRelation struct {
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"`
+}
+
+// JoinField returns the mapping of a join field defining the parent/child relations
+// between documents of the same index, e.g. {"case": {"evidence"}}.
+func JoinField(relations map[string][]string) map[string]interface{} {
+	var rel = make(map[string]interface{}, len(relations))
+
+	for parent, children := range relations {
+		if len(children) == 1 {
+			rel[parent] = children[0]
+		} else {
+			rel[parent] = children
+		}
+	}
+
+	return map[string]interface{}{
+		"type":      "join",
+		"relations": rel,
+	}
+}
+
+// HasChildQuery returns a query matching the parent documents having children of
+// childType matching q. ScoreMode is "none", the default, "avg", "max", "min" or "sum".
+func HasChildQuery(childType string, q Query, scoreMode string) Query {
+	return Query{HasChild: &HasChild{Type: childType, Query: q, ScoreMode: scoreMode}}
+}
+
+// HasParentQuery returns a query matching the child documents whose parent of
+// parentType matches q. If score is true, children get the score of their parent.
+func HasParentQuery(parentType string, q Query, score bool) Query {
+	return Query{HasParent: &HasParent{ParentType: parentType, Query: q, Score: score}}
+}
+
+// ParentIDQuery returns a query matching the child documents of childType whose
+// parent has the given ID.
+func ParentIDQuery(childType, parentID string) Query {
+	return Query{ParentID: &ParentID{Type: childType, ID: parentID}}
+}
@@ -0,0 +1,247 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// KNNQuery returns a query for the k nearest neighbors of vector in field. The filter
+// is optional and applied during the search, so k hits are returned when k documents
+// match it.
+func KNNQuery(field string, vector []float32, k int, filter *Query) Query {
+	return Query{KNN: map[string]KNN{field: {Vector: vector, K: k, Filter: filter}}}
+}
+
+// KNNParams holds the optional settings of a k-NN index.
+type KNNParams struct {
+	// Field is the name of the vector field, "vector" by default.
+	Field string
+	// Method is "hnsw", the default, or "ivf" (faiss only).
+	Method string
+	// M and EfConstruction tune HNSW graphs. Zero keeps the engine defaults.
+	M              int
+	EfConstruction int
+	// EfSearch is the index-wide HNSW search queue size, not used by lucene.
+	EfSearch int
+	// NList and NProbes tune IVF indices.
+	NList   int
+	NProbes int
+	// Encoder compresses the vectors, e.g. {"name": "sq", "parameters": {"type": "fp16"}}.
+	Encoder map[string]interface{}
+	// ModelID maps the field to a trained model, as faiss IVF and PQ methods require,
+	// instead of a method definition. Dimension, space type and engine then come from
+	// the model.
+	ModelID string
+	// Properties are the other fields of the index mapping.
+	Properties map[string]interface{}
+	// Shards and Replicas set the number of primary shards and replicas when not zero.
+	Shards   int
+	Replicas int
+}
+
+var (
+	knnEngines    = map[string]bool{"nmslib": true, "faiss": true, "lucene": true}
+	knnSpaceTypes = map[string]bool{
+		"l2": true, "cosinesimil": true, "innerproduct": true, "l1": true, "linf": true, "hamming": true,
+	}
+)
+
+// CreateKNNIndex creates an index with k-NN search enabled and a knn_vector field of
+// dim dimensions, compared with spaceType ("l2", "cosinesimil", "innerproduct"...) and
+// indexed by engine ("faiss", "lucene" or "nmslib").
+func CreateKNNIndex(ctx context.Context, name string, dim int, spaceType, engine string, params KNNParams) error {
+	body, err := KNNIndexBody(dim, spaceType, engine, params)
+	if err != nil {
+		return err
+	}
+
+	return CreateIndex(ctx, name, body)
+}
+
+// KNNIndexBody returns the settings and mappings used by CreateKNNIndex.
+func KNNIndexBody(dim int, spaceType, engine string, params KNNParams) (map[string]interface{}, error) {
+	if params.Field == "" {
+		params.Field = "vector"
+	}
+
+	if params.Method == "" {
+		params.Method = "hnsw"
+	}
+
+	var field map[string]interface{}
+
+	if params.ModelID != "" {
+		field = map[string]interface{}{"type": "knn_vector", "model_id": params.ModelID}
+	} else {
+		method, err := knnMethod(dim, spaceType, engine, params)
+		if err != nil {
+			return nil, err
+		}
+
+		field = map[string]interface{}{"type": "knn_vector", "dimension": dim, "method": method}
+	}
+
+	var properties = make(map[string]interface{}, len(params.Properties)+1)
+	for k, v := range params.Properties {
+		properties[k] = v
+	}
+
+	properties[params.Field] = field
+
+	var settings = map[string]interface{}{"knn": true}
+
+	if params.EfSearch != 0 && engine != "lucene" {
+		settings["knn.algo_param.ef_search"] = params.EfSearch
+	}
+
+	if params.Shards != 0 {
+		settings["number_of_shards"] = params.Shards
+	}
+
+	if params.Replicas != 0 {
+		settings["number_of_replicas"] = params.Replicas
+	}
+
+	return map[string]interface{}{
+		"settings": map[string]interface{}{"index": settings},
+		"mappings": map[string]interface{}{"properties": properties},
+	}, nil
+}
+
+func knnMethod(dim int, spaceType, engine string, params KNNParams) (map[string]interface{}, error) {
+	if dim <= 0 || dim > 16000 {
+		return nil, fmt.Errorf("invalid k-NN dimension %d, must be between 1 and 16000", dim)
+	}
+
+	if !knnEngines[engine] {
+		return nil, fmt.Errorf("unknown k-NN engine %s", engine)
+	}
+
+	if !knnSpaceTypes[spaceType] {
+		return nil, fmt.Errorf("unknown k-NN space type %s", spaceType)
+	}
+
+	var parameters = make(map[string]interface{})
+
+	switch params.Method {
+	case "hnsw":
+		if params.M != 0 {
+			parameters["m"] = params.M
+		}
+
+		if params.EfConstruction != 0 {
+			parameters["ef_construction"] = params.EfConstruction
+		}
+	case "ivf":
+		if engine != "faiss" {
+			return nil, fmt.Errorf("k-NN method ivf requires the faiss engine")
+		}
+
+		if params.NList != 0 {
+			parameters["nlist"] = params.NList
+		}
+
+		if params.NProbes != 0 {
+			parameters["nprobes"] = params.NProbes
+		}
+	default:
+		return nil, fmt.Errorf("unknown k-NN method %s", params.Method)
+	}
+
+	if params.Encoder != nil {
+		parameters["encoder"] = params.Encoder
+	}
+
+	var method = map[string]interface{}{
+		"name":       params.Method,
+		"space_type": spaceType,
+		"engine":     engine,
+	}
+
+	if len(parameters) != 0 {
+		method["parameters"] = parameters
+	}
+
+	return method, nil
+}
+
+// RadiusMetric tells how the radius of KNNRadius is expressed.
+type RadiusMetric string
+
+const (
+	// RadiusDistance is a distance in the vector space: the squared L2, L1, L-infinity
+	// or Hamming distance, or the cosine distance (1 - cosine similarity).
+	RadiusDistance RadiusMetric = "distance"
+	// RadiusSimilarity is a cosine similarity or an inner product, for cosinesimil and
+	// innerproduct spaces.
+	RadiusSimilarity RadiusMetric = "similarity"
+)
+
+// KNNRadius returns a radial search query matching every document of field within
+// radius of vector. Depending on the space type of field in the indices matching
+// index, read from their mappings, the radius becomes a max_distance or is converted
+// to the equivalent min_score, so callers do not depend on the engine scoring rules.
+// Fields mapped to a trained model have no space type in their mapping and are
+// assumed to use l2, the engine default. Cosine similarities are only converted for
+// fields whose mapping names the engine, as engines score them differently.
+func KNNRadius(ctx context.Context, index, field string, vector []float32, radius float64, metric RadiusMetric) (Query, error) {
+	info, ok, err := Mapper().Field(ctx, index, field)
+	if err != nil {
+		return Query{}, err
+	}
+
+	if !ok {
+		return Query{}, fmt.Errorf("field %s is not mapped in %s", field, index)
+	}
+
+	if info.Type != "knn_vector" {
+		return Query{}, fmt.Errorf("field %s is a %s field, not a knn_vector", field, info.Type)
+	}
+
+	if info.Dimension != 0 && info.Dimension != len(vector) {
+		return Query{}, fmt.Errorf("field %s has %d dimensions, the vector has %d", field, info.Dimension, len(vector))
+	}
+
+	spaceType := info.SpaceType
+	if spaceType == "" {
+		spaceType = "l2"
+	}
+
+	var knn = KNN{Vector: vector}
+
+	switch {
+	case metric == RadiusDistance && spaceType != "innerproduct":
+		knn.MaxDistance = &radius
+	case metric == RadiusSimilarity && spaceType == "cosinesimil":
+		score, err := cosineScore(info.Engine, radius)
+		if err != nil {
+			return Query{}, fmt.Errorf("field %s: %w", field, err)
+		}
+		knn.MinScore = &score
+	case metric == RadiusSimilarity && spaceType == "innerproduct":
+		score := radius + 1
+		if radius < 0 {
+			score = 1 / (1 - radius)
+		}
+		knn.MinScore = &score
+	default:
+		return Query{}, fmt.Errorf("a %s radius cannot be used on the %s space of field %s", metric, spaceType, field)
+	}
+
+	return Query{KNN: map[string]KNN{field: knn}}, nil
+}
+
+// cosineScore returns the score the engine gives a cosine similarity.
+func cosineScore(engine string, similarity float64) (float64, error) {
+	switch engine {
+	case "lucene":
+		return (1 + similarity) / 2, nil
+	case "faiss", "nmslib":
+		return 1 / (2 - similarity), nil
+	case "":
+		return 0, errors.New("the engine is not in the mapping, so a cosine similarity cannot be converted to a score")
+	default:
+		return 0, fmt.Errorf("the cosine scores of the %s engine are unknown", engine)
+	}
+}
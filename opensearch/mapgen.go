@@ -0,0 +1,271 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MappingFor generates the index mappings of the documents represented by v, a struct
+// value, a pointer to one or its reflect.Type. Field names come from the json tags,
+// and types are derived from the Go types: strings are keyword, integers long,
+// float64 double, float32 float, bools boolean, time.Time date, and structs objects.
+// The opensearch tag overrides or completes each field definition, with comma
+// separated options:
+//
+//	type=<type>          mapping type, e.g. text, ip, nested or knn_vector
+//	analyzer=<name>      analyzer of text fields
+//	search_analyzer=<n>  search analyzer of text fields
+//	normalizer=<name>    normalizer of keyword fields
+//	copy_to=<field>      copies the value to another field, may be repeated
+//	format=<format>      date format
+//	ignore_above=<n>     longest keyword indexed
+//	dims=<n>             dimension of knn_vector fields
+//	keyword              adds a "keyword" sub-field to text fields
+//	noindex              the field is stored but not searchable
+//	nodocvalues          disables doc values
+//	-                    the field is left out of the mappings
+//
+// Map fields become dynamic templates applying the mapping of the map values to every
+// key, e.g. Labels map[string]string `json:"labels"` maps "labels.*" as keyword.
+func MappingFor(v interface{}) (map[string]interface{}, error) {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mappings can only be generated from structs, not from %v", t)
+	}
+
+	var g mappingGenerator
+
+	properties, err := g.properties("", t)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings = map[string]interface{}{"properties": properties}
+
+	if len(g.templates) != 0 {
+		mappings["dynamic_templates"] = g.templates
+	}
+
+	return mappings, nil
+}
+
+// EnsureIndex creates the index with the mappings generated by MappingFor from
+// structType, or adds the missing fields to the mappings of the index if it exists.
+// Changing the type of an existing field is rejected by the search engine.
+func EnsureIndex(ctx context.Context, name string, structType interface{}) error {
+	mappings, err := MappingFor(structType)
+	if err != nil {
+		return err
+	}
+
+	_, err = Do(ctx, http.MethodHead, "/"+url.PathEscape(name), nil, nil)
+
+	var status *StatusError
+
+	switch {
+	case errors.As(err, &status) && status.StatusCode == http.StatusNotFound:
+		return CreateIndex(ctx, name, map[string]interface{}{"mappings": mappings})
+	case err != nil:
+		return err
+	}
+
+	_, err = Do(ctx, http.MethodPut, "/"+url.PathEscape(name)+"/_mapping", nil, mappings)
+
+	return err
+}
+
+type mappingGenerator struct {
+	templates []map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *mappingGenerator) properties(prefix string, t reflect.Type) (map[string]interface{}, error) {
+	var properties = make(map[string]interface{})
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("opensearch")
+		if tag == "-" || strings.Split(f.Tag.Get("json"), ",")[0] == "-" {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened into the parent.
+		if f.Anonymous && strings.Split(f.Tag.Get("json"), ",")[0] == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				embedded, err := g.properties(prefix, ft)
+				if err != nil {
+					return nil, err
+				}
+
+				for k, v := range embedded {
+					properties[k] = v
+				}
+
+				continue
+			}
+		}
+
+		name := jsonName(f)
+
+		field, err := g.field(prefix+name, f.Type, tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", prefix+name, err)
+		}
+
+		if field != nil {
+			properties[name] = field
+		}
+	}
+
+	return properties, nil
+}
+
+// field returns the mapping of a field of type t. Map fields are mapped as objects,
+// with a dynamic template for their values.
+func (g *mappingGenerator) field(path string, t reflect.Type, tag string) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Ptr || ((t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isVector(tag)) {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Map {
+		value, err := g.field(path+".*", t.Elem(), tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if value != nil {
+			g.templates = append(g.templates, map[string]interface{}{
+				strings.ReplaceAll(path, ".", "_"): map[string]interface{}{
+					"path_match": path + ".*",
+					"mapping":    value,
+				},
+			})
+		}
+
+		return map[string]interface{}{"type": "object"}, nil
+	}
+
+	var field = make(map[string]interface{})
+
+	if t.Kind() == reflect.Struct && t != timeType {
+		properties, err := g.properties(path+".", t)
+		if err != nil {
+			return nil, err
+		}
+
+		field["properties"] = properties
+	} else if typ := defaultType(t); typ != "" {
+		field["type"] = typ
+	}
+
+	err := applyTag(field, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if field["type"] == nil && field["properties"] == nil {
+		return nil, fmt.Errorf("no mapping type for Go type %s, set one with the opensearch tag", t)
+	}
+
+	return field, nil
+}
+
+// isVector reports whether a slice field is a knn_vector rather than a list of values.
+func isVector(tag string) bool {
+	return strings.Contains(","+tag+",", ",type=knn_vector,")
+}
+
+func defaultType(t reflect.Type) string {
+	if t == timeType {
+		return "date"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "keyword"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "long"
+	case reflect.Uint64:
+		return "unsigned_long"
+	case reflect.Float64:
+		return "double"
+	case reflect.Float32:
+		return "float"
+	default:
+		return ""
+	}
+}
+
+func applyTag(field map[string]interface{}, tag string) error {
+	if tag == "" {
+		return nil
+	}
+
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+
+		switch key {
+		case "type":
+			field["type"] = value
+			if value == "nested" || value == "object" {
+				continue
+			}
+			delete(field, "properties")
+		case "analyzer", "search_analyzer", "normalizer", "format":
+			field[key] = value
+		case "copy_to":
+			copyTo, _ := field[key].([]string)
+			field[key] = append(copyTo, value)
+		case "ignore_above", "dims":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s option %q", key, value)
+			}
+			if key == "dims" {
+				key = "dimension"
+			}
+			field[key] = n
+		case "keyword":
+			field["fields"] = map[string]interface{}{
+				"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+			}
+		case "noindex":
+			field["index"] = false
+		case "nodocvalues":
+			field["doc_values"] = false
+		case "":
+		default:
+			return fmt.Errorf("unknown opensearch tag option %q", key)
+		}
+	}
+
+	return nil
+}
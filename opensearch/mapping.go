@@ -0,0 +1,630 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// FieldInfo describes a field as defined in the index mappings.
+type FieldInfo struct {
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Analyzer       string `json:"analyzer,omitempty"`
+	SearchAnalyzer string `json:"search_analyzer,omitempty"`
+	Normalizer     string `json:"normalizer,omitempty"`
+	Dimension      int    `json:"dimension,omitempty"`
+	SpaceType      string `json:"space_type,omitempty"`
+	Engine         string `json:"engine,omitempty"`
+}
+
+// Lowercased reports whether the indexed values of the field are lowercased, by a
+// lowercase normalizer on keyword fields or by the analyzer of text fields, so that
+// queries on it are case-insensitive. Custom normalizers are recognized when their
+// name contains "lowercase".
+func (f FieldInfo) Lowercased() bool {
+	switch {
+	case keywordTypes[f.Type]:
+		return strings.Contains(f.Normalizer, "lowercase")
+	case textTypes[f.Type]:
+		analyzer := f.SearchAnalyzer
+		if analyzer == "" {
+			analyzer = f.Analyzer
+		}
+		return !caseSensitiveAnalyzers[analyzer]
+	default:
+		return false
+	}
+}
+
+// caseSensitiveAnalyzers are the built-in analyzers not lowercasing tokens. The others,
+// including the default standard analyzer, do, and so are assumed custom analyzers.
+var caseSensitiveAnalyzers = map[string]bool{
+	"whitespace": true,
+	"keyword":    true,
+}
+
+// UnmarshalJSON decodes a field mapping, reading the space type and the engine of
+// knn_vector fields from their method definition.
+func (f *FieldInfo) UnmarshalJSON(data []byte) error {
+	type plain FieldInfo
+
+	var def struct {
+		plain
+		Method struct {
+			SpaceType string `json:"space_type"`
+			Engine    string `json:"engine"`
+		} `json:"method"`
+	}
+
+	err := json.Unmarshal(data, &def)
+	if err != nil {
+		return err
+	}
+
+	*f = FieldInfo(def.plain)
+
+	if f.SpaceType == "" {
+		f.SpaceType = def.Method.SpaceType
+	}
+
+	if f.Engine == "" {
+		f.Engine = def.Method.Engine
+	}
+
+	return nil
+}
+
+type fieldMappingResponse map[string]struct {
+	Mappings map[string]struct {
+		FullName string                     `json:"full_name"`
+		Mapping  map[string]json.RawMessage `json:"mapping"`
+	} `json:"mappings"`
+}
+
+// GetFieldMapping returns the mapping of the fields matching the given field expressions
+// (exact names or wildcards like "source.*") in the indices matching the given patterns.
+// When a field is mapped in several indices, the definition of the first index in
+// lexicographic order is returned.
+func GetFieldMapping(ctx context.Context, index []string, fields ...string) (map[string]FieldInfo, error) {
+	result, err := getFieldMappings(ctx, index, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var indices = make([]string, 0, len(result))
+	for name := range result {
+		indices = append(indices, name)
+	}
+
+	sort.Strings(indices)
+
+	var infos = make(map[string]FieldInfo)
+
+	for _, name := range indices {
+		for field, mapping := range result[name].Mappings {
+			if _, ok := infos[field]; ok {
+				continue
+			}
+
+			for _, raw := range mapping.Mapping {
+				var info FieldInfo
+
+				err = json.Unmarshal(raw, &info)
+				if err != nil {
+					return nil, err
+				}
+
+				info.Name = mapping.FullName
+				if info.Name == "" {
+					info.Name = field
+				}
+
+				infos[field] = info
+			}
+		}
+	}
+
+	return infos, nil
+}
+
+// getFieldMappings requests the mapping of the fields matching the expressions in the
+// indices, per index.
+func getFieldMappings(ctx context.Context, index []string, fields []string) (fieldMappingResponse, error) {
+	index, err := checkIndexAccess(index...)
+	if err != nil {
+		return nil, err
+	}
+
+	req := opensearchapi.IndicesGetFieldMappingRequest{
+		Index:  index,
+		Fields: fields,
+	}
+
+	resp, err := req.Do(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fieldMappingResponse{}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(ctx, resp.StatusCode, body)
+	}
+
+	var result fieldMappingResponse
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+var sharedMapper atomic.Pointer[FieldMapper]
+
+func init() {
+	sharedMapper.Store(NewFieldMapper())
+}
+
+// Mapper returns the FieldMapper used by the package to resolve fields, e.g. when
+// validating search requests.
+func Mapper() *FieldMapper {
+	return sharedMapper.Load()
+}
+
+// SetMapper replaces the FieldMapper used by the package, e.g. with a static one in tests.
+func SetMapper(m *FieldMapper) {
+	sharedMapper.Store(m)
+}
+
+// FieldMapper caches field definitions per index pattern. Instead of downloading the
+// whole mapping of an index pattern, it fetches only the fields that are referenced,
+// which keeps the memory footprint low for indices with tens of thousands of fields.
+//
+// A FieldMapper configured with WithStaticMapping never contacts the cluster.
+//
+// A FieldMapper is safe for concurrent use. A single mapping request per pattern is in
+// flight at a time: concurrent lookups of the fields it fetches wait for it, and those
+// of other missing fields share the next one. Requests run without the cancellation of
+// the context of their first caller, while every caller stops waiting when its own
+// context is done. A failed fetch is not cached, so the next lookup retries it.
+type FieldMapper struct {
+	mu       sync.RWMutex
+	patterns map[string]*patternFields
+	flights  flightGroup
+	static   map[string]FieldInfo
+}
+
+type patternFields struct {
+	fields  map[string]FieldInfo
+	fetched map[string]bool
+}
+
+// NewFieldMapper returns an empty FieldMapper.
+func NewFieldMapper() *FieldMapper {
+	return &FieldMapper{
+		patterns: make(map[string]*patternFields),
+	}
+}
+
+// Fields returns the fields of the indices matching pattern whose names start with any
+// of the given prefixes. Only prefixes not requested before are fetched from the cluster.
+func (m *FieldMapper) Fields(ctx context.Context, pattern string, prefixes ...string) (map[string]FieldInfo, error) {
+	var expressions = make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		expressions = append(expressions, prefix+"*")
+	}
+
+	err := m.fetch(ctx, pattern, expressions)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var fields = make(map[string]FieldInfo)
+
+	for name, info := range m.lookup(pattern) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				fields[name] = info
+				break
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// Field resolves a single field of the indices matching pattern, along with its
+// multi-fields, fetching them from the cluster the first time they are referenced.
+// The boolean result is false if the field is not mapped.
+func (m *FieldMapper) Field(ctx context.Context, pattern, name string) (FieldInfo, bool, error) {
+	err := m.fetch(ctx, pattern, []string{name, name + ".*"})
+	if err != nil {
+		return FieldInfo{}, false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	info, ok := m.lookup(pattern)[name]
+
+	return info, ok, nil
+}
+
+// WithStaticMapping switches the mapper to offline mode, resolving every pattern
+// against the given mapping instead of fetching it from the cluster. The mapping
+// can be JSON ([]byte, string or json.RawMessage) or any value that marshals to
+// JSON, in the shape returned by GET index/_mapping, as a single
+// {"mappings": {...}} object, or as a bare {"properties": {...}} object.
+func (m *FieldMapper) WithStaticMapping(mapping interface{}) (*FieldMapper, error) {
+	var raw []byte
+
+	switch v := mapping.(type) {
+	case []byte:
+		raw = v
+	case json.RawMessage:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		raw = j
+	}
+
+	var doc map[string]json.RawMessage
+
+	err := json.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid static mapping: %s", err.Error())
+	}
+
+	var mappings []json.RawMessage
+
+	if _, ok := doc["properties"]; ok {
+		mappings = append(mappings, raw)
+	} else if mp, ok := doc["mappings"]; ok {
+		mappings = append(mappings, mp)
+	} else {
+		var indices = make([]string, 0, len(doc))
+		for name := range doc {
+			indices = append(indices, name)
+		}
+
+		sort.Strings(indices)
+
+		for _, name := range indices {
+			var index struct {
+				Mappings json.RawMessage `json:"mappings"`
+			}
+
+			err = json.Unmarshal(doc[name], &index)
+			if err != nil {
+				return nil, fmt.Errorf("invalid static mapping for index %s: %s", name, err.Error())
+			}
+
+			if index.Mappings != nil {
+				mappings = append(mappings, index.Mappings)
+			}
+		}
+	}
+
+	var fields = make(map[string]FieldInfo)
+
+	for _, mp := range mappings {
+		var typeMapping struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+
+		err = json.Unmarshal(mp, &typeMapping)
+		if err != nil {
+			return nil, fmt.Errorf("invalid static mapping: %s", err.Error())
+		}
+
+		err = flattenProperties("", typeMapping.Properties, fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	m.static = fields
+	m.mu.Unlock()
+
+	return m, nil
+}
+
+// flattenProperties adds to fields every field defined in properties, including
+// object sub-fields and multi-fields, keyed by their dot-notation names. Fields
+// already present are kept.
+func flattenProperties(prefix string, properties map[string]json.RawMessage, fields map[string]FieldInfo) error {
+	for name, raw := range properties {
+		var def struct {
+			Type       string                     `json:"type"`
+			Properties map[string]json.RawMessage `json:"properties"`
+			Fields     map[string]json.RawMessage `json:"fields"`
+		}
+
+		err := json.Unmarshal(raw, &def)
+		if err != nil {
+			return fmt.Errorf("invalid mapping for field %s%s: %s", prefix, name, err.Error())
+		}
+
+		fullName := prefix + name
+
+		if def.Type == "" && def.Properties != nil {
+			def.Type = "object"
+		}
+
+		if _, ok := fields[fullName]; !ok {
+			var info FieldInfo
+
+			err = json.Unmarshal(raw, &info)
+			if err != nil {
+				return fmt.Errorf("invalid mapping for field %s: %s", fullName, err.Error())
+			}
+
+			info.Name, info.Type = fullName, def.Type
+			fields[fullName] = info
+		}
+
+		err = flattenProperties(fullName+".", def.Properties, fields)
+		if err != nil {
+			return err
+		}
+
+		err = flattenProperties(fullName+".", def.Fields, fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the known fields of pattern. The caller must hold m.mu.
+func (m *FieldMapper) lookup(pattern string) map[string]FieldInfo {
+	if m.static != nil {
+		return m.static
+	}
+
+	pf, ok := m.patterns[pattern]
+	if !ok {
+		return nil
+	}
+
+	return pf.fields
+}
+
+func (m *FieldMapper) fetch(ctx context.Context, pattern string, expressions []string) error {
+	m.mu.RLock()
+	static := m.static != nil
+	missing := m.missing(pattern, expressions)
+	m.mu.RUnlock()
+
+	if static {
+		return nil
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return m.flights.do(ctx, pattern, missing, func(ctx context.Context, missing []string) error {
+		fields, err := GetFieldMapping(ctx, []string{pattern}, missing...)
+		if err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		pf := m.pattern(pattern)
+
+		for name, info := range fields {
+			pf.fields[name] = info
+		}
+
+		for _, expression := range missing {
+			pf.fetched[expression] = true
+		}
+
+		return nil
+	})
+}
+
+// missing returns the sorted expressions not fetched yet for pattern.
+// The caller must hold m.mu.
+func (m *FieldMapper) missing(pattern string, expressions []string) []string {
+	pf := m.patterns[pattern]
+
+	var missing = make([]string, 0, len(expressions))
+	for _, expression := range expressions {
+		if pf == nil || !pf.fetched[expression] {
+			missing = append(missing, expression)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
+
+// pattern returns the cache entry of pattern, creating it if needed.
+// The caller must hold m.mu for writing.
+func (m *FieldMapper) pattern(pattern string) *patternFields {
+	pf, ok := m.patterns[pattern]
+	if !ok {
+		pf = &patternFields{
+			fields:  make(map[string]FieldInfo),
+			fetched: make(map[string]bool),
+		}
+		m.patterns[pattern] = pf
+	}
+
+	return pf
+}
+
+// flightFetchTimeout bounds the fetches of a flightGroup, which don't end with the
+// context of any of their callers.
+const flightFetchTimeout = time.Minute
+
+// flightGroup batches the fetches of the fields of each key: a single fetch is in
+// flight per key, and the fields missing meanwhile are gathered in the next one.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*keyFlights
+}
+
+type keyFlights struct {
+	running *flightCall
+	next    *flightCall
+}
+
+type flightCall struct {
+	ctx    context.Context
+	fn     func(ctx context.Context, fields []string) error
+	fields map[string]bool
+	done   chan struct{}
+	err    error
+}
+
+// do fetches the fields of key with fn, joining the fetch in flight when it covers
+// them, or the next one otherwise, and waits for it or for ctx to be done. Fetches run
+// with the values, but not the cancellation, of the context of the caller starting
+// them, so that callers waiting on them can still honor their own.
+func (g *flightGroup) do(ctx context.Context, key string, fields []string, fn func(ctx context.Context, fields []string) error) error {
+	g.mu.Lock()
+
+	if g.calls == nil {
+		g.calls = make(map[string]*keyFlights)
+	}
+
+	flights, ok := g.calls[key]
+	if !ok {
+		flights = new(keyFlights)
+		g.calls[key] = flights
+	}
+
+	var call *flightCall
+
+	switch {
+	case flights.running == nil:
+		call = newFlightCall(ctx, fn)
+		flights.running = call
+		call.add(fields)
+
+		go g.run(key, call)
+	case flights.running.covers(fields):
+		call = flights.running
+	default:
+		if flights.next == nil {
+			flights.next = newFlightCall(ctx, fn)
+		}
+
+		call = flights.next
+		call.add(fields)
+	}
+
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run executes the call, then starts the next one of key, if any.
+func (g *flightGroup) run(key string, call *flightCall) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("fetching fields of %s: panic: %v", key, r)
+		}
+
+		close(call.done)
+
+		g.mu.Lock()
+		flights := g.calls[key]
+		next := flights.next
+		flights.running, flights.next = next, nil
+
+		if next == nil {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		if next != nil {
+			go g.run(key, next)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(call.ctx, flightFetchTimeout)
+	defer cancel()
+
+	// Fields only join a call before it runs.
+	g.mu.Lock()
+	var fields = make([]string, 0, len(call.fields))
+	for field := range call.fields {
+		fields = append(fields, field)
+	}
+	g.mu.Unlock()
+
+	sort.Strings(fields)
+
+	call.err = call.fn(ctx, fields)
+}
+
+func newFlightCall(ctx context.Context, fn func(ctx context.Context, fields []string) error) *flightCall {
+	return &flightCall{
+		ctx:    context.WithoutCancel(ctx),
+		fn:     fn,
+		fields: make(map[string]bool),
+		done:   make(chan struct{}),
+	}
+}
+
+// add adds fields to the call. The caller must hold the mutex of the group.
+func (c *flightCall) add(fields []string) {
+	for _, field := range fields {
+		c.fields[field] = true
+	}
+}
+
+// covers reports whether the call fetches all the fields. The caller must hold the
+// mutex of the group.
+func (c *flightCall) covers(fields []string) bool {
+	for _, field := range fields {
+		if !c.fields[field] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,326 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MappingExport is the merged mappings and settings of the indices matching a
+// pattern, portable to another cluster.
+type MappingExport struct {
+	Pattern    string    `json:"pattern"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Indices are the indices the export was merged from, sorted.
+	Indices []string `json:"indices"`
+	// Settings are flat index settings, like "index.number_of_shards", without those
+	// only meaningful in the source cluster, like the UUID or the creation date.
+	Settings map[string]interface{} `json:"settings,omitempty"`
+	// Mappings merge the mappings of the indices. Definitions of the first index in
+	// lexicographic order win, as in GetMergedMapping.
+	Mappings map[string]interface{} `json:"mappings"`
+	// Conflicts are the fields having different types across the indices, as
+	// reported by GetMergedMapping.
+	Conflicts map[string]map[string][]string `json:"conflicts,omitempty"`
+}
+
+// clusterSettings are the index settings left out of exports, as prefixes of the flat
+// setting names.
+var clusterSettings = []string{
+	"index.uuid",
+	"index.creation_date",
+	"index.version.",
+	"index.provided_name",
+	"index.history.",
+	"index.routing.",
+	"index.resize.",
+	"index.blocks.",
+	"index.verified_before_close",
+}
+
+// ExportMappings returns the merged mappings and settings of the indices matching
+// each pattern, e.g. to reproduce the indices of a development cluster in staging
+// with ImportMappings. Patterns matching no index are exported empty.
+func ExportMappings(ctx context.Context, patterns ...string) ([]MappingExport, error) {
+	var exports = make([]MappingExport, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		e, err := exportMapping(ctx, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", pattern, err)
+		}
+
+		exports = append(exports, e)
+	}
+
+	return exports, nil
+}
+
+func exportMapping(ctx context.Context, pattern string) (MappingExport, error) {
+	var params = url.Values{}
+	params.Set("ignore_unavailable", "true")
+	params.Set("allow_no_indices", "true")
+
+	body, err := Do(ctx, http.MethodGet, "/"+pattern+"/_mapping", params, nil)
+	if err != nil {
+		return MappingExport{}, err
+	}
+
+	var mappings map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+
+	err = json.Unmarshal(body, &mappings)
+	if err != nil {
+		return MappingExport{}, err
+	}
+
+	params.Set("flat_settings", "true")
+
+	body, err = Do(ctx, http.MethodGet, "/"+pattern+"/_settings", params, nil)
+	if err != nil {
+		return MappingExport{}, err
+	}
+
+	var settings map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+
+	err = json.Unmarshal(body, &settings)
+	if err != nil {
+		return MappingExport{}, err
+	}
+
+	e := MappingExport{
+		Pattern:    pattern,
+		ExportedAt: time.Now().UTC(),
+		Indices:    make([]string, 0, len(mappings)),
+		Mappings:   make(map[string]interface{}),
+	}
+
+	for name := range mappings {
+		e.Indices = append(e.Indices, name)
+	}
+
+	sort.Strings(e.Indices)
+
+	if len(e.Indices) == 0 {
+		return e, nil
+	}
+
+	for _, name := range e.Indices {
+		mergeMapping(e.Mappings, mappings[name].Mappings)
+	}
+
+	e.Settings = portableSettings(settings[e.Indices[0]].Settings)
+
+	merged, err := GetMergedMapping(ctx, []string{pattern})
+	if err != nil {
+		return MappingExport{}, err
+	}
+
+	e.Conflicts = merged.Conflicts
+
+	return e, nil
+}
+
+// mergeMapping adds the definitions of src missing from dst, merging the properties
+// and sub-fields of fields of the same type. The definition of dst wins when the
+// types differ.
+func mergeMapping(dst, src map[string]interface{}) {
+	for k, v := range src {
+		current, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+
+		cm, cok := current.(map[string]interface{})
+		vm, vok := v.(map[string]interface{})
+
+		if cok && vok && mappingType(cm) == mappingType(vm) {
+			mergeMapping(cm, vm)
+		}
+	}
+}
+
+// mappingType returns the type of a field definition, empty for objects and for maps
+// of properties, which may hold a field named "type".
+func mappingType(m map[string]interface{}) string {
+	t, _ := m["type"].(string)
+
+	return t
+}
+
+func portableSettings(settings map[string]interface{}) map[string]interface{} {
+	var portable = make(map[string]interface{}, len(settings))
+
+	for name, v := range settings {
+		var skip bool
+
+		for _, prefix := range clusterSettings {
+			if strings.HasPrefix(name, prefix) {
+				skip = true
+				break
+			}
+		}
+
+		if !skip {
+			portable[name] = v
+		}
+	}
+
+	return portable
+}
+
+// WriteMappingExports writes exports to a JSON file.
+func WriteMappingExports(path string, exports []MappingExport) error {
+	j, err := json.MarshalIndent(exports, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(j, '\n'), 0o644)
+}
+
+// ReadMappingExports reads the exports written by WriteMappingExports.
+func ReadMappingExports(path string) ([]MappingExport, error) {
+	j, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var exports []MappingExport
+
+	err = json.Unmarshal(j, &exports)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping exports %s: %w", path, err)
+	}
+
+	return exports, nil
+}
+
+// ImportOptions configure ImportMappings.
+type ImportOptions struct {
+	// DryRun only returns the plans, without changing the cluster.
+	DryRun bool
+	// Index names the index created when none matches the pattern of an export, the
+	// last index of the export by default.
+	Index func(e MappingExport) string
+	// IgnoreSettings are flat settings left out of the plans and of created indices,
+	// like "index.number_of_replicas" when the clusters have a different number of
+	// nodes.
+	IgnoreSettings []string
+}
+
+// MappingPlan is what ImportMappings does, or would do on a dry run, for an export.
+type MappingPlan struct {
+	Pattern string `json:"pattern"`
+	// Create is the index created when none matches the pattern, empty when the
+	// mappings of the matching indices are updated instead.
+	Create string `json:"create,omitempty"`
+	// Indices are the matching indices whose mappings are updated.
+	Indices []string `json:"indices,omitempty"`
+	// Changes compare the cluster to the export, with paths starting with "settings"
+	// or "mappings".
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// ImportMappings applies exports to the cluster the client is connected to, and
+// returns the changes of each. Indices matching the pattern of an export get the
+// mappings they lack, while the settings and the fields they have and the export
+// lacks are only reported; changing the type of an existing field is rejected by the
+// search engine. When no index matches, one is created with the settings and
+// mappings of the export. Dry runs return the same plans and change nothing, to
+// review the differences between the clusters first.
+func ImportMappings(ctx context.Context, exports []MappingExport, o ImportOptions) ([]MappingPlan, error) {
+	var plans = make([]MappingPlan, 0, len(exports))
+
+	for _, e := range exports {
+		plan, err := importMapping(ctx, e, o)
+		if err != nil {
+			return plans, fmt.Errorf("import %s: %w", e.Pattern, err)
+		}
+
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+func importMapping(ctx context.Context, e MappingExport, o ImportOptions) (MappingPlan, error) {
+	current, err := exportMapping(ctx, e.Pattern)
+	if err != nil {
+		return MappingPlan{}, err
+	}
+
+	settings := withoutSettings(e.Settings, o.IgnoreSettings)
+
+	plan := MappingPlan{Pattern: e.Pattern, Indices: current.Indices}
+
+	if len(current.Indices) == 0 {
+		plan.Indices = nil
+
+		if o.Index != nil {
+			plan.Create = o.Index(e)
+		} else if len(e.Indices) != 0 {
+			plan.Create = e.Indices[len(e.Indices)-1]
+		}
+
+		if plan.Create == "" {
+			return MappingPlan{}, errors.New("no index matches the pattern and the export names none to create")
+		}
+	}
+
+	from, err := toGeneric(map[string]interface{}{"settings": withoutSettings(current.Settings, o.IgnoreSettings), "mappings": current.Mappings})
+	if err != nil {
+		return MappingPlan{}, err
+	}
+
+	to, err := toGeneric(map[string]interface{}{"settings": settings, "mappings": e.Mappings})
+	if err != nil {
+		return MappingPlan{}, err
+	}
+
+	diffValues("", "", from, to, &plan.Changes)
+
+	if o.DryRun || len(plan.Changes) == 0 {
+		return plan, nil
+	}
+
+	if plan.Create != "" {
+		err = CreateIndex(ctx, plan.Create, map[string]interface{}{"settings": settings, "mappings": e.Mappings})
+	} else {
+		_, err = Do(ctx, http.MethodPut, "/"+e.Pattern+"/_mapping", nil, e.Mappings)
+	}
+
+	if err != nil {
+		return plan, err
+	}
+
+	// Fields cached before the import miss the new ones.
+	Mapper().forget(e.Pattern)
+
+	return plan, nil
+}
+
+// withoutSettings returns a copy of settings without the ignored ones.
+func withoutSettings(settings map[string]interface{}, ignored []string) map[string]interface{} {
+	var out = make(map[string]interface{}, len(settings))
+	for name, v := range settings {
+		out[name] = v
+	}
+
+	for _, name := range ignored {
+		delete(out, name)
+	}
+
+	return out
+}
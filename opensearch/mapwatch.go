@@ -0,0 +1,333 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MergedMapping is the mapping of the fields of several indices.
+type MergedMapping struct {
+	// Fields are the leaf fields, keyed by their dot-notation names, with the
+	// definition of the first index in lexicographic order.
+	Fields map[string]FieldInfo `json:"fields"`
+	// Conflicts maps the fields having different types across the indices to the
+	// sorted indices of each type.
+	Conflicts map[string]map[string][]string `json:"conflicts,omitempty"`
+}
+
+// GetMergedMapping returns the merged mapping of every field of the indices matching
+// the patterns.
+func GetMergedMapping(ctx context.Context, index []string) (MergedMapping, error) {
+	result, err := getFieldMappings(ctx, index, []string{"*"})
+	if err != nil {
+		return MergedMapping{}, err
+	}
+
+	var indices = make([]string, 0, len(result))
+	for name := range result {
+		indices = append(indices, name)
+	}
+
+	sort.Strings(indices)
+
+	merged := MergedMapping{Fields: make(map[string]FieldInfo)}
+	types := make(map[string]map[string][]string)
+
+	for _, name := range indices {
+		for field, mapping := range result[name].Mappings {
+			for _, raw := range mapping.Mapping {
+				var info FieldInfo
+
+				err = json.Unmarshal(raw, &info)
+				if err != nil {
+					return MergedMapping{}, err
+				}
+
+				// Metadata fields, like _id, have no type.
+				if info.Type == "" {
+					continue
+				}
+
+				info.Name = mapping.FullName
+				if info.Name == "" {
+					info.Name = field
+				}
+
+				if _, ok := merged.Fields[field]; !ok {
+					merged.Fields[field] = info
+				}
+
+				if types[field] == nil {
+					types[field] = make(map[string][]string)
+				}
+
+				types[field][info.Type] = append(types[field][info.Type], name)
+			}
+		}
+	}
+
+	for field, byType := range types {
+		if len(byType) < 2 {
+			continue
+		}
+
+		if merged.Conflicts == nil {
+			merged.Conflicts = make(map[string]map[string][]string)
+		}
+
+		merged.Conflicts[field] = byType
+	}
+
+	return merged, nil
+}
+
+// MappingChangeKind is the kind of a MappingChange.
+type MappingChangeKind string
+
+const (
+	// FieldAdded reports a field mapped for the first time.
+	FieldAdded MappingChangeKind = "field_added"
+	// TypeChanged reports a field whose merged type changed.
+	TypeChanged MappingChangeKind = "type_changed"
+	// ConflictAdded reports a field newly mapped with different types across the
+	// indices, or with a new conflicting type.
+	ConflictAdded MappingChangeKind = "conflict_added"
+)
+
+// MappingChange is a change between successive merged mappings of an index pattern.
+type MappingChange struct {
+	Pattern string            `json:"pattern"`
+	Field   string            `json:"field"`
+	Kind    MappingChangeKind `json:"kind"`
+	OldType string            `json:"old_type,omitempty"`
+	NewType string            `json:"new_type,omitempty"`
+	// Types are the indices of each type of conflicting fields.
+	Types map[string][]string `json:"types,omitempty"`
+	At    time.Time           `json:"at"`
+}
+
+// DiffMappings returns the changes from a merged mapping to the next one of pattern,
+// sorted by field and kind.
+func DiffMappings(pattern string, from, to MergedMapping, at time.Time) []MappingChange {
+	var changes []MappingChange
+
+	for field, info := range to.Fields {
+		old, ok := from.Fields[field]
+
+		switch {
+		case !ok:
+			changes = append(changes, MappingChange{Pattern: pattern, Field: field, Kind: FieldAdded, NewType: info.Type, At: at})
+		case old.Type != info.Type:
+			changes = append(changes, MappingChange{Pattern: pattern, Field: field, Kind: TypeChanged, OldType: old.Type, NewType: info.Type, At: at})
+		}
+	}
+
+	for field, types := range to.Conflicts {
+		old := from.Conflicts[field]
+
+		for t := range types {
+			if _, ok := old[t]; !ok {
+				changes = append(changes, MappingChange{Pattern: pattern, Field: field, Kind: ConflictAdded, Types: types, At: at})
+				break
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Field != changes[j].Field {
+			return changes[i].Field < changes[j].Field
+		}
+
+		return changes[i].Kind < changes[j].Kind
+	})
+
+	return changes
+}
+
+// MappingWatcher polls the merged mapping of an index pattern and publishes its
+// changes to the subscribers.
+type MappingWatcher struct {
+	pattern string
+	mapper  *FieldMapper
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu          sync.Mutex
+	subscribers map[int]func(MappingChange)
+	nextID      int
+	err         error
+	started     time.Time
+	polled      time.Time
+	static      bool
+	once        sync.Once
+}
+
+// WatchMapping polls the merged mapping of the indices matching pattern every
+// interval, starting now, and publishes the fields added, the type changes and the
+// new conflicts since the previous poll, e.g. to warn detection engineers when the
+// format of a log source drifts. The first poll only records the mapping. The fields
+// of pattern cached by the mapper are forgotten on changes, so that lookups fetch
+// them again. Mappers configured with WithStaticMapping are not polled, their mapping
+// never changing. Stop ends the polling.
+func (m *FieldMapper) WatchMapping(pattern string, interval time.Duration) (*MappingWatcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("mapping watch interval must be positive, got %s", interval)
+	}
+
+	m.mu.RLock()
+	static := m.static != nil
+	m.mu.RUnlock()
+
+	w := &MappingWatcher{
+		pattern:     pattern,
+		mapper:      m,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		subscribers: make(map[int]func(MappingChange)),
+		started:     time.Now(),
+		static:      static,
+	}
+
+	go w.run(interval)
+
+	return w, nil
+}
+
+// Subscribe calls fn with every change, from the polling goroutine, until the
+// returned function is called.
+func (w *MappingWatcher) Subscribe(fn func(MappingChange)) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		delete(w.subscribers, id)
+		w.mu.Unlock()
+	}
+}
+
+// Err returns the error of the last poll, nil if it succeeded.
+func (w *MappingWatcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// LastPoll returns when the mapping was last polled successfully, or the zero time if
+// it never was. Watchers of a static mapping, which is always current, return the
+// current time.
+func (w *MappingWatcher) LastPoll() time.Time {
+	if w.static {
+		return time.Now()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.polled
+}
+
+// Started returns when the watcher was started.
+func (w *MappingWatcher) Started() time.Time {
+	return w.started
+}
+
+// Stop ends the polling and waits for the running poll to return.
+func (w *MappingWatcher) Stop() {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+func (w *MappingWatcher) run(interval time.Duration) {
+	defer close(w.done)
+
+	if w.static {
+		<-w.stop
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-w.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *MergedMapping
+
+	for {
+		current, err := GetMergedMapping(ctx, []string{w.pattern})
+
+		w.mu.Lock()
+		w.err = err
+		if err == nil {
+			w.polled = time.Now()
+		}
+		w.mu.Unlock()
+
+		if err == nil {
+			if last != nil {
+				w.publish(DiffMappings(w.pattern, *last, current, time.Now().UTC()))
+			}
+
+			last = &current
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *MappingWatcher) publish(changes []MappingChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	w.mapper.forget(w.pattern)
+
+	w.mu.Lock()
+	var ids = make([]int, 0, len(w.subscribers))
+	for id := range w.subscribers {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	var subscribers = make([]func(MappingChange), len(ids))
+	for i, id := range ids {
+		subscribers[i] = w.subscribers[id]
+	}
+	w.mu.Unlock()
+
+	for _, change := range changes {
+		for _, fn := range subscribers {
+			fn(change)
+		}
+	}
+}
+
+// forget drops the cached fields of pattern.
+func (m *FieldMapper) forget(pattern string) {
+	m.mu.Lock()
+	delete(m.patterns, pattern)
+	m.mu.Unlock()
+}
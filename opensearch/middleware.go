@@ -0,0 +1,108 @@
+package opensearch
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Middleware wraps the transport of the client, to inject headers, record requests,
+// enforce policies or answer requests without the cluster, e.g. in tests.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is a function implementing http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chain wraps next with the middlewares, the first being the outermost.
+func chain(next http.RoundTripper, middlewares []Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		next = middlewares[i](next)
+	}
+
+	return next
+}
+
+// WithHeader sets a header on every request.
+func WithHeader(key, value string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set(key, value)
+
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// OnRequest calls fn before sending each request. Requests for which fn returns an
+// error fail with it without being sent, e.g. to forbid deletions from a service.
+func OnRequest(fn func(req *http.Request) error) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			err := fn(req)
+			if err != nil {
+				return nil, err
+			}
+
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Exchange is a request and its outcome, as recorded by OnResponse.
+type Exchange struct {
+	Method      string
+	Path        string
+	Query       string
+	RequestBody []byte
+	StatusCode  int
+	Duration    time.Duration
+	Err         error
+}
+
+// OnResponse calls fn after each request with its outcome, e.g. to log the query DSL
+// sent by the application. Request bodies are buffered to be recorded, and are empty
+// for compressed requests.
+func OnResponse(fn func(Exchange)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ex := Exchange{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery}
+
+			if req.Body != nil && req.Header.Get("Content-Encoding") == "" {
+				body, err := io.ReadAll(req.Body)
+				req.Body.Close()
+
+				if err != nil {
+					return nil, err
+				}
+
+				ex.RequestBody = body
+
+				req = req.Clone(req.Context())
+				req.Body = io.NopCloser(bytes.NewReader(body))
+				req.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(body)), nil
+				}
+			}
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			ex.Duration = time.Since(start)
+			ex.Err = err
+
+			if resp != nil {
+				ex.StatusCode = resp.StatusCode
+			}
+
+			fn(ex)
+
+			return resp, err
+		})
+	}
+}
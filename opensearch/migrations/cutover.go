@@ -0,0 +1,450 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Cutover moves an alias from the indices it points to onto a new index holding a
+// copy of their documents, e.g. to change the mappings of an index in use. The copy
+// is verified before the alias is swapped, and every change is rolled back when a
+// step fails, so that the alias never points to an index missing documents.
+type Cutover struct {
+	Alias string
+	// Target is the index the documents are copied to. It must not exist unless it
+	// has the mappings expected and holds no document, since a failed cutover leaves
+	// an existing target in place.
+	Target string
+	// Type is the struct type EnsureIndex creates the target from, or Body the
+	// settings and mappings it is created with, as taken by CreateIndex.
+	Type interface{}
+	Body map[string]interface{}
+	// SpotChecks is the number of random documents compared between the source and
+	// the target by hashing their sources, 100 by default.
+	SpotChecks int
+	// AllowWrites keeps the sources writable during the cutover. Documents written to
+	// them while the documents are copied are then lost. By default, the sources are
+	// made read-only until the cutover fails, and remain so after it succeeds, so that
+	// writers still using them fail instead of losing documents.
+	AllowWrites bool
+}
+
+// CutoverReport describes a cutover.
+type CutoverReport struct {
+	Alias   string   `json:"alias"`
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+	// SourceCount and TargetCount are the number of documents of the sources and of
+	// the target once copied.
+	SourceCount int64 `json:"source_count"`
+	TargetCount int64 `json:"target_count"`
+	// SpotChecked is the number of documents compared, and Mismatches the IDs of those
+	// missing or different in the target.
+	SpotChecked int      `json:"spot_checked"`
+	Mismatches  []string `json:"mismatches,omitempty"`
+	Swapped     bool     `json:"swapped"`
+	RolledBack  bool     `json:"rolled_back,omitempty"`
+}
+
+// CutoverError is returned when a cutover fails, after its rollback.
+type CutoverError struct {
+	Step string
+	Err  error
+	// Rollback is the error of the rollback, nil if it restored the sources, the
+	// alias, and removed the target when the cutover created it.
+	Rollback error
+}
+
+func (e *CutoverError) Error() string {
+	if e.Rollback != nil {
+		return fmt.Sprintf("cutover failed at %s: %v; rollback failed: %v", e.Step, e.Err, e.Rollback)
+	}
+
+	return fmt.Sprintf("cutover failed at %s: %v; rolled back", e.Step, e.Err)
+}
+
+func (e *CutoverError) Unwrap() error {
+	return e.Err
+}
+
+// cutoverState tracks the changes to roll back.
+type cutoverState struct {
+	blocked []string
+	created bool
+	swapped bool
+}
+
+// Run creates the target, copies the documents of the indices the alias points to,
+// verifies that the target holds as many documents as the sources and the same
+// content for a random sample of them, and atomically swaps the alias onto the
+// target. The sources are kept, to be deleted once the target is known to be good.
+func (c Cutover) Run(ctx context.Context) (CutoverReport, error) {
+	if c.SpotChecks <= 0 {
+		c.SpotChecks = 100
+	}
+
+	report := CutoverReport{Alias: c.Alias, Target: c.Target}
+
+	if c.Alias == "" || c.Target == "" {
+		return report, errors.New("cutover needs an alias and a target")
+	}
+
+	if c.Type == nil && c.Body == nil {
+		return report, errors.New("cutover needs the type or the body of the target")
+	}
+
+	sources, definitions, err := aliasIndices(ctx, c.Alias)
+	if err != nil {
+		return report, err
+	}
+
+	target, err := targetDefinition(c.Alias, sources, definitions)
+	if err != nil {
+		return report, err
+	}
+
+	for _, source := range sources {
+		if source == c.Target {
+			return report, fmt.Errorf("alias %s already points to %s", c.Alias, c.Target)
+		}
+	}
+
+	report.Sources = sources
+
+	var state cutoverState
+
+	fail := func(step string, err error) (CutoverReport, error) {
+		rollbackErr := c.rollback(ctx, sources, definitions, &state)
+		report.RolledBack = rollbackErr == nil
+		report.Swapped = state.swapped
+
+		return report, &CutoverError{Step: step, Err: err, Rollback: rollbackErr}
+	}
+
+	state.created, err = c.createTarget(ctx)
+	if err != nil {
+		return fail("create target", err)
+	}
+
+	if !c.AllowWrites {
+		err = setWriteBlock(ctx, sources, true)
+		state.blocked = sources
+		if err != nil {
+			return fail("block writes", err)
+		}
+	}
+
+	err = reindex(ctx, map[string]interface{}{
+		"source": map[string]interface{}{"index": sources},
+		"dest":   map[string]interface{}{"index": c.Target},
+	})
+	if err != nil {
+		return fail("reindex", err)
+	}
+
+	err = opensearch.RefreshIndex(ctx, c.Target)
+	if err != nil {
+		return fail("refresh", err)
+	}
+
+	report.SourceCount, err = countDocs(ctx, sources)
+	if err != nil {
+		return fail("count", err)
+	}
+
+	report.TargetCount, err = countDocs(ctx, []string{c.Target})
+	if err != nil {
+		return fail("count", err)
+	}
+
+	if report.SourceCount != report.TargetCount {
+		return fail("verify count", fmt.Errorf("sources hold %d documents and the target %d", report.SourceCount, report.TargetCount))
+	}
+
+	report.SpotChecked, report.Mismatches, err = spotCheck(ctx, sources, c.Target, c.SpotChecks)
+	if err != nil {
+		return fail("spot check", err)
+	}
+
+	if len(report.Mismatches) != 0 {
+		return fail("verify content", fmt.Errorf("%d of %d spot-checked documents differ, first: %s", len(report.Mismatches), report.SpotChecked, report.Mismatches[0]))
+	}
+
+	// The alias may filter the documents, so it is counted through before and after
+	// the swap.
+	before, err := countDocs(ctx, []string{c.Alias})
+	if err != nil {
+		return fail("count", err)
+	}
+
+	err = swapAliasWith(ctx, AliasSwap{Alias: c.Alias, Remove: sources, Add: []string{c.Target}},
+		map[string]aliasDefinition{c.Target: target})
+	if err != nil {
+		return fail("swap alias", err)
+	}
+
+	state.swapped = true
+
+	// The alias must now resolve to the copy.
+	count, err := countDocs(ctx, []string{c.Alias})
+	if err != nil {
+		return fail("verify alias", err)
+	}
+
+	if count != before {
+		return fail("verify alias", fmt.Errorf("alias holds %d documents instead of %d", count, before))
+	}
+
+	report.Swapped = true
+
+	return report, nil
+}
+
+// createTarget creates the target and reports whether it didn't exist.
+func (c Cutover) createTarget(ctx context.Context) (bool, error) {
+	_, err := opensearch.Do(ctx, http.MethodHead, "/"+url.PathEscape(c.Target), nil, nil)
+
+	exists := err == nil
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+
+	if exists {
+		// Documents of an existing target would be mixed with the copy, and remain
+		// in it if the cutover fails.
+		count, err := countDocs(ctx, []string{c.Target})
+		if err != nil {
+			return false, err
+		}
+
+		if count != 0 {
+			return false, fmt.Errorf("target %s already holds %d documents", c.Target, count)
+		}
+	}
+
+	if c.Type != nil {
+		return !exists, opensearch.EnsureIndex(ctx, c.Target, c.Type)
+	}
+
+	if exists {
+		return false, nil
+	}
+
+	return true, opensearch.CreateIndex(ctx, c.Target, c.Body)
+}
+
+// rollback restores the alias, with its definitions, and the write blocks of the
+// sources, and deletes the target when the cutover created it.
+func (c Cutover) rollback(ctx context.Context, sources []string, definitions map[string]aliasDefinition, state *cutoverState) error {
+	// The caller's context may be the reason of the failure.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+
+	var errs []error
+
+	if state.swapped {
+		err := swapAliasWith(ctx, AliasSwap{Alias: c.Alias, Remove: []string{c.Target}, Add: sources}, definitions)
+		if err != nil {
+			// The target is left in place while the alias points to it.
+			return fmt.Errorf("restoring alias %s: %w", c.Alias, err)
+		}
+
+		state.swapped = false
+	}
+
+	if len(state.blocked) != 0 {
+		err := setWriteBlock(ctx, state.blocked, false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unblocking writes: %w", err))
+		}
+	}
+
+	if state.created {
+		_, err := opensearch.Do(ctx, http.MethodDelete, "/"+url.PathEscape(c.Target), nil, nil)
+		if err != nil && !isNotFound(err) {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", c.Target, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// aliasDefinition is the definition of an alias on an index, e.g. its filter, its
+// routing and whether the index is its write index, as returned by the get alias API
+// and taken by the add action of the aliases API.
+type aliasDefinition map[string]interface{}
+
+// aliasIndices returns the sorted indices the alias points to, and the definition of
+// the alias on each of them.
+func aliasIndices(ctx context.Context, alias string) ([]string, map[string]aliasDefinition, error) {
+	resp, err := opensearch.Do(ctx, http.MethodGet, "/_alias/"+url.PathEscape(alias), nil, nil)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil, fmt.Errorf("alias %s not found", alias)
+		}
+
+		return nil, nil, err
+	}
+
+	var result map[string]struct {
+		Aliases map[string]aliasDefinition `json:"aliases"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var indices = make([]string, 0, len(result))
+	var definitions = make(map[string]aliasDefinition, len(result))
+
+	for index, aliases := range result {
+		indices = append(indices, index)
+		definitions[index] = aliases.Aliases[alias]
+	}
+
+	if len(indices) == 0 {
+		return nil, nil, fmt.Errorf("alias %s points to no index", alias)
+	}
+
+	sort.Strings(indices)
+
+	return indices, definitions, nil
+}
+
+// targetDefinition returns the definition of the alias on the target, that of the
+// sources, which must share their filter and routing, as the write index.
+func targetDefinition(alias string, sources []string, definitions map[string]aliasDefinition) (aliasDefinition, error) {
+	var target aliasDefinition
+	var first []byte
+
+	for i, source := range sources {
+		var definition = make(aliasDefinition, len(definitions[source]))
+		for k, v := range definitions[source] {
+			if k != "is_write_index" {
+				definition[k] = v
+			}
+		}
+
+		j, err := json.Marshal(definition)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			target, first = definition, j
+			continue
+		}
+
+		if string(j) != string(first) {
+			return nil, fmt.Errorf("alias %s is defined differently on %s and %s, which a single target can't keep", alias, sources[0], source)
+		}
+	}
+
+	target["is_write_index"] = true
+
+	return target, nil
+}
+
+func setWriteBlock(ctx context.Context, indices []string, blocked bool) error {
+	var value interface{} = blocked
+	if !blocked {
+		// Resetting the setting drops it rather than storing false.
+		value = nil
+	}
+
+	_, err := opensearch.Do(ctx, http.MethodPut, "/"+strings.Join(indices, ",")+"/_settings", nil,
+		map[string]interface{}{"index.blocks.write": value})
+
+	return err
+}
+
+func countDocs(ctx context.Context, indices []string) (int64, error) {
+	resp, err := opensearch.Do(ctx, http.MethodGet, "/"+strings.Join(indices, ",")+"/_count", nil, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Count int64 `json:"count"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+
+	return result.Count, err
+}
+
+// spotCheck compares the sources of a random sample of n documents of the sources to
+// those of the target, and returns the number compared and the IDs of those missing
+// or different.
+func spotCheck(ctx context.Context, sources []string, target string, n int) (int, []string, error) {
+	sample, err := opensearch.SearchRequest{}.WithoutTenantScope().
+		SampleIn(ctx, sources, int64(n), opensearch.SampleOptions{Seed: time.Now().UnixNano()})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	hits := sample.Hits.Hits
+	if len(hits) == 0 {
+		return 0, nil, nil
+	}
+
+	var ids = make([]interface{}, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	copies, err := opensearch.SearchRequest{
+		Size:  int64(len(hits)),
+		Query: &opensearch.Query{IDs: map[string][]interface{}{"values": ids}},
+	}.WithoutTenantScope().SearchIn(ctx, []string{target})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var hashes = make(map[string]string, len(copies.Hits.Hits))
+	for _, hit := range copies.Hits.Hits {
+		hashes[hit.ID], err = hashSource(hit.Source)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var mismatches []string
+
+	for _, hit := range hits {
+		h, err := hashSource(hit.Source)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if hashes[hit.ID] != h {
+			mismatches = append(mismatches, hit.ID)
+		}
+	}
+
+	return len(hits), mismatches, nil
+}
+
+// hashSource hashes the JSON of a document source, whose keys are sorted.
+func hashSource(src opensearch.HitSource) (string, error) {
+	j, err := json.Marshal(src)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(j)
+
+	return hex.EncodeToString(sum[:]), nil
+}
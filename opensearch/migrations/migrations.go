@@ -0,0 +1,350 @@
+// Package migrations evolves index schemas with ordered migration files, applying
+// each of them once and recording the applied versions in a meta index, like SQL
+// migration tools do.
+//
+// Migrations are YAML (or JSON) files named "<version>_<name>.yaml", e.g.
+// "0003_add_geo_fields.yaml", applied in ascending version order:
+//
+//	description: Add geolocation fields
+//	steps:
+//	  - put_mapping:
+//	      index: "*-events-*"
+//	      body: {properties: {source: {properties: {geo: {type: geo_point}}}}}
+//	  - create_index: {index: events-v2, body: {mappings: {...}}}
+//	  - reindex: {body: {source: {index: events-v1}, dest: {index: events-v2}}}
+//	  - alias_swap: {alias: events, remove: [events-v1], add: [events-v2]}
+//	  - delete_index: {index: events-v1}
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMetaIndex is the index recording the applied migrations.
+const DefaultMetaIndex = "schema-migrations"
+
+// Migration is a set of steps applied together.
+type Migration struct {
+	Version     int64  `yaml:"-"`
+	Name        string `yaml:"-"`
+	Description string `yaml:"description"`
+	Steps       []Step `yaml:"steps"`
+	// Checksum identifies the content of the migration file, to detect applied
+	// migrations modified afterwards.
+	Checksum string `yaml:"-"`
+}
+
+// Step is a single operation of a migration. Exactly one of its members is set.
+type Step struct {
+	PutMapping  *IndexBody `yaml:"put_mapping,omitempty"`
+	CreateIndex *IndexBody `yaml:"create_index,omitempty"`
+	DeleteIndex *IndexBody `yaml:"delete_index,omitempty"`
+	Reindex     *Reindex   `yaml:"reindex,omitempty"`
+	AliasSwap   *AliasSwap `yaml:"alias_swap,omitempty"`
+}
+
+type IndexBody struct {
+	Index string                 `yaml:"index"`
+	Body  map[string]interface{} `yaml:"body,omitempty"`
+}
+
+type Reindex struct {
+	Body map[string]interface{} `yaml:"body"`
+}
+
+// AliasSwap atomically removes the alias from the indices in Remove and adds it to the
+// indices in Add.
+type AliasSwap struct {
+	Alias  string   `yaml:"alias"`
+	Remove []string `yaml:"remove,omitempty"`
+	Add    []string `yaml:"add,omitempty"`
+}
+
+// Record is the document stored in the meta index for an applied migration.
+type Record struct {
+	Version     int64     `json:"version"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Checksum    string    `json:"checksum"`
+	AppliedAt   time.Time `json:"appliedAt"`
+}
+
+// Load reads the migration files of dir, sorted by version.
+func Load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	var versions = make(map[int64]string)
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		m, err := loadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if other, ok := versions[m.Version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), m.Version)
+		}
+
+		versions[m.Version] = entry.Name()
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func loadFile(file string) (Migration, error) {
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+	prefix, name, _ := strings.Cut(base, "_")
+
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return Migration{}, fmt.Errorf("migration file %s does not start with a version number", file)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	var m Migration
+
+	err = yaml.Unmarshal(content, &m)
+	if err != nil {
+		return Migration{}, fmt.Errorf("invalid migration file %s: %s", file, err.Error())
+	}
+
+	for i, step := range m.Steps {
+		if count(step) != 1 {
+			return Migration{}, fmt.Errorf("step %d of migration file %s must set exactly one operation", i+1, file)
+		}
+	}
+
+	sum := sha256.Sum256(content)
+
+	m.Version = version
+	m.Name = name
+	m.Checksum = hex.EncodeToString(sum[:])
+
+	return m, nil
+}
+
+func count(s Step) int {
+	var n int
+	for _, set := range []bool{s.PutMapping != nil, s.CreateIndex != nil, s.DeleteIndex != nil, s.Reindex != nil, s.AliasSwap != nil} {
+		if set {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Runner applies migrations, tracking them in MetaIndex.
+type Runner struct {
+	MetaIndex  string
+	Migrations []Migration
+}
+
+// NewRunner returns a Runner for the migrations of dir, tracked in DefaultMetaIndex.
+func NewRunner(dir string) (*Runner, error) {
+	migrations, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Runner{MetaIndex: DefaultMetaIndex, Migrations: migrations}, nil
+}
+
+// Applied returns the migrations recorded in the meta index, by version.
+func (r *Runner) Applied(ctx context.Context) (map[int64]Record, error) {
+	q := opensearch.SearchRequest{Size: 10000}.WithoutTenantScope()
+
+	result, err := q.SearchIn(ctx, []string{r.MetaIndex})
+	if err != nil {
+		if isNotFound(err) {
+			return map[int64]Record{}, nil
+		}
+
+		return nil, err
+	}
+
+	var applied = make(map[int64]Record, len(result.Hits.Hits))
+
+	for _, hit := range result.Hits.Hits {
+		var record Record
+
+		err = hit.Source.ParseSource(&record)
+		if err != nil {
+			return nil, err
+		}
+
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// Pending returns the migrations not applied yet. It fails if an applied migration
+// was modified since, as the indices would not match its current content.
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := r.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+
+	for _, m := range r.Migrations {
+		record, ok := applied[m.Version]
+		if !ok {
+			pending = append(pending, m)
+			continue
+		}
+
+		if record.Checksum != m.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was modified after being applied", m.Version, m.Name)
+		}
+	}
+
+	return pending, nil
+}
+
+// Apply applies the pending migrations in order and returns them. It stops at the
+// first failing step; the migration is then not recorded and the steps already
+// applied must be reverted or made idempotent before retrying. Recording a migration
+// fails if another runner applied it concurrently.
+func (r *Runner) Apply(ctx context.Context) ([]Migration, error) {
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range pending {
+		for j, step := range m.Steps {
+			err = applyStep(ctx, step)
+			if err != nil {
+				return pending[:i], fmt.Errorf("migration %d_%s step %d: %w", m.Version, m.Name, j+1, err)
+			}
+		}
+
+		record := Record{
+			Version:     m.Version,
+			Name:        m.Name,
+			Description: m.Description,
+			Checksum:    m.Checksum,
+			AppliedAt:   time.Now().UTC(),
+		}
+
+		err = opensearch.IndexDoc(ctx, record, r.MetaIndex, strconv.FormatInt(m.Version, 10))
+		if err != nil {
+			return pending[:i], fmt.Errorf("recording migration %d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+func applyStep(ctx context.Context, s Step) error {
+	var err error
+
+	switch {
+	case s.PutMapping != nil:
+		_, err = opensearch.Do(ctx, http.MethodPut, "/"+url.PathEscape(s.PutMapping.Index)+"/_mapping", nil, s.PutMapping.Body)
+	case s.CreateIndex != nil:
+		err = opensearch.CreateIndex(ctx, s.CreateIndex.Index, s.CreateIndex.Body)
+	case s.DeleteIndex != nil:
+		_, err = opensearch.Do(ctx, http.MethodDelete, "/"+url.PathEscape(s.DeleteIndex.Index), nil, nil)
+	case s.Reindex != nil:
+		err = reindex(ctx, s.Reindex.Body)
+	case s.AliasSwap != nil:
+		err = swapAlias(ctx, *s.AliasSwap)
+	}
+
+	return err
+}
+
+func reindex(ctx context.Context, body map[string]interface{}) error {
+	resp, err := opensearch.Do(ctx, http.MethodPost, "/_reindex", url.Values{"wait_for_completion": {"true"}}, body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Failures []json.RawMessage `json:"failures"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Failures) != 0 {
+		return fmt.Errorf("reindex had %d failures, first: %s", len(result.Failures), result.Failures[0])
+	}
+
+	return nil
+}
+
+func swapAlias(ctx context.Context, swap AliasSwap) error {
+	return swapAliasWith(ctx, swap, nil)
+}
+
+// swapAliasWith swaps the alias, adding it to each index of swap.Add with its
+// definition in definitions, e.g. the filter, the routing and whether it is the write
+// index, if any.
+func swapAliasWith(ctx context.Context, swap AliasSwap, definitions map[string]aliasDefinition) error {
+	var actions []map[string]interface{}
+
+	for _, index := range swap.Remove {
+		actions = append(actions, map[string]interface{}{
+			"remove": map[string]string{"index": index, "alias": swap.Alias},
+		})
+	}
+
+	for _, index := range swap.Add {
+		var add = map[string]interface{}{"index": index, "alias": swap.Alias}
+		for k, v := range definitions[index] {
+			add[k] = v
+		}
+
+		actions = append(actions, map[string]interface{}{"add": add})
+	}
+
+	_, err := opensearch.Do(ctx, http.MethodPost, "/_aliases", nil, map[string]interface{}{"actions": actions})
+
+	return err
+}
+
+func isNotFound(err error) bool {
+	var status *opensearch.StatusError
+
+	return errors.As(err, &status) && status.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,295 @@
+// Package ml manages the models of the ML Commons plugin: model groups, connectors to
+// remote models, registration, deployment and predictions. Deployed text embedding
+// models compute the embeddings of embeddings.MLCommonsEmbedder and neural queries.
+package ml
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+const mlPath = "/_plugins/_ml"
+
+// do sends a request to ML Commons and decodes its response into dst.
+func do(ctx context.Context, method, path string, params url.Values, body, dst interface{}) error {
+	resp, err := opensearch.Do(ctx, method, mlPath+path, params, body)
+	if err != nil {
+		return err
+	}
+
+	if dst == nil {
+		return nil
+	}
+
+	return json.Unmarshal(resp, dst)
+}
+
+// ModelGroup groups the versions of a model and controls their access.
+type ModelGroup struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// AccessMode is "public", "private" or "restricted", when access control is
+	// enabled in the cluster.
+	AccessMode string `json:"access_mode,omitempty"`
+	// BackendRoles may access restricted groups.
+	BackendRoles []string `json:"backend_roles,omitempty"`
+}
+
+// RegisterModelGroup creates a model group and returns its ID.
+func RegisterModelGroup(ctx context.Context, g ModelGroup) (string, error) {
+	var result struct {
+		ID string `json:"model_group_id"`
+	}
+
+	err := do(ctx, http.MethodPost, "/model_groups/_register", nil, g, &result)
+
+	return result.ID, err
+}
+
+// DeleteModelGroup deletes a model group, which must have no models.
+func DeleteModelGroup(ctx context.Context, id string) error {
+	return do(ctx, http.MethodDelete, "/model_groups/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ConnectorAction is a call of a connector to its remote service.
+type ConnectorAction struct {
+	// ActionType is "predict".
+	ActionType string            `json:"action_type"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	// RequestBody is the template of the request, with ${parameters.name} variables.
+	RequestBody string `json:"request_body,omitempty"`
+	// PreProcessFunction and PostProcessFunction convert the input and output, e.g.
+	// "connector.pre_process.openai.embedding".
+	PreProcessFunction  string `json:"pre_process_function,omitempty"`
+	PostProcessFunction string `json:"post_process_function,omitempty"`
+}
+
+// Connector connects ML Commons to a model hosted by a remote service.
+type Connector struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Version     string                 `json:"version"`
+	Protocol    string                 `json:"protocol"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	// Credential holds the secrets of the service, encrypted by the cluster, e.g. an
+	// "openAI_key" referenced as ${credential.openAI_key} by the actions.
+	Credential map[string]string `json:"credential,omitempty"`
+	Actions    []ConnectorAction `json:"actions"`
+}
+
+// CreateConnector creates a connector and returns its ID.
+func CreateConnector(ctx context.Context, c Connector) (string, error) {
+	if c.Version == "" {
+		c.Version = "1"
+	}
+
+	if c.Protocol == "" {
+		c.Protocol = "http"
+	}
+
+	var result struct {
+		ID string `json:"connector_id"`
+	}
+
+	err := do(ctx, http.MethodPost, "/connectors/_create", nil, c, &result)
+
+	return result.ID, err
+}
+
+// DeleteConnector deletes a connector, which must have no models.
+func DeleteConnector(ctx context.Context, id string) error {
+	return do(ctx, http.MethodDelete, "/connectors/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Model is a model to register: a pretrained model, like
+// "huggingface/sentence-transformers/all-MiniLM-L6-v2", a custom model downloaded from
+// URL, or a remote model served through ConnectorID.
+type Model struct {
+	Name         string `json:"name"`
+	Version      string `json:"version,omitempty"`
+	Description  string `json:"description,omitempty"`
+	ModelGroupID string `json:"model_group_id,omitempty"`
+	// ModelFormat is "TORCH_SCRIPT" or "ONNX", for pretrained and custom models.
+	ModelFormat string `json:"model_format,omitempty"`
+	// FunctionName is "remote" for remote models, set by default with ConnectorID.
+	FunctionName string `json:"function_name,omitempty"`
+	ConnectorID  string `json:"connector_id,omitempty"`
+	// URL, ContentHash and Config describe custom models.
+	URL         string                 `json:"url,omitempty"`
+	ContentHash string                 `json:"model_content_hash_value,omitempty"`
+	Config      map[string]interface{} `json:"model_config,omitempty"`
+}
+
+// Task is an asynchronous operation of ML Commons, like a registration.
+type Task struct {
+	ID       string `json:"task_id"`
+	ModelID  string `json:"model_id"`
+	TaskType string `json:"task_type"`
+	// State is "CREATED", "RUNNING", "COMPLETED", "COMPLETED_WITH_ERROR" or "FAILED".
+	State string `json:"state"`
+	Error string `json:"error"`
+}
+
+// Done reports whether the task ended.
+func (t Task) Done() bool {
+	switch t.State {
+	case "COMPLETED", "COMPLETED_WITH_ERROR", "FAILED", "CANCELLED":
+		return true
+	}
+
+	return false
+}
+
+// Register registers a model, returning the registration task, whose ModelID is set
+// once completed. With deploy, the model is also deployed once registered.
+func Register(ctx context.Context, m Model, deploy bool) (Task, error) {
+	if m.ConnectorID != "" && m.FunctionName == "" {
+		m.FunctionName = "remote"
+	}
+
+	var params url.Values
+	if deploy {
+		params = url.Values{"deploy": {"true"}}
+	}
+
+	var task Task
+
+	err := do(ctx, http.MethodPost, "/models/_register", params, m, &task)
+
+	return task, err
+}
+
+// GetTask returns the state of a task.
+func GetTask(ctx context.Context, id string) (Task, error) {
+	var task Task
+
+	err := do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id), nil, nil, &task)
+	if task.ID == "" {
+		task.ID = id
+	}
+
+	return task, err
+}
+
+// WaitTask polls a task every interval, one second by default, until it ends, and
+// fails if it didn't complete.
+func WaitTask(ctx context.Context, id string, interval time.Duration) (Task, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		task, err := GetTask(ctx, id)
+		if err != nil {
+			return task, err
+		}
+
+		if task.Done() {
+			if task.State != "COMPLETED" {
+				return task, fmt.Errorf("ml task %s %s: %s", id, task.State, task.Error)
+			}
+
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return task, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deploy loads a registered model in the ML nodes, returning the deployment task.
+func Deploy(ctx context.Context, modelID string) (Task, error) {
+	var task Task
+
+	err := do(ctx, http.MethodPost, "/models/"+url.PathEscape(modelID)+"/_deploy", nil, nil, &task)
+
+	return task, err
+}
+
+// Undeploy unloads a model from the ML nodes.
+func Undeploy(ctx context.Context, modelID string) error {
+	return do(ctx, http.MethodPost, "/models/"+url.PathEscape(modelID)+"/_undeploy", nil, nil, nil)
+}
+
+// DeleteModel deletes a model, which must be undeployed.
+func DeleteModel(ctx context.Context, modelID string) error {
+	return do(ctx, http.MethodDelete, "/models/"+url.PathEscape(modelID), nil, nil, nil)
+}
+
+// ModelInfo is a registered model.
+type ModelInfo struct {
+	Name         string `json:"name"`
+	Version      string `json:"model_version"`
+	ModelGroupID string `json:"model_group_id"`
+	Algorithm    string `json:"algorithm"`
+	// State is "REGISTERED", "DEPLOYING", "DEPLOYED", "PARTIALLY_DEPLOYED",
+	// "UNDEPLOYED" or "DEPLOY_FAILED", among others.
+	State       string `json:"model_state"`
+	ConnectorID string `json:"connector_id"`
+}
+
+// GetModel returns a registered model.
+func GetModel(ctx context.Context, modelID string) (ModelInfo, error) {
+	var info ModelInfo
+
+	err := do(ctx, http.MethodGet, "/models/"+url.PathEscape(modelID), nil, nil, &info)
+
+	return info, err
+}
+
+// RegisterAndDeploy registers and deploys a model, waiting for both, and returns its
+// ID, e.g. to set up the embedding model of a neural search at startup.
+func RegisterAndDeploy(ctx context.Context, m Model) (string, error) {
+	task, err := Register(ctx, m, false)
+	if err != nil {
+		return "", err
+	}
+
+	task, err = WaitTask(ctx, task.ID, 0)
+	if err != nil {
+		return "", fmt.Errorf("registering model %s: %w", m.Name, err)
+	}
+
+	modelID := task.ModelID
+
+	task, err = Deploy(ctx, modelID)
+	if err != nil {
+		return modelID, err
+	}
+
+	// Deployments of models already deployed complete right away, without a task.
+	if task.ID != "" && !task.Done() {
+		_, err = WaitTask(ctx, task.ID, 0)
+		if err != nil {
+			return modelID, fmt.Errorf("deploying model %s: %w", modelID, err)
+		}
+	}
+
+	return modelID, nil
+}
+
+// Predict invokes a model with the parameters, e.g. {"inputs": "..."} for remote
+// models, and returns the raw inference results.
+func Predict(ctx context.Context, modelID string, parameters map[string]interface{}) (json.RawMessage, error) {
+	var result struct {
+		InferenceResults json.RawMessage `json:"inference_results"`
+	}
+
+	err := do(ctx, http.MethodPost, "/models/"+url.PathEscape(modelID)+"/_predict", nil, map[string]interface{}{"parameters": parameters}, &result)
+
+	return result.InferenceResults, err
+}
@@ -0,0 +1,45 @@
+package opensearch
+
+type MoreLikeThis struct {
+	Fields             []string      `json:"fields,omitempty"`
+	Like               []interface{} `json:"like"`
+	Unlike             []interface{} `json:"unlike,omitempty"`
+	MinTermFreq        int64         `json:"min_term_freq,omitempty"`
+	MaxQueryTerms      int64         `json:"max_query_terms,omitempty"`
+	MinDocFreq         int64         `json:"min_doc_freq,omitempty"`
+	MaxDocFreq         int64         `json:"max_doc_freq,omitempty"`
+	MinWordLength      int64         `json:"min_word_length,omitempty"`
+	MaxWordLength      int64         `json:"max_word_length,omitempty"`
+	StopWords          []string      `json:"stop_words,omitempty"`
+	Analyzer           string        `json:"analyzer,omitempty"`
+	MinimumShouldMatch string        `json:"minimum_should_match,omitempty"`
+	Include            bool          `json:"include,omitempty"`
+	BoostTerms         float64       `json:"boost_terms,omitempty"`
+}
+
+// LikeDoc references a stored document to take terms from in a more_like_this query,
+// or holds an artificial document in Doc.
+type LikeDoc struct {
+	Index string                 `json:"_index,omitempty"`
+	ID    string                 `json:"_id,omitempty"`
+	Doc   map[string]interface{} `json:"doc,omitempty"`
+}
+
+// MoreLikeThisQuery returns a query matching the documents textually similar to like,
+// whose items are texts or LikeDoc values, comparing the given fields. Terms appearing
+// less than minTermFreq times in the like items are ignored, and at most maxQueryTerms
+// terms are selected. Zero values keep the defaults of 2 and 25.
+func MoreLikeThisQuery(fields []string, like []interface{}, minTermFreq, maxQueryTerms int64) Query {
+	return Query{MoreLikeThis: &MoreLikeThis{
+		Fields:        fields,
+		Like:          like,
+		MinTermFreq:   minTermFreq,
+		MaxQueryTerms: maxQueryTerms,
+	}}
+}
+
+// SimilarTo returns a query matching the documents textually similar to the given
+// document, excluding it, e.g. to pivot from a suspicious event to related ones.
+func SimilarTo(index, id string, fields ...string) Query {
+	return MoreLikeThisQuery(fields, []interface{}{LikeDoc{Index: index, ID: id}}, 1, 0)
+}
@@ -0,0 +1,259 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexedSearch is a search request along with the indices it targets, for MultiSearch.
+type IndexedSearch struct {
+	Index   []string
+	Request SearchRequest
+}
+
+// MultiSearch runs several searches in a single _msearch request and returns their
+// results in the same order. Index pruning, index access control, tenant scopes and
+// the options of the results, like FailOnPartialResults and RetryWithoutFailedIndices,
+// are applied to each search as SearchIn does; retries are sent as single searches.
+// The _msearch request is bounded by the smallest TimeBudget of the searches, and
+// cancelled in the cluster when the context is done if any of them is Cancellable. If
+// any search fails, an error naming it is returned.
+func MultiSearch(ctx context.Context, searches []IndexedSearch) ([]SearchResult, error) {
+	results, err := multiSearch(ctx, searches)
+
+	for _, s := range searches {
+		recordAudit(ctx, "msearch", s.Index, "", s.Request.Query, s.Request.TenantID, err)
+	}
+
+	return results, err
+}
+
+func multiSearch(ctx context.Context, searches []IndexedSearch) ([]SearchResult, error) {
+	var body bytes.Buffer
+
+	enc := json.NewEncoder(&body)
+
+	var prepared = make([]SearchRequest, len(searches))
+	var indices = make([][]string, len(searches))
+
+	var budget time.Duration
+	var cancellable bool
+
+	for i, s := range searches {
+		q := s.Request
+
+		if q.Source == nil {
+			q.Source = new(Source)
+		}
+
+		index, err := q.prepare(ctx, s.Index)
+		if err != nil {
+			return nil, fmt.Errorf("search %d: %w", i, err)
+		}
+
+		prepared[i], indices[i] = q, index
+
+		if q.TimeBudget > 0 && (budget == 0 || q.TimeBudget < budget) {
+			budget = q.TimeBudget
+		}
+
+		cancellable = cancellable || q.Cancellable
+
+		var header = map[string]interface{}{}
+
+		if len(index) != 0 {
+			header["index"] = strings.Join(index, ",")
+		}
+
+		for k, v := range q.params() {
+			header[k] = v[0]
+		}
+
+		err = enc.Encode(header)
+		if err != nil {
+			return nil, err
+		}
+
+		err = enc.Encode(q)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if budget > 0 && budgetFrom(ctx) == nil {
+		var cancel context.CancelFunc
+
+		ctx, cancel = WithTimeBudget(ctx, RetryBudget{Budget: budget})
+		defer cancel()
+	}
+
+	resp, err := sendMultiSearch(ctx, body.Bytes(), cancellable)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Responses []struct {
+			SearchResult
+			Error  json.RawMessage `json:"error"`
+			Status int             `json:"status"`
+		} `json:"responses"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Responses) != len(searches) {
+		return nil, fmt.Errorf("expected %d search responses, got %d", len(searches), len(result.Responses))
+	}
+
+	var results = make([]SearchResult, len(searches))
+
+	for i, r := range result.Responses {
+		if r.Error != nil {
+			return nil, fmt.Errorf("search %d: %w", i, &StatusError{StatusCode: r.Status, Body: r.Error})
+		}
+
+		results[i], err = prepared[i].complete(ctx, indices[i], r.SearchResult)
+		if err != nil {
+			return nil, fmt.Errorf("search %d: %w", i, err)
+		}
+	}
+
+	return results, nil
+}
+
+func sendMultiSearch(ctx context.Context, body []byte, cancellable bool) ([]byte, error) {
+	ctx, stop := cancelOnDone(ctx, cancellable)
+	defer stop()
+
+	return do(ctx, http.MethodPost, "/_msearch", nil, body)
+}
+
+// complete applies the options of the prepared request to the result of its search in
+// index, as SearchIn does: the retry without the failed indices, the deduplication,
+// the transformation of the hits and the partial results check.
+func (q SearchRequest) complete(ctx context.Context, index []string, result SearchResult) (SearchResult, error) {
+	result.page = q.pageInfo(result)
+
+	if q.RetryWithoutFailedIndices && len(result.Shards.Failures) != 0 {
+		failed := failedIndices(result.Shards.Failures)
+
+		if reduced := excludeIndices(index, failed); len(failed) != 0 && len(reduced) != 0 {
+			retried, err := q.search(ctx, reduced)
+			if err == nil {
+				result = retried
+				result.SkippedIndices = failed
+			}
+		}
+	}
+
+	if q.DedupeBy != "" {
+		result.Dedupe(q.DedupeBy)
+	}
+
+	err := result.transformHits()
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	if q.FailOnPartialResults && result.Partial() {
+		return result, &PartialResultsError{
+			TimedOut: result.TimedOut,
+			Failures: result.Shards.Failures,
+		}
+	}
+
+	return result, nil
+}
+
+// FuseRRF merges ranked result lists with Reciprocal Rank Fusion: each hit scores the
+// sum of 1/(k+rank) over the lists containing it, rank starting at 1. Hits are matched
+// by index and ID, and returned by descending fused score, which is set as their
+// Score. k dampens the weight of the top ranks, 60 when zero as commonly used.
+func FuseRRF(k int, results ...SearchResult) []Hit {
+	if k <= 0 {
+		k = 60
+	}
+
+	type fused struct {
+		hit   Hit
+		score float64
+		first int
+	}
+
+	var byKey = make(map[string]*fused)
+	var order int
+
+	for _, r := range results {
+		for rank, hit := range r.Hits.Hits {
+			key := hit.Index + "/" + hit.ID
+
+			f, ok := byKey[key]
+			if !ok {
+				f = &fused{hit: hit, first: order}
+				byKey[key] = f
+				order++
+			}
+
+			f.score += 1 / float64(k+rank+1)
+		}
+	}
+
+	var all = make([]*fused, 0, len(byKey))
+	for _, f := range byKey {
+		all = append(all, f)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].score != all[j].score {
+			return all[i].score > all[j].score
+		}
+		return all[i].first < all[j].first
+	})
+
+	var hits = make([]Hit, len(all))
+	for i, f := range all {
+		hits[i] = f.hit
+		hits[i].Score = f.score
+	}
+
+	return hits
+}
+
+// MultiKNN searches the k nearest neighbors of each vector in field with a single
+// _msearch request and returns the top k hits after fusing the lists with FuseRRF.
+// This matches documents similar to several aspects at once, e.g. the embeddings of
+// the different sections of a threat report.
+func MultiKNN(ctx context.Context, index []string, field string, vectors [][]float32, k int, filter *Query) ([]Hit, error) {
+	var searches = make([]IndexedSearch, len(vectors))
+
+	for i, v := range vectors {
+		query := KNNQuery(field, v, k, filter)
+
+		searches[i] = IndexedSearch{
+			Index:   index,
+			Request: SearchRequest{Size: int64(k), Query: &query},
+		}
+	}
+
+	results, err := MultiSearch(ctx, searches)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := FuseRRF(0, results...)
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+
+	return hits, nil
+}
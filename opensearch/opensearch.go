@@ -2,10 +2,12 @@ package opensearch
 
 import (
 	"crypto/tls"
+	"errors"
 	"net/http"
 	"sync"
 
 	osgo "github.com/opensearch-project/opensearch-go/v2"
+	"github.com/threatwinds/go-sdk/helpers"
 )
 
 var (
@@ -15,15 +17,58 @@ var (
 
 var once = sync.Once{}
 
+// ErrAlreadyConnected is returned by ConnectWithOptions when the process is already
+// connected, its options then being ignored.
+var ErrAlreadyConnected = errors.New("already connected to the search engine")
+
 func Connect(nodes []string) error {
-	once.Do(func() {
-		client, err = osgo.NewClient(osgo.Config{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-			Addresses: nodes,
-		})
+	return ConnectWithConfig(osgo.Config{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		Addresses: nodes,
 	})
+}
+
+// ConnectWithConfig connects using a custom client configuration, e.g. to set
+// credentials or to wrap the HTTP transport. As with Connect, only the first
+// call in the process takes effect. Requests whose context carries a request ID,
+// set with helpers.WithRequestID, are sent with it as X-Opaque-Id header.
+func ConnectWithConfig(cfg osgo.Config) error {
+	_, err := connect(cfg)
 
 	return err
 }
+
+// connect creates the client on the first call, and reports whether this call did.
+func connect(cfg osgo.Config) (bool, error) {
+	var created bool
+
+	once.Do(func() {
+		cfg.Transport = requestIDTransport{next: cfg.Transport}
+		client, err = osgo.NewClient(cfg)
+		created = true
+	})
+
+	return created, err
+}
+
+// requestIDTransport sets the X-Opaque-Id header from the request ID of the request
+// context, so that slow logs and tasks of the cluster can be traced to the request.
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if id := helpers.RequestIDFromContext(req.Context()); id != "" && req.Header.Get("X-Opaque-Id") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Opaque-Id", id)
+	}
+
+	return next.RoundTrip(req)
+}
@@ -0,0 +1,25 @@
+package opensearch
+
+// DocOption customizes single-document requests like IndexDoc, UpsertDoc and GetDoc.
+type DocOption func(*docOptions)
+
+type docOptions struct {
+	routing string
+}
+
+// Routing routes the request to the shard of the given routing value instead of the
+// one derived from the document ID, as required by custom-routed indices.
+func Routing(value string) DocOption {
+	return func(o *docOptions) {
+		o.routing = value
+	}
+}
+
+func newDocOptions(opts []DocOption) docOptions {
+	var o docOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
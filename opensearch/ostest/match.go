@@ -0,0 +1,479 @@
+package ostest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// matches reports whether hit satisfies q. Only bool, constant_score, dis_max, term,
+// terms, ids, range, exists, prefix and wildcard clauses are supported; any other
+// clause is an error so tests never pass by silently ignoring part of a query.
+func matches(q opensearch.Query, hit opensearch.Hit) (bool, error) {
+	var clauses []func() (bool, error)
+
+	if q.Bool != nil {
+		clauses = append(clauses, func() (bool, error) { return matchBool(*q.Bool, hit) })
+	}
+
+	if q.ConstantScore != nil {
+		clauses = append(clauses, func() (bool, error) { return matches(q.ConstantScore.Filter, hit) })
+	}
+
+	if q.DisMax != nil {
+		clauses = append(clauses, func() (bool, error) { return matchAny(q.DisMax.Queries, hit) })
+	}
+
+	for field, params := range q.Term {
+		field, params := field, params
+		clauses = append(clauses, func() (bool, error) {
+			return anyValue(hit.Source, field, func(v interface{}) bool {
+				if caseInsensitive(params) {
+					return strings.EqualFold(fmt.Sprint(v), fmt.Sprint(params["value"]))
+				}
+				return equal(v, params["value"])
+			}), nil
+		})
+	}
+
+	for field, values := range q.Terms {
+		field, values := field, values
+		clauses = append(clauses, func() (bool, error) {
+			return anyValue(hit.Source, field, func(v interface{}) bool {
+				for _, value := range values {
+					if equal(v, value) {
+						return true
+					}
+				}
+				return false
+			}), nil
+		})
+	}
+
+	for _, ids := range q.IDs {
+		ids := ids
+		clauses = append(clauses, func() (bool, error) {
+			for _, id := range ids {
+				if fmt.Sprint(id) == hit.ID {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+	}
+
+	for field, bounds := range q.Range {
+		field, bounds := field, bounds
+		clauses = append(clauses, func() (bool, error) {
+			return anyValue(hit.Source, field, func(v interface{}) bool {
+				return inRange(v, bounds)
+			}), nil
+		})
+	}
+
+	if field, ok := q.Exists["field"]; ok {
+		clauses = append(clauses, func() (bool, error) {
+			return anyValue(hit.Source, field, func(v interface{}) bool { return v != nil }), nil
+		})
+	}
+
+	for field, prefix := range q.Prefix {
+		field, prefix := field, prefix
+		clauses = append(clauses, func() (bool, error) {
+			return anyValue(hit.Source, field, func(v interface{}) bool {
+				return strings.HasPrefix(fmt.Sprint(v), prefix)
+			}), nil
+		})
+	}
+
+	for field, params := range q.Wildcard {
+		field, pattern, fold := field, fmt.Sprint(params["value"]), caseInsensitive(params)
+		clauses = append(clauses, func() (bool, error) {
+			return anyValue(hit.Source, field, func(v interface{}) bool {
+				if fold {
+					ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(fmt.Sprint(v)))
+					return ok
+				}
+				ok, _ := path.Match(pattern, fmt.Sprint(v))
+				return ok
+			}), nil
+		})
+	}
+
+	if unsupported := unsupportedClauses(q); len(unsupported) != 0 {
+		return false, fmt.Errorf("ostest does not support %s queries", strings.Join(unsupported, ", "))
+	}
+
+	for _, clause := range clauses {
+		ok, err := clause()
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func caseInsensitive(params map[string]interface{}) bool {
+	b, _ := params["case_insensitive"].(bool)
+	return b
+}
+
+// matchesAll reports whether hit satisfies every non-nil query.
+func matchesAll(hit opensearch.Hit, queries ...*opensearch.Query) (bool, error) {
+	for _, q := range queries {
+		if q == nil {
+			continue
+		}
+
+		ok, err := matches(*q, hit)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchAny reports whether hit satisfies any of the queries.
+func matchAny(queries []opensearch.Query, hit opensearch.Hit) (bool, error) {
+	for _, q := range queries {
+		ok, err := matches(q, hit)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+func matchBool(b opensearch.Bool, hit opensearch.Hit) (bool, error) {
+	for _, list := range [][]opensearch.Query{b.Must, b.Filter} {
+		for _, q := range list {
+			ok, err := matches(q, hit)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+
+	for _, q := range b.MustNot {
+		ok, err := matches(q, hit)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+
+	if len(b.Should) == 0 {
+		return true, nil
+	}
+
+	minimum := 0
+	if b.MinimumShouldMatch != nil {
+		minimum, _ = strconv.Atoi(fmt.Sprint(b.MinimumShouldMatch))
+	} else if len(b.Must) == 0 && len(b.Filter) == 0 {
+		minimum = 1
+	}
+
+	matched := 0
+	for _, q := range b.Should {
+		ok, err := matches(q, hit)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched++
+		}
+	}
+
+	return matched >= minimum, nil
+}
+
+var supportedClauses = map[string]bool{
+	"bool":           true,
+	"term":           true,
+	"terms":          true,
+	"ids":            true,
+	"range":          true,
+	"exists":         true,
+	"prefix":         true,
+	"wildcard":       true,
+	"constant_score": true,
+	"dis_max":        true,
+}
+
+// unsupportedClauses returns the JSON names of the non-empty clauses of q
+// that the matcher cannot evaluate.
+func unsupportedClauses(q opensearch.Query) []string {
+	var names []string
+
+	v := reflect.ValueOf(q)
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if supportedClauses[name] || v.Field(i).IsZero() {
+			continue
+		}
+
+		if f := v.Field(i); f.Kind() == reflect.Map && f.Len() == 0 {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// values returns the values found at the dot-notation field of src. A trailing
+// ".keyword" is ignored when the exact field is not present.
+func values(src opensearch.HitSource, field string) []interface{} {
+	if v, ok := src[field]; ok {
+		return flatten(v)
+	}
+
+	var current []interface{} = []interface{}{map[string]interface{}(src)}
+
+	for _, part := range strings.Split(field, ".") {
+		var next []interface{}
+		for _, c := range current {
+			if m, ok := c.(map[string]interface{}); ok {
+				if v, ok := m[part]; ok {
+					next = append(next, flatten(v)...)
+				}
+			}
+		}
+		current = next
+	}
+
+	if len(current) == 0 && strings.HasSuffix(field, ".keyword") {
+		return values(src, strings.TrimSuffix(field, ".keyword"))
+	}
+
+	return current
+}
+
+func flatten(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		var out []interface{}
+		for _, item := range list {
+			out = append(out, flatten(item)...)
+		}
+		return out
+	}
+
+	return []interface{}{v}
+}
+
+func anyValue(src opensearch.HitSource, field string, fn func(interface{}) bool) bool {
+	for _, v := range values(src, field) {
+		if fn(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	af, aok := number(a)
+	bf, bok := number(b)
+	if aok && bok {
+		return af == bf
+	}
+
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// compare returns -1, 0 or 1 comparing a and b as numbers, RFC 3339 dates or strings.
+func compare(a, b interface{}) int {
+	if af, ok := number(a); ok {
+		if bf, ok := number(b); ok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	as, bs := fmt.Sprint(a), fmt.Sprint(b)
+
+	if at, err := time.Parse(time.RFC3339Nano, as); err == nil {
+		if bt, err := time.Parse(time.RFC3339Nano, bs); err == nil {
+			return at.Compare(bt)
+		}
+	}
+
+	return strings.Compare(as, bs)
+}
+
+func inRange(v interface{}, bounds map[string]interface{}) bool {
+	for op, bound := range bounds {
+		c := compare(v, bound)
+
+		switch op {
+		case "gt":
+			if c <= 0 {
+				return false
+			}
+		case "gte":
+			if c < 0 {
+				return false
+			}
+		case "lt":
+			if c >= 0 {
+				return false
+			}
+		case "lte":
+			if c > 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// sortKey is a field sort of a search request.
+type sortKey struct {
+	field        string
+	desc         bool
+	missingFirst bool
+}
+
+// sortHits orders hits by the given field sorts, filling their sort values, and drops
+// the hits not following after when it is set. Documents missing a sort field go last
+// unless the sort sets "missing" to "_first".
+func sortHits(hits []opensearch.Hit, sorts []map[string]map[string]interface{}, after opensearch.SortValues) ([]opensearch.Hit, error) {
+	var keys []sortKey
+
+	for _, s := range sorts {
+		for field, params := range s {
+			if strings.HasPrefix(field, "_") && field != "_id" {
+				return nil, fmt.Errorf("ostest does not support sorting by %s", field)
+			}
+
+			keys = append(keys, sortKey{
+				field:        field,
+				desc:         params["order"] == "desc",
+				missingFirst: params["missing"] == "_first",
+			})
+		}
+	}
+
+	if len(keys) == 0 {
+		if len(after) != 0 {
+			return nil, fmt.Errorf("search_after requires a sort")
+		}
+
+		sort.SliceStable(hits, func(i, j int) bool {
+			if hits[i].Index != hits[j].Index {
+				return hits[i].Index < hits[j].Index
+			}
+			return hits[i].ID < hits[j].ID
+		})
+		return hits, nil
+	}
+
+	if len(after) != 0 && len(after) != len(keys) {
+		return nil, fmt.Errorf("search_after has %d values but the sort has %d fields", len(after), len(keys))
+	}
+
+	for i := range hits {
+		hits[i].SortValues = make(opensearch.SortValues, len(keys))
+
+		for k, key := range keys {
+			if key.field == "_id" {
+				hits[i].SortValues[k] = hits[i].ID
+				continue
+			}
+
+			if vs := values(hits[i].Source, key.field); len(vs) != 0 {
+				hits[i].SortValues[k] = vs[0]
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return compareSort(keys, hits[i].SortValues, hits[j].SortValues) < 0
+	})
+
+	if len(after) == 0 {
+		return hits, nil
+	}
+
+	var following = make([]opensearch.Hit, 0, len(hits))
+	for _, hit := range hits {
+		if compareSort(keys, hit.SortValues, after) > 0 {
+			following = append(following, hit)
+		}
+	}
+
+	return following, nil
+}
+
+// compareSort compares two lists of sort values in the order defined by keys.
+func compareSort(keys []sortKey, a, b opensearch.SortValues) int {
+	for k, key := range keys {
+		av, bv := a[k], b[k]
+
+		switch {
+		case av == nil && bv == nil:
+			continue
+		case av == nil:
+			if key.missingFirst {
+				return -1
+			}
+			return 1
+		case bv == nil:
+			if key.missingFirst {
+				return 1
+			}
+			return -1
+		}
+
+		c := compare(av, bv)
+		if c == 0 {
+			continue
+		}
+
+		if key.desc {
+			return -c
+		}
+
+		return c
+	}
+
+	return 0
+}
@@ -0,0 +1,154 @@
+package ostest
+
+import (
+	"testing"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+func TestMatches(t *testing.T) {
+	hit := opensearch.Hit{
+		Index: "logs",
+		ID:    "1",
+		Source: opensearch.HitSource{
+			"host":       map[string]interface{}{"name": "web-01", "ip": "10.0.0.1"},
+			"user":       "Alice",
+			"tags":       []interface{}{"prod", "eu"},
+			"bytes":      float64(512),
+			"@timestamp": "2024-05-01T10:00:00Z",
+			"empty":      nil,
+		},
+	}
+
+	term := func(field string, value interface{}) opensearch.Query {
+		return opensearch.Query{Term: map[string]map[string]interface{}{field: {"value": value}}}
+	}
+
+	tests := []struct {
+		name    string
+		query   opensearch.Query
+		want    bool
+		wantErr bool
+	}{
+		{name: "term on nested field", query: term("host.name", "web-01"), want: true},
+		{name: "term on dotted keyword", query: term("host.name.keyword", "web-01"), want: true},
+		{name: "term mismatch", query: term("host.name", "web-02")},
+		{name: "term is case sensitive", query: term("user", "alice")},
+		{name: "term case insensitive", query: opensearch.TermQuery("user", "alice", true), want: true},
+		{name: "term numeric string", query: term("bytes", "512"), want: true},
+		{name: "term on array element", query: term("tags", "eu"), want: true},
+		{name: "terms", query: opensearch.Query{Terms: map[string][]interface{}{"tags": {"us", "prod"}}}, want: true},
+		{name: "terms none", query: opensearch.Query{Terms: map[string][]interface{}{"tags": {"us", "asia"}}}},
+		{name: "ids", query: opensearch.Query{IDs: map[string][]interface{}{"values": {"2", "1"}}}, want: true},
+		{name: "ids mismatch", query: opensearch.Query{IDs: map[string][]interface{}{"values": {"2"}}}},
+		{name: "numeric range", query: opensearch.Query{Range: map[string]map[string]interface{}{"bytes": {"gte": 512, "lt": 1024}}}, want: true},
+		{name: "numeric range excluded bound", query: opensearch.Query{Range: map[string]map[string]interface{}{"bytes": {"gt": 512}}}},
+		{name: "date range", query: opensearch.Query{Range: map[string]map[string]interface{}{"@timestamp": {"gte": "2024-05-01T09:00:00+00:00", "lte": "2024-05-01T10:00:00Z"}}}, want: true},
+		{name: "exists", query: opensearch.Query{Exists: map[string]string{"field": "host.ip"}}, want: true},
+		{name: "exists null", query: opensearch.Query{Exists: map[string]string{"field": "empty"}}},
+		{name: "exists missing", query: opensearch.Query{Exists: map[string]string{"field": "process"}}},
+		{name: "prefix", query: opensearch.PrefixQuery("host.name", "web", false), want: true},
+		{name: "prefix mismatch", query: opensearch.PrefixQuery("host.name", "db", false)},
+		{name: "prefix case insensitive", query: opensearch.PrefixQuery("user", "ali", true), want: true},
+		{name: "wildcard", query: opensearch.WildcardQuery("host.name", "web-?1", false), want: true},
+		{name: "wildcard case insensitive", query: opensearch.WildcardQuery("user", "A*E", true), want: true},
+		{name: "wildcard is case sensitive", query: opensearch.WildcardQuery("user", "a*", false)},
+		{name: "bool must and must_not", query: opensearch.Query{Bool: &opensearch.Bool{
+			Must:    []opensearch.Query{term("user", "Alice")},
+			MustNot: []opensearch.Query{term("tags", "dev")},
+		}}, want: true},
+		{name: "bool must_not excludes", query: opensearch.Query{Bool: &opensearch.Bool{
+			MustNot: []opensearch.Query{term("tags", "prod")},
+		}}},
+		{name: "bool should alone needs one", query: opensearch.Query{Bool: &opensearch.Bool{
+			Should: []opensearch.Query{term("user", "Bob"), term("user", "Carol")},
+		}}},
+		{name: "bool should optional with filter", query: opensearch.Query{Bool: &opensearch.Bool{
+			Filter: []opensearch.Query{term("user", "Alice")},
+			Should: []opensearch.Query{term("user", "Bob")},
+		}}, want: true},
+		{name: "bool minimum_should_match", query: opensearch.Query{Bool: &opensearch.Bool{
+			Should:             []opensearch.Query{term("user", "Alice"), term("tags", "prod"), term("tags", "us")},
+			MinimumShouldMatch: 2,
+		}}, want: true},
+		{name: "bool minimum_should_match unmet", query: opensearch.Query{Bool: &opensearch.Bool{
+			Should:             []opensearch.Query{term("user", "Alice"), term("tags", "dev"), term("tags", "us")},
+			MinimumShouldMatch: "2",
+		}}},
+		{name: "constant_score", query: opensearch.ConstantScoreQuery(term("user", "Alice"), 0), want: true},
+		{name: "dis_max", query: opensearch.Query{DisMax: &opensearch.DisMax{
+			Queries: []opensearch.Query{term("user", "Bob"), term("tags", "eu")},
+		}}, want: true},
+		{name: "empty query matches all", query: opensearch.Query{}, want: true},
+		{name: "unsupported clause", query: opensearch.Query{Match: map[string]opensearch.Match{"user": {Query: "alice"}}}, wantErr: true},
+		{name: "unsupported nested clause", query: opensearch.Query{Bool: &opensearch.Bool{
+			Filter: []opensearch.Query{{Regexp: map[string]string{"user": "A.*"}}},
+		}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matches(tt.query, hit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("matches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortHits(t *testing.T) {
+	hits := func() []opensearch.Hit {
+		return []opensearch.Hit{
+			{Index: "a", ID: "3", Source: opensearch.HitSource{"n": float64(2)}},
+			{Index: "a", ID: "1", Source: opensearch.HitSource{"n": float64(3)}},
+			{Index: "a", ID: "2", Source: opensearch.HitSource{}},
+			{Index: "a", ID: "4", Source: opensearch.HitSource{"n": float64(1)}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		sort    []map[string]map[string]interface{}
+		after   opensearch.SortValues
+		want    []string
+		wantErr bool
+	}{
+		{name: "no sort orders by id", want: []string{"1", "2", "3", "4"}},
+		{name: "ascending, missing last", sort: []map[string]map[string]interface{}{{"n": {"order": "asc"}}}, want: []string{"4", "3", "1", "2"}},
+		{name: "descending, missing last", sort: []map[string]map[string]interface{}{{"n": {"order": "desc"}}}, want: []string{"1", "3", "4", "2"}},
+		{name: "missing first", sort: []map[string]map[string]interface{}{{"n": {"order": "asc", "missing": "_first"}}}, want: []string{"2", "4", "3", "1"}},
+		{name: "search after", sort: []map[string]map[string]interface{}{{"n": {"order": "asc"}}}, after: opensearch.SortValues{float64(2)}, want: []string{"1", "2"}},
+		{name: "search after by id", sort: []map[string]map[string]interface{}{{"_id": {"order": "desc"}}}, after: opensearch.SortValues{"3"}, want: []string{"2", "1"}},
+		{name: "search after without sort", after: opensearch.SortValues{"1"}, wantErr: true},
+		{name: "search after of another length", sort: []map[string]map[string]interface{}{{"n": {}}}, after: opensearch.SortValues{1, 2}, wantErr: true},
+		{name: "unsupported metadata sort", sort: []map[string]map[string]interface{}{{"_score": {}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted, err := sortHits(hits(), tt.sort, tt.after)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortHits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var ids []string
+			for _, hit := range sorted {
+				ids = append(ids, hit.ID)
+			}
+
+			if len(ids) != len(tt.want) {
+				t.Fatalf("sortHits() = %v, want %v", ids, tt.want)
+			}
+
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("sortHits() = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
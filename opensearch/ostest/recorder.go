@@ -0,0 +1,204 @@
+package ostest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mode selects how a Recorder handles requests.
+type Mode int
+
+const (
+	// Replay serves responses from the fixtures directory and fails on unknown requests.
+	Replay Mode = iota
+	// Record forwards requests to the cluster and saves every interaction as a fixture.
+	Record
+)
+
+// ModeFromEnv returns Record if the named environment variable is "record" and
+// Replay otherwise, so CI replays fixtures unless they are explicitly refreshed.
+func ModeFromEnv(name string) Mode {
+	if strings.EqualFold(os.Getenv(name), "record") {
+		return Record
+	}
+
+	return Replay
+}
+
+// Recorder is an http.RoundTripper that records OpenSearch request/response pairs
+// to JSON fixtures and replays them deterministically. Use it as the transport of
+// opensearch.ConnectWithConfig, where osgo is github.com/opensearch-project/opensearch-go/v2:
+//
+//	rec := ostest.NewRecorder("testdata/fixtures", ostest.ModeFromEnv("OSTEST_MODE"), nil)
+//	err := opensearch.ConnectWithConfig(osgo.Config{Addresses: nodes, Transport: rec})
+//
+// Requests are identified by method, path, query string and body (JSON bodies are
+// compared canonically). Identical requests issued several times are numbered in
+// order, so a search repeated after an indexing call replays its second response.
+type Recorder struct {
+	dir       string
+	mode      Mode
+	transport http.RoundTripper
+
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+type fixture struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+	} `json:"response"`
+}
+
+// NewRecorder returns a Recorder storing fixtures in dir. In Record mode requests
+// are sent through transport, or http.DefaultTransport if it is nil.
+func NewRecorder(dir string, mode Mode, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{
+		dir:       dir,
+		mode:      mode,
+		transport: transport,
+		seen:      make(map[string]int),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+
+	if req.Body != nil {
+		var err error
+
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	file := filepath.Join(r.dir, r.name(req, body))
+
+	if r.mode == Record {
+		return r.record(req, body, file)
+	}
+
+	return r.replay(req, file)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte, file string) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var f fixture
+	f.Request.Method = req.Method
+	f.Request.URL = req.URL.RequestURI()
+	f.Request.Body = string(body)
+	f.Response.StatusCode = resp.StatusCode
+	f.Response.Header = resp.Header
+	f.Response.Body = string(respBody)
+
+	j, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(r.dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(file, j, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, file string) (*http.Response, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture recorded for %s %s: %s", req.Method, req.URL.RequestURI(), err.Error())
+	}
+
+	var f fixture
+
+	err = json.Unmarshal(content, &f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %s", file, err.Error())
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Response.Header,
+		Body:          io.NopCloser(strings.NewReader(f.Response.Body)),
+		ContentLength: int64(len(f.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// name returns the fixture file name of the next occurrence of the request.
+func (r *Recorder) name(req *http.Request, body []byte) string {
+	var canonical interface{}
+	if json.Unmarshal(body, &canonical) == nil {
+		if j, err := json.Marshal(canonical); err == nil {
+			body = j
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "?" + req.URL.Query().Encode() + "\n"))
+	h.Write(body)
+	key := hex.EncodeToString(h.Sum(nil))[:12]
+
+	r.mu.Lock()
+	r.seen[key]++
+	n := r.seen[key]
+	r.mu.Unlock()
+
+	p := strings.Trim(unsafeChars.ReplaceAllString(req.URL.Path, "_"), "_")
+	if len(p) > 60 {
+		p = p[:60]
+	}
+
+	return fmt.Sprintf("%s_%s_%s_%03d.json", req.Method, p, key, n)
+}
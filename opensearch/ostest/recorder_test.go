@@ -0,0 +1,151 @@
+package ostest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+
+	var calls int
+
+	cluster := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+
+		var body []byte
+		if req.Body != nil {
+			body, _ = io.ReadAll(req.Body)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"call":%d,"body":%q}`, calls, body))),
+		}, nil
+	})
+
+	requests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		want   string
+	}{
+		{name: "first search", method: http.MethodPost, url: "http://node/logs/_search?size=1", body: `{"query": {"match_all": {}}}`, want: `{"call":1,"body":"{\"query\": {\"match_all\": {}}}"}`},
+		{name: "same search, reformatted", method: http.MethodPost, url: "http://node/logs/_search?size=1", body: `{"query":{"match_all":{}}}`, want: `{"call":2,"body":"{\"query\":{\"match_all\":{}}}"}`},
+		{name: "other parameters", method: http.MethodPost, url: "http://node/logs/_search?size=2", body: `{"query":{"match_all":{}}}`, want: `{"call":3,"body":"{\"query\":{\"match_all\":{}}}"}`},
+		{name: "no body", method: http.MethodGet, url: "http://node/_cluster/health", want: `{"call":4,"body":""}`},
+	}
+
+	send := func(t *testing.T, rec *Recorder, method, url, body string) (string, error) {
+		t.Helper()
+
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+
+		req, err := http.NewRequest(method, url, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := rec.RoundTrip(req)
+		if err != nil {
+			return "", err
+		}
+
+		defer resp.Body.Close()
+
+		got, err := io.ReadAll(resp.Body)
+
+		return string(got), err
+	}
+
+	record := NewRecorder(dir, Record, cluster)
+
+	for _, tt := range requests {
+		t.Run("record "+tt.name, func(t *testing.T) {
+			got, err := send(t, record, tt.method, tt.url, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("recorded response = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != len(requests) {
+		t.Fatalf("%d fixtures recorded, want %d", len(files), len(requests))
+	}
+
+	replay := NewRecorder(dir, Replay, roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Fatal("replay sent a request to the cluster")
+		return nil, nil
+	}))
+
+	for _, tt := range requests {
+		t.Run("replay "+tt.name, func(t *testing.T) {
+			got, err := send(t, replay, tt.method, tt.url, tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("replayed response = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("replay unknown request", func(t *testing.T) {
+		_, err := send(t, replay, http.MethodDelete, "http://node/logs", "")
+		if err == nil {
+			t.Fatal("replaying an unrecorded request succeeded")
+		}
+	})
+
+	t.Run("replay beyond the recorded occurrences", func(t *testing.T) {
+		_, err := send(t, replay, http.MethodGet, "http://node/_cluster/health", "")
+		if err == nil {
+			t.Fatal("replaying a request more times than recorded succeeded")
+		}
+	})
+}
+
+func TestModeFromEnv(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Mode
+	}{
+		{value: "record", want: Record},
+		{value: "RECORD", want: Record},
+		{value: "replay", want: Replay},
+		{value: "", want: Replay},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("OSTEST_MODE", tt.value)
+
+		if got := ModeFromEnv("OSTEST_MODE"); got != tt.want {
+			t.Errorf("ModeFromEnv() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
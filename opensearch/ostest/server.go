@@ -0,0 +1,493 @@
+// Package ostest provides an in-memory fake of the OpenSearch REST API covering the
+// search, multi-search, index, update, get and delete calls made by the opensearch
+// package, so query logic can be unit tested without running a cluster.
+//
+// The opensearch package keeps a single connection per process, so tests should share
+// one Server, usually created in TestMain, and call Reset between tests:
+//
+//	func TestMain(m *testing.M) {
+//		srv = ostest.NewServer()
+//		if err := srv.Connect(); err != nil {
+//			panic(err)
+//		}
+//		code := m.Run()
+//		srv.Close()
+//		os.Exit(code)
+//	}
+package ostest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Server is an in-memory fake OpenSearch node.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.RWMutex
+	indices map[string]map[string]*document
+}
+
+type document struct {
+	source  opensearch.HitSource
+	version int64
+}
+
+// NewServer starts a new fake node. The caller must call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		indices: make(map[string]map[string]*document),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// Connect connects the opensearch package to the fake node.
+func (s *Server) Connect() error {
+	return opensearch.Connect([]string{s.URL})
+}
+
+// Reset removes every index and document.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.indices = make(map[string]map[string]*document)
+}
+
+// Put stores doc in index with the given ID, replacing any existing document.
+func (s *Server) Put(index, id string, doc interface{}) error {
+	var src opensearch.HitSource
+
+	err := src.SetSource(doc)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store(index, id, src)
+
+	return nil
+}
+
+// Get returns the source of a stored document.
+func (s *Server) Get(index, id string) (opensearch.HitSource, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.indices[index][id]
+	if !ok {
+		return nil, false
+	}
+
+	return doc.source, true
+}
+
+// Count returns the number of documents stored in index.
+func (s *Server) Count(index string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.indices[index])
+}
+
+// store saves src and returns the new document version. The caller must hold s.mu.
+func (s *Server) store(index, id string, src opensearch.HitSource) int64 {
+	docs, ok := s.indices[index]
+	if !ok {
+		docs = make(map[string]*document)
+		s.indices[index] = docs
+	}
+
+	var version int64 = 1
+	if doc, ok := docs[id]; ok {
+		version = doc.version + 1
+	}
+
+	docs[id] = &document{source: src, version: version}
+
+	return version
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "_search":
+		s.search(w, []string{"*"}, body)
+	case len(parts) == 1 && parts[0] == "_msearch":
+		s.msearch(w, body)
+	case len(parts) == 2 && parts[1] == "_search":
+		s.search(w, strings.Split(parts[0], ","), body)
+	case len(parts) == 2 && parts[1] == "_doc" && r.Method == http.MethodPost:
+		s.index(w, parts[0], uuid.NewString(), body, false)
+	case len(parts) == 3 && parts[1] == "_create":
+		s.index(w, parts[0], parts[2], body, true)
+	case len(parts) == 3 && parts[1] == "_update" && r.Method == http.MethodPost:
+		s.update(w, parts[0], parts[2], body)
+	case len(parts) == 3 && parts[1] == "_doc":
+		switch r.Method {
+		case http.MethodGet:
+			s.get(w, parts[0], parts[2])
+		case http.MethodDelete:
+			s.delete(w, parts[0], parts[2])
+		case http.MethodPut, http.MethodPost:
+			s.index(w, parts[0], parts[2], body, r.URL.Query().Get("op_type") == "create")
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "illegal_argument_exception", "method not allowed")
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "illegal_argument_exception",
+			fmt.Sprintf("ostest does not support %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) index(w http.ResponseWriter, index, id string, body []byte, create bool) {
+	var src opensearch.HitSource
+
+	err := json.Unmarshal(body, &src)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "mapper_parsing_exception", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.indices[index][id]; exists && create {
+		writeError(w, http.StatusConflict, "version_conflict_engine_exception",
+			fmt.Sprintf("[%s]: version conflict, document already exists", id))
+		return
+	}
+
+	version := s.store(index, id, src)
+
+	result, status := "created", http.StatusCreated
+	if version > 1 {
+		result, status = "updated", http.StatusOK
+	}
+
+	writeJSON(w, status, map[string]interface{}{
+		"_index":   index,
+		"_id":      id,
+		"_version": version,
+		"result":   result,
+	})
+}
+
+func (s *Server) update(w http.ResponseWriter, index, id string, body []byte) {
+	var req struct {
+		Doc         map[string]interface{} `json:"doc"`
+		DocAsUpsert bool                   `json:"doc_as_upsert"`
+		Upsert      map[string]interface{} `json:"upsert"`
+	}
+
+	err := json.Unmarshal(body, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var src opensearch.HitSource
+
+	doc, exists := s.indices[index][id]
+
+	switch {
+	case exists:
+		src = merge(copyMap(doc.source), req.Doc)
+	case req.DocAsUpsert:
+		src = req.Doc
+	case req.Upsert != nil:
+		src = req.Upsert
+	default:
+		writeError(w, http.StatusNotFound, "document_missing_exception",
+			fmt.Sprintf("[%s]: document missing", id))
+		return
+	}
+
+	version := s.store(index, id, src)
+
+	result := "updated"
+	if !exists {
+		result = "created"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_index":   index,
+		"_id":      id,
+		"_version": version,
+		"result":   result,
+	})
+}
+
+func (s *Server) get(w http.ResponseWriter, index, id string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.indices[index][id]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"_index": index,
+			"_id":    id,
+			"found":  false,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, opensearch.Hit{
+		Index:   index,
+		ID:      id,
+		Version: doc.version,
+		Source:  doc.source,
+		Found:   true,
+	})
+}
+
+func (s *Server) delete(w http.ResponseWriter, index, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.indices[index][id]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"_index": index,
+			"_id":    id,
+			"result": "not_found",
+		})
+		return
+	}
+
+	delete(s.indices[index], id)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_index":   index,
+		"_id":      id,
+		"_version": doc.version + 1,
+		"result":   "deleted",
+	})
+}
+
+func (s *Server) search(w http.ResponseWriter, patterns []string, body []byte) {
+	result, err := s.runSearch(patterns, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.kind, err.reason)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// msearch runs the header and body pairs of a _msearch request.
+func (s *Server) msearch(w http.ResponseWriter, body []byte) {
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines)%2 != 0 {
+		writeError(w, http.StatusBadRequest, "parse_exception", "msearch body must hold header and body pairs")
+		return
+	}
+
+	var responses = make([]interface{}, 0, len(lines)/2)
+
+	for i := 0; i < len(lines); i += 2 {
+		var header struct {
+			Index string `json:"index"`
+		}
+
+		err := json.Unmarshal([]byte(lines[i]), &header)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "parse_exception", err.Error())
+			return
+		}
+
+		patterns := []string{"*"}
+		if header.Index != "" {
+			patterns = strings.Split(header.Index, ",")
+		}
+
+		result, serr := s.runSearch(patterns, []byte(lines[i+1]))
+		if serr != nil {
+			responses = append(responses, map[string]interface{}{
+				"error":  map[string]interface{}{"type": serr.kind, "reason": serr.reason},
+				"status": http.StatusBadRequest,
+			})
+			continue
+		}
+
+		responses = append(responses, result)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"responses": responses})
+}
+
+type searchError struct {
+	kind, reason string
+}
+
+func (s *Server) runSearch(patterns []string, body []byte) (opensearch.SearchResult, *searchError) {
+	var req opensearch.SearchRequest
+
+	if len(body) != 0 {
+		err := json.Unmarshal(body, &req)
+		if err != nil {
+			return opensearch.SearchResult{}, &searchError{"parsing_exception", err.Error()}
+		}
+	}
+
+	if len(req.Aggs) != 0 {
+		return opensearch.SearchResult{}, &searchError{"illegal_argument_exception", "ostest does not support aggregations"}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hits = make([]opensearch.Hit, 0)
+
+	for _, index := range s.match(patterns) {
+		for id, doc := range s.indices[index] {
+			hit := opensearch.Hit{
+				Index:  index,
+				ID:     id,
+				Score:  1.0,
+				Source: doc.source,
+			}
+
+			ok, err := matchesAll(hit, req.Query, req.PostFilter)
+			if err != nil {
+				return opensearch.SearchResult{}, &searchError{"illegal_argument_exception", err.Error()}
+			}
+
+			if !ok {
+				continue
+			}
+
+			if req.Version {
+				hit.Version = doc.version
+			}
+
+			hits = append(hits, hit)
+		}
+	}
+
+	matched := int64(len(hits))
+
+	hits, err := sortHits(hits, req.Sort, req.SearchAfterValues)
+	if err != nil {
+		return opensearch.SearchResult{}, &searchError{"illegal_argument_exception", err.Error()}
+	}
+
+	from := req.From
+	if from > int64(len(hits)) {
+		from = int64(len(hits))
+	}
+
+	to := from + req.Size
+	if to > int64(len(hits)) {
+		to = int64(len(hits))
+	}
+
+	return opensearch.SearchResult{
+		Shards: opensearch.Shards{Total: 1, Successful: 1},
+		Hits: opensearch.Hits{
+			Total: opensearch.Total{Value: matched, Relation: "eq"},
+			Hits:  hits[from:to],
+		},
+	}, nil
+}
+
+// match returns the sorted names of the stored indices selected by the given
+// multi-target expressions, honoring wildcards and "-" exclusions.
+func (s *Server) match(patterns []string) []string {
+	var selected = make(map[string]bool)
+
+	for _, pattern := range patterns {
+		exclude := strings.HasPrefix(pattern, "-")
+		pattern = strings.TrimPrefix(pattern, "-")
+
+		if pattern == "_all" {
+			pattern = "*"
+		}
+
+		for index := range s.indices {
+			if ok, _ := path.Match(pattern, index); ok {
+				selected[index] = !exclude
+			}
+		}
+	}
+
+	var indices = make([]string, 0, len(selected))
+	for index, ok := range selected {
+		if ok {
+			indices = append(indices, index)
+		}
+	}
+
+	sort.Strings(indices)
+
+	return indices
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, kind, reason string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"type":   kind,
+			"reason": reason,
+		},
+		"status": status,
+	})
+}
+
+func copyMap(src map[string]interface{}) map[string]interface{} {
+	var dst = make(map[string]interface{}, len(src))
+	for k, v := range src {
+		if m, ok := v.(map[string]interface{}); ok {
+			v = copyMap(m)
+		}
+		dst[k] = v
+	}
+
+	return dst
+}
+
+// merge deep merges src into dst the same way partial updates do.
+func merge(dst, src map[string]interface{}) map[string]interface{} {
+	for k, v := range src {
+		sm, sok := v.(map[string]interface{})
+		dm, dok := dst[k].(map[string]interface{})
+		if sok && dok {
+			dst[k] = merge(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+
+	return dst
+}
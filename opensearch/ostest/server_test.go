@@ -0,0 +1,165 @@
+package ostest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+var srv *Server
+
+func TestMain(m *testing.M) {
+	srv = NewServer()
+	if err := srv.Connect(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	srv.Close()
+	os.Exit(code)
+}
+
+func seed(t *testing.T) {
+	t.Helper()
+
+	srv.Reset()
+
+	docs := map[string]map[string]interface{}{
+		"1": {"user": "alice", "bytes": 100, "@timestamp": "2024-05-01T10:00:00Z"},
+		"2": {"user": "bob", "bytes": 300, "@timestamp": "2024-05-01T11:00:00Z"},
+		"3": {"user": "carol", "bytes": 200, "@timestamp": "2024-05-02T10:00:00Z"},
+	}
+
+	for id, doc := range docs {
+		if err := srv.Put("logs-2024.05", id, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := srv.Put("other", "9", map[string]interface{}{"user": "alice"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerSearch(t *testing.T) {
+	seed(t)
+
+	term := func(field string, value interface{}) *opensearch.Query {
+		return &opensearch.Query{Term: map[string]map[string]interface{}{field: {"value": value}}}
+	}
+
+	tests := []struct {
+		name    string
+		index   []string
+		request opensearch.SearchRequest
+		want    []string
+		wantErr bool
+	}{
+		{name: "pattern", index: []string{"logs-*"}, request: opensearch.SearchRequest{Size: 10}, want: []string{"1", "2", "3"}},
+		{name: "several indices", index: []string{"logs-*", "other"}, request: opensearch.SearchRequest{Size: 10, Query: term("user", "alice")}, want: []string{"1", "9"}},
+		{name: "range", index: []string{"logs-*"}, request: opensearch.SearchRequest{Size: 10, Query: &opensearch.Query{
+			Range: map[string]map[string]interface{}{"@timestamp": {"gte": "2024-05-01T10:30:00Z"}},
+		}}, want: []string{"2", "3"}},
+		{name: "sorted", index: []string{"logs-*"}, request: opensearch.SearchRequest{Size: 10,
+			Sort: []map[string]map[string]interface{}{{"bytes": {"order": "desc"}}},
+		}, want: []string{"2", "3", "1"}},
+		{name: "size", index: []string{"logs-*"}, request: opensearch.SearchRequest{Size: 2,
+			Sort: []map[string]map[string]interface{}{{"bytes": {"order": "asc"}}},
+		}, want: []string{"1", "3"}},
+		{name: "search after", index: []string{"logs-*"}, request: opensearch.SearchRequest{Size: 10,
+			Sort:        []map[string]map[string]interface{}{{"bytes": {"order": "asc"}}},
+			SearchAfter: []int64{100},
+		}, want: []string{"3", "2"}},
+		{name: "search after keyword", index: []string{"logs-*"}, request: opensearch.SearchRequest{Size: 10,
+			Sort:              []map[string]map[string]interface{}{{"user": {"order": "asc"}}},
+			SearchAfterValues: opensearch.SortValues{"alice"},
+		}, want: []string{"2", "3"}},
+		{name: "unsupported query", index: []string{"logs-*"}, request: opensearch.SearchRequest{Size: 10, Query: &opensearch.Query{
+			Match: map[string]opensearch.Match{"user": {Query: "alice"}},
+		}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.request.SearchIn(context.Background(), tt.index)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SearchIn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var ids []string
+			for _, hit := range result.Hits.Hits {
+				ids = append(ids, hit.ID)
+			}
+
+			if len(ids) != len(tt.want) {
+				t.Fatalf("hits = %v, want %v", ids, tt.want)
+			}
+
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("hits = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestServerDocuments(t *testing.T) {
+	seed(t)
+
+	ctx := context.Background()
+
+	hit, err := opensearch.GetDoc(ctx, "logs-2024.05", "2")
+	if err != nil || !hit.Found || hit.Source["user"] != "bob" {
+		t.Fatalf("GetDoc() = %+v, %v", hit, err)
+	}
+
+	hit, err = opensearch.GetDoc(ctx, "logs-2024.05", "404")
+	if err != nil || hit.Found {
+		t.Fatalf("GetDoc() of a missing document = %+v, %v", hit, err)
+	}
+
+	err = opensearch.IndexDoc(ctx, map[string]interface{}{"user": "dave"}, "logs-2024.05", "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if src, ok := srv.Get("logs-2024.05", "4"); !ok || src["user"] != "dave" {
+		t.Fatalf("indexed document = %v, %v", src, ok)
+	}
+
+	err = opensearch.Hit{Index: "logs-2024.05", ID: "1"}.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := srv.Count("logs-2024.05"); n != 3 {
+		t.Fatalf("%d documents left, want 3", n)
+	}
+}
+
+func TestServerMultiSearch(t *testing.T) {
+	seed(t)
+
+	results, err := opensearch.MultiSearch(context.Background(), []opensearch.IndexedSearch{
+		{Index: []string{"logs-*"}, Request: opensearch.SearchRequest{Size: 10, Query: &opensearch.Query{
+			Terms: map[string][]interface{}{"user": {"bob", "carol"}},
+		}}},
+		{Index: []string{"other"}, Request: opensearch.SearchRequest{Size: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+
+	for i, want := range []int64{2, 1} {
+		if got := results[i].Hits.Total.Value; got != want {
+			t.Errorf("search %d matched %d documents, want %d", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoMorePages is returned by Paginator.NextPage and PrevPage when there is no page
+// in that direction.
+var ErrNoMorePages = errors.New("no more pages")
+
+// Paginator pages through the hits of a search using search_after. Each Page carries
+// opaque Next and Prev cursors that API services can hand to their clients and pass
+// back to Page to continue from there, without keeping any state between calls.
+//
+// An ascending "_id" sort is appended as tie-breaker when the request does not sort
+// by "_id" already, so hits sharing the same sort values are never skipped or repeated.
+type Paginator struct {
+	request SearchRequest
+	index   []string
+
+	current Page
+	started bool
+}
+
+// Page is a page of hits. Next and Prev are empty when there are no hits after or
+// before the page.
+type Page struct {
+	SearchResult
+	Next string
+	Prev string
+}
+
+type cursor struct {
+	After   SortValues `json:"after"`
+	Reverse bool       `json:"reverse,omitempty"`
+}
+
+// NewPaginator returns a Paginator over the results of q in index, with pages of
+// q.Size hits, or 10 when q.Size is not set.
+func NewPaginator(q SearchRequest, index []string) *Paginator {
+	if q.Size <= 0 {
+		q.Size = 10
+	}
+
+	q.From = 0
+	q.SearchAfter = nil
+	q.SearchAfterValues = nil
+	q.Sort = withTieBreaker(q.Sort)
+
+	return &Paginator{request: q, index: index}
+}
+
+// NextPage returns the first page on the first call and the page following the last
+// returned one afterwards.
+func (p *Paginator) NextPage(ctx context.Context) (Page, error) {
+	if p.started && p.current.Next == "" {
+		return Page{}, ErrNoMorePages
+	}
+
+	return p.move(ctx, p.current.Next)
+}
+
+// PrevPage returns the page preceding the last returned one.
+func (p *Paginator) PrevPage(ctx context.Context) (Page, error) {
+	if p.current.Prev == "" {
+		return Page{}, ErrNoMorePages
+	}
+
+	return p.move(ctx, p.current.Prev)
+}
+
+// HasNext reports whether NextPage will return a page.
+func (p *Paginator) HasNext() bool {
+	return !p.started || p.current.Next != ""
+}
+
+// HasPrev reports whether PrevPage will return a page.
+func (p *Paginator) HasPrev() bool {
+	return p.current.Prev != ""
+}
+
+func (p *Paginator) move(ctx context.Context, c string) (Page, error) {
+	page, err := p.Page(ctx, c)
+	if err != nil {
+		return Page{}, err
+	}
+
+	p.current = page
+	p.started = true
+
+	return page, nil
+}
+
+// Page returns the page identified by a cursor from a previous Page, or the first
+// page when cursor is empty.
+func (p *Paginator) Page(ctx context.Context, c string) (Page, error) {
+	var cur cursor
+
+	if c != "" {
+		var err error
+
+		cur, err = decodeCursor(c)
+		if err != nil {
+			return Page{}, err
+		}
+
+		var fields int
+		for _, s := range p.request.Sort {
+			fields += len(s)
+		}
+
+		if len(cur.After) != fields {
+			return Page{}, fmt.Errorf("cursor does not match the sort of the request")
+		}
+	}
+
+	q := p.request
+	size := q.Size
+
+	// Fetch an extra hit to know whether there is another page in this direction.
+	q.Size = size + 1
+	q.SearchAfterValues = cur.After
+
+	if cur.Reverse {
+		q.Sort = reverseSort(q.Sort)
+	}
+
+	result, err := q.SearchIn(ctx, p.index)
+	if err != nil {
+		return Page{}, err
+	}
+
+	hits := result.Hits.Hits
+
+	more := int64(len(hits)) > size
+	if more {
+		hits = hits[:size]
+	}
+
+	if cur.Reverse {
+		for i, j := 0, len(hits)-1; i < j; i, j = i+1, j-1 {
+			hits[i], hits[j] = hits[j], hits[i]
+		}
+	}
+
+	result.Hits.Hits = hits
+	result.page = pageInfo{known: true, more: more || cur.Reverse}
+
+	if len(hits) != 0 {
+		result.page.last = hits[len(hits)-1].values()
+	}
+
+	var page = Page{SearchResult: result}
+
+	if len(hits) == 0 {
+		return page, nil
+	}
+
+	first, last := hits[0].values(), hits[len(hits)-1].values()
+
+	if more || cur.Reverse {
+		page.Next = encodeCursor(cursor{After: last})
+	}
+
+	if (more && cur.Reverse) || (c != "" && !cur.Reverse) {
+		page.Prev = encodeCursor(cursor{After: first, Reverse: true})
+	}
+
+	return page, nil
+}
+
+// pageInfo is what a search result tells about the hits following it, computed from
+// the request and the hits returned by the cluster, before they are deduplicated or
+// transformed.
+type pageInfo struct {
+	known bool
+	more  bool
+	last  SortValues
+}
+
+func (q SearchRequest) pageInfo(r SearchResult) pageInfo {
+	hits := r.Hits.Hits
+
+	var p = pageInfo{known: true}
+
+	if len(hits) != 0 {
+		p.last = hits[len(hits)-1].values()
+	}
+
+	switch {
+	case int64(len(hits)) < q.Size:
+		// A short page is the last one.
+	case len(q.searchAfter()) == 0 && r.Hits.Total.Relation == "eq":
+		p.more = q.From+int64(len(hits)) < r.Hits.Total.Value
+	default:
+		// A full page is followed by more hits unless an exact total says otherwise.
+		p.more = true
+	}
+
+	return p
+}
+
+// HasMore reports whether more hits match the search after the returned ones. Results
+// not returned by a search, like those decoded from JSON, only tell from their total,
+// assuming they are a first page.
+func (r SearchResult) HasMore() bool {
+	if r.page.known {
+		return r.page.more
+	}
+
+	if r.TotalIsLowerBound() {
+		return true
+	}
+
+	return int64(len(r.Hits.Hits)) < r.Hits.Total.Value
+}
+
+// NextSearchAfter returns the sort values to search after to get the next page, those
+// of the last hit returned, nil when there are no hits or the search is not sorted.
+func (r SearchResult) NextSearchAfter() SortValues {
+	if r.page.known {
+		return r.page.last
+	}
+
+	if len(r.Hits.Hits) == 0 {
+		return nil
+	}
+
+	return r.Hits.Hits[len(r.Hits.Hits)-1].values()
+}
+
+// TotalIsLowerBound reports whether the total hits are a lower bound of the matching
+// documents, which happens when more documents match than track_total_hits counts.
+func (r SearchResult) TotalIsLowerBound() bool {
+	return r.Hits.Total.Relation == "gte"
+}
+
+// withTieBreaker returns a copy of sorts ending with an ascending "_id" sort, unless
+// sorts already includes one.
+func withTieBreaker(sorts []map[string]map[string]interface{}) []map[string]map[string]interface{} {
+	for _, s := range sorts {
+		if _, ok := s["_id"]; ok {
+			return sorts
+		}
+	}
+
+	var out = make([]map[string]map[string]interface{}, len(sorts), len(sorts)+1)
+
+	copy(out, sorts)
+
+	return append(out, SortByField("_id", "asc"))
+}
+
+// reverseSort inverts the order of every sort, and the placement of documents missing
+// a sorted field, so that searching after the first hit of a page returns the hits
+// preceding it.
+func reverseSort(sorts []map[string]map[string]interface{}) []map[string]map[string]interface{} {
+	var out = make([]map[string]map[string]interface{}, 0, len(sorts))
+
+	for _, s := range sorts {
+		var reversed = make(map[string]map[string]interface{}, len(s))
+
+		for field, params := range s {
+			var rp = make(map[string]interface{}, len(params)+1)
+			for k, v := range params {
+				rp[k] = v
+			}
+
+			order, _ := params["order"].(string)
+			if order == "" {
+				order = "asc"
+				if field == "_score" {
+					order = "desc"
+				}
+			}
+
+			if order == "desc" {
+				rp["order"] = "asc"
+			} else {
+				rp["order"] = "desc"
+			}
+
+			if !strings.HasPrefix(field, "_") {
+				switch params["missing"] {
+				case nil, "_last":
+					rp["missing"] = "_first"
+				case "_first":
+					rp["missing"] = "_last"
+				}
+			}
+
+			reversed[field] = rp
+		}
+
+		out = append(out, reversed)
+	}
+
+	return out
+}
+
+func encodeCursor(c cursor) string {
+	j, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(j)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	j, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	err = json.Unmarshal(j, &c)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,112 @@
+package opensearch
+
+import (
+	"strings"
+)
+
+const (
+	// PreviewSize is the maximum number of hits of preview requests.
+	PreviewSize = 20
+	// PreviewTerminateAfter is the number of documents per shard preview requests
+	// collect.
+	PreviewTerminateAfter = 10000
+)
+
+// PreviewMode returns a copy of the request for a quick look, like the first rows a UI
+// shows while the query is edited: at most PreviewSize hits, collected from the first
+// PreviewTerminateAfter documents of each shard, without counting the total hits, and
+// without the aggregations expensive to compute, like cardinalities, percentiles,
+// significant terms and top hits, nor the pipeline aggregations reading them. Results
+// are partial, so exports must use the full request.
+func (q SearchRequest) PreviewMode() SearchRequest {
+	if q.Size > PreviewSize || q.Size < 0 {
+		q.Size = PreviewSize
+	}
+
+	if q.TerminateAfter == 0 || q.TerminateAfter > PreviewTerminateAfter {
+		q.TerminateAfter = PreviewTerminateAfter
+	}
+
+	q.TrackTotalHits = false
+	q.Aggs = previewAggs(q.Aggs)
+
+	return q
+}
+
+// previewAggs returns the aggregations without the expensive ones.
+func previewAggs(aggs map[string]Aggs) map[string]Aggs {
+	if len(aggs) == 0 {
+		return aggs
+	}
+
+	var kept = make(map[string]Aggs, len(aggs))
+
+	for name, agg := range aggs {
+		if expensiveAgg(agg) {
+			continue
+		}
+
+		agg.Aggs = previewAggs(agg.Aggs)
+		kept[name] = agg
+	}
+
+	// Pipeline aggregations may read other pipeline aggregations removed in turn.
+	for removed := true; removed; {
+		removed = false
+
+		for name, agg := range kept {
+			target := pathTarget(agg)
+			if target == "" {
+				continue
+			}
+
+			if _, ok := kept[target]; !ok {
+				delete(kept, name)
+				removed = true
+			}
+		}
+	}
+
+	return kept
+}
+
+// pathTarget returns the aggregation read by a pipeline aggregation, or an empty
+// string for other aggregations and paths to the document count, like "_count".
+func pathTarget(agg Aggs) string {
+	segments := strings.FieldsFunc(bucketsPath(agg), isPathSeparator)
+	if len(segments) == 0 || strings.HasPrefix(segments[0], "_") {
+		return ""
+	}
+
+	return segments[0]
+}
+
+func expensiveAgg(agg Aggs) bool {
+	return agg.Cardinality != nil || agg.Percentiles != nil || agg.PercentileRanks != nil ||
+		agg.TopHits != nil || agg.SignificantTerms != nil || agg.SignificantText != nil ||
+		agg.MatrixStats != nil || agg.AdjacencyMatrix != nil || agg.DiversifiedSampler != nil
+}
+
+// bucketsPath returns the buckets path of a pipeline aggregation.
+func bucketsPath(agg Aggs) string {
+	for _, p := range []*PipelineAgg{agg.SumBucket, agg.AvgBucket, agg.MinBucket, agg.MaxBucket,
+		agg.StatsBucket, agg.ExtendedStatsBucket, agg.CumulativeSum, agg.Derivative} {
+		if p != nil {
+			return p.BucketsPath
+		}
+	}
+
+	if agg.MovingAvg != nil {
+		return agg.MovingAvg.BucketsPath
+	}
+
+	if agg.SerialDiff != nil {
+		return agg.SerialDiff.BucketsPath
+	}
+
+	return ""
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '>' || r == '.'
+}
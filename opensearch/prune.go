@@ -0,0 +1,59 @@
+package opensearch
+
+import (
+	"sync"
+)
+
+// IndexPruner rewrites the index expressions of a search, e.g. to replace a wildcard
+// over time-partitioned indices by the partitions overlapping the time range of the
+// query. It returns index unchanged when it can't narrow it.
+type IndexPruner func(q SearchRequest, index []string) []string
+
+var indexPruner struct {
+	sync.RWMutex
+	fn IndexPruner
+}
+
+// SetIndexPruner makes SearchIn rewrite the indices of every search with fn, after
+// which missing indices are ignored, since pruners may name partitions not created.
+// Access is checked on the rewritten indices. A nil fn disables pruning, the default.
+func SetIndexPruner(fn IndexPruner) {
+	indexPruner.Lock()
+	defer indexPruner.Unlock()
+
+	indexPruner.fn = fn
+}
+
+// pruneIndices applies the index pruner to the request, returning its indices.
+func (q *SearchRequest) pruneIndices(index []string) []string {
+	indexPruner.RLock()
+	fn := indexPruner.fn
+	indexPruner.RUnlock()
+
+	if fn == nil || q.KeepIndices {
+		return index
+	}
+
+	pruned := fn(*q, index)
+	if equalStrings(pruned, index) || len(pruned) == 0 {
+		return index
+	}
+
+	q.IgnoreUnavailable = true
+
+	return pruned
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,407 @@
+package querylang
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// ParseKQL parses a search string of the Kibana Query Language, like
+//
+//	event.action: "logon failed" and not user.name: svc_* and bytes >= 1000
+//
+// into a query, so that expressions pasted from Kibana aren't searched as Lucene
+// query strings. Clauses are joined with and, or and not, in any case, and
+// parentheses. Values are terms, which may contain spaces, "phrases" and wildcards
+// with *; field: (a or b) compares a field to several values, field: * checks its
+// existence and field < a, <=, > and >= compare it to a bound. Nested field queries,
+// like field: { sub: value }, aren't supported.
+func ParseKQL(ctx context.Context, s string, o Options) (opensearch.Query, error) {
+	p := &kqlParser{runes: []rune(s)}
+
+	n, err := p.parse()
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	return compiler{ctx: ctx, o: o}.compile(n)
+}
+
+// kqlSpecial are the characters ending unquoted literals unless escaped.
+const kqlSpecial = `\():<>"{}`
+
+// kqlLiteral is a quoted or unquoted literal.
+type kqlLiteral struct {
+	text     string
+	pattern  string
+	quoted   bool
+	wildcard bool
+	pos      int
+	end      int
+}
+
+// kqlParser parses the runes of a KQL expression, with not binding tighter than and,
+// and and tighter than or.
+type kqlParser struct {
+	runes []rune
+	pos   int
+}
+
+func (p *kqlParser) skipSpace() {
+	for p.pos < len(p.runes) && unicode.IsSpace(p.runes[p.pos]) {
+		p.pos++
+	}
+}
+
+// peek returns the next rune after spaces, zero at the end.
+func (p *kqlParser) peek() rune {
+	p.skipSpace()
+
+	if p.pos < len(p.runes) {
+		return p.runes[p.pos]
+	}
+
+	return 0
+}
+
+// keywordAt returns the length of the keyword and, or or not at i, zero if there is
+// none.
+func (p *kqlParser) keywordAt(i int) int {
+	for _, keyword := range []string{"and", "or", "not"} {
+		end := i + len(keyword)
+		if end > len(p.runes) || !strings.EqualFold(string(p.runes[i:end]), keyword) {
+			continue
+		}
+
+		if end == len(p.runes) || unicode.IsSpace(p.runes[end]) || p.runes[end] == '(' || p.runes[end] == '"' {
+			return len(keyword)
+		}
+	}
+
+	return 0
+}
+
+// keyword consumes the keyword after spaces, if any.
+func (p *kqlParser) keyword(keyword string) bool {
+	p.skipSpace()
+
+	n := p.keywordAt(p.pos)
+	if n != len(keyword) || !strings.EqualFold(string(p.runes[p.pos:p.pos+n]), keyword) {
+		return false
+	}
+
+	p.pos += n
+
+	return true
+}
+
+func (p *kqlParser) parse() (node, error) {
+	if p.peek() == 0 {
+		return nil, nil
+	}
+
+	n, err := p.or(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() != 0 {
+		return nil, errorAt(p.pos, p.pos+1, "unexpected %q", p.runes[p.pos])
+	}
+
+	return n, nil
+}
+
+// or parses clauses joined with or, comparing values to field when not nil.
+func (p *kqlParser) or(field *kqlLiteral) (node, error) {
+	n, err := p.and(field)
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses = []node{n}
+
+	for p.keyword("or") {
+		n, err := p.and(field)
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, n)
+	}
+
+	if len(clauses) == 1 {
+		return n, nil
+	}
+
+	return orNode(clauses), nil
+}
+
+func (p *kqlParser) and(field *kqlLiteral) (node, error) {
+	n, err := p.not(field)
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses = []node{n}
+
+	for p.keyword("and") {
+		n, err := p.not(field)
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, n)
+	}
+
+	if len(clauses) == 1 {
+		return n, nil
+	}
+
+	return andNode(clauses), nil
+}
+
+func (p *kqlParser) not(field *kqlLiteral) (node, error) {
+	if p.keyword("not") {
+		n, err := p.not(field)
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{clause: n}, nil
+	}
+
+	if p.peek() == '(' {
+		open := p.pos
+		p.pos++
+
+		n, err := p.or(field)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != ')' {
+			return nil, errorAt(open, open+1, "missing closing parenthesis")
+		}
+
+		p.pos++
+
+		return n, nil
+	}
+
+	if field != nil {
+		return p.value(field)
+	}
+
+	return p.expression()
+}
+
+// expression parses a field expression, or a value searched in the default fields.
+func (p *kqlParser) expression() (node, error) {
+	lit, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.peek() {
+	case ':':
+		p.pos++
+
+		switch p.peek() {
+		case '(':
+			open := p.pos
+			p.pos++
+
+			n, err := p.or(&lit)
+			if err != nil {
+				return nil, err
+			}
+
+			if p.peek() != ')' {
+				return nil, errorAt(open, open+1, "missing closing parenthesis")
+			}
+
+			p.pos++
+
+			return n, nil
+		case '{':
+			return nil, errorAt(p.pos, p.pos+1, "nested field queries are not supported")
+		}
+
+		return p.value(&lit)
+	case '<', '>':
+		return p.comparison(lit)
+	}
+
+	return valueOf(nil, lit), nil
+}
+
+// value parses a value of field.
+func (p *kqlParser) value(field *kqlLiteral) (node, error) {
+	lit, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+
+	return valueOf(field, lit), nil
+}
+
+// comparison parses the range operator and bound following field.
+func (p *kqlParser) comparison(field kqlLiteral) (node, error) {
+	op := string(p.runes[p.pos])
+	p.pos++
+
+	if p.pos < len(p.runes) && p.runes[p.pos] == '=' {
+		op += "="
+		p.pos++
+	}
+
+	bound, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+
+	if bound.wildcard {
+		return nil, errorAt(bound.pos, bound.end, "range bounds can't be wildcards")
+	}
+
+	n := &valueNode{
+		field: field.text, fieldPos: field.pos, fieldEnd: field.end,
+		kind: rangeValue, pos: field.pos, end: bound.end,
+	}
+
+	if op[0] == '>' {
+		n.lower, n.lowerIncl, n.lowerValid, n.lowerPos, n.lowerEnd = bound.text, op == ">=", true, bound.pos, bound.end
+	} else {
+		n.upper, n.upperIncl, n.upperValid, n.upperPos, n.upperEnd = bound.text, op == "<=", true, bound.pos, bound.end
+	}
+
+	return n, nil
+}
+
+// valueOf returns the node comparing field, the default fields when nil, to lit.
+func valueOf(field *kqlLiteral, lit kqlLiteral) node {
+	n := &valueNode{value: lit.text, pos: lit.pos, end: lit.end}
+	if field != nil {
+		n.field, n.fieldPos, n.fieldEnd = field.text, field.pos, field.end
+	}
+
+	switch {
+	case lit.quoted:
+		n.kind = phraseValue
+	case lit.pattern == "*" && field != nil:
+		n.kind = existsValue
+	case lit.pattern == "*":
+		// A lone wildcard matches every document.
+		return nil
+	case lit.wildcard:
+		n.kind, n.value = wildcardValue, lit.pattern
+	}
+
+	return n
+}
+
+// literal parses a quoted literal, or an unquoted one, which runs until a special
+// character or a keyword, trimming its spaces.
+func (p *kqlParser) literal() (kqlLiteral, error) {
+	p.skipSpace()
+
+	lit := kqlLiteral{pos: p.pos}
+
+	if p.pos == len(p.runes) {
+		return lit, errorAt(p.pos, p.pos, "unexpected end of query")
+	}
+
+	if p.runes[p.pos] == '"' {
+		end, text, err := quoted(p.runes, p.pos)
+		if err != nil {
+			return lit, err
+		}
+
+		p.pos = end
+		lit.text, lit.pattern, lit.quoted, lit.end = text, text, true, end
+
+		return lit, nil
+	}
+
+	if p.keywordAt(p.pos) != 0 {
+		return lit, errorAt(p.pos, p.pos+p.keywordAt(p.pos), "expected a value, found %q", string(p.runes[p.pos:p.pos+p.keywordAt(p.pos)]))
+	}
+
+	var text, pattern strings.Builder
+
+	end := p.pos
+
+	for p.pos < len(p.runes) {
+		r := p.runes[p.pos]
+
+		if unicode.IsSpace(r) {
+			next := p.pos
+			for next < len(p.runes) && unicode.IsSpace(p.runes[next]) {
+				next++
+			}
+
+			if next == len(p.runes) || p.keywordAt(next) != 0 || strings.ContainsRune(kqlSpecial, p.runes[next]) && p.runes[next] != '\\' {
+				break
+			}
+
+			text.WriteString(string(p.runes[p.pos:next]))
+			pattern.WriteString(string(p.runes[p.pos:next]))
+			p.pos = next
+
+			continue
+		}
+
+		if r == '\\' {
+			if p.pos+1 == len(p.runes) {
+				return lit, errorAt(p.pos, p.pos+1, "incomplete escape")
+			}
+
+			p.pos++
+			r = p.runes[p.pos]
+
+			text.WriteRune(r)
+
+			if r == '*' || r == '?' || r == '\\' {
+				pattern.WriteRune('\\')
+			}
+
+			pattern.WriteRune(r)
+
+			p.pos++
+			end = p.pos
+
+			continue
+		}
+
+		if strings.ContainsRune(kqlSpecial, r) {
+			break
+		}
+
+		if r == '*' {
+			lit.wildcard = true
+		}
+
+		text.WriteRune(r)
+
+		// Question marks aren't wildcards in KQL.
+		if r == '?' {
+			pattern.WriteRune('\\')
+		}
+
+		pattern.WriteRune(r)
+
+		p.pos++
+		end = p.pos
+	}
+
+	if end == lit.pos {
+		return lit, errorAt(p.pos, p.pos+1, "expected a value, found %q", p.runes[p.pos])
+	}
+
+	lit.text, lit.pattern, lit.end = text.String(), pattern.String(), end
+
+	return lit, nil
+}
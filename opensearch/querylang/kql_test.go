@@ -0,0 +1,19 @@
+package querylang
+
+import "testing"
+
+func TestParseKQL(t *testing.T) {
+	runParseTests(t, ParseKQL, []parseTest{
+		{in: `status: failed and bytes >= 1000`, want: `{"bool":{"must":[{"term":{"status":{"value":"failed"}}},{"range":{"bytes":{"gte":"1000"}}}]}}`},
+		{in: `not user: bob or status: (a or b)`, want: `{"bool":{"should":[{"bool":{"must_not":[{"term":{"user.name":{"value":"bob"}}}]}},{"bool":{"should":[{"term":{"status":{"value":"a"}}},{"term":{"status":{"value":"b"}}}],"minimum_should_match":1}}],"minimum_should_match":1}}`},
+		{in: `(status: a or status: b) and user: c`, want: `{"bool":{"must":[{"bool":{"should":[{"term":{"status":{"value":"a"}}},{"term":{"status":{"value":"b"}}}],"minimum_should_match":1}},{"term":{"user.name":{"value":"c"}}}]}}`},
+		{in: `user.name: bob smith`, want: `{"term":{"user.name":{"value":"bob smith"}}}`},
+		{in: `user: svc_*`, want: `{"prefix":{"user.name":"svc_"}}`},
+		{in: `user: *`, want: `{"exists":{"field":"user.name"}}`},
+		{in: `message: "access denied"`, want: `{"match_phrase":{"message":{"query":"access denied"}}}`},
+		{in: `nope: x`, wantPos: 0},
+		{in: `bytes < abc`, wantPos: 8},
+		{in: `status: failed and`, wantPos: 18},
+		{in: `status: {x}`, wantPos: 8},
+	})
+}
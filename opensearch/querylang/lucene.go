@@ -0,0 +1,558 @@
+package querylang
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// ParseLucene parses a search string of the Lucene query syntax, like
+//
+//	status:failed AND bytes:[1000 TO *] NOT user.name:svc_* "access denied"
+//
+// into a query. Clauses are joined with AND, OR and NOT, or &&, || and !, and
+// parentheses, adjacent clauses being required. A clause prefixed with - is excluded
+// and with + required. Values are terms, "phrases", wildcards with * and ?, /regular
+// expressions/, fuzzy terms like term~1, ranges like [a TO b], {a TO b} or >=a, and *
+// for the existence of a field; field:(a OR b) compares a field to several values and
+// _exists_:field checks a field. Boosts aren't supported.
+func ParseLucene(ctx context.Context, s string, o Options) (opensearch.Query, error) {
+	tokens, err := lexLucene(s)
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	p := &luceneParser{tokens: tokens}
+
+	n, err := p.parse()
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	return compiler{ctx: ctx, o: o}.compile(n)
+}
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	wordToken
+	phraseToken
+	regexpToken
+	andToken
+	orToken
+	notToken
+	toToken
+	plusToken
+	minusToken
+	colonToken
+	lparenToken
+	rparenToken
+	lbracketToken
+	rbracketToken
+	lbraceToken
+	rbraceToken
+	tildeToken
+	caretToken
+)
+
+type token struct {
+	kind tokenKind
+	// text is the unescaped text of words and phrases, and pattern the text of words
+	// keeping the escapes of wildcards.
+	text     string
+	pattern  string
+	wildcard bool
+	pos      int
+	end      int
+}
+
+// luceneSpecial are the characters ending words unless escaped.
+const luceneSpecial = `()[]{}:"~^`
+
+var luceneSingle = map[rune]tokenKind{
+	'(': lparenToken, ')': rparenToken, '[': lbracketToken, ']': rbracketToken,
+	'{': lbraceToken, '}': rbraceToken, ':': colonToken, '^': caretToken,
+}
+
+func lexLucene(s string) ([]token, error) {
+	runes := []rune(s)
+
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+
+		prevColon := len(tokens) != 0 && tokens[len(tokens)-1].kind == colonToken
+
+		switch {
+		case luceneSingle[r] != eofToken:
+			tokens = append(tokens, token{kind: luceneSingle[r], text: string(r), pos: i, end: i + 1})
+			i++
+		case r == '~':
+			// The fuzziness, or slop, follows the tilde.
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+
+			tokens = append(tokens, token{kind: tildeToken, text: string(runes[i+1 : j]), pos: i, end: j})
+			i = j
+		case r == '"' || r == '/':
+			// Slashes within words, like paths, don't open regular expressions.
+			j, text, err := quoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+
+			kind := phraseToken
+			if r == '/' {
+				kind = regexpToken
+			}
+
+			tokens = append(tokens, token{kind: kind, text: text, pos: i, end: j})
+			i = j
+		case i+1 < len(runes) && (r == '&' && runes[i+1] == '&' || r == '|' && runes[i+1] == '|'):
+			kind := andToken
+			if r == '|' {
+				kind = orToken
+			}
+
+			tokens = append(tokens, token{kind: kind, text: string(runes[i : i+2]), pos: i, end: i + 2})
+			i += 2
+		case r == '!':
+			tokens = append(tokens, token{kind: notToken, text: "!", pos: i, end: i + 1})
+			i++
+		case (r == '+' || r == '-') && !prevColon:
+			kind := plusToken
+			if r == '-' {
+				kind = minusToken
+			}
+
+			tokens = append(tokens, token{kind: kind, text: string(r), pos: i, end: i + 1})
+			i++
+		default:
+			t, err := word(runes, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, t)
+			i = t.end
+		}
+	}
+
+	return append(tokens, token{kind: eofToken, pos: len(runes), end: len(runes)}), nil
+}
+
+// quoted lexes a phrase or a regular expression starting at i, delimited by the rune
+// at i, and returns the end of the token and its text, unescaping the delimiter.
+func quoted(runes []rune, i int) (int, string, error) {
+	delim := runes[i]
+
+	var b strings.Builder
+
+	for j := i + 1; j < len(runes); j++ {
+		switch {
+		case runes[j] == '\\' && j+1 < len(runes):
+			j++
+			// Regular expressions keep their escapes, but those of the delimiter.
+			if delim == '/' && runes[j] != '/' {
+				b.WriteRune('\\')
+			}
+
+			b.WriteRune(runes[j])
+		case runes[j] == delim:
+			return j + 1, b.String(), nil
+		default:
+			b.WriteRune(runes[j])
+		}
+	}
+
+	if delim == '"' {
+		return 0, "", errorAt(i, len(runes), "unterminated phrase")
+	}
+
+	return 0, "", errorAt(i, len(runes), "unterminated regular expression")
+}
+
+// word lexes a word starting at i.
+func word(runes []rune, i int) (token, error) {
+	var text, pattern strings.Builder
+
+	t := token{kind: wordToken, pos: i}
+	escaped := false
+
+	j := i
+	for ; j < len(runes); j++ {
+		r := runes[j]
+
+		if unicode.IsSpace(r) || strings.ContainsRune(luceneSpecial, r) {
+			break
+		}
+
+		if r == '\\' {
+			if j+1 == len(runes) {
+				return token{}, errorAt(j, j+1, "incomplete escape")
+			}
+
+			j++
+			escaped = true
+
+			text.WriteRune(runes[j])
+
+			if runes[j] == '*' || runes[j] == '?' || runes[j] == '\\' {
+				pattern.WriteRune('\\')
+			}
+
+			pattern.WriteRune(runes[j])
+
+			continue
+		}
+
+		if r == '*' || r == '?' {
+			t.wildcard = true
+		}
+
+		text.WriteRune(r)
+		pattern.WriteRune(r)
+	}
+
+	t.text, t.pattern, t.end = text.String(), pattern.String(), j
+
+	if !escaped {
+		switch t.text {
+		case "AND":
+			t.kind = andToken
+		case "OR":
+			t.kind = orToken
+		case "NOT":
+			t.kind = notToken
+		case "TO":
+			t.kind = toToken
+		}
+	}
+
+	return t, nil
+}
+
+// luceneParser parses tokens, with NOT binding tighter than AND, and AND tighter
+// than OR.
+type luceneParser struct {
+	tokens []token
+	pos    int
+	// field is the field of the values of a field group, like field:(a OR b).
+	field *token
+}
+
+func (p *luceneParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *luceneParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != eofToken {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *luceneParser) parse() (node, error) {
+	if p.peek().kind == eofToken {
+		return nil, nil
+	}
+
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != eofToken {
+		return nil, errorAt(t.pos, t.end, "unexpected %q", t.text)
+	}
+
+	return n, nil
+}
+
+func (p *luceneParser) or() (node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses = []node{n}
+
+	for p.peek().kind == orToken {
+		p.next()
+
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, n)
+	}
+
+	if len(clauses) == 1 {
+		return n, nil
+	}
+
+	return orNode(clauses), nil
+}
+
+func (p *luceneParser) and() (node, error) {
+	n, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+
+	var clauses = []node{n}
+
+	for {
+		switch p.peek().kind {
+		case andToken:
+			p.next()
+		case eofToken, orToken, rparenToken:
+			if len(clauses) == 1 {
+				return n, nil
+			}
+
+			return andNode(clauses), nil
+		}
+
+		// Adjacent clauses are required.
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, n)
+	}
+}
+
+func (p *luceneParser) not() (node, error) {
+	switch p.peek().kind {
+	case notToken, minusToken:
+		p.next()
+
+		n, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{clause: n}, nil
+	case plusToken:
+		p.next()
+
+		return p.not()
+	}
+
+	return p.primary()
+}
+
+func (p *luceneParser) primary() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case lparenToken:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if c := p.next(); c.kind != rparenToken {
+			return nil, errorAt(t.pos, t.end, "missing closing parenthesis")
+		}
+
+		return n, nil
+	case wordToken:
+		if p.peek().kind != colonToken {
+			return p.value(p.field, t)
+		}
+
+		if p.field != nil {
+			return nil, errorAt(t.pos, t.end, "field %s inside the values of field %s", t.text, p.field.text)
+		}
+
+		p.next()
+
+		if t.text == "_exists_" {
+			f := p.next()
+			if f.kind != wordToken || f.wildcard {
+				return nil, errorAt(f.pos, f.end, "expected a field after _exists_")
+			}
+
+			return &valueNode{field: f.text, fieldPos: f.pos, fieldEnd: f.end, kind: existsValue, pos: f.pos, end: f.end}, nil
+		}
+
+		if p.peek().kind == lparenToken {
+			open := p.next()
+			p.field = &t
+
+			n, err := p.or()
+
+			p.field = nil
+
+			if err != nil {
+				return nil, err
+			}
+
+			if c := p.next(); c.kind != rparenToken {
+				return nil, errorAt(open.pos, open.end, "missing closing parenthesis")
+			}
+
+			return n, nil
+		}
+
+		return p.value(&t, p.next())
+	case phraseToken, regexpToken, lbracketToken, lbraceToken:
+		return p.value(p.field, t)
+	case eofToken:
+		return nil, errorAt(t.pos, t.end, "unexpected end of query")
+	}
+
+	return nil, errorAt(t.pos, t.end, "unexpected %q", t.text)
+}
+
+// value parses the value t of field, the default fields when nil.
+func (p *luceneParser) value(field *token, t token) (node, error) {
+	n := &valueNode{value: t.text, pos: t.pos, end: t.end}
+	if field != nil {
+		n.field, n.fieldPos, n.fieldEnd = field.text, field.pos, field.end
+	}
+
+	switch t.kind {
+	case wordToken:
+		switch {
+		case t.pattern == "*" && field != nil:
+			n.kind = existsValue
+		case t.wildcard:
+			n.kind, n.value = wildcardValue, t.pattern
+		case strings.HasPrefix(t.text, ">") || strings.HasPrefix(t.text, "<"):
+			return p.comparison(n, t)
+		}
+	case phraseToken:
+		n.kind = phraseValue
+	case regexpToken:
+		n.kind = regexpValue
+	case lbracketToken, lbraceToken:
+		return p.rangeValue(n, t)
+	case eofToken:
+		return nil, errorAt(t.pos, t.end, "unexpected end of query")
+	default:
+		return nil, errorAt(t.pos, t.end, "expected a value, found %q", t.text)
+	}
+
+	switch p.peek().kind {
+	case tildeToken:
+		tilde := p.next()
+		n.end = tilde.end
+
+		switch n.kind {
+		case termValue:
+			n.kind, n.fuzziness = fuzzyValue, tilde.text
+			if n.fuzziness == "" {
+				n.fuzziness = "AUTO"
+			}
+		case phraseValue:
+			slop, err := parseSlop(tilde)
+			if err != nil {
+				return nil, err
+			}
+
+			n.slop = slop
+		default:
+			return nil, errorAt(tilde.pos, tilde.end, "%s can't be fuzzy", n.kind)
+		}
+	case caretToken:
+		caret := p.next()
+
+		return nil, errorAt(caret.pos, caret.end, "boosts are not supported")
+	}
+
+	return n, nil
+}
+
+func parseSlop(t token) (int64, error) {
+	var slop int64
+
+	for _, r := range t.text {
+		if r < '0' || r > '9' {
+			return 0, errorAt(t.pos, t.end, "invalid phrase slop %q", t.text)
+		}
+
+		slop = slop*10 + int64(r-'0')
+	}
+
+	return slop, nil
+}
+
+// comparison parses a one-sided range like >=10.
+func (p *luceneParser) comparison(n *valueNode, t token) (node, error) {
+	op, value := t.text[:1], t.text[1:]
+	if strings.HasPrefix(value, "=") {
+		op, value = op+"=", value[1:]
+	}
+
+	if value == "" {
+		return nil, errorAt(t.pos, t.end, "expected a value after %s", op)
+	}
+
+	start := t.pos + len([]rune(op))
+
+	n.kind = rangeValue
+
+	if op[0] == '>' {
+		n.lower, n.lowerIncl, n.lowerValid, n.lowerPos, n.lowerEnd = value, op == ">=", true, start, t.end
+	} else {
+		n.upper, n.upperIncl, n.upperValid, n.upperPos, n.upperEnd = value, op == "<=", true, start, t.end
+	}
+
+	return n, nil
+}
+
+// rangeValue parses a range like [a TO b], whose opening bracket is open.
+func (p *luceneParser) rangeValue(n *valueNode, open token) (node, error) {
+	n.kind = rangeValue
+
+	lower := p.next()
+	if lower.kind != wordToken && lower.kind != phraseToken {
+		return nil, errorAt(lower.pos, lower.end, "expected the lower bound of the range")
+	}
+
+	if to := p.next(); to.kind != toToken {
+		return nil, errorAt(to.pos, to.end, "expected TO in the range")
+	}
+
+	upper := p.next()
+	if upper.kind != wordToken && upper.kind != phraseToken {
+		return nil, errorAt(upper.pos, upper.end, "expected the upper bound of the range")
+	}
+
+	closing := p.next()
+	if closing.kind != rbracketToken && closing.kind != rbraceToken {
+		return nil, errorAt(open.pos, closing.end, "missing closing bracket of the range")
+	}
+
+	n.end = closing.end
+
+	if lower.kind == phraseToken || lower.text != "*" {
+		n.lower, n.lowerValid, n.lowerPos, n.lowerEnd = lower.text, true, lower.pos, lower.end
+		n.lowerIncl = open.kind == lbracketToken
+	}
+
+	if upper.kind == phraseToken || upper.text != "*" {
+		n.upper, n.upperValid, n.upperPos, n.upperEnd = upper.text, true, upper.pos, upper.end
+		n.upperIncl = closing.kind == rbracketToken
+	}
+
+	return n, nil
+}
@@ -0,0 +1,416 @@
+// Package querylang parses the search strings typed by users into queries, instead of
+// passing them to the cluster as query_string queries, so that every UI parses them
+// the same way, fields are resolved against the mappings and errors point at the
+// offending position of the string.
+package querylang
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// SyntaxError is an error of a search string, between the characters Pos and End,
+// counted in runes from zero.
+type SyntaxError struct {
+	Pos int
+	End int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+func errorAt(pos, end int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos, End: end, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Field is a resolved field.
+type Field struct {
+	Name string
+	// Type is the mapping type of the field, empty when unknown.
+	Type string
+	// Keyword is the keyword sub-field of text fields, used for wildcards, regular
+	// expressions and ranges.
+	Keyword string
+}
+
+// Resolver resolves the fields named in search strings. The boolean result is false
+// for unknown fields.
+type Resolver func(ctx context.Context, name string) (Field, bool, error)
+
+// MapperResolver returns a Resolver of the fields of the indices, as mapped according
+// to opensearch.Mapper.
+func MapperResolver(index []string) Resolver {
+	pattern := strings.Join(index, ",")
+
+	return func(ctx context.Context, name string) (Field, bool, error) {
+		m := opensearch.Mapper()
+
+		info, ok, err := m.Field(ctx, pattern, name)
+		if err != nil || !ok {
+			return Field{}, ok, err
+		}
+
+		f := Field{Name: name, Type: info.Type}
+
+		if textTypes[info.Type] {
+			subFields, err := m.Fields(ctx, pattern, name+".")
+			if err != nil {
+				return Field{}, false, err
+			}
+
+			if sub, ok := subFields[name+".keyword"]; ok && keywordTypes[sub.Type] {
+				f.Keyword = name + ".keyword"
+			}
+		}
+
+		return f, true, nil
+	}
+}
+
+// Options configure the parsers.
+type Options struct {
+	// DefaultFields are searched by the values without a field. Values without a
+	// field search all the fields otherwise, and can't be wildcards, regular
+	// expressions, fuzzy terms or ranges.
+	DefaultFields []string
+	// Aliases maps the names of fields typed by users to the fields of the
+	// documents.
+	Aliases map[string]string
+	// Resolver resolves the fields, rejecting the unknown ones. Fields are used as
+	// typed, with an unknown type, when nil.
+	Resolver Resolver
+}
+
+var (
+	textTypes    = map[string]bool{"text": true, "match_only_text": true}
+	keywordTypes = map[string]bool{"keyword": true, "constant_keyword": true, "wildcard": true}
+	numericTypes = map[string]bool{
+		"long": true, "integer": true, "short": true, "byte": true, "unsigned_long": true,
+		"double": true, "float": true, "half_float": true, "scaled_float": true,
+	}
+)
+
+// node is a parsed expression of a search string.
+type node interface{}
+
+type andNode []node
+
+type orNode []node
+
+type notNode struct {
+	clause node
+}
+
+// valueKind is the kind of a value compared to a field.
+type valueKind int
+
+const (
+	termValue valueKind = iota
+	phraseValue
+	wildcardValue
+	regexpValue
+	fuzzyValue
+	rangeValue
+	existsValue
+)
+
+// valueNode compares a field, the default fields when empty, to a value.
+type valueNode struct {
+	field    string
+	fieldPos int
+	fieldEnd int
+
+	kind  valueKind
+	value string
+	pos   int
+	end   int
+
+	// fuzziness of fuzzy values, and slop of phrases.
+	fuzziness string
+	slop      int64
+
+	// lower and upper bounds of ranges, open when empty.
+	lower, upper           string
+	lowerIncl, upperIncl   bool
+	lowerPos, upperPos     int
+	lowerEnd, upperEnd     int
+	lowerValid, upperValid bool
+}
+
+func (k valueKind) String() string {
+	switch k {
+	case phraseValue:
+		return "phrases"
+	case wildcardValue:
+		return "wildcards"
+	case regexpValue:
+		return "regular expressions"
+	case fuzzyValue:
+		return "fuzzy terms"
+	case rangeValue:
+		return "ranges"
+	case existsValue:
+		return "existence checks"
+	}
+
+	return "terms"
+}
+
+// compiler compiles parsed expressions into queries.
+type compiler struct {
+	ctx context.Context
+	o   Options
+}
+
+func (c compiler) compile(n node) (opensearch.Query, error) {
+	switch n := n.(type) {
+	case nil:
+		return opensearch.Query{Bool: &opensearch.Bool{}}, nil
+	case andNode:
+		clauses, err := c.compileAll(n)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		return allOf(clauses), nil
+	case orNode:
+		clauses, err := c.compileAll(n)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		return anyOf(clauses), nil
+	case notNode:
+		q, err := c.compile(n.clause)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		return opensearch.Query{Bool: &opensearch.Bool{MustNot: []opensearch.Query{q}}}, nil
+	case *valueNode:
+		return c.value(n)
+	}
+
+	return opensearch.Query{}, fmt.Errorf("unexpected node %T", n)
+}
+
+func (c compiler) compileAll(nodes []node) ([]opensearch.Query, error) {
+	var clauses = make([]opensearch.Query, 0, len(nodes))
+
+	for _, n := range nodes {
+		q, err := c.compile(n)
+		if err != nil {
+			return nil, err
+		}
+
+		clauses = append(clauses, q)
+	}
+
+	return clauses, nil
+}
+
+func (c compiler) value(n *valueNode) (opensearch.Query, error) {
+	if n.field != "" {
+		f, err := c.resolve(n.field, n.fieldPos, n.fieldEnd)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		return c.fieldValue(f, n)
+	}
+
+	if len(c.o.DefaultFields) == 0 {
+		switch n.kind {
+		case termValue:
+			return opensearch.Query{MultiMatch: &opensearch.MultiMatch{Query: n.value}}, nil
+		case phraseValue:
+			return opensearch.Query{MultiMatch: &opensearch.MultiMatch{Query: n.value, Type: "phrase"}}, nil
+		}
+
+		return opensearch.Query{}, errorAt(n.pos, n.end, "%s require a field", n.kind)
+	}
+
+	var clauses []opensearch.Query
+
+	for _, name := range c.o.DefaultFields {
+		f, err := c.resolve(name, n.pos, n.end)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		q, err := c.fieldValue(f, n)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		clauses = append(clauses, q)
+	}
+
+	return anyOf(clauses), nil
+}
+
+func (c compiler) resolve(name string, pos, end int) (Field, error) {
+	if alias, ok := c.o.Aliases[name]; ok {
+		name = alias
+	}
+
+	if c.o.Resolver == nil {
+		return Field{Name: name}, nil
+	}
+
+	f, ok, err := c.o.Resolver(c.ctx, name)
+	if err != nil {
+		return Field{}, err
+	}
+
+	if !ok {
+		return Field{}, errorAt(pos, end, "unknown field %s", name)
+	}
+
+	return f, nil
+}
+
+// stringField returns the field to match wildcards, regular expressions and ranges
+// against, the keyword sub-field of text fields.
+func (c compiler) stringField(f Field, n *valueNode) (string, error) {
+	switch {
+	case f.Type == "" || keywordTypes[f.Type]:
+		return f.Name, nil
+	case textTypes[f.Type] && f.Keyword != "":
+		return f.Keyword, nil
+	case textTypes[f.Type]:
+		// Matched against the terms of the analyzed text.
+		return f.Name, nil
+	}
+
+	return "", errorAt(n.pos, n.end, "%s are not supported on %s field %s", n.kind, f.Type, f.Name)
+}
+
+func (c compiler) fieldValue(f Field, n *valueNode) (opensearch.Query, error) {
+	switch n.kind {
+	case existsValue:
+		return opensearch.Query{Exists: map[string]string{"field": f.Name}}, nil
+	case termValue, phraseValue:
+		err := checkValue(f, n.value, n.pos, n.end)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		switch {
+		case n.kind == phraseValue && (f.Type == "" || textTypes[f.Type]):
+			return opensearch.Query{MatchPhrase: map[string]opensearch.MatchPhrase{f.Name: {Query: n.value, Slop: n.slop}}}, nil
+		case f.Type == "" || textTypes[f.Type]:
+			return opensearch.Query{Match: map[string]opensearch.Match{f.Name: {Query: n.value}}}, nil
+		}
+
+		return opensearch.Query{Term: map[string]map[string]interface{}{f.Name: {"value": n.value}}}, nil
+	case wildcardValue:
+		name, err := c.stringField(f, n)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		if prefix := strings.TrimSuffix(n.value, "*"); !strings.ContainsAny(prefix, `*?\`) && prefix != n.value {
+			return opensearch.Query{Prefix: map[string]string{name: prefix}}, nil
+		}
+
+		return opensearch.Query{Wildcard: map[string]map[string]interface{}{name: {"value": n.value}}}, nil
+	case regexpValue:
+		name, err := c.stringField(f, n)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		return opensearch.Query{Regexp: map[string]string{name: n.value}}, nil
+	case fuzzyValue:
+		name, err := c.stringField(f, n)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		return opensearch.Query{Fuzzy: map[string]map[string]interface{}{name: {"value": n.value, "fuzziness": n.fuzziness}}}, nil
+	case rangeValue:
+		name := f.Name
+		if textTypes[f.Type] {
+			if f.Keyword == "" {
+				return opensearch.Query{}, errorAt(n.pos, n.end, "ranges are not supported on text field %s", f.Name)
+			}
+
+			name = f.Keyword
+		}
+
+		var bounds = make(map[string]interface{}, 2)
+
+		if n.lowerValid {
+			err := checkValue(f, n.lower, n.lowerPos, n.lowerEnd)
+			if err != nil {
+				return opensearch.Query{}, err
+			}
+
+			bounds[bound("gt", n.lowerIncl)] = n.lower
+		}
+
+		if n.upperValid {
+			err := checkValue(f, n.upper, n.upperPos, n.upperEnd)
+			if err != nil {
+				return opensearch.Query{}, err
+			}
+
+			bounds[bound("lt", n.upperIncl)] = n.upper
+		}
+
+		if len(bounds) == 0 {
+			return opensearch.Query{Exists: map[string]string{"field": f.Name}}, nil
+		}
+
+		return opensearch.Query{Range: map[string]map[string]interface{}{name: bounds}}, nil
+	}
+
+	return opensearch.Query{}, errorAt(n.pos, n.end, "unsupported value")
+}
+
+func bound(op string, inclusive bool) string {
+	if inclusive {
+		return op + "e"
+	}
+
+	return op
+}
+
+// checkValue verifies that the value suits the type of the field.
+func checkValue(f Field, value string, pos, end int) error {
+	switch {
+	case numericTypes[f.Type]:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return errorAt(pos, end, "%q is not a number, as %s field %s requires", value, f.Type, f.Name)
+		}
+	case f.Type == "boolean":
+		if value != "true" && value != "false" {
+			return errorAt(pos, end, "%q is not true or false, as boolean field %s requires", value, f.Name)
+		}
+	}
+
+	return nil
+}
+
+func allOf(clauses []opensearch.Query) opensearch.Query {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+
+	return opensearch.Query{Bool: &opensearch.Bool{Must: clauses}}
+}
+
+func anyOf(clauses []opensearch.Query) opensearch.Query {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+
+	return opensearch.Query{Bool: &opensearch.Bool{Should: clauses, MinimumShouldMatch: 1}}
+}
@@ -0,0 +1,90 @@
+package querylang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+var testFields = map[string]Field{
+	"message":   {Name: "message", Type: "text", Keyword: "message.keyword"},
+	"user.name": {Name: "user.name", Type: "keyword"},
+	"status":    {Name: "status", Type: "keyword"},
+	"bytes":     {Name: "bytes", Type: "long"},
+	"ok":        {Name: "ok", Type: "boolean"},
+}
+
+var testOptions = Options{
+	Aliases: map[string]string{"user": "user.name"},
+	Resolver: func(_ context.Context, name string) (Field, bool, error) {
+		f, ok := testFields[name]
+		return f, ok, nil
+	},
+}
+
+type parseTest struct {
+	in      string
+	want    string
+	wantPos int
+}
+
+func runParseTests(t *testing.T, parse func(context.Context, string, Options) (opensearch.Query, error), tests []parseTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			q, err := parse(context.Background(), tt.in, testOptions)
+
+			if tt.want == "" {
+				var syntaxErr *SyntaxError
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("error = %v, want a *SyntaxError", err)
+				}
+
+				if syntaxErr.Pos != tt.wantPos {
+					t.Errorf("error at position %d, want %d: %v", syntaxErr.Pos, tt.wantPos, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.Marshal(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("query = %s\nwant    %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLucene(t *testing.T) {
+	runParseTests(t, ParseLucene, []parseTest{
+		{in: `status:failed AND bytes:[1000 TO *]`, want: `{"bool":{"must":[{"term":{"status":{"value":"failed"}}},{"range":{"bytes":{"gte":"1000"}}}]}}`},
+		{in: `status:a || status:b && !status:c`, want: `{"bool":{"should":[{"term":{"status":{"value":"a"}}},{"bool":{"must":[{"term":{"status":{"value":"b"}}},{"bool":{"must_not":[{"term":{"status":{"value":"c"}}}]}}]}}],"minimum_should_match":1}}`},
+		{in: `-status:ok +user:bob`, want: `{"bool":{"must":[{"bool":{"must_not":[{"term":{"status":{"value":"ok"}}}]}},{"term":{"user.name":{"value":"bob"}}}]}}`},
+		{in: `status:(a OR b)`, want: `{"bool":{"should":[{"term":{"status":{"value":"a"}}},{"term":{"status":{"value":"b"}}}],"minimum_should_match":1}}`},
+		{in: `a b`, want: `{"bool":{"must":[{"multi_match":{"query":"a"}},{"multi_match":{"query":"b"}}]}}`},
+		{in: `user:svc_*`, want: `{"prefix":{"user.name":"svc_"}}`},
+		{in: `user:sv?c`, want: `{"wildcard":{"user.name":{"value":"sv?c"}}}`},
+		{in: `user:/sv.*/`, want: `{"regexp":{"user.name":"sv.*"}}`},
+		{in: `user:jon~1`, want: `{"fuzzy":{"user.name":{"fuzziness":"1","value":"jon"}}}`},
+		{in: `message:"access denied"`, want: `{"match_phrase":{"message":{"query":"access denied"}}}`},
+		{in: `message:{a TO b}`, want: `{"range":{"message.keyword":{"gt":"a","lt":"b"}}}`},
+		{in: `bytes:>=10`, want: `{"range":{"bytes":{"gte":"10"}}}`},
+		{in: `_exists_:user`, want: `{"exists":{"field":"user.name"}}`},
+		{in: `nope:x`, wantPos: 0},
+		{in: `bytes:abc`, wantPos: 6},
+		{in: `ok:maybe`, wantPos: 3},
+		{in: `status:(a`, wantPos: 7},
+	})
+}
@@ -0,0 +1,144 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch/dashboard"
+)
+
+// ChartCSV renders a chart as CSV: the columns and rows of tables, and a row per
+// point of the series of other charts, with the series, the time or category of the
+// point and its value.
+func ChartCSV(chart dashboard.Chart) ([]byte, error) {
+	var b bytes.Buffer
+
+	w := csv.NewWriter(&b)
+
+	header, rows := tableOf(chart)
+
+	err := w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.WriteAll(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// tableOf returns the header and rows of a chart.
+func tableOf(chart dashboard.Chart) ([]string, [][]string) {
+	if chart.Kind == dashboard.Table {
+		var rows = make([][]string, len(chart.Rows))
+		for i, row := range chart.Rows {
+			rows[i] = make([]string, len(row))
+			for j, v := range row {
+				if v != nil {
+					rows[i][j] = fmt.Sprint(v)
+				}
+			}
+		}
+
+		return chart.Columns, rows
+	}
+
+	x := "category"
+	if chart.Kind == dashboard.TimeSeries {
+		x = "time"
+	}
+
+	var rows [][]string
+
+	for _, s := range chart.Series {
+		for _, p := range s.Points {
+			at := p.Category
+			if p.Time != nil {
+				at = p.Time.Format(time.RFC3339)
+			}
+
+			rows = append(rows, []string{s.Name, at, fmt.Sprint(p.Value)})
+		}
+	}
+
+	return []string{"series", x, "value"}, rows
+}
+
+var page = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; font-size: 11pt; margin: 2em; }
+h1 { font-size: 16pt; }
+h2 { font-size: 13pt; margin-top: 2em; }
+table { border-collapse: collapse; width: 100%; page-break-inside: auto; }
+tr { page-break-inside: avoid; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+.generated { color: #666; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">Generated {{.Generated}}</p>
+{{range .Charts}}
+<h2>{{.Title}}</h2>
+<table>
+<thead><tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// DashboardHTML renders the charts of a dashboard as an HTML document of tables,
+// styled for printing, e.g. to convert it into PDF with a headless browser.
+func DashboardHTML(d dashboard.Dashboard, charts []dashboard.Chart, generated time.Time) ([]byte, error) {
+	type chartData struct {
+		Title  string
+		Header []string
+		Rows   [][]string
+	}
+
+	title := d.Title
+	if title == "" {
+		title = d.Name
+	}
+
+	data := struct {
+		Title     string
+		Generated string
+		Charts    []chartData
+	}{Title: title, Generated: generated.Format(time.RFC1123)}
+
+	for _, chart := range charts {
+		header, rows := tableOf(chart)
+
+		chartTitle := chart.Title
+		if chartTitle == "" {
+			chartTitle = chart.Name
+		}
+
+		data.Charts = append(data.Charts, chartData{Title: chartTitle, Header: header, Rows: rows})
+	}
+
+	var b bytes.Buffer
+
+	err := page.Execute(&b, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
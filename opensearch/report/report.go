@@ -0,0 +1,230 @@
+// Package report generates reports from saved dashboards on a schedule. Each run
+// executes the data sources of the dashboard, renders the charts as CSV files, one per
+// chart, and optionally as an HTML document to convert into PDF, and hands the files
+// to a sink that stores or dispatches them.
+package report
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch/dashboard"
+	"github.com/threatwinds/go-sdk/schedule"
+)
+
+// Format is a format of the files of a report.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	HTML Format = "html"
+)
+
+// Report is a dashboard generated on a schedule.
+type Report struct {
+	Name      string
+	Dashboard dashboard.Dashboard
+	// Schedule is when the report is generated, e.g. a schedule.Cron.
+	Schedule schedule.Schedule
+	// Formats are the formats rendered, CSV by default.
+	Formats []Format
+	// TenantID scopes the data sources to a tenant.
+	TenantID string
+	// Timeout cancels runs lasting longer, unlimited when zero.
+	Timeout time.Duration
+}
+
+// File is a rendered file of a report.
+type File struct {
+	Report      string
+	Format      Format
+	GeneratedAt time.Time
+	// Name is the file name, the report name, the generation time and, for CSV
+	// files, the chart name, e.g. "weekly-20240603T0800Z-logins.csv".
+	Name string
+	Data []byte
+}
+
+// Sink stores or dispatches the files of a run, e.g. uploading them or attaching
+// them to an email.
+type Sink func(ctx context.Context, files []File) error
+
+// DirSink returns a sink writing the files in dir.
+func DirSink(dir string) Sink {
+	return func(ctx context.Context, files []File) error {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			err = os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o644)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// Generator generates registered reports and hands their files to Sink.
+type Generator struct {
+	Sink Sink
+
+	mu      sync.Mutex
+	reports map[string]Report
+}
+
+// New returns a Generator handing the files to sink.
+func New(sink Sink) *Generator {
+	return &Generator{Sink: sink, reports: make(map[string]Report)}
+}
+
+// Register adds a report. Reports must be registered before Schedule.
+func (g *Generator) Register(r Report) error {
+	if r.Name == "" || r.Schedule == nil || len(r.Dashboard.Sources) == 0 {
+		return fmt.Errorf("report requires name, schedule and dashboard sources")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.reports[r.Name]; ok {
+		return fmt.Errorf("report %s already registered", r.Name)
+	}
+
+	g.reports[r.Name] = r
+
+	return nil
+}
+
+// Schedule adds a job per registered report to s, named "report:" and the report
+// name, so that runs are claimed by a single replica when s has a Claimer.
+func (g *Generator) Schedule(s *schedule.Scheduler) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var names = make([]string, 0, len(g.reports))
+	for name := range g.reports {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		r := g.reports[name]
+
+		err := s.Add(schedule.Job{
+			Name:     "report:" + r.Name,
+			Schedule: r.Schedule,
+			Timeout:  r.Timeout,
+			Task: func(ctx context.Context) error {
+				_, err := g.generate(ctx, r)
+
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Generate generates the named report now, hands its files to the sink and returns
+// them.
+func (g *Generator) Generate(ctx context.Context, name string) ([]File, error) {
+	g.mu.Lock()
+	r, ok := g.reports[name]
+	g.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown report %s", name)
+	}
+
+	return g.generate(ctx, r)
+}
+
+func (g *Generator) generate(ctx context.Context, r Report) ([]File, error) {
+	files, err := Render(ctx, r, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if g.Sink != nil {
+		err = g.Sink(ctx, files)
+		if err != nil {
+			return files, fmt.Errorf("report %s: %w", r.Name, err)
+		}
+	}
+
+	return files, nil
+}
+
+// Render runs the dashboard of the report and renders its files, generated at now.
+func Render(ctx context.Context, r Report, now time.Time) ([]File, error) {
+	charts, err := r.Dashboard.Run(ctx, dashboard.Options{TenantID: r.TenantID})
+	if err != nil {
+		return nil, fmt.Errorf("report %s: %w", r.Name, err)
+	}
+
+	formats := r.Formats
+	if len(formats) == 0 {
+		formats = []Format{CSV}
+	}
+
+	prefix := fileName(r.Name) + "-" + now.Format("20060102T1504Z")
+
+	var files []File
+
+	for _, format := range formats {
+		switch format {
+		case CSV:
+			for _, chart := range charts {
+				data, err := ChartCSV(chart)
+				if err != nil {
+					return nil, fmt.Errorf("report %s: %w", r.Name, err)
+				}
+
+				files = append(files, File{
+					Report:      r.Name,
+					Format:      CSV,
+					GeneratedAt: now,
+					Name:        prefix + "-" + fileName(chart.Name) + ".csv",
+					Data:        data,
+				})
+			}
+		case HTML:
+			data, err := DashboardHTML(r.Dashboard, charts, now)
+			if err != nil {
+				return nil, fmt.Errorf("report %s: %w", r.Name, err)
+			}
+
+			files = append(files, File{Report: r.Name, Format: HTML, GeneratedAt: now, Name: prefix + ".html", Data: data})
+		default:
+			return nil, fmt.Errorf("report %s: unknown format %q", r.Name, format)
+		}
+	}
+
+	return files, nil
+}
+
+// fileName replaces the characters of name not safe in file names.
+func fileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+
+		return '_'
+	}, name)
+}
@@ -0,0 +1,138 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/threatwinds/go-sdk/helpers"
+)
+
+// StatusError is returned when the search engine answers with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+	// RequestID is the request ID of the failed request context, if any.
+	RequestID string
+}
+
+func (e *StatusError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("search engine status %d, request %s, response: %s", e.StatusCode, e.RequestID, e.Body)
+	}
+
+	return fmt.Sprintf("search engine status %d, response: %s", e.StatusCode, e.Body)
+}
+
+// statusError returns the *StatusError of a response, with the request ID of ctx.
+func statusError(ctx context.Context, statusCode int, body []byte) error {
+	return &StatusError{StatusCode: statusCode, Body: body, RequestID: helpers.RequestIDFromContext(ctx)}
+}
+
+// Do sends a request to the search engine and returns the response body. It covers the
+// endpoints not exposed by opensearchapi, like search pipelines or plugin APIs. The body
+// can be nil, a []byte, an io.Reader, or any value that can be marshalled to JSON.
+// Responses with a non-2xx status code are returned as a *StatusError. Requests whose
+// context has a time budget, set with WithTimeBudget, are retried within it.
+//
+// The indices the path targets are checked against the ACL set by SetIndexACL.
+// Cluster-level paths are allowed, except those reaching every index, checked as "*",
+// and those naming their indices in the body, like _bulk, _reindex or _plugins/_sql,
+// which are rejected unless the ACL allows every index.
+func Do(ctx context.Context, method, path string, params url.Values, body interface{}) ([]byte, error) {
+	path, err := checkPathAccess(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return do(ctx, method, path, params, body)
+}
+
+// do sends a request like Do without checking the ACL, for the functions checking
+// the indices of the request themselves.
+func do(ctx context.Context, method, path string, params url.Values, body interface{}) ([]byte, error) {
+	var payload []byte
+	var stream io.Reader
+
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		payload = b
+	case io.Reader:
+		stream = b
+	default:
+		j, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		payload = j
+	}
+
+	hasBody := body != nil
+
+	if len(params) != 0 {
+		path += "?" + params.Encode()
+	}
+
+	budget := budgetFrom(ctx)
+	if budget == nil {
+		if stream == nil && hasBody {
+			stream = bytes.NewReader(payload)
+		}
+
+		return doOnce(ctx, method, path, stream)
+	}
+
+	// Bodies are replayed on retries.
+	if stream != nil {
+		var err error
+
+		payload, err = io.ReadAll(stream)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return budget.do(ctx, func(ctx context.Context) ([]byte, error) {
+		var reader io.Reader
+		if hasBody {
+			reader = bytes.NewReader(payload)
+		}
+
+		return doOnce(ctx, method, path, reader)
+	})
+}
+
+// doOnce sends a request once.
+func doOnce(ctx context.Context, method, path string, reader io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, statusError(ctx, resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
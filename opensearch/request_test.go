@@ -0,0 +1,43 @@
+package opensearch_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/helpers"
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+func TestStatusErrorRequestID(t *testing.T) {
+	seed(t)
+
+	ctx := helpers.WithRequestID(context.Background(), "req-1")
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "index", call: func() error {
+			return opensearch.IndexDoc(ctx, map[string]interface{}{"n": 1}, "logs-a", "1")
+		}},
+		{name: "delete", call: func() error {
+			return opensearch.Hit{Index: "logs-a", ID: "missing"}.Delete(ctx)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var statusErr *opensearch.StatusError
+
+			err := tt.call()
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("error = %v, want a *StatusError", err)
+			}
+
+			if statusErr.RequestID != "req-1" {
+				t.Errorf("request ID = %q, want req-1", statusErr.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// Package retention enforces data retention periods, deleting the documents older
+// than the retention of their index, either periodically from the service with
+// delete_by_query or by installing Index State Management policies.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Policy is the retention period of the documents of the indices matching Index.
+type Policy struct {
+	Index  string
+	MaxAge time.Duration
+	// Field is the date field compared to MaxAge, "@timestamp" by default.
+	Field string
+	// Filter optionally restricts the deleted documents, e.g. to a tenant.
+	Filter *opensearch.Query
+}
+
+// Report is the outcome of enforcing a policy. In dry runs Deleted is zero and
+// Matched is the number of documents that would be deleted.
+type Report struct {
+	Index   string
+	Before  time.Time
+	Matched int64
+	Deleted int64
+	DryRun  bool
+}
+
+// Enforcer deletes the documents exceeding the retention of their policy.
+type Enforcer struct {
+	Policies []Policy
+	// DryRun only counts the documents to delete.
+	DryRun bool
+}
+
+// Enforce applies every policy once and returns their reports. It continues with the
+// next policies when one fails and returns the first error.
+func (e Enforcer) Enforce(ctx context.Context) ([]Report, error) {
+	var reports = make([]Report, 0, len(e.Policies))
+	var firstErr error
+
+	for _, p := range e.Policies {
+		report, err := e.enforce(ctx, p, time.Now().UTC())
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("retention of %s: %w", p.Index, err)
+			}
+			continue
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, firstErr
+}
+
+// Run calls Enforce every interval until ctx is done, passing each outcome to report,
+// which may be nil. It returns an error only when interval is not positive.
+func (e Enforcer) Run(ctx context.Context, interval time.Duration, report func([]Report, error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("retention interval must be positive, got %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		reports, err := e.Enforce(ctx)
+		if report != nil {
+			report(reports, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e Enforcer) enforce(ctx context.Context, p Policy, now time.Time) (Report, error) {
+	if p.MaxAge <= 0 {
+		return Report{}, fmt.Errorf("retention period must be positive")
+	}
+
+	field := p.Field
+	if field == "" {
+		field = "@timestamp"
+	}
+
+	before := now.Add(-p.MaxAge)
+
+	var query = opensearch.Query{Bool: &opensearch.Bool{
+		Filter: []opensearch.Query{{Range: map[string]map[string]interface{}{
+			field: {"lt": before.Format(time.RFC3339Nano)},
+		}}},
+	}}
+
+	if p.Filter != nil {
+		query.Bool.Filter = append(query.Bool.Filter, *p.Filter)
+	}
+
+	var report = Report{Index: p.Index, Before: before, DryRun: e.DryRun}
+
+	if e.DryRun {
+		result, err := opensearch.SearchRequest{Query: &query, TrackTotalHits: true}.
+			WithoutTenantScope().IncludeDeleted().SearchIn(ctx, []string{p.Index})
+		if err != nil {
+			return Report{}, err
+		}
+
+		report.Matched = result.Hits.Total.Value
+
+		return report, nil
+	}
+
+	result, err := opensearch.DeleteByQuery(ctx, []string{p.Index}, query)
+
+	report.Matched, report.Deleted = result.Total, result.Deleted
+
+	return report, err
+}
+
+// ISMPolicy returns an Index State Management policy deleting whole indices once they
+// are older than the retention of p, applied automatically to new indices matching
+// p.Index. It is cheaper than delete_by_query for time-based indices, like the daily
+// indices built by opensearch.BuildIndex, but ignores Field and Filter.
+func ISMPolicy(p Policy) map[string]interface{} {
+	return map[string]interface{}{
+		"policy": map[string]interface{}{
+			"description":   fmt.Sprintf("Delete %s indices after %s", p.Index, p.MaxAge),
+			"default_state": "retained",
+			"states": []map[string]interface{}{
+				{
+					"name":    "retained",
+					"actions": []interface{}{},
+					"transitions": []map[string]interface{}{
+						{
+							"state_name": "deleted",
+							"conditions": map[string]interface{}{"min_index_age": ismAge(p.MaxAge)},
+						},
+					},
+				},
+				{
+					"name":        "deleted",
+					"actions":     []map[string]interface{}{{"delete": map[string]interface{}{}}},
+					"transitions": []interface{}{},
+				},
+			},
+			"ism_template": []map[string]interface{}{
+				{"index_patterns": []string{p.Index}},
+			},
+		},
+	}
+}
+
+// PutISMPolicy creates or replaces the ISM policy with the given ID built by ISMPolicy.
+// A policy is replaced only if it didn't change since it was read, otherwise the
+// *opensearch.StatusError of the version conflict is returned.
+func PutISMPolicy(ctx context.Context, id string, p Policy) error {
+	path := "/_plugins/_ism/policies/" + url.PathEscape(id)
+
+	// ISM requires the version of the policy it replaces.
+	var params url.Values
+
+	resp, err := opensearch.Do(ctx, http.MethodGet, path, nil, nil)
+
+	var status *opensearch.StatusError
+
+	switch {
+	case errors.As(err, &status) && status.StatusCode == http.StatusNotFound:
+	case err != nil:
+		return err
+	default:
+		var current struct {
+			SeqNo       int64 `json:"_seq_no"`
+			PrimaryTerm int64 `json:"_primary_term"`
+		}
+
+		err = json.Unmarshal(resp, &current)
+		if err != nil {
+			return err
+		}
+
+		params = url.Values{
+			"if_seq_no":       {strconv.FormatInt(current.SeqNo, 10)},
+			"if_primary_term": {strconv.FormatInt(current.PrimaryTerm, 10)},
+		}
+	}
+
+	_, err = opensearch.Do(ctx, http.MethodPut, path, params, ISMPolicy(p))
+
+	return err
+}
+
+// ismAge formats d in the largest unit dividing it, as ISM expects, e.g. "30d".
+func ismAge(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
@@ -0,0 +1,279 @@
+// Package rollup manages Index Rollup jobs, which summarize old documents into buckets
+// of a rollup index so that long retention periods stay affordable, and routes the
+// aggregations of a time range to the rollup index once the raw documents expire.
+package rollup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Job is an Index Rollup job, bucketing the documents of SourceIndex by time and
+// Dimensions into TargetIndex, with the Metrics of each bucket.
+type Job struct {
+	ID          string
+	Description string
+	SourceIndex string
+	TargetIndex string
+	// Every is how often the job runs, one hour by default, in whole minutes.
+	Every time.Duration
+	// TimestampField is the time dimension, "@timestamp" by default, bucketed by
+	// FixedInterval, e.g. "1h", in TimeZone, UTC by default.
+	TimestampField string
+	FixedInterval  string
+	TimeZone       string
+	// Dimensions are the keyword fields buckets are split by.
+	Dimensions []string
+	// Metrics are the metrics kept for each field: "avg", "sum", "min", "max" or
+	// "value_count".
+	Metrics map[string][]string
+	// PageSize is the number of buckets per search of the job, 1000 by default.
+	PageSize int
+	// Delay postpones rolling up the buckets of the last Delay, for late documents.
+	Delay time.Duration
+	// Continuous keeps rolling up new documents rather than stopping after the
+	// existing ones.
+	Continuous bool
+	// Disabled creates the job without starting it.
+	Disabled bool
+}
+
+// Body returns the job as expected by the rollup API.
+func Body(j Job) map[string]interface{} {
+	if j.TimestampField == "" {
+		j.TimestampField = "@timestamp"
+	}
+
+	if j.TimeZone == "" {
+		j.TimeZone = "UTC"
+	}
+
+	if j.PageSize <= 0 {
+		j.PageSize = 1000
+	}
+
+	every := int64(j.Every / time.Minute)
+	if j.Every <= 0 {
+		every = 60
+	} else if every < 1 {
+		every = 1
+	}
+
+	var dimensions = []map[string]interface{}{
+		{"date_histogram": map[string]interface{}{
+			"source_field":   j.TimestampField,
+			"fixed_interval": j.FixedInterval,
+			"timezone":       j.TimeZone,
+		}},
+	}
+
+	for _, d := range j.Dimensions {
+		dimensions = append(dimensions, map[string]interface{}{"terms": map[string]interface{}{"source_field": d}})
+	}
+
+	var metrics = make([]map[string]interface{}, 0, len(j.Metrics))
+
+	for _, field := range sortedKeys(j.Metrics) {
+		var m = make([]map[string]interface{}, len(j.Metrics[field]))
+		for i, name := range j.Metrics[field] {
+			m[i] = map[string]interface{}{name: map[string]interface{}{}}
+		}
+
+		metrics = append(metrics, map[string]interface{}{"source_field": field, "metrics": m})
+	}
+
+	return map[string]interface{}{
+		"rollup": map[string]interface{}{
+			"description":  j.Description,
+			"source_index": j.SourceIndex,
+			"target_index": j.TargetIndex,
+			"enabled":      !j.Disabled,
+			"schedule": map[string]interface{}{
+				"interval": map[string]interface{}{
+					"start_time": time.Now().UnixMilli(),
+					"period":     every,
+					"unit":       "Minutes",
+				},
+			},
+			"page_size":  j.PageSize,
+			"delay":      j.Delay.Milliseconds(),
+			"continuous": j.Continuous,
+			"dimensions": dimensions,
+			"metrics":    metrics,
+		},
+	}
+}
+
+func jobPath(id string) string {
+	return "/_plugins/_rollup/jobs/" + url.PathEscape(id)
+}
+
+// Create creates the job. Jobs can't be changed once created; delete and create them
+// again instead.
+func Create(ctx context.Context, j Job) error {
+	if j.ID == "" || j.SourceIndex == "" || j.TargetIndex == "" || j.FixedInterval == "" {
+		return fmt.Errorf("rollup job requires ID, source index, target index and fixed interval")
+	}
+
+	_, err := opensearch.Do(ctx, http.MethodPut, jobPath(j.ID), nil, Body(j))
+
+	return err
+}
+
+// Start starts a stopped or disabled job.
+func Start(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodPost, jobPath(id)+"/_start", nil, nil)
+
+	return err
+}
+
+// Stop stops a job. Its progress is kept, to resume with Start.
+func Stop(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodPost, jobPath(id)+"/_stop", nil, nil)
+
+	return err
+}
+
+// Delete deletes a job. The rollup index is kept.
+func Delete(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodDelete, jobPath(id), nil, nil)
+
+	return err
+}
+
+// Explanation is the progress of a job.
+type Explanation struct {
+	ID string
+	// Status is "init", "started", "stopped", "finished", "failed" or "retry".
+	Status        string
+	FailureReason string
+	LastUpdated   time.Time
+	// NextWindowStart and NextWindowEnd bound the next documents rolled up by
+	// continuous jobs.
+	NextWindowStart    time.Time
+	NextWindowEnd      time.Time
+	PagesProcessed     int64
+	DocumentsProcessed int64
+	RollupsIndexed     int64
+	IndexTime          time.Duration
+	SearchTime         time.Duration
+}
+
+// Explain returns the progress of a job, with a zero Status if it hasn't run yet.
+func Explain(ctx context.Context, id string) (Explanation, error) {
+	resp, err := opensearch.Do(ctx, http.MethodGet, jobPath(id)+"/_explain", nil, nil)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	var result map[string]*struct {
+		Metadata *struct {
+			Status        string `json:"status"`
+			FailureReason string `json:"failure_reason"`
+			LastUpdated   int64  `json:"last_updated_time"`
+			Continuous    *struct {
+				NextWindowStart int64 `json:"next_window_start_time"`
+				NextWindowEnd   int64 `json:"next_window_end_time"`
+			} `json:"continuous"`
+			Stats struct {
+				PagesProcessed     int64 `json:"pages_processed"`
+				DocumentsProcessed int64 `json:"documents_processed"`
+				RollupsIndexed     int64 `json:"rollups_indexed"`
+				IndexTimeInMillis  int64 `json:"index_time_in_millis"`
+				SearchTimeInMillis int64 `json:"search_time_in_millis"`
+			} `json:"stats"`
+		} `json:"rollup_metadata"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	job, ok := result[id]
+	if !ok || job == nil {
+		return Explanation{}, fmt.Errorf("rollup job %s not found", id)
+	}
+
+	e := Explanation{ID: id}
+
+	if m := job.Metadata; m != nil {
+		e.Status = m.Status
+		e.FailureReason = m.FailureReason
+		e.PagesProcessed = m.Stats.PagesProcessed
+		e.DocumentsProcessed = m.Stats.DocumentsProcessed
+		e.RollupsIndexed = m.Stats.RollupsIndexed
+		e.IndexTime = time.Duration(m.Stats.IndexTimeInMillis) * time.Millisecond
+		e.SearchTime = time.Duration(m.Stats.SearchTimeInMillis) * time.Millisecond
+
+		if m.LastUpdated != 0 {
+			e.LastUpdated = epoch(m.LastUpdated)
+		}
+
+		if m.Continuous != nil {
+			e.NextWindowStart = time.UnixMilli(m.Continuous.NextWindowStart).UTC()
+			e.NextWindowEnd = time.UnixMilli(m.Continuous.NextWindowEnd).UTC()
+		}
+	}
+
+	return e, nil
+}
+
+// epoch converts the last update time, reported in seconds by some versions and in
+// milliseconds by others.
+func epoch(t int64) time.Time {
+	if t < 1e11 {
+		return time.Unix(t, 0).UTC()
+	}
+
+	return time.UnixMilli(t).UTC()
+}
+
+// Target routes searches between raw indices and the rollup index holding their older
+// buckets.
+type Target struct {
+	Raw    []string
+	Rollup string
+	// RawRetention is how long raw documents are kept. Searches starting earlier are
+	// served by the rollup index.
+	RawRetention time.Duration
+}
+
+// Indices returns the indices to search for a time range starting at from, the rollup
+// index when raw documents from then have expired.
+func (t Target) Indices(from time.Time) []string {
+	if t.Rollup != "" && t.RawRetention > 0 && from.Before(time.Now().Add(-t.RawRetention)) {
+		return []string{t.Rollup}
+	}
+
+	return t.Raw
+}
+
+// SearchIn searches the indices holding the documents since from. Only aggregations
+// over the dimensions and metrics of the rollup job can be served by the rollup index,
+// so requests for hits always search the raw indices.
+func (t Target) SearchIn(ctx context.Context, q opensearch.SearchRequest, from time.Time) (opensearch.SearchResult, error) {
+	if q.Size != 0 {
+		return q.SearchIn(ctx, t.Raw)
+	}
+
+	return q.SearchIn(ctx, t.Indices(from))
+}
+
+func sortedKeys(m map[string][]string) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,91 @@
+package opensearch
+
+import (
+	"context"
+)
+
+type FunctionScore struct {
+	Query     *Query          `json:"query,omitempty"`
+	Functions []ScoreFunction `json:"functions,omitempty"`
+	ScoreMode string          `json:"score_mode,omitempty"`
+	BoostMode string          `json:"boost_mode,omitempty"`
+	MaxBoost  float64         `json:"max_boost,omitempty"`
+	MinScore  *float64        `json:"min_score,omitempty"`
+}
+
+type ScoreFunction struct {
+	Filter           *Query            `json:"filter,omitempty"`
+	Weight           *float64          `json:"weight,omitempty"`
+	RandomScore      *RandomScore      `json:"random_score,omitempty"`
+	FieldValueFactor *FieldValueFactor `json:"field_value_factor,omitempty"`
+	ScriptScore      *ScriptScore      `json:"script_score,omitempty"`
+}
+
+type RandomScore struct {
+	Seed  interface{} `json:"seed,omitempty"`
+	Field string      `json:"field,omitempty"`
+}
+
+type FieldValueFactor struct {
+	Field    string   `json:"field"`
+	Factor   float64  `json:"factor,omitempty"`
+	Modifier string   `json:"modifier,omitempty"`
+	Missing  *float64 `json:"missing,omitempty"`
+}
+
+type ScriptScore struct {
+	Script Script `json:"script"`
+}
+
+// SampleOptions configure Sample.
+type SampleOptions struct {
+	// Seed makes the sample reproducible: the same seed selects the same documents
+	// as long as the indices don't change.
+	Seed int64
+	// Weight is a numeric field making documents more likely to be sampled in
+	// proportion to its value. Documents without it weigh 1, and those weighing zero
+	// or less are never sampled.
+	Weight string
+}
+
+// weightedSampleScript scores documents with the keys of the Efraimidis-Spirakis
+// weighted sampling, u^(1/w), so that the n highest keys are a weighted sample.
+const weightedSampleScript = `double w = doc[params.field].size() == 0 ? 1.0 : doc[params.field].value;
+if (w <= 0) { return 0; }
+return Math.pow(randomScore(params.seed, '_seq_no'), 1.0 / w);`
+
+// Sample returns a copy of the request returning a random sample of n of the matching
+// documents, instead of the most relevant ones, e.g. for data-quality checks of huge
+// result sets. Documents are scored by a seeded random_score function, ignoring their
+// relevance, and the sorts, the pagination and the collapse of the request are
+// dropped, since they would bias the sample.
+func (q SearchRequest) Sample(n int64, o SampleOptions) SearchRequest {
+	var fn ScoreFunction
+
+	if o.Weight == "" {
+		fn.RandomScore = &RandomScore{Seed: o.Seed, Field: "_seq_no"}
+	} else {
+		fn.ScriptScore = &ScriptScore{Script: Script{
+			Lang:   "painless",
+			Source: weightedSampleScript,
+			Params: map[string]interface{}{"seed": o.Seed, "field": o.Weight},
+		}}
+	}
+
+	q.Query = &Query{FunctionScore: &FunctionScore{
+		Query:     q.Query,
+		Functions: []ScoreFunction{fn},
+		BoostMode: "replace",
+	}}
+
+	q.Size, q.From = n, 0
+	q.Sort, q.SearchAfter, q.Collapse = nil, nil, nil
+
+	return q
+}
+
+// SampleIn searches a random sample of n of the documents of the indices matching
+// the request, as Sample describes.
+func (q SearchRequest) SampleIn(ctx context.Context, index []string, n int64, o SampleOptions) (SearchResult, error) {
+	return q.Sample(n, o).SearchIn(ctx, index)
+}
@@ -1,11 +1,16 @@
 package opensearch
 
+import "time"
+
 type SearchResult struct {
-	Took         int64                  `json:"took"`
-	TimedOut     bool                   `json:"timed_out"`
-	Shards       Shards                 `json:"_shards"`
-	Hits         Hits                   `json:"hits"`
-	Aggregations map[string]interface{} `json:"aggregations"`
+	Took           int64                  `json:"took"`
+	TimedOut       bool                   `json:"timed_out"`
+	Shards         Shards                 `json:"_shards"`
+	Hits           Hits                   `json:"hits"`
+	Aggregations   map[string]interface{} `json:"aggregations"`
+	SkippedIndices []string               `json:"-"`
+
+	page pageInfo
 }
 
 type Hits struct {
@@ -20,11 +25,19 @@ type Hit struct {
 	Index   string                 `json:"_index"`
 	ID      string                 `json:"_id"`
 	Version int64                  `json:"_version"`
+	Routing string                 `json:"_routing,omitempty"`
 	Score   interface{}            `json:"_score"`
 	Source  HitSource              `json:"_source"`
 	Fields  map[string]interface{} `json:"fields"`
-	Sort    []int64                `json:"sort"`
-	Found   bool                   `json:"found,omitempty"`
+	// Sort holds the sort values of the hit when they are all integers, and
+	// SortValues all of them, e.g. the strings of keyword fields.
+	Sort       []int64    `json:"sort"`
+	SortValues SortValues `json:"-"`
+	Found      bool       `json:"found,omitempty"`
+	// SeqNo and PrimaryTerm identify the revision of the document, returned by GetDoc
+	// and by searches with SeqNoPrimaryTerm.
+	SeqNo       int64 `json:"_seq_no,omitempty"`
+	PrimaryTerm int64 `json:"_primary_term,omitempty"`
 }
 
 type Total struct {
@@ -33,10 +46,23 @@ type Total struct {
 }
 
 type Shards struct {
-	Total      int64 `json:"total"`
-	Successful int64 `json:"successful"`
-	Skipped    int64 `json:"skipped"`
-	Failed     int64 `json:"failed"`
+	Total      int64          `json:"total"`
+	Successful int64          `json:"successful"`
+	Skipped    int64          `json:"skipped"`
+	Failed     int64          `json:"failed"`
+	Failures   []ShardFailure `json:"failures,omitempty"`
+}
+
+type ShardFailure struct {
+	Shard  int64              `json:"shard"`
+	Index  string             `json:"index"`
+	Node   string             `json:"node"`
+	Reason ShardFailureReason `json:"reason"`
+}
+
+type ShardFailureReason struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
 }
 
 type SearchRequest struct {
@@ -47,10 +73,58 @@ type SearchRequest struct {
 	StoredFields []string                            `json:"stored_fields,omitempty"`
 	Source       *Source                             `json:"_source,omitempty"`
 	Query        *Query                              `json:"query,omitempty"`
+	PostFilter   *Query                              `json:"post_filter,omitempty"`
 	Collapse     *Collapse                           `json:"collapse,omitempty"`
 	Aggs         map[string]Aggs                     `json:"aggs,omitempty"`
 	SearchAfter  []int64                             `json:"search_after,omitempty"`
 	ScriptFields interface{}                         `json:"script_fields,omitempty"`
+	// IndexBoosts multiply the scores of the hits of the indices, the first entry
+	// matching an index applying, see IndicesBoost.
+	IndexBoosts []map[string]float64 `json:"indices_boost,omitempty"`
+	// TerminateAfter stops collecting the documents of each shard after that many.
+	TerminateAfter int64 `json:"terminate_after,omitempty"`
+	// TrackTotalHits is true to count every hit, false to skip counting them, or the
+	// number of hits counted accurately, 10000 by default.
+	TrackTotalHits interface{} `json:"track_total_hits,omitempty"`
+	// SeqNoPrimaryTerm returns the sequence number and primary term of the hits, to
+	// update them only if they didn't change since.
+	SeqNoPrimaryTerm bool `json:"seq_no_primary_term,omitempty"`
+	// SearchAfterValues are the sort values to search after when they are not all
+	// integers, e.g. Hit.SortValues. They take precedence over SearchAfter.
+	SearchAfterValues SortValues `json:"-"`
+
+	// Pipeline is the search pipeline processing the request, see SearchPipeline.
+	Pipeline                  string `json:"-"`
+	Routing                   string `json:"-"`
+	FailOnPartialResults      bool   `json:"-"`
+	RetryWithoutFailedIndices bool   `json:"-"`
+	DedupeBy                  string `json:"-"`
+	TenantID                  string `json:"-"`
+	BypassTenantScope         bool   `json:"-"`
+
+	// ExpandWildcards is the kind of indices wildcard expressions match, "open" by
+	// default, or "all", "closed", "hidden" and "none", comma-separated.
+	ExpandWildcards string `json:"-"`
+	// IgnoreUnavailable skips missing and closed indices instead of failing.
+	IgnoreUnavailable bool `json:"-"`
+	// AllowNoIndices, when set to false, fails searches whose wildcard expressions
+	// match no index. The cluster allows them by default.
+	AllowNoIndices *bool `json:"-"`
+	// KeepIndices searches the indices as given, without the index pruner set by
+	// SetIndexPruner.
+	KeepIndices bool `json:"-"`
+	// Cancellable cancels the search task in the cluster when the context is done
+	// before the search returns, e.g. when a UI tab is closed, instead of only closing
+	// the connection while the cluster keeps searching.
+	Cancellable bool `json:"-"`
+	// TimeBudget bounds the search, across the retries of its requests, as
+	// WithTimeBudget does, unless the context already has a budget.
+	TimeBudget time.Duration `json:"-"`
+	// IncludeSoftDeleted searches soft-deleted documents too, see IncludeDeleted.
+	IncludeSoftDeleted bool `json:"-"`
+	// ConstantScoring wraps filter-only queries in constant_score, see
+	// ConstantScoreMode.
+	ConstantScoring bool `json:"-"`
 }
 
 type Collapse struct {
@@ -146,15 +220,32 @@ type DateRange struct {
 }
 
 type Terms struct {
-	Field       string `json:"field,omitempty"`
-	Size        int64  `json:"size,omitempty"`
-	Missing     string `json:"missing,omitempty"`
-	MinDocCount int64  `json:"min_doc_count,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Missing string `json:"missing,omitempty"`
+	// ShardSize is the number of terms collected per shard, larger than Size by
+	// default, reducing the approximation error of the counts.
+	ShardSize int64 `json:"shard_size,omitempty"`
+	// MinDocCount is the number of documents a term needs to be returned, 1 by
+	// default. ShardMinDocCount is the number each shard needs to collect a term.
+	MinDocCount      int64  `json:"min_doc_count,omitempty"`
+	ShardMinDocCount *int64 `json:"shard_min_doc_count,omitempty"`
+	// ZeroMinDocCount sends a MinDocCount of zero, returning terms without documents
+	// too.
+	ZeroMinDocCount bool `json:"-"`
+	// ShowTermDocCountError returns the DocCountErrorUpperBound of each bucket.
+	ShowTermDocCountError bool `json:"show_term_doc_count_error,omitempty"`
 }
 
 type Histogram struct {
-	Field    string      `json:"field,omitempty"`
-	Interval interface{} `json:"interval,omitempty"`
+	Field            string                 `json:"field,omitempty"`
+	Interval         interface{}            `json:"interval,omitempty"`
+	CalendarInterval string                 `json:"calendar_interval,omitempty"`
+	FixedInterval    string                 `json:"fixed_interval,omitempty"`
+	TimeZone         string                 `json:"time_zone,omitempty"`
+	Format           string                 `json:"format,omitempty"`
+	MinDocCount      *int64                 `json:"min_doc_count,omitempty"`
+	ExtendedBounds   map[string]interface{} `json:"extended_bounds,omitempty"`
 }
 
 type MultiTerms struct {
@@ -174,6 +265,9 @@ type PercentileRanks struct {
 type Agg struct {
 	Field string `json:"field,omitempty"`
 	Size  int64  `json:"size,omitempty"`
+	// SignificantTermsParams are only set on significant_terms aggregations, see
+	// SignificantTermsAgg.
+	*SignificantTermsParams
 }
 
 type ExtendedStats struct {
@@ -204,6 +298,49 @@ type Query struct {
 	MatchPhrasePrefix map[string]MatchPhrasePrefix      `json:"match_phrase_prefix,omitempty"`
 	QueryString       *QueryString                      `json:"query_string,omitempty"`
 	SimpleQueryString *SimpleQueryString                `json:"simple_query_string,omitempty"`
+	KNN               map[string]KNN                    `json:"knn,omitempty"`
+	HasChild          *HasChild                         `json:"has_child,omitempty"`
+	HasParent         *HasParent                        `json:"has_parent,omitempty"`
+	ParentID          *ParentID                         `json:"parent_id,omitempty"`
+	Intervals         map[string]IntervalsRule          `json:"intervals,omitempty"`
+	MoreLikeThis      *MoreLikeThis                     `json:"more_like_this,omitempty"`
+	FunctionScore     *FunctionScore                    `json:"function_score,omitempty"`
+	ConstantScore     *ConstantScore                    `json:"constant_score,omitempty"`
+	DisMax            *DisMax                           `json:"dis_max,omitempty"`
+}
+
+type HasChild struct {
+	Type           string                 `json:"type"`
+	Query          Query                  `json:"query"`
+	ScoreMode      string                 `json:"score_mode,omitempty"`
+	MinChildren    int64                  `json:"min_children,omitempty"`
+	MaxChildren    int64                  `json:"max_children,omitempty"`
+	IgnoreUnmapped bool                   `json:"ignore_unmapped,omitempty"`
+	InnerHits      map[string]interface{} `json:"inner_hits,omitempty"`
+}
+
+type HasParent struct {
+	ParentType     string                 `json:"parent_type"`
+	Query          Query                  `json:"query"`
+	Score          bool                   `json:"score,omitempty"`
+	IgnoreUnmapped bool                   `json:"ignore_unmapped,omitempty"`
+	InnerHits      map[string]interface{} `json:"inner_hits,omitempty"`
+}
+
+type ParentID struct {
+	Type           string `json:"type"`
+	ID             string `json:"id"`
+	IgnoreUnmapped bool   `json:"ignore_unmapped,omitempty"`
+}
+
+type KNN struct {
+	Vector           []float32              `json:"vector"`
+	K                int                    `json:"k,omitempty"`
+	Filter           *Query                 `json:"filter,omitempty"`
+	MethodParameters map[string]interface{} `json:"method_parameters,omitempty"`
+	Boost            float64                `json:"boost,omitempty"`
+	MaxDistance      *float64               `json:"max_distance,omitempty"`
+	MinScore         *float64               `json:"min_score,omitempty"`
 }
 
 type Bool struct {
@@ -4,44 +4,81 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
 )
 
 func (q SearchRequest) SearchIn(ctx context.Context, index []string) (SearchResult, error) {
+	result, err := q.searchIn(ctx, index)
+
+	recordAudit(ctx, "search", index, "", q.Query, q.TenantID, err)
+
+	return result, err
+}
+
+func (q SearchRequest) searchIn(ctx context.Context, index []string) (SearchResult, error) {
+	if q.TimeBudget > 0 && budgetFrom(ctx) == nil {
+		var cancel context.CancelFunc
+
+		ctx, cancel = WithTimeBudget(ctx, RetryBudget{Budget: q.TimeBudget})
+		defer cancel()
+	}
+
 	if q.Source == nil {
 		q.Source = new(Source)
 	}
 
-	j, err := json.Marshal(q)
+	index, err := q.prepare(ctx, index)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	result, err := q.search(ctx, index)
 	if err != nil {
 		return SearchResult{}, err
 	}
 
-	reader := strings.NewReader(string(j))
+	return q.complete(ctx, index, result)
+}
 
-	req := opensearchapi.SearchRequest{
-		Index: index,
-		Body:  reader,
+// prepare prunes the indices and checks them against the ACL, checks the fields of the
+// request, and applies the constant scoring, the tenant scope and the soft-delete
+// filter to its query. It returns the indices to search.
+func (q *SearchRequest) prepare(ctx context.Context, index []string) ([]string, error) {
+	index = q.pruneIndices(index)
+
+	index, err := checkIndexAccess(index...)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := req.Do(ctx, client)
+	err = q.checkUnknownFields(ctx, index)
 	if err != nil {
-		return SearchResult{}, err
+		return nil, err
 	}
 
-	defer resp.Body.Close()
+	q.constantScore()
 
-	body, err := io.ReadAll(resp.Body)
+	err = q.scopeTenant()
 	if err != nil {
-		return SearchResult{}, err
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return SearchResult{}, fmt.Errorf("search engine status %d, response: %s", resp.StatusCode, body)
+	q.excludeDeleted()
+
+	return index, nil
+}
+
+func (q SearchRequest) search(ctx context.Context, index []string) (SearchResult, error) {
+	ctx, stop := cancelOnDone(ctx, q.Cancellable)
+	defer stop()
+
+	body, err := Do(ctx, http.MethodPost, searchPath(index), q.params(), q)
+	if err != nil {
+		return SearchResult{}, err
 	}
 
 	var result SearchResult
@@ -51,5 +88,151 @@ func (q SearchRequest) SearchIn(ctx context.Context, index []string) (SearchResu
 		return SearchResult{}, err
 	}
 
+	result.page = q.pageInfo(result)
+
 	return result, nil
 }
+
+// failedIndices returns the distinct indices referenced by the shard failures.
+func failedIndices(failures []ShardFailure) []string {
+	var seen = make(map[string]bool)
+	var indices []string
+
+	for _, f := range failures {
+		if f.Index == "" || seen[f.Index] {
+			continue
+		}
+
+		seen[f.Index] = true
+		indices = append(indices, f.Index)
+	}
+
+	return indices
+}
+
+// excludeIndices removes the excluded indices from the index list. Concrete names are
+// dropped, and exclusions are appended when the list contains wildcard expressions.
+func excludeIndices(index, excluded []string) []string {
+	var skip = make(map[string]bool, len(excluded))
+	for _, name := range excluded {
+		skip[name] = true
+	}
+
+	var reduced = make([]string, 0, len(index)+len(excluded))
+	var wildcards bool
+
+	for _, name := range index {
+		if skip[name] {
+			continue
+		}
+
+		if strings.Contains(name, "*") {
+			wildcards = true
+		}
+
+		reduced = append(reduced, name)
+	}
+
+	if len(reduced) == 0 {
+		return nil
+	}
+
+	if wildcards {
+		for _, name := range excluded {
+			reduced = append(reduced, "-"+name)
+		}
+	}
+
+	return reduced
+}
+
+// Partial reports whether the result is incomplete because the search timed out
+// or some shards failed.
+func (r SearchResult) Partial() bool {
+	return r.TimedOut || r.Shards.Failed > 0 || len(r.Shards.Failures) > 0
+}
+
+// PartialResultsError is returned by SearchIn, along with the partial result, when
+// SearchRequest.FailOnPartialResults is set and the result is incomplete.
+type PartialResultsError struct {
+	TimedOut bool
+	Failures []ShardFailure
+}
+
+func (e *PartialResultsError) Error() string {
+	if len(e.Failures) == 0 {
+		return "search returned partial results: timed out"
+	}
+
+	f := e.Failures[0]
+
+	return fmt.Sprintf("search returned partial results: %d shard failures, first on index %s shard %d: %s: %s",
+		len(e.Failures), f.Index, f.Shard, f.Reason.Type, f.Reason.Reason)
+}
+
+// params returns the URL parameters of the search request.
+func (q SearchRequest) params() url.Values {
+	var params = make(url.Values)
+
+	if q.Pipeline != "" {
+		params.Set("search_pipeline", q.Pipeline)
+	}
+
+	if q.Routing != "" {
+		params.Set("routing", q.Routing)
+	}
+
+	if q.ExpandWildcards != "" {
+		params.Set("expand_wildcards", q.ExpandWildcards)
+	}
+
+	if q.IgnoreUnavailable {
+		params.Set("ignore_unavailable", "true")
+	}
+
+	if q.AllowNoIndices != nil {
+		params.Set("allow_no_indices", strconv.FormatBool(*q.AllowNoIndices))
+	}
+
+	return params
+}
+
+// IgnoreMissingIndices returns a copy of the request succeeding with the indices that
+// exist, and with no hits when none does, e.g. to search the daily indices of a range
+// including today's before it is created.
+func (q SearchRequest) IgnoreMissingIndices() SearchRequest {
+	allow := true
+
+	q.IgnoreUnavailable = true
+	q.AllowNoIndices = &allow
+
+	return q
+}
+
+// IndicesBoost returns a copy of the request multiplying the scores of the hits of
+// each index, or index expression, by its boost, e.g. to rank the hits of hot indices
+// above those of archives. The boosts are sent sorted by index expression, the first
+// matching an index applying.
+func (q SearchRequest) IndicesBoost(boosts map[string]float64) SearchRequest {
+	var indices = make([]string, 0, len(boosts))
+	for index := range boosts {
+		indices = append(indices, index)
+	}
+
+	sort.Strings(indices)
+
+	q.IndexBoosts = make([]map[string]float64, len(indices))
+	for i, index := range indices {
+		q.IndexBoosts[i] = map[string]float64{index: boosts[index]}
+	}
+
+	return q
+}
+
+func searchPath(index []string) string {
+	if len(index) == 0 {
+		return "/_search"
+	}
+
+	return "/" + strings.Join(index, ",") + "/_search"
+}
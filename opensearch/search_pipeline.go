@@ -0,0 +1,90 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SearchPipeline is a search pipeline definition. Processors are expressed as
+// single-key maps, e.g. {"filter_query": {...}}.
+type SearchPipeline struct {
+	Description            string                   `json:"description,omitempty"`
+	RequestProcessors      []map[string]interface{} `json:"request_processors,omitempty"`
+	ResponseProcessors     []map[string]interface{} `json:"response_processors,omitempty"`
+	PhaseResultsProcessors []map[string]interface{} `json:"phase_results_processors,omitempty"`
+}
+
+// NormalizationProcessor returns a phase results processor normalizing and combining the
+// scores of hybrid queries. Technique is "min_max" or "l2", combination is
+// "arithmetic_mean", "geometric_mean" or "harmonic_mean", and weights are optional.
+func NormalizationProcessor(technique, combination string, weights ...float64) map[string]interface{} {
+	comb := map[string]interface{}{"technique": combination}
+	if len(weights) != 0 {
+		comb["parameters"] = map[string]interface{}{"weights": weights}
+	}
+
+	return map[string]interface{}{
+		"normalization-processor": map[string]interface{}{
+			"normalization": map[string]interface{}{"technique": technique},
+			"combination":   comb,
+		},
+	}
+}
+
+// RerankProcessor returns a response processor re-ranking hits with an ML Commons
+// cross-encoder model, using the given document fields as context.
+func RerankProcessor(modelID string, fields ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"rerank": map[string]interface{}{
+			"ml_opensearch": map[string]interface{}{"model_id": modelID},
+			"context":       map[string]interface{}{"document_fields": fields},
+		},
+	}
+}
+
+// SearchPipeline returns a copy of the request processed by the search pipeline with
+// the given name instead of the default pipeline of the indices.
+func (q SearchRequest) SearchPipeline(name string) SearchRequest {
+	q.Pipeline = name
+
+	return q
+}
+
+// PutSearchPipeline creates or replaces the search pipeline with the given name.
+func PutSearchPipeline(ctx context.Context, name string, pipeline SearchPipeline) error {
+	_, err := Do(ctx, http.MethodPut, "/_search/pipeline/"+url.PathEscape(name), nil, pipeline)
+
+	return err
+}
+
+// GetSearchPipeline returns the search pipeline with the given name.
+func GetSearchPipeline(ctx context.Context, name string) (SearchPipeline, error) {
+	body, err := Do(ctx, http.MethodGet, "/_search/pipeline/"+url.PathEscape(name), nil, nil)
+	if err != nil {
+		return SearchPipeline{}, err
+	}
+
+	var pipelines map[string]SearchPipeline
+
+	err = json.Unmarshal(body, &pipelines)
+	if err != nil {
+		return SearchPipeline{}, err
+	}
+
+	pipeline, ok := pipelines[name]
+	if !ok {
+		return SearchPipeline{}, fmt.Errorf("search pipeline %s not found", name)
+	}
+
+	return pipeline, nil
+}
+
+// DeleteSearchPipeline deletes the search pipeline with the given name.
+func DeleteSearchPipeline(ctx context.Context, name string) error {
+	_, err := Do(ctx, http.MethodDelete, "/_search/pipeline/"+url.PathEscape(name), nil, nil)
+
+	return err
+}
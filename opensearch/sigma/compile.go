@@ -0,0 +1,414 @@
+package sigma
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"gopkg.in/yaml.v3"
+)
+
+// Compiler compiles rules into queries for the indices matching Index.
+type Compiler struct {
+	Index string
+	// FieldMap renames the Sigma fields of the rules to the fields of the indices,
+	// e.g. "Image" to "process.executable". Fields not in the map are used as is.
+	FieldMap map[string]string
+	// Mapper resolves the mappings of the fields, opensearch.Mapper() when nil.
+	Mapper *opensearch.FieldMapper
+}
+
+func (c *Compiler) mapper() *opensearch.FieldMapper {
+	if c.Mapper != nil {
+		return c.Mapper
+	}
+
+	return opensearch.Mapper()
+}
+
+func (c *Compiler) field(name string) string {
+	if mapped, ok := c.FieldMap[name]; ok {
+		return mapped
+	}
+
+	return name
+}
+
+// Compile returns the query matching the documents detected by r.
+func (c *Compiler) Compile(ctx context.Context, r Rule) (opensearch.Query, error) {
+	var names = make([]string, 0, len(r.Detection.Searches))
+	for name := range r.Detection.Searches {
+		names = append(names, name)
+	}
+
+	cond := condition{
+		tokens: tokenize(r.Detection.Condition),
+		names:  names,
+		search: func(name string) (opensearch.Query, error) {
+			node, ok := r.Detection.Searches[name]
+			if !ok {
+				return opensearch.Query{}, fmt.Errorf("unknown search identifier %q in condition", name)
+			}
+
+			q, err := c.search(ctx, &node)
+			if err != nil {
+				return opensearch.Query{}, fmt.Errorf("search %s: %w", name, err)
+			}
+
+			return q, nil
+		},
+	}
+
+	q, err := cond.parse()
+	if err != nil {
+		return opensearch.Query{}, fmt.Errorf("sigma rule %q: %w", r.Title, err)
+	}
+
+	return q, nil
+}
+
+// SearchRequest returns a search request for the documents detected by r, sorted by
+// @timestamp, most recent first.
+func (c *Compiler) SearchRequest(ctx context.Context, r Rule, size int64) (opensearch.SearchRequest, error) {
+	q, err := c.Compile(ctx, r)
+	if err != nil {
+		return opensearch.SearchRequest{}, err
+	}
+
+	return opensearch.SearchRequest{
+		Size:  size,
+		Query: &q,
+		Sort:  []map[string]map[string]interface{}{{"@timestamp": {"order": "desc", "unmapped_type": "date"}}},
+	}, nil
+}
+
+// search compiles a search identifier: a map of fields, whose conditions must all
+// match, a list of maps, one of which must match, or a list of keywords.
+func (c *Compiler) search(ctx context.Context, node *yaml.Node) (opensearch.Query, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return c.fields(ctx, node)
+	case yaml.SequenceNode:
+		var clauses []opensearch.Query
+
+		for _, item := range node.Content {
+			var q opensearch.Query
+			var err error
+
+			if item.Kind == yaml.ScalarNode {
+				q, err = keyword(item.Value)
+			} else {
+				q, err = c.search(ctx, item)
+			}
+
+			if err != nil {
+				return opensearch.Query{}, err
+			}
+
+			clauses = append(clauses, q)
+		}
+
+		if len(clauses) == 0 {
+			return opensearch.Query{}, fmt.Errorf("empty search")
+		}
+
+		return anyOf(clauses), nil
+	case yaml.ScalarNode:
+		return keyword(node.Value)
+	default:
+		return opensearch.Query{}, fmt.Errorf("invalid search")
+	}
+}
+
+// fields compiles a map of field conditions, sorted by field for stable queries.
+func (c *Compiler) fields(ctx context.Context, node *yaml.Node) (opensearch.Query, error) {
+	type pair struct {
+		key   string
+		value *yaml.Node
+	}
+
+	var pairs []pair
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{node.Content[i].Value, node.Content[i+1]})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
+
+	var clauses []opensearch.Query
+
+	for _, p := range pairs {
+		q, err := c.fieldCondition(ctx, p.key, p.value)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		clauses = append(clauses, q)
+	}
+
+	if len(clauses) == 0 {
+		return opensearch.Query{}, fmt.Errorf("empty search")
+	}
+
+	return allOf(clauses), nil
+}
+
+// fieldCondition compiles "field|modifier|...: value", where a list of values matches
+// when any value matches, or all of them with the all modifier.
+func (c *Compiler) fieldCondition(ctx context.Context, key string, node *yaml.Node) (opensearch.Query, error) {
+	parts := strings.Split(key, "|")
+	field := c.field(parts[0])
+	modifiers := parts[1:]
+
+	var all bool
+	var values []*yaml.Node
+
+	if node.Kind == yaml.SequenceNode {
+		values = node.Content
+	} else {
+		values = []*yaml.Node{node}
+	}
+
+	var clauses []opensearch.Query
+
+	for _, m := range modifiers {
+		if m == "all" {
+			all = true
+		}
+	}
+
+	for _, v := range values {
+		if v.Kind != yaml.ScalarNode {
+			return opensearch.Query{}, fmt.Errorf("invalid value of field %s", parts[0])
+		}
+
+		q, err := c.value(ctx, field, modifiers, v)
+		if err != nil {
+			return opensearch.Query{}, fmt.Errorf("field %s: %w", parts[0], err)
+		}
+
+		clauses = append(clauses, q)
+	}
+
+	if len(clauses) == 0 {
+		return opensearch.Query{}, fmt.Errorf("field %s has no values", parts[0])
+	}
+
+	if all {
+		return allOf(clauses), nil
+	}
+
+	return anyOf(clauses), nil
+}
+
+// value compiles the condition on a single value of field.
+func (c *Compiler) value(ctx context.Context, field string, modifiers []string, node *yaml.Node) (opensearch.Query, error) {
+	if node.Tag == "!!null" {
+		return opensearch.Query{Bool: &opensearch.Bool{MustNot: []opensearch.Query{{Exists: map[string]string{"field": field}}}}}, nil
+	}
+
+	var value interface{}
+
+	err := node.Decode(&value)
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	s := node.Value
+
+	var position string
+
+	for _, m := range modifiers {
+		switch m {
+		case "all":
+		case "base64":
+			s = base64.StdEncoding.EncodeToString([]byte(unescape(s)))
+			s = escape(s)
+			value = s
+		case "contains", "startswith", "endswith":
+			position = m
+		case "exists":
+			exists := opensearch.Query{Exists: map[string]string{"field": field}}
+			if b, ok := value.(bool); ok && !b {
+				return opensearch.Query{Bool: &opensearch.Bool{MustNot: []opensearch.Query{exists}}}, nil
+			}
+
+			return exists, nil
+		case "re":
+			keyword, err := c.keywordField(ctx, field)
+			if err != nil {
+				return opensearch.Query{}, err
+			}
+
+			return opensearch.RegexpQuery(keyword, s, false), nil
+		case "cidr":
+			return opensearch.TermQuery(field, s, false), nil
+		case "gt", "gte", "lt", "lte":
+			return opensearch.Query{Range: map[string]map[string]interface{}{field: {m: value}}}, nil
+		default:
+			return opensearch.Query{}, fmt.Errorf("unsupported modifier %q", m)
+		}
+	}
+
+	switch position {
+	case "contains":
+		s = "*" + s + "*"
+	case "startswith":
+		s = s + "*"
+	case "endswith":
+		s = "*" + s
+	}
+
+	_, isString := value.(string)
+	if !isString && position == "" {
+		return opensearch.TermQuery(field, value, false), nil
+	}
+
+	// Sigma string values match ignoring case, on keyword fields.
+	keyword, err := c.keywordField(ctx, field)
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	if hasWildcard(s) {
+		return opensearch.WildcardQuery(keyword, wildcardPattern(s), true), nil
+	}
+
+	if keyword == field {
+		info, ok, err := c.mapper().Field(ctx, c.Index, field)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		if ok {
+			return c.mapper().ValueQuery(ctx, c.Index, field, unescape(s), info.Type == "keyword" || info.Type == "wildcard")
+		}
+	}
+
+	return opensearch.TermQuery(keyword, unescape(s), true), nil
+}
+
+// keywordField returns the field to run term-level queries on, field itself when it
+// has no keyword variant or isn't mapped.
+func (c *Compiler) keywordField(ctx context.Context, field string) (string, error) {
+	keyword, err := c.mapper().KeywordField(ctx, c.Index, field)
+	if err != nil {
+		return "", err
+	}
+
+	if keyword == "" {
+		return field, nil
+	}
+
+	return keyword, nil
+}
+
+// keyword compiles a keyword, matching the value anywhere in the documents.
+func keyword(s string) (opensearch.Query, error) {
+	if s == "" {
+		return opensearch.Query{}, fmt.Errorf("empty keyword")
+	}
+
+	var query string
+
+	if hasWildcard(s) {
+		query = queryStringEscape(s)
+	} else {
+		query = `"` + strings.ReplaceAll(unescape(s), `"`, `\"`) + `"`
+	}
+
+	return opensearch.Query{QueryString: &opensearch.QueryString{
+		Query:                query,
+		Lenient:              true,
+		AllowLeadingWildcard: true,
+	}}, nil
+}
+
+// hasWildcard reports whether s has unescaped * or ? wildcards.
+func hasWildcard(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		}
+	}
+
+	return false
+}
+
+// unescape removes the escaping of the Sigma wildcards and backslashes.
+func unescape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`*?\`, s[i+1]) >= 0 {
+			i++
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// wildcardPattern converts a Sigma value with wildcards to a wildcard query pattern,
+// where every literal backslash is escaped.
+func wildcardPattern(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`*?\`, s[i+1]) >= 0:
+			i++
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		case s[i] == '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// escape escapes the wildcards and backslashes of a literal value.
+func escape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(`*?\`, s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// queryStringEscape escapes the reserved characters of the query_string syntax but
+// the wildcards.
+func queryStringEscape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		case strings.IndexByte(`+-=&|><!(){}[]^"~:/ `, s[i]) >= 0:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package sigma
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+func TestCompile(t *testing.T) {
+	mapper, err := opensearch.NewFieldMapper().WithStaticMapping(`{"properties": {
+		"process": {"properties": {
+			"executable": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+			"pid": {"type": "long"}
+		}},
+		"user": {"type": "keyword"},
+		"cmd": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+		"ip": {"type": "ip"}
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Compiler{
+		Index:    "logs-*",
+		Mapper:   mapper,
+		FieldMap: map[string]string{"Image": "process.executable", "User": "user", "CommandLine": "cmd"},
+	}
+
+	tests := []struct {
+		name      string
+		detection string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "endswith on text field",
+			detection: "sel:\n  Image|endswith: '\\whoami.exe'\ncondition: sel",
+			want:      `{"wildcard":{"process.executable.keyword":{"case_insensitive":true,"value":"*\\\\whoami.exe"}}}`,
+		},
+		{
+			name:      "field map",
+			detection: "sel:\n  User: admin\n  process.pid: 4\ncondition: sel",
+			want:      `{"bool":{"filter":[{"term":{"user":{"case_insensitive":true,"value":"admin"}}},{"term":{"process.pid":{"value":4}}}]}}`,
+		},
+		{
+			name:      "all modifier, 1 of and not",
+			detection: "sel1:\n  CommandLine|contains|all:\n    - ' -enc '\n    - bypass\nsel2:\n  User: [a, b]\nfilter:\n  User: svc\ncondition: 1 of sel* and not filter",
+			want:      `{"bool":{"filter":[{"bool":{"should":[{"bool":{"filter":[{"wildcard":{"cmd.keyword":{"case_insensitive":true,"value":"* -enc *"}}},{"wildcard":{"cmd.keyword":{"case_insensitive":true,"value":"*bypass*"}}}]}},{"bool":{"should":[{"term":{"user":{"case_insensitive":true,"value":"a"}}},{"term":{"user":{"case_insensitive":true,"value":"b"}}}],"minimum_should_match":1}}],"minimum_should_match":1}},{"bool":{"must_not":[{"term":{"user":{"case_insensitive":true,"value":"svc"}}}]}}]}}`,
+		},
+		{
+			name:      "keywords",
+			detection: "keywords:\n  - mimikatz\n  - 'sekur*'\ncondition: keywords",
+			want:      `{"bool":{"should":[{"query_string":{"query":"\"mimikatz\"","lenient":true,"allow_leading_wildcard":true}},{"query_string":{"query":"sekur*","lenient":true,"allow_leading_wildcard":true}}],"minimum_should_match":1}}`,
+		},
+		{
+			name:      "cidr and comparison",
+			detection: "sel:\n  ip|cidr: 10.0.0.0/8\n  process.pid|gte: 100\ncondition: all of them",
+			want:      `{"bool":{"filter":[{"term":{"ip":{"value":"10.0.0.0/8"}}},{"range":{"process.pid":{"gte":100}}}]}}`,
+		},
+		{
+			name:      "regular expression and exists",
+			detection: "sel:\n  CommandLine|re: '.*foo.*'\n  User|exists: false\ncondition: sel",
+			want:      `{"bool":{"filter":[{"regexp":{"cmd.keyword":".*foo.*"}},{"bool":{"must_not":[{"exists":{"field":"user"}}]}}]}}`,
+		},
+		{
+			name:      "null",
+			detection: "sel:\n  User: null\ncondition: sel",
+			want:      `{"bool":{"must_not":[{"exists":{"field":"user"}}]}}`,
+		},
+		{
+			name:      "base64",
+			detection: "sel:\n  CommandLine|base64: abc\ncondition: sel",
+			want:      `{"term":{"cmd.keyword":{"case_insensitive":true,"value":"YWJj"}}}`,
+		},
+		{
+			name:      "aggregation condition",
+			detection: "sel:\n  User: a\ncondition: sel | count() > 5",
+			wantErr:   "aggregation conditions are not supported",
+		},
+		{
+			name:      "unknown identifier",
+			detection: "sel:\n  User: a\ncondition: missing",
+			wantErr:   `unknown search identifier "missing"`,
+		},
+		{
+			name:      "unsupported modifier",
+			detection: "sel:\n  User|bogus: a\ncondition: sel",
+			wantErr:   `unsupported modifier "bogus"`,
+		},
+		{
+			name:      "unbalanced parenthesis",
+			detection: "sel:\n  User: a\ncondition: (sel",
+			wantErr:   "missing closing parenthesis",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse([]byte("title: t\ndetection:\n  " + strings.ReplaceAll(tt.detection, "\n", "\n  ") + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			q, err := c.Compile(context.Background(), r)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Compile() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.Marshal(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("Compile() = %s\nwant        %s", got, tt.want)
+			}
+		})
+	}
+}
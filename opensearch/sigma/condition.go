@@ -0,0 +1,222 @@
+package sigma
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// condition parses a detection condition, with "not" binding tighter than "and", and
+// "and" tighter than "or". search compiles a search identifier.
+type condition struct {
+	tokens []string
+	pos    int
+	names  []string
+	search func(name string) (opensearch.Query, error)
+}
+
+func tokenize(s string) []string {
+	var tokens []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() != 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	flush()
+
+	return tokens
+}
+
+func (c *condition) peek() string {
+	if c.pos < len(c.tokens) {
+		return c.tokens[c.pos]
+	}
+
+	return ""
+}
+
+func (c *condition) next() string {
+	t := c.peek()
+	c.pos++
+
+	return t
+}
+
+func (c *condition) parse() (opensearch.Query, error) {
+	if strings.Contains(strings.Join(c.tokens, " "), "|") {
+		return opensearch.Query{}, fmt.Errorf("aggregation conditions are not supported")
+	}
+
+	q, err := c.or()
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	if c.pos != len(c.tokens) {
+		return opensearch.Query{}, fmt.Errorf("unexpected %q in condition", c.peek())
+	}
+
+	return q, nil
+}
+
+func (c *condition) or() (opensearch.Query, error) {
+	q, err := c.and()
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	var clauses = []opensearch.Query{q}
+
+	for strings.EqualFold(c.peek(), "or") {
+		c.next()
+
+		q, err := c.and()
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		clauses = append(clauses, q)
+	}
+
+	return anyOf(clauses), nil
+}
+
+func (c *condition) and() (opensearch.Query, error) {
+	q, err := c.not()
+	if err != nil {
+		return opensearch.Query{}, err
+	}
+
+	var clauses = []opensearch.Query{q}
+
+	for strings.EqualFold(c.peek(), "and") {
+		c.next()
+
+		q, err := c.not()
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		clauses = append(clauses, q)
+	}
+
+	return allOf(clauses), nil
+}
+
+func (c *condition) not() (opensearch.Query, error) {
+	if strings.EqualFold(c.peek(), "not") {
+		c.next()
+
+		q, err := c.not()
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		return opensearch.Query{Bool: &opensearch.Bool{MustNot: []opensearch.Query{q}}}, nil
+	}
+
+	return c.primary()
+}
+
+func (c *condition) primary() (opensearch.Query, error) {
+	t := c.next()
+
+	switch {
+	case t == "":
+		return opensearch.Query{}, fmt.Errorf("unexpected end of condition")
+	case t == "(":
+		q, err := c.or()
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		if c.next() != ")" {
+			return opensearch.Query{}, fmt.Errorf("missing closing parenthesis in condition")
+		}
+
+		return q, nil
+	case t == "1" || strings.EqualFold(t, "all") || strings.EqualFold(t, "any"):
+		if !strings.EqualFold(c.next(), "of") {
+			return opensearch.Query{}, fmt.Errorf("expected \"of\" after %q in condition", t)
+		}
+
+		return c.quantified(t != "1" && !strings.EqualFold(t, "any"), c.next())
+	default:
+		return c.search(t)
+	}
+}
+
+// quantified compiles "1 of" and "all of" the searches matching pattern.
+func (c *condition) quantified(all bool, pattern string) (opensearch.Query, error) {
+	var names []string
+
+	for _, name := range c.names {
+		if ok, _ := path.Match(pattern, name); ok || pattern == "them" {
+			// "them" excludes the identifiers starting with an underscore.
+			if pattern == "them" && strings.HasPrefix(name, "_") {
+				continue
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return opensearch.Query{}, fmt.Errorf("no search identifier matches %q", pattern)
+	}
+
+	sort.Strings(names)
+
+	var clauses []opensearch.Query
+
+	for _, name := range names {
+		q, err := c.search(name)
+		if err != nil {
+			return opensearch.Query{}, err
+		}
+
+		clauses = append(clauses, q)
+	}
+
+	if all {
+		return allOf(clauses), nil
+	}
+
+	return anyOf(clauses), nil
+}
+
+func allOf(clauses []opensearch.Query) opensearch.Query {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+
+	return opensearch.Query{Bool: &opensearch.Bool{Filter: clauses}}
+}
+
+func anyOf(clauses []opensearch.Query) opensearch.Query {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+
+	return opensearch.Query{Bool: &opensearch.Bool{Should: clauses, MinimumShouldMatch: 1}}
+}
@@ -0,0 +1,104 @@
+// Package sigma compiles Sigma detection rules into search requests, resolving the
+// rule fields with the field mappings of the searched indices, so that community rules
+// run directly on the indices queried by the SDK.
+//
+// Supported are the detection maps and lists, keyword lists, the condition operators
+// and, or, not, "1 of" and "all of" with wildcards or "them", and the value modifiers
+// contains, startswith, endswith, all, re, cidr, base64, exists, gt, gte, lt and lte.
+// Aggregation conditions, deprecated by the Sigma specification, are rejected.
+package sigma
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is a Sigma rule.
+type Rule struct {
+	Title          string    `yaml:"title"`
+	ID             string    `yaml:"id"`
+	Status         string    `yaml:"status"`
+	Description    string    `yaml:"description"`
+	Author         string    `yaml:"author"`
+	References     []string  `yaml:"references"`
+	Tags           []string  `yaml:"tags"`
+	Level          string    `yaml:"level"`
+	FalsePositives []string  `yaml:"falsepositives"`
+	Fields         []string  `yaml:"fields"`
+	LogSource      LogSource `yaml:"logsource"`
+	Detection      Detection `yaml:"detection"`
+}
+
+// LogSource describes the logs a rule applies to.
+type LogSource struct {
+	Category string `yaml:"category"`
+	Product  string `yaml:"product"`
+	Service  string `yaml:"service"`
+}
+
+// Detection holds the named search identifiers of a rule and its condition.
+type Detection struct {
+	Searches  map[string]yaml.Node
+	Condition string
+}
+
+func (d *Detection) UnmarshalYAML(node *yaml.Node) error {
+	var all map[string]yaml.Node
+
+	err := node.Decode(&all)
+	if err != nil {
+		return err
+	}
+
+	condition, ok := all["condition"]
+	if !ok {
+		return fmt.Errorf("detection has no condition")
+	}
+
+	switch condition.Kind {
+	case yaml.ScalarNode:
+		d.Condition = condition.Value
+	case yaml.SequenceNode:
+		// Several conditions are alternatives.
+		var conditions []string
+
+		err = condition.Decode(&conditions)
+		if err != nil {
+			return err
+		}
+
+		for i, c := range conditions {
+			if i > 0 {
+				d.Condition += " or "
+			}
+
+			d.Condition += "(" + c + ")"
+		}
+	default:
+		return fmt.Errorf("invalid detection condition")
+	}
+
+	delete(all, "condition")
+	delete(all, "timeframe")
+
+	d.Searches = all
+
+	return nil
+}
+
+// Parse decodes a Sigma rule from YAML.
+func Parse(data []byte) (Rule, error) {
+	var r Rule
+
+	err := yaml.Unmarshal(data, &r)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid sigma rule: %w", err)
+	}
+
+	if r.Detection.Condition == "" {
+		return Rule{}, fmt.Errorf("sigma rule %q has no detection condition", r.Title)
+	}
+
+	return r, nil
+}
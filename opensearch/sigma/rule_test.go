@@ -0,0 +1,65 @@
+package sigma
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		condition string
+		searches  []string
+		wantErr   string
+	}{
+		{
+			name:      "single condition",
+			yaml:      "title: t\ndetection:\n  sel:\n    User: a\n  timeframe: 5m\n  condition: sel\n",
+			condition: "sel",
+			searches:  []string{"sel"},
+		},
+		{
+			name:      "condition list",
+			yaml:      "title: t\ndetection:\n  sel:\n    User: a\n  filter:\n    User: b\n  condition:\n    - sel\n    - not filter\n",
+			condition: "(sel) or (not filter)",
+			searches:  []string{"filter", "sel"},
+		},
+		{name: "no detection", yaml: "title: t\n", wantErr: `sigma rule "t" has no detection condition`},
+		{name: "no condition", yaml: "title: t\ndetection:\n  sel:\n    User: a\n", wantErr: "detection has no condition"},
+		{name: "invalid condition", yaml: "title: t\ndetection:\n  condition: {a: b}\n", wantErr: "invalid detection condition"},
+		{name: "invalid yaml", yaml: "title: [\n", wantErr: "invalid sigma rule"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse([]byte(tt.yaml))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r.Detection.Condition != tt.condition {
+				t.Errorf("condition = %q, want %q", r.Detection.Condition, tt.condition)
+			}
+
+			if len(r.Detection.Searches) != len(tt.searches) {
+				t.Fatalf("searches = %v, want %v", r.Detection.Searches, tt.searches)
+			}
+
+			for _, name := range tt.searches {
+				if _, ok := r.Detection.Searches[name]; !ok {
+					t.Errorf("search %s missing", name)
+				}
+			}
+		})
+	}
+}
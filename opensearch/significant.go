@@ -0,0 +1,112 @@
+package opensearch
+
+import (
+	"fmt"
+)
+
+// SignificantTermsParams are the parameters of a significant_terms aggregation besides
+// its field and size, embedded in Agg.
+type SignificantTermsParams struct {
+	ShardSize        int64  `json:"shard_size,omitempty"`
+	MinDocCount      *int64 `json:"min_doc_count,omitempty"`
+	ShardMinDocCount *int64 `json:"shard_min_doc_count,omitempty"`
+	BackgroundFilter *Query `json:"background_filter,omitempty"`
+
+	JLH               *SignificanceHeuristic `json:"jlh,omitempty"`
+	GND               *SignificanceHeuristic `json:"gnd,omitempty"`
+	MutualInformation *SignificanceHeuristic `json:"mutual_information,omitempty"`
+	ChiSquare         *SignificanceHeuristic `json:"chi_square,omitempty"`
+	Percentage        *SignificanceHeuristic `json:"percentage,omitempty"`
+}
+
+type SignificanceHeuristic struct {
+	IncludeNegatives     *bool `json:"include_negatives,omitempty"`
+	BackgroundIsSuperset *bool `json:"background_is_superset,omitempty"`
+}
+
+// Heuristic scores the significance of the terms of a significant_terms aggregation.
+type Heuristic string
+
+const (
+	JLH               Heuristic = "jlh"
+	GND               Heuristic = "gnd"
+	MutualInformation Heuristic = "mutual_information"
+	ChiSquare         Heuristic = "chi_square"
+	Percentage        Heuristic = "percentage"
+)
+
+// SignificantTermsOptions configure SignificantTermsAgg.
+type SignificantTermsOptions struct {
+	// Size is the number of terms returned, 10 by default.
+	Size int64
+	// MinDocCount is the number of documents of the foreground set a term needs to
+	// be returned, 3 by default.
+	MinDocCount *int64
+	// BackgroundFilter narrows the documents the foreground set is compared to, the
+	// whole indices by default, e.g. to the hosts of the same network segment.
+	BackgroundFilter *Query
+	// Heuristic scores the terms, JLH by default.
+	Heuristic Heuristic
+	// IncludeNegatives keeps the terms less frequent in the foreground set than in the
+	// background under MutualInformation and ChiSquare.
+	IncludeNegatives bool
+	// BackgroundIsSuperset tells MutualInformation, ChiSquare and GND whether the
+	// background contains the foreground set, true by default. It must be false when
+	// BackgroundFilter excludes foreground documents.
+	BackgroundIsSuperset *bool
+}
+
+// SignificantTermsAgg returns a significant_terms aggregation of field, returning
+// the terms unusually frequent among the documents matching the query compared to
+// the background, e.g. the processes of a set of compromised hosts rare elsewhere.
+// Buckets carry the Score and BgCount of each term.
+func SignificantTermsAgg(field string, o SignificantTermsOptions) (Aggs, error) {
+	st := &SignificantTermsParams{
+		MinDocCount:      o.MinDocCount,
+		BackgroundFilter: o.BackgroundFilter,
+	}
+
+	h := &SignificanceHeuristic{BackgroundIsSuperset: o.BackgroundIsSuperset}
+	if o.IncludeNegatives {
+		h.IncludeNegatives = &o.IncludeNegatives
+	}
+
+	switch o.Heuristic {
+	case "", JLH:
+	case GND:
+		if h.IncludeNegatives != nil {
+			return Aggs{}, fmt.Errorf("heuristic gnd doesn't support include_negatives")
+		}
+
+		st.GND = h
+	case MutualInformation:
+		st.MutualInformation = h
+	case ChiSquare:
+		st.ChiSquare = h
+	case Percentage:
+		st.Percentage = &SignificanceHeuristic{}
+	default:
+		return Aggs{}, fmt.Errorf("unknown significance heuristic %q", o.Heuristic)
+	}
+
+	if (o.Heuristic == "" || o.Heuristic == JLH || o.Heuristic == Percentage) && (o.IncludeNegatives || o.BackgroundIsSuperset != nil) {
+		return Aggs{}, fmt.Errorf("heuristic %s doesn't support include_negatives nor background_is_superset", st.heuristic())
+	}
+
+	return Aggs{SignificantTerms: &Agg{Field: field, Size: o.Size, SignificantTermsParams: st}}, nil
+}
+
+func (st *SignificantTermsParams) heuristic() Heuristic {
+	switch {
+	case st.GND != nil:
+		return GND
+	case st.MutualInformation != nil:
+		return MutualInformation
+	case st.ChiSquare != nil:
+		return ChiSquare
+	case st.Percentage != nil:
+		return Percentage
+	}
+
+	return JLH
+}
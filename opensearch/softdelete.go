@@ -0,0 +1,168 @@
+package opensearch
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SoftDeleteFields are the document fields marking soft-deleted documents.
+type SoftDeleteFields struct {
+	// Flag is true on deleted documents, "deleted" by default.
+	Flag string
+	// At is the time of the deletion, "deletedAt" by default.
+	At string
+	// By is the user who deleted the document, from the audit actor of the context,
+	// "deletedBy" by default.
+	By string
+}
+
+var softDelete = struct {
+	sync.RWMutex
+	enabled bool
+	fields  SoftDeleteFields
+}{fields: SoftDeleteFields{Flag: "deleted", At: "deletedAt", By: "deletedBy"}}
+
+// EnableSoftDelete makes SearchIn and MultiSearch leave out the documents deleted
+// with SoftDelete, unless the request includes them with IncludeDeleted. Soft deleted
+// documents are searched like any other by default.
+func EnableSoftDelete(enabled bool) {
+	softDelete.Lock()
+	defer softDelete.Unlock()
+
+	softDelete.enabled = enabled
+}
+
+// SetSoftDeleteFields sets the fields marking soft-deleted documents. Empty fields
+// keep their current name.
+func SetSoftDeleteFields(f SoftDeleteFields) {
+	softDelete.Lock()
+	defer softDelete.Unlock()
+
+	if f.Flag != "" {
+		softDelete.fields.Flag = f.Flag
+	}
+
+	if f.At != "" {
+		softDelete.fields.At = f.At
+	}
+
+	if f.By != "" {
+		softDelete.fields.By = f.By
+	}
+}
+
+func softDeleteFields() SoftDeleteFields {
+	softDelete.RLock()
+	defer softDelete.RUnlock()
+
+	return softDelete.fields
+}
+
+// IncludeDeleted returns a copy of the request also searching soft-deleted documents,
+// e.g. to list the documents to restore.
+func (q SearchRequest) IncludeDeleted() SearchRequest {
+	q.IncludeSoftDeleted = true
+
+	return q
+}
+
+// excludeDeleted leaves the soft-deleted documents out of the query when soft delete
+// is enabled.
+func (q *SearchRequest) excludeDeleted() {
+	softDelete.RLock()
+	enabled, flag := softDelete.enabled, softDelete.fields.Flag
+	softDelete.RUnlock()
+
+	if !enabled || q.IncludeSoftDeleted {
+		return
+	}
+
+	var live = Bool{
+		MustNot: []Query{{Term: map[string]map[string]interface{}{flag: {"value": true}}}},
+	}
+
+	if q.Query != nil {
+		live.Must = []Query{*q.Query}
+	}
+
+	q.Query = &Query{Bool: &live}
+}
+
+// SoftDelete marks the document as deleted instead of removing it, so that it can be
+// restored with Restore until it is purged with PurgeDeleted. When the hit carries
+// its sequence number and primary term, e.g. from a search with SeqNoPrimaryTerm or
+// from GetDoc, the deletion fails with a 409 status if the document changed since it
+// was read.
+func (h Hit) SoftDelete(ctx context.Context) error {
+	f := softDeleteFields()
+
+	doc := map[string]interface{}{
+		f.Flag: true,
+		f.At:   time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if actor, ok := ctx.Value(auditActorKey{}).(AuditActor); ok && actor.User != "" {
+		doc[f.By] = actor.User
+	}
+
+	err := h.markDeleted(ctx, doc)
+
+	recordAudit(ctx, "soft_delete", []string{h.Index}, h.ID, nil, "", err)
+
+	return err
+}
+
+// Restore undoes SoftDelete. It is versioned like SoftDelete.
+func (h Hit) Restore(ctx context.Context) error {
+	f := softDeleteFields()
+
+	err := h.markDeleted(ctx, map[string]interface{}{f.Flag: false, f.At: nil, f.By: nil})
+
+	recordAudit(ctx, "restore", []string{h.Index}, h.ID, nil, "", err)
+
+	return err
+}
+
+func (h Hit) markDeleted(ctx context.Context, doc map[string]interface{}) error {
+	_, err := checkIndexAccess(h.Index)
+	if err != nil {
+		return err
+	}
+
+	var params = url.Values{}
+
+	if h.Routing != "" {
+		params.Set("routing", h.Routing)
+	}
+
+	if h.PrimaryTerm != 0 {
+		params.Set("if_seq_no", strconv.FormatInt(h.SeqNo, 10))
+		params.Set("if_primary_term", strconv.FormatInt(h.PrimaryTerm, 10))
+	}
+
+	_, err = Do(ctx, http.MethodPost, "/"+url.PathEscape(h.Index)+"/_update/"+url.PathEscape(h.ID), params, Update{Doc: doc})
+
+	return err
+}
+
+// PurgeDeleted permanently removes the documents of the indices soft-deleted longer
+// than retention ago, and returns the number removed. It is meant to run on a
+// schedule, after the time analysts have to notice and undo accidental deletions.
+func PurgeDeleted(ctx context.Context, index []string, retention time.Duration) (int64, error) {
+	f := softDeleteFields()
+
+	query := &Query{Bool: &Bool{Filter: []Query{
+		{Term: map[string]map[string]interface{}{f.Flag: {"value": true}}},
+		{Range: map[string]map[string]interface{}{f.At: {"lte": time.Now().UTC().Add(-retention).Format(time.RFC3339Nano)}}},
+	}}}
+
+	result, err := deleteByQuery(ctx, index, query)
+
+	recordAudit(ctx, "purge", index, "", query, "", err)
+
+	return result.Deleted, err
+}
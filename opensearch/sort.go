@@ -0,0 +1,235 @@
+package opensearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+)
+
+// Script is an inline script, or a reference to a stored script when ID is set.
+type Script struct {
+	Source string                 `json:"source,omitempty"`
+	ID     string                 `json:"id,omitempty"`
+	Lang   string                 `json:"lang,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// SortOptions holds the optional settings of a field sort.
+type SortOptions struct {
+	// Missing places documents without the field: "_first", "_last" or a custom value.
+	Missing interface{}
+	// UnmappedType is the type assumed in indices where the field is not mapped.
+	UnmappedType string
+	// Mode picks the value of multi-valued fields: "min", "max", "sum", "avg" or "median".
+	Mode string
+	// Nested sorts by a field inside nested objects.
+	Nested *NestedSort
+}
+
+type NestedSort struct {
+	Path        string      `json:"path"`
+	Filter      *Query      `json:"filter,omitempty"`
+	MaxChildren int64       `json:"max_children,omitempty"`
+	Nested      *NestedSort `json:"nested,omitempty"`
+}
+
+// SortByField returns a sort clause for SearchRequest.Sort ordering by field
+// ("asc" or "desc") with the given options.
+func SortByField(field, order string, opts ...SortOptions) map[string]map[string]interface{} {
+	var params = map[string]interface{}{"order": order}
+
+	for _, opt := range opts {
+		if opt.Missing != nil {
+			params["missing"] = opt.Missing
+		}
+
+		if opt.UnmappedType != "" {
+			params["unmapped_type"] = opt.UnmappedType
+		}
+
+		if opt.Mode != "" {
+			params["mode"] = opt.Mode
+		}
+
+		if opt.Nested != nil {
+			params["nested"] = opt.Nested
+		}
+	}
+
+	return map[string]map[string]interface{}{field: params}
+}
+
+// SortByScript returns a sort clause ordering by the value computed by script.
+// scriptType is "number" or "string".
+func SortByScript(script Script, scriptType, order string) map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"_script": {
+			"type":   scriptType,
+			"script": script,
+			"order":  order,
+		},
+	}
+}
+
+// SortByGeoDistance returns a sort clause ordering by the distance between the
+// geo_point field and point, expressed in any format accepted by OpenSearch
+// (e.g. {"lat": 40.7, "lon": -74.0} or "drm3btev3e86"). Unit defaults to meters.
+func SortByGeoDistance(field string, point interface{}, order, unit string) map[string]map[string]interface{} {
+	var params = map[string]interface{}{
+		field:   point,
+		"order": order,
+	}
+
+	if unit != "" {
+		params["unit"] = unit
+	}
+
+	return map[string]map[string]interface{}{"_geo_distance": params}
+}
+
+// SortValues are the sort values of a hit, used as SearchRequest.SearchAfterValues to
+// fetch the hits following it. Numbers are decoded as json.Number so long values keep
+// their precision when sent back.
+type SortValues []interface{}
+
+func (s *SortValues) UnmarshalJSON(data []byte) error {
+	var values []interface{}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	err := d.Decode(&values)
+	if err != nil {
+		return err
+	}
+
+	*s = values
+
+	return nil
+}
+
+// int64s returns the values as integers, and whether they all are.
+func (s SortValues) int64s() ([]int64, bool) {
+	var ints = make([]int64, len(s))
+
+	for i, v := range s {
+		switch n := v.(type) {
+		case json.Number:
+			i64, err := n.Int64()
+			if err != nil {
+				return nil, false
+			}
+
+			ints[i] = i64
+		case int64:
+			ints[i] = n
+		case int:
+			ints[i] = int64(n)
+		case float64:
+			if n != math.Trunc(n) {
+				return nil, false
+			}
+
+			ints[i] = int64(n)
+		default:
+			return nil, false
+		}
+	}
+
+	return ints, true
+}
+
+func sortValuesOf(ints []int64) SortValues {
+	if ints == nil {
+		return nil
+	}
+
+	var s = make(SortValues, len(ints))
+	for i, v := range ints {
+		s[i] = v
+	}
+
+	return s
+}
+
+// values returns the sort values of the hit, SortValues or, for hits built with
+// integer values only, Sort.
+func (h Hit) values() SortValues {
+	if h.SortValues != nil {
+		return h.SortValues
+	}
+
+	return sortValuesOf(h.Sort)
+}
+
+// MarshalJSON encodes the hit with its sort values.
+func (h Hit) MarshalJSON() ([]byte, error) {
+	type hit Hit
+
+	return json.Marshal(struct {
+		hit
+		Sort SortValues `json:"sort"`
+	}{hit: hit(h), Sort: h.values()})
+}
+
+// UnmarshalJSON decodes the hit, with its sort values in SortValues, and in Sort when
+// they are all integers.
+func (h *Hit) UnmarshalJSON(data []byte) error {
+	type hit Hit
+
+	var v = struct {
+		*hit
+		Sort SortValues `json:"sort"`
+	}{hit: (*hit)(h)}
+
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	h.SortValues = v.Sort
+	h.Sort, _ = v.Sort.int64s()
+
+	return nil
+}
+
+// searchAfter returns the sort values to search after, SearchAfterValues or
+// SearchAfter.
+func (q SearchRequest) searchAfter() SortValues {
+	if q.SearchAfterValues != nil {
+		return q.SearchAfterValues
+	}
+
+	return sortValuesOf(q.SearchAfter)
+}
+
+// MarshalJSON encodes the request with the sort values to search after.
+func (q SearchRequest) MarshalJSON() ([]byte, error) {
+	type request SearchRequest
+
+	return json.Marshal(struct {
+		request
+		SearchAfter SortValues `json:"search_after,omitempty"`
+	}{request: request(q), SearchAfter: q.searchAfter()})
+}
+
+// UnmarshalJSON decodes the request, with the sort values to search after in
+// SearchAfterValues, and in SearchAfter when they are all integers.
+func (q *SearchRequest) UnmarshalJSON(data []byte) error {
+	type request SearchRequest
+
+	var v = struct {
+		*request
+		SearchAfter SortValues `json:"search_after"`
+	}{request: (*request)(q)}
+
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	q.SearchAfterValues = v.SearchAfter
+	q.SearchAfter, _ = v.SearchAfter.int64s()
+
+	return nil
+}
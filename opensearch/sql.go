@@ -0,0 +1,241 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Column is a column of a Table.
+type Column struct {
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+	Type  string `json:"type"`
+}
+
+// Table is the tabular result of a SQL or PPL query. Cursor is set when more rows can be
+// fetched with NextSQLPage.
+type Table struct {
+	Schema []Column        `json:"schema"`
+	Rows   [][]interface{} `json:"datarows"`
+	Total  int64           `json:"total"`
+	Size   int64           `json:"size"`
+	Cursor string          `json:"cursor,omitempty"`
+}
+
+// Records returns the rows as maps keyed by the alias, or the name, of the columns.
+func (t Table) Records() []map[string]interface{} {
+	var records = make([]map[string]interface{}, len(t.Rows))
+
+	for i, row := range t.Rows {
+		record := make(map[string]interface{}, len(t.Schema))
+
+		for j, col := range t.Schema {
+			if j >= len(row) {
+				break
+			}
+
+			name := col.Alias
+			if name == "" {
+				name = col.Name
+			}
+
+			record[name] = row[j]
+		}
+
+		records[i] = record
+	}
+
+	return records
+}
+
+var (
+	sqlIndexPattern = regexp.MustCompile("(?i)\\b(?:from|join)\\s+((?:`[^`]+`|[^\\s,;()]+)(?:\\s*,\\s*(?:`[^`]+`|[^\\s,;()]+))*)")
+	pplIndexPattern = regexp.MustCompile("(?i)\\bsource\\s*=\\s*((?:`[^`]+`|[^\\s,|]+)(?:\\s*,\\s*(?:`[^`]+`|[^\\s,|]+))*)")
+)
+
+// SQLOption customizes SQL and PPL queries.
+type SQLOption func(*sqlOptions)
+
+type sqlOptions struct {
+	bypassTenantScope bool
+}
+
+// SQLWithoutTenantScope allows a SQL or PPL query across tenants when tenant scope
+// enforcement is enabled. Queries can't be scoped to a tenant as search requests are,
+// so with enforcement enabled every query must bypass it explicitly, after the caller
+// restricted it to the tenant in its WHERE clause, or made sure it must read them all.
+func SQLWithoutTenantScope() SQLOption {
+	return func(o *sqlOptions) {
+		o.bypassTenantScope = true
+	}
+}
+
+func newSQLOptions(opts []SQLOption) sqlOptions {
+	var o sqlOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// queryIndices returns the index expressions of a SQL or PPL query.
+func queryIndices(query string, pattern *regexp.Regexp) []string {
+	var index []string
+
+	for _, m := range pattern.FindAllStringSubmatch(query, -1) {
+		for _, name := range strings.Split(m[1], ",") {
+			index = append(index, strings.Trim(strings.TrimSpace(name), "`"))
+		}
+	}
+
+	return index
+}
+
+// checkQueryAccess verifies a SQL or PPL query against tenant scope enforcement and
+// the indices of index against the ACL set by SetIndexACL. Queries whose indices can't
+// be found, and wildcards reaching denied indices, which can't be excluded from the
+// query, are rejected.
+func checkQueryAccess(index []string, o sqlOptions) error {
+	tenantScope.RLock()
+	enforce := tenantScope.enforce
+	tenantScope.RUnlock()
+
+	if enforce && !o.bypassTenantScope {
+		return ErrMissingTenantScope
+	}
+
+	indexACL.RLock()
+	restricted := len(indexACL.allow) != 0 || len(indexACL.deny) != 0
+	indexACL.RUnlock()
+
+	if !restricted {
+		return nil
+	}
+
+	if len(index) == 0 {
+		return &IndexAccessError{Index: "*"}
+	}
+
+	checked, err := checkIndexAccess(index...)
+	if err != nil {
+		return err
+	}
+
+	if len(checked) != len(index) {
+		return &IndexAccessError{Index: strings.Join(index, ",")}
+	}
+
+	return nil
+}
+
+func executeTable(ctx context.Context, path string, body map[string]interface{}) (Table, error) {
+	resp, err := do(ctx, http.MethodPost, path, nil, body)
+	if err != nil {
+		return Table{}, err
+	}
+
+	var table Table
+
+	err = json.Unmarshal(resp, &table)
+	if err != nil {
+		return Table{}, err
+	}
+
+	return table, nil
+}
+
+// ExecuteSQL runs a SQL query, returning up to the default page size of the cluster,
+// 200 rows, without a cursor.
+func ExecuteSQL(ctx context.Context, query string, opts ...SQLOption) (Table, error) {
+	return ExecuteSQLPaged(ctx, query, 0, opts...)
+}
+
+// ExecuteSQLPaged runs a SQL query returning pages of fetchSize rows. The Cursor of the
+// first page fetches the next one with NextSQLPage; cursors are opaque, so API services
+// can hand them to their clients. Cursors left open expire, or are closed with
+// CloseSQLCursor. With tenant scope enforcement enabled, queries fail with
+// ErrMissingTenantScope unless given SQLWithoutTenantScope.
+func ExecuteSQLPaged(ctx context.Context, query string, fetchSize int, opts ...SQLOption) (Table, error) {
+	index := queryIndices(query, sqlIndexPattern)
+
+	table, err := executeSQL(ctx, query, index, fetchSize, newSQLOptions(opts))
+
+	recordStatementAudit(ctx, "sql", index, query, err)
+
+	return table, err
+}
+
+func executeSQL(ctx context.Context, query string, index []string, fetchSize int, o sqlOptions) (Table, error) {
+	err := checkQueryAccess(index, o)
+	if err != nil {
+		return Table{}, err
+	}
+
+	body := map[string]interface{}{"query": query}
+	if fetchSize > 0 {
+		body["fetch_size"] = fetchSize
+	}
+
+	table, err := executeTable(ctx, "/_plugins/_sql", body)
+	if err != nil {
+		return Table{}, fmt.Errorf("sql query: %w", err)
+	}
+
+	return table, nil
+}
+
+// NextSQLPage returns the page of the cursor of a previous page. Following pages have
+// no schema, the one of the first page applying. It returns ErrNoMorePages when cursor
+// is empty.
+func NextSQLPage(ctx context.Context, cursor string) (Table, error) {
+	if cursor == "" {
+		return Table{}, ErrNoMorePages
+	}
+
+	table, err := executeTable(ctx, "/_plugins/_sql", map[string]interface{}{"cursor": cursor})
+	if err != nil {
+		return Table{}, fmt.Errorf("sql cursor: %w", err)
+	}
+
+	return table, nil
+}
+
+// CloseSQLCursor releases the resources of a cursor before it expires.
+func CloseSQLCursor(ctx context.Context, cursor string) error {
+	_, err := do(ctx, http.MethodPost, "/_plugins/_sql/close", nil, map[string]interface{}{"cursor": cursor})
+
+	return err
+}
+
+// ExecutePPL runs a Piped Processing Language query, e.g.
+// "source=logs-* | where severity > 3 | stats count() by host". With tenant scope
+// enforcement enabled, queries fail with ErrMissingTenantScope unless given
+// SQLWithoutTenantScope.
+func ExecutePPL(ctx context.Context, query string, opts ...SQLOption) (Table, error) {
+	index := queryIndices(query, pplIndexPattern)
+
+	table, err := executePPL(ctx, query, index, newSQLOptions(opts))
+
+	recordStatementAudit(ctx, "ppl", index, query, err)
+
+	return table, err
+}
+
+func executePPL(ctx context.Context, query string, index []string, o sqlOptions) (Table, error) {
+	err := checkQueryAccess(index, o)
+	if err != nil {
+		return Table{}, err
+	}
+
+	table, err := executeTable(ctx, "/_plugins/_ppl", map[string]interface{}{"query": query})
+	if err != nil {
+		return Table{}, fmt.Errorf("ppl query: %w", err)
+	}
+
+	return table, nil
+}
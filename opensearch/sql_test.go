@@ -0,0 +1,43 @@
+package opensearch_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+func TestQueryAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		enforce bool
+		query   string
+		opts    []opensearch.SQLOption
+		wantErr func(error) bool
+	}{
+		{name: "index not allowed", allow: []string{"logs-*"}, query: "SELECT * FROM other", wantErr: isAccessError},
+		{name: "one index not allowed", allow: []string{"logs-*"}, query: "SELECT * FROM logs-a, other", wantErr: isAccessError},
+		{name: "wildcard reaching denied", deny: []string{"logs-secret"}, query: "SELECT * FROM logs-*", wantErr: isAccessError},
+		{name: "no index found", allow: []string{"logs-*"}, query: "SHOW TABLES LIKE %", wantErr: isAccessError},
+		{name: "tenant scope missing", enforce: true, query: "SELECT * FROM logs-a", wantErr: func(err error) bool {
+			return errors.Is(err, opensearch.ErrMissingTenantScope)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setACL(t, tt.allow, tt.deny)
+			if tt.enforce {
+				enforceTenantScope(t)
+			}
+
+			_, err := opensearch.ExecuteSQL(context.Background(), tt.query, tt.opts...)
+			if !tt.wantErr(err) {
+				t.Errorf("ExecuteSQL() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Package summary materializes recurring aggregations. Registered queries are run on
+// their schedules and their results stored in a summary index, from which dashboards
+// and reports read them with their freshness, instead of aggregating the source
+// indices on every view.
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/threatwinds/go-sdk/schedule"
+)
+
+// ErrNotMaterialized is returned by Read for queries without a stored result yet.
+var ErrNotMaterialized = errors.New("aggregation not materialized yet")
+
+// Query is a recurring aggregation.
+type Query struct {
+	// Name identifies the query and its result in the summary index.
+	Name    string
+	Index   []string
+	Request opensearch.SearchRequest
+	// Schedule is when the query runs, e.g. schedule.Every(5 * time.Minute).
+	Schedule schedule.Schedule
+	// Timeout cancels runs lasting longer, unlimited when zero.
+	Timeout time.Duration
+}
+
+// Snapshot is the stored result of a query.
+type Snapshot struct {
+	Name       string    `json:"name"`
+	ComputedAt time.Time `json:"@timestamp"`
+	// NextRun is when the query is scheduled to run again.
+	NextRun time.Time `json:"nextRun"`
+	// StaleAfter is when the run following NextRun is due, after which the result is
+	// stale.
+	StaleAfter   time.Time              `json:"staleAfter"`
+	Took         int64                  `json:"took"`
+	Total        int64                  `json:"total"`
+	Aggregations map[string]interface{} `json:"aggregations"`
+
+	// Age is the time since the result was computed, when read.
+	Age time.Duration `json:"-"`
+	// Stale is set when read after StaleAfter, i.e. when a run was missed, e.g. because
+	// the materializing service is down.
+	Stale bool `json:"-"`
+}
+
+// Materializer runs registered queries and stores their results in Index.
+type Materializer struct {
+	Index string
+
+	mu      sync.Mutex
+	queries map[string]Query
+}
+
+// New returns a Materializer storing the results in index.
+func New(index string) *Materializer {
+	return &Materializer{Index: index, queries: make(map[string]Query)}
+}
+
+// Register adds a query. Queries must be registered before Schedule.
+func (m *Materializer) Register(q Query) error {
+	if q.Name == "" || len(q.Index) == 0 || q.Schedule == nil {
+		return fmt.Errorf("summary query requires name, index and schedule")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.queries[q.Name]; ok {
+		return fmt.Errorf("summary query %s already registered", q.Name)
+	}
+
+	m.queries[q.Name] = q
+
+	return nil
+}
+
+// EnsureIndex creates the summary index unless it exists. Aggregations are stored
+// without being indexed, so that their keys don't grow the mapping.
+func (m *Materializer) EnsureIndex(ctx context.Context) error {
+	_, err := opensearch.Do(ctx, http.MethodHead, "/"+url.PathEscape(m.Index), nil, nil)
+	if err == nil {
+		return nil
+	}
+
+	var status *opensearch.StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		return err
+	}
+
+	err = opensearch.CreateIndex(ctx, m.Index, map[string]interface{}{
+		"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"name":         map[string]interface{}{"type": "keyword"},
+				"@timestamp":   map[string]interface{}{"type": "date"},
+				"nextRun":      map[string]interface{}{"type": "date"},
+				"staleAfter":   map[string]interface{}{"type": "date"},
+				"took":         map[string]interface{}{"type": "long"},
+				"total":        map[string]interface{}{"type": "long"},
+				"aggregations": map[string]interface{}{"type": "object", "enabled": false},
+			},
+		},
+	})
+
+	// Another replica may have created it meanwhile.
+	if errors.As(err, &status) && status.StatusCode == http.StatusBadRequest {
+		return nil
+	}
+
+	return err
+}
+
+// Schedule adds a job per registered query to s, named "summary:" and the query name,
+// so that runs are claimed by a single replica when s has a Claimer.
+func (m *Materializer) Schedule(s *schedule.Scheduler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names = make([]string, 0, len(m.queries))
+	for name := range m.queries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		q := m.queries[name]
+
+		err := s.Add(schedule.Job{
+			Name:     "summary:" + q.Name,
+			Schedule: q.Schedule,
+			Timeout:  q.Timeout,
+			Task: func(ctx context.Context) error {
+				_, err := m.materialize(ctx, q)
+
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Refresh runs the named query now and stores its result.
+func (m *Materializer) Refresh(ctx context.Context, name string) (Snapshot, error) {
+	m.mu.Lock()
+	q, ok := m.queries[name]
+	m.mu.Unlock()
+
+	if !ok {
+		return Snapshot{}, fmt.Errorf("unknown summary query %s", name)
+	}
+
+	return m.materialize(ctx, q)
+}
+
+func (m *Materializer) materialize(ctx context.Context, q Query) (Snapshot, error) {
+	req := q.Request
+	// Only the aggregations are materialized.
+	req.Size = 0
+
+	result, err := req.SearchIn(ctx, q.Index)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("summary query %s: %w", q.Name, err)
+	}
+
+	if result.Partial() {
+		return Snapshot{}, fmt.Errorf("summary query %s returned partial results", q.Name)
+	}
+
+	now := time.Now().UTC()
+	next := q.Schedule.Next(now)
+
+	snap := Snapshot{
+		Name:         q.Name,
+		ComputedAt:   now,
+		NextRun:      next.UTC(),
+		StaleAfter:   q.Schedule.Next(next).UTC(),
+		Took:         result.Took,
+		Total:        result.Hits.Total.Value,
+		Aggregations: result.Aggregations,
+	}
+
+	_, err = opensearch.Do(ctx, http.MethodPut, "/"+url.PathEscape(m.Index)+"/_doc/"+url.PathEscape(q.Name), nil, snap)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("storing summary %s: %w", q.Name, err)
+	}
+
+	return snap, nil
+}
+
+// Read returns the last stored result of the named query from the summary index.
+func Read(ctx context.Context, index, name string) (Snapshot, error) {
+	body, err := opensearch.Do(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(name), nil, nil)
+
+	var status *opensearch.StatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusNotFound {
+		return Snapshot{}, ErrNotMaterialized
+	}
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var doc struct {
+		Source Snapshot `json:"_source"`
+	}
+
+	err = json.Unmarshal(body, &doc)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snap := doc.Source
+	now := time.Now()
+
+	snap.Age = now.Sub(snap.ComputedAt)
+	snap.Stale = !snap.StaleAfter.IsZero() && now.After(snap.StaleAfter)
+
+	return snap, nil
+}
+
+// Read returns the last stored result of the named query.
+func (m *Materializer) Read(ctx context.Context, name string) (Snapshot, error) {
+	return Read(ctx, m.Index, name)
+}
@@ -0,0 +1,190 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/threatwinds/go-sdk/helpers"
+)
+
+// Task is a task running in the cluster.
+type Task struct {
+	// ID is the task ID, the node and the number of the task, like "oTUltX4IQMOUUVeiohTt8A:124".
+	ID          string            `json:"id"`
+	Node        string            `json:"node"`
+	Type        string            `json:"type"`
+	Action      string            `json:"action"`
+	Description string            `json:"description,omitempty"`
+	StartTime   time.Time         `json:"start_time"`
+	RunningTime time.Duration     `json:"running_time"`
+	Cancellable bool              `json:"cancellable"`
+	Cancelled   bool              `json:"cancelled,omitempty"`
+	ParentID    string            `json:"parent_id,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// OpaqueID returns the X-Opaque-Id header of the request that started the task.
+func (t Task) OpaqueID() string {
+	return t.Headers["X-Opaque-Id"]
+}
+
+// TaskFilter selects the tasks listed by ListTasks.
+type TaskFilter struct {
+	// Actions are action names or wildcards, like "*search*".
+	Actions []string
+	// Nodes are node IDs or names.
+	Nodes []string
+	// ParentID selects the children of a task.
+	ParentID string
+	// Detailed includes the descriptions of the tasks, like the queries of searches.
+	Detailed bool
+}
+
+type tasksResponse struct {
+	Nodes map[string]struct {
+		Tasks map[string]struct {
+			Node               string            `json:"node"`
+			ID                 int64             `json:"id"`
+			Type               string            `json:"type"`
+			Action             string            `json:"action"`
+			Description        string            `json:"description"`
+			StartTimeInMillis  int64             `json:"start_time_in_millis"`
+			RunningTimeInNanos int64             `json:"running_time_in_nanos"`
+			Cancellable        bool              `json:"cancellable"`
+			Cancelled          bool              `json:"cancelled"`
+			ParentTaskID       string            `json:"parent_task_id"`
+			Headers            map[string]string `json:"headers"`
+		} `json:"tasks"`
+	} `json:"nodes"`
+}
+
+// ListTasks returns the tasks running in the cluster matching the filter, sorted by
+// start time.
+func ListTasks(ctx context.Context, f TaskFilter) ([]Task, error) {
+	var params = url.Values{}
+
+	if len(f.Actions) != 0 {
+		params.Set("actions", strings.Join(f.Actions, ","))
+	}
+
+	if len(f.Nodes) != 0 {
+		params.Set("nodes", strings.Join(f.Nodes, ","))
+	}
+
+	if f.ParentID != "" {
+		params.Set("parent_task_id", f.ParentID)
+	}
+
+	if f.Detailed {
+		params.Set("detailed", "true")
+	}
+
+	body, err := Do(ctx, http.MethodGet, "/_tasks", params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp tasksResponse
+
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+
+	for _, node := range resp.Nodes {
+		for id, t := range node.Tasks {
+			tasks = append(tasks, Task{
+				ID:          id,
+				Node:        t.Node,
+				Type:        t.Type,
+				Action:      t.Action,
+				Description: t.Description,
+				StartTime:   time.UnixMilli(t.StartTimeInMillis).UTC(),
+				RunningTime: time.Duration(t.RunningTimeInNanos),
+				Cancellable: t.Cancellable,
+				Cancelled:   t.Cancelled,
+				ParentID:    t.ParentTaskID,
+				Headers:     t.Headers,
+			})
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].StartTime.Equal(tasks[j].StartTime) {
+			return tasks[i].StartTime.Before(tasks[j].StartTime)
+		}
+
+		return tasks[i].ID < tasks[j].ID
+	})
+
+	return tasks, nil
+}
+
+// CancelTask cancels a task, and its children, by task ID. Only cancellable tasks,
+// like searches, can be cancelled.
+func CancelTask(ctx context.Context, id string) error {
+	_, err := Do(ctx, http.MethodPost, "/_tasks/"+url.PathEscape(id)+"/_cancel", nil, nil)
+
+	return err
+}
+
+// CancelTimeout bounds the requests cancelling the tasks of abandoned searches.
+const CancelTimeout = 10 * time.Second
+
+// cancelOnDone returns a context tagging the requests with a unique X-Opaque-Id
+// header when enabled, and cancels their search tasks in the cluster, in the
+// background, when ctx is done before stop is called. The request ID of ctx, if any,
+// prefixes the header.
+func cancelOnDone(ctx context.Context, enabled bool) (context.Context, func()) {
+	if !enabled {
+		return ctx, func() {}
+	}
+
+	opaqueID := uuid.NewString()
+	if id := helpers.RequestIDFromContext(ctx); id != "" {
+		opaqueID = id + "/" + opaqueID
+	}
+
+	stop := make(chan struct{})
+
+	go func() {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+		}
+
+		// The search context is done, so cancellation uses its own.
+		cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), CancelTimeout)
+		defer cancel()
+
+		cancelTasks(cancelCtx, opaqueID)
+	}()
+
+	return helpers.WithRequestID(ctx, opaqueID), func() { close(stop) }
+}
+
+// cancelTasks cancels the top-level search tasks started with the opaque ID.
+func cancelTasks(ctx context.Context, opaqueID string) {
+	tasks, err := ListTasks(ctx, TaskFilter{Actions: []string{"*search*"}})
+	if err != nil {
+		return
+	}
+
+	for _, t := range tasks {
+		// Children are cancelled along with their parents.
+		if t.OpaqueID() != opaqueID || t.ParentID != "" || !t.Cancellable || t.Cancelled {
+			continue
+		}
+
+		_ = CancelTask(ctx, t.ID)
+	}
+}
@@ -0,0 +1,82 @@
+package opensearch
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrMissingTenantScope is returned by SearchIn when tenant scope enforcement is
+// enabled and the request neither sets a tenant scope nor bypasses it, and by the SQL
+// and PPL queries not bypassing it.
+var ErrMissingTenantScope = errors.New("search request has no tenant scope")
+
+var tenantScope = struct {
+	sync.RWMutex
+	field   string
+	enforce bool
+}{field: "tenantId"}
+
+// EnforceTenantScope makes SearchIn reject every request without a tenant scope, set
+// with SearchRequest.TenantScope, unless it is explicitly bypassed with
+// SearchRequest.WithoutTenantScope. SQL and PPL queries, which can't be scoped, are
+// rejected unless given SQLWithoutTenantScope. Enforcement is disabled by default.
+func EnforceTenantScope(enabled bool) {
+	tenantScope.Lock()
+	defer tenantScope.Unlock()
+
+	tenantScope.enforce = enabled
+}
+
+// SetTenantField sets the document field holding the tenant ID, "tenantId" by default.
+func SetTenantField(field string) {
+	tenantScope.Lock()
+	defer tenantScope.Unlock()
+
+	tenantScope.field = field
+}
+
+// TenantScope returns a copy of the request restricted to the documents of tenantID.
+// The restriction is added by SearchIn as a term filter around the query, so it also
+// applies to the aggregations.
+func (q SearchRequest) TenantScope(tenantID string) SearchRequest {
+	q.TenantID = tenantID
+	q.BypassTenantScope = false
+
+	return q
+}
+
+// WithoutTenantScope returns a copy of the request allowed to search across tenants
+// when tenant scope enforcement is enabled.
+func (q SearchRequest) WithoutTenantScope() SearchRequest {
+	q.TenantID = ""
+	q.BypassTenantScope = true
+
+	return q
+}
+
+// scopeTenant applies the tenant scope of the request to its query.
+func (q *SearchRequest) scopeTenant() error {
+	tenantScope.RLock()
+	field, enforce := tenantScope.field, tenantScope.enforce
+	tenantScope.RUnlock()
+
+	if q.TenantID == "" {
+		if enforce && !q.BypassTenantScope {
+			return ErrMissingTenantScope
+		}
+
+		return nil
+	}
+
+	var scoped = Bool{
+		Filter: []Query{{Term: map[string]map[string]interface{}{field: {"value": q.TenantID}}}},
+	}
+
+	if q.Query != nil {
+		scoped.Must = []Query{*q.Query}
+	}
+
+	q.Query = &Query{Bool: &scoped}
+
+	return nil
+}
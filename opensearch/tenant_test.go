@@ -0,0 +1,50 @@
+package opensearch_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+func enforceTenantScope(t *testing.T) {
+	t.Helper()
+
+	opensearch.EnforceTenantScope(true)
+	t.Cleanup(func() { opensearch.EnforceTenantScope(false) })
+}
+
+func TestTenantScope(t *testing.T) {
+	seed(t)
+	enforceTenantScope(t)
+
+	tests := []struct {
+		name    string
+		request opensearch.SearchRequest
+		want    string
+		wantErr error
+	}{
+		{name: "no scope", request: opensearch.SearchRequest{Size: 10}, wantErr: opensearch.ErrMissingTenantScope},
+		{name: "scoped", request: opensearch.SearchRequest{Size: 10}.TenantScope("t1"), want: "1"},
+		{name: "scoped query", request: opensearch.SearchRequest{
+			Size:  10,
+			Query: &opensearch.Query{IDs: map[string][]interface{}{"values": {"1", "2"}}},
+		}.TenantScope("t2"), want: "2"},
+		{name: "scope bypassed", request: opensearch.SearchRequest{Size: 10}.WithoutTenantScope(), want: "1,2"},
+		{name: "scope after bypass", request: opensearch.SearchRequest{Size: 10}.WithoutTenantScope().TenantScope("t2"), want: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.request.SearchIn(context.Background(), []string{"logs-a"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SearchIn() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := hitIDs(result); got != tt.want {
+				t.Errorf("SearchIn() hits = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
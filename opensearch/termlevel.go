@@ -0,0 +1,135 @@
+package opensearch
+
+import "strings"
+
+// TermQuery returns a query matching the documents where field is exactly value. If
+// caseInsensitive is true, keyword values are compared ignoring ASCII case.
+func TermQuery(field string, value interface{}, caseInsensitive bool) Query {
+	return Query{Term: map[string]map[string]interface{}{field: termParams(value, caseInsensitive)}}
+}
+
+// PrefixQuery returns a query matching the documents where field starts with prefix.
+// Prefix clauses only hold the prefix, so a case-insensitive one is sent as the
+// equivalent wildcard query.
+func PrefixQuery(field, prefix string, caseInsensitive bool) Query {
+	if caseInsensitive {
+		return WildcardQuery(field, wildcardEscaper.Replace(prefix)+"*", true)
+	}
+
+	return Query{Prefix: map[string]string{field: prefix}}
+}
+
+// WildcardQuery returns a query matching the documents where field matches pattern,
+// in which "*" matches any sequence of characters and "?" a single character.
+func WildcardQuery(field, pattern string, caseInsensitive bool) Query {
+	return Query{Wildcard: map[string]map[string]interface{}{field: termParams(pattern, caseInsensitive)}}
+}
+
+// RegexpQuery returns a query matching the documents where field matches the Lucene
+// regular expression pattern. Regexp clauses only hold the pattern, so a
+// case-insensitive one has its ASCII letters replaced by classes of both cases, e.g.
+// "ab[c-d]" by "[aA][bB][c-dC-D]".
+func RegexpQuery(field, pattern string, caseInsensitive bool) Query {
+	if caseInsensitive {
+		pattern = foldRegexp(pattern)
+	}
+
+	return Query{Regexp: map[string]string{field: pattern}}
+}
+
+func termParams(value interface{}, caseInsensitive bool) map[string]interface{} {
+	var params = map[string]interface{}{"value": value}
+	if caseInsensitive {
+		params["case_insensitive"] = true
+	}
+
+	return params
+}
+
+var wildcardEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`)
+
+// foldRegexp returns a Lucene regular expression matching what pattern matches,
+// ignoring the case of ASCII letters.
+func foldRegexp(pattern string) string {
+	var b strings.Builder
+	var class, quoted bool
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		other, letter := otherCase(c)
+
+		switch {
+		case quoted:
+			// Quoted strings are literal, so letters are folded between quotes.
+			switch {
+			case c == '"':
+				quoted = false
+				b.WriteByte(c)
+			case letter:
+				b.WriteString(`"[` + string(c) + string(other) + `]"`)
+			default:
+				b.WriteByte(c)
+			}
+		case c == '\\' && i+1 < len(pattern):
+			i++
+
+			switch other, ok := otherCase(pattern[i]); {
+			case ok && class:
+				b.WriteString(pattern[i-1:i+1] + string(other))
+			case ok:
+				// Escaped letters are literals, as in classes.
+				b.WriteString("[" + pattern[i:i+1] + string(other) + "]")
+			default:
+				b.WriteString(pattern[i-1 : i+1])
+			}
+		case class:
+			switch {
+			case c == ']':
+				class = false
+				b.WriteByte(c)
+			case letter && i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+				b.WriteString(pattern[i : i+3])
+
+				if last, ok := otherCase(pattern[i+2]); ok && (c >= 'a') == (pattern[i+2] >= 'a') {
+					b.WriteString(string(other) + "-" + string(last))
+				}
+
+				i += 2
+			case letter:
+				b.WriteByte(c)
+				b.WriteByte(other)
+			default:
+				b.WriteByte(c)
+			}
+		case c == '"':
+			quoted = true
+			b.WriteByte(c)
+		case c == '[':
+			class = true
+			b.WriteByte(c)
+
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				i++
+				b.WriteByte('^')
+			}
+		case letter:
+			b.WriteString("[" + string(c) + string(other) + "]")
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// otherCase returns the other case of an ASCII letter.
+func otherCase(c byte) (byte, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - 'a' + 'A', true
+	case c >= 'A' && c <= 'Z':
+		return c - 'A' + 'a', true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,152 @@
+package opensearch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimePoint is a bucket of a date_histogram aggregation. Values holds the bucket
+// "doc_count" and the values of its sub-aggregations keyed by name: single-value
+// metrics by their name, multi-value metrics like stats or percentiles as
+// "name.stat", and bucket aggregations like terms as "name.key" with the bucket
+// document count.
+type TimePoint struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// TimeSeries converts the buckets of the date_histogram aggregation name into time
+// points with times in loc, or UTC when loc is nil. Nested aggregations are selected
+// with ">" separated paths, e.g. "errors>per_hour". Metrics of empty buckets are null
+// in the response and are left out of Values, while "doc_count" is always set.
+func (r SearchResult) TimeSeries(name string, loc *time.Location) ([]TimePoint, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var agg interface{} = r.Aggregations
+
+	for _, part := range strings.Split(name, ">") {
+		m, ok := agg.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s not found in the response", name)
+		}
+
+		agg, ok = m[part]
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s not found in the response", name)
+		}
+	}
+
+	m, _ := agg.(map[string]interface{})
+
+	buckets, ok := m["buckets"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("aggregation %s is not a date_histogram", name)
+	}
+
+	var points = make([]TimePoint, 0, len(buckets))
+
+	for _, b := range buckets {
+		bucket, ok := b.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s has an invalid bucket", name)
+		}
+
+		key, ok := bucket["key"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s is not a date_histogram", name)
+		}
+
+		var point = TimePoint{
+			Time:   time.UnixMilli(int64(key)).In(loc),
+			Values: make(map[string]float64),
+		}
+
+		for k, v := range bucket {
+			switch k {
+			case "key", "key_as_string":
+			case "doc_count":
+				point.Values[k], _ = v.(float64)
+			default:
+				bucketValues(k, v, point.Values)
+			}
+		}
+
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// bucketValues adds the numeric values found in the sub-aggregation agg to values.
+func bucketValues(prefix string, agg interface{}, values map[string]float64) {
+	switch v := agg.(type) {
+	case float64:
+		values[prefix] = v
+	case map[string]interface{}:
+		if value, ok := v["value"]; ok {
+			if f, ok := value.(float64); ok {
+				values[prefix] = f
+			}
+			return
+		}
+
+		// Percentiles hold their values keyed by percent.
+		if percents, ok := v["values"].(map[string]interface{}); ok {
+			for k, sub := range percents {
+				bucketValues(prefix+"."+k, sub, values)
+			}
+			return
+		}
+
+		if buckets, ok := v["buckets"].([]interface{}); ok {
+			for _, b := range buckets {
+				if bucket, ok := b.(map[string]interface{}); ok {
+					key := bucket["key_as_string"]
+					if key == nil {
+						key = bucket["key"]
+					}
+
+					count, _ := bucket["doc_count"].(float64)
+					values[fmt.Sprintf("%s.%v", prefix, key)] = count
+				}
+			}
+			return
+		}
+
+		for k, sub := range v {
+			if k == "meta" || strings.HasSuffix(k, "_as_string") {
+				continue
+			}
+
+			bucketValues(prefix+"."+k, sub, values)
+		}
+	}
+}
+
+// FillGaps returns points with a zero valued point added at every missing step of
+// interval between the first and last points, for histograms with min_doc_count
+// above zero. Points must be sorted by time, as histogram buckets are. Calendar
+// intervals like months vary in length and cannot be filled this way; request
+// min_doc_count 0 instead.
+func FillGaps(points []TimePoint, interval time.Duration) []TimePoint {
+	if len(points) < 2 || interval <= 0 {
+		return points
+	}
+
+	var filled = make([]TimePoint, 0, len(points))
+
+	for i, p := range points {
+		if i > 0 {
+			for t := points[i-1].Time.Add(interval); t.Before(p.Time); t = t.Add(interval) {
+				filled = append(filled, TimePoint{Time: t, Values: map[string]float64{"doc_count": 0}})
+			}
+		}
+
+		filled = append(filled, p)
+	}
+
+	return filled
+}
@@ -0,0 +1,314 @@
+// Package transform manages Transform jobs, which continuously aggregate the documents
+// of source indices into entity-centric summary indices, e.g. a document per host or
+// per user with their last activity and counters.
+package transform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Group is a field the documents are grouped by, each group becoming a document of the
+// target index. Build them with Terms, DateHistogram and Histogram.
+type Group struct {
+	kind   string
+	params map[string]interface{}
+}
+
+// Terms groups the documents by the values of field, stored in target, field by
+// default.
+func Terms(field, target string) Group {
+	return Group{kind: "terms", params: groupParams(field, target)}
+}
+
+// DateHistogram groups the documents by the fixed interval, e.g. "1h", of the date
+// field, in UTC.
+func DateHistogram(field, target, fixedInterval string) Group {
+	params := groupParams(field, target)
+	params["fixed_interval"] = fixedInterval
+	params["timezone"] = "UTC"
+
+	return Group{kind: "date_histogram", params: params}
+}
+
+// Histogram groups the documents by the interval of the numeric field.
+func Histogram(field, target string, interval float64) Group {
+	params := groupParams(field, target)
+	params["interval"] = interval
+
+	return Group{kind: "histogram", params: params}
+}
+
+func groupParams(field, target string) map[string]interface{} {
+	var params = map[string]interface{}{"source_field": field}
+	if target != "" {
+		params["target_field"] = target
+	}
+
+	return params
+}
+
+func (g Group) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{g.kind: g.params})
+}
+
+// Transform is a Transform job.
+type Transform struct {
+	ID          string
+	Description string
+	SourceIndex string
+	TargetIndex string
+	// Query selects the documents transformed, all by default.
+	Query *opensearch.Query
+	// Groups are the fields of the documents of the target index, one per group.
+	Groups []Group
+	// Aggregations are computed for each group, and stored in the fields named after
+	// them.
+	Aggregations map[string]opensearch.Aggs
+	// Every is how often the job runs, one minute by default, in whole minutes.
+	Every time.Duration
+	// PageSize is the number of groups per search of the job, 1000 by default.
+	PageSize int
+	// Continuous keeps the target index up to date with new documents rather than
+	// stopping after the existing ones.
+	Continuous bool
+	// Disabled creates the job without starting it.
+	Disabled bool
+}
+
+// Body returns the transform as expected by the transforms API.
+func Body(t Transform) map[string]interface{} {
+	every := int64(t.Every / time.Minute)
+	if every < 1 {
+		every = 1
+	}
+
+	pageSize := t.PageSize
+	if pageSize <= 0 {
+		pageSize = 1000
+	}
+
+	aggs := t.Aggregations
+	if aggs == nil {
+		aggs = map[string]opensearch.Aggs{}
+	}
+
+	var query interface{} = map[string]interface{}{"match_all": map[string]interface{}{}}
+	if t.Query != nil {
+		query = t.Query
+	}
+
+	return map[string]interface{}{
+		"transform": map[string]interface{}{
+			"description":  t.Description,
+			"source_index": t.SourceIndex,
+			"target_index": t.TargetIndex,
+			"enabled":      !t.Disabled,
+			"continuous":   t.Continuous,
+			"schedule": map[string]interface{}{
+				"interval": map[string]interface{}{
+					"start_time": time.Now().UnixMilli(),
+					"period":     every,
+					"unit":       "Minutes",
+				},
+			},
+			"data_selection_query": query,
+			"page_size":            pageSize,
+			"groups":               t.Groups,
+			"aggregations":         aggs,
+		},
+	}
+}
+
+// Entity returns a continuous transform summarizing the documents of source per value
+// of field into target, e.g. per host or per user, with the given aggregations, like
+// the last seen time as a max of "@timestamp".
+func Entity(id, source, target, field string, aggs map[string]opensearch.Aggs) Transform {
+	return Transform{
+		ID:           id,
+		Description:  fmt.Sprintf("%s summary of %s", field, source),
+		SourceIndex:  source,
+		TargetIndex:  target,
+		Groups:       []Group{Terms(field, "")},
+		Aggregations: aggs,
+		Continuous:   true,
+	}
+}
+
+func transformPath(id string) string {
+	return "/_plugins/_transform/" + url.PathEscape(id)
+}
+
+func (t Transform) validate() error {
+	if t.ID == "" || t.SourceIndex == "" || t.TargetIndex == "" || len(t.Groups) == 0 {
+		return fmt.Errorf("transform requires ID, source index, target index and groups")
+	}
+
+	return nil
+}
+
+// Create creates the transform.
+func Create(ctx context.Context, t Transform) error {
+	err := t.validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = opensearch.Do(ctx, http.MethodPut, transformPath(t.ID), nil, Body(t))
+
+	return err
+}
+
+// Update replaces the transform, which must be stopped or disabled.
+func Update(ctx context.Context, t Transform) error {
+	err := t.validate()
+	if err != nil {
+		return err
+	}
+
+	resp, err := opensearch.Do(ctx, http.MethodGet, transformPath(t.ID), nil, nil)
+	if err != nil {
+		return err
+	}
+
+	var current struct {
+		SeqNo       int64 `json:"_seq_no"`
+		PrimaryTerm int64 `json:"_primary_term"`
+	}
+
+	err = json.Unmarshal(resp, &current)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("if_seq_no", fmt.Sprint(current.SeqNo))
+	params.Set("if_primary_term", fmt.Sprint(current.PrimaryTerm))
+
+	_, err = opensearch.Do(ctx, http.MethodPut, transformPath(t.ID), params, Body(t))
+
+	return err
+}
+
+// Start starts a stopped or disabled transform.
+func Start(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodPost, transformPath(id)+"/_start", nil, nil)
+
+	return err
+}
+
+// Stop stops a transform. Its progress is kept, to resume with Start.
+func Stop(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodPost, transformPath(id)+"/_stop", nil, nil)
+
+	return err
+}
+
+// Delete deletes a transform, which must be stopped or disabled. The target index is
+// kept.
+func Delete(ctx context.Context, id string) error {
+	_, err := opensearch.Do(ctx, http.MethodDelete, transformPath(id), nil, nil)
+
+	return err
+}
+
+// Preview returns the documents the transform would write to its target index,
+// without creating it.
+func Preview(ctx context.Context, t Transform) ([]map[string]interface{}, error) {
+	resp, err := opensearch.Do(ctx, http.MethodPost, "/_plugins/_transform/_preview", nil, Body(t))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Documents []map[string]interface{} `json:"documents"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Documents, nil
+}
+
+// Explanation is the progress of a transform.
+type Explanation struct {
+	ID string
+	// Status is "init", "started", "stopped", "finished" or "failed".
+	Status             string
+	FailureReason      string
+	LastUpdated        time.Time
+	PagesProcessed     int64
+	DocumentsProcessed int64
+	DocumentsIndexed   int64
+	IndexTime          time.Duration
+	SearchTime         time.Duration
+	// DocumentsBehind is, for continuous transforms, the number of documents of each
+	// source index not transformed yet.
+	DocumentsBehind map[string]int64
+}
+
+// Explain returns the progress of a transform, with a zero Status if it hasn't run yet.
+func Explain(ctx context.Context, id string) (Explanation, error) {
+	resp, err := opensearch.Do(ctx, http.MethodGet, transformPath(id)+"/_explain", nil, nil)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	var result map[string]*struct {
+		Metadata *struct {
+			Status          string `json:"status"`
+			FailureReason   string `json:"failure_reason"`
+			LastUpdated     int64  `json:"last_updated_at"`
+			ContinuousStats *struct {
+				DocumentsBehind map[string]int64 `json:"documents_behind"`
+			} `json:"continuous_stats"`
+			Stats struct {
+				PagesProcessed     int64 `json:"pages_processed"`
+				DocumentsProcessed int64 `json:"documents_processed"`
+				DocumentsIndexed   int64 `json:"documents_indexed"`
+				IndexTimeInMillis  int64 `json:"index_time_in_millis"`
+				SearchTimeInMillis int64 `json:"search_time_in_millis"`
+			} `json:"stats"`
+		} `json:"transform_metadata"`
+	}
+
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	transform, ok := result[id]
+	if !ok || transform == nil {
+		return Explanation{}, fmt.Errorf("transform %s not found", id)
+	}
+
+	e := Explanation{ID: id}
+
+	if m := transform.Metadata; m != nil {
+		e.Status = m.Status
+		e.FailureReason = m.FailureReason
+		e.PagesProcessed = m.Stats.PagesProcessed
+		e.DocumentsProcessed = m.Stats.DocumentsProcessed
+		e.DocumentsIndexed = m.Stats.DocumentsIndexed
+		e.IndexTime = time.Duration(m.Stats.IndexTimeInMillis) * time.Millisecond
+		e.SearchTime = time.Duration(m.Stats.SearchTimeInMillis) * time.Millisecond
+
+		if m.LastUpdated != 0 {
+			e.LastUpdated = time.UnixMilli(m.LastUpdated).UTC()
+		}
+
+		if m.ContinuousStats != nil {
+			e.DocumentsBehind = m.ContinuousStats.DocumentsBehind
+		}
+	}
+
+	return e, nil
+}
@@ -0,0 +1,199 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownFieldPolicy tells what searches do with the fields they reference that
+// aren't mapped in their indices.
+type UnknownFieldPolicy int
+
+const (
+	// AllowUnknownFields searches the fields as referenced, the default.
+	AllowUnknownFields UnknownFieldPolicy = iota
+	// WarnUnknownFields calls the warning function set with the policy, then
+	// searches.
+	WarnUnknownFields
+	// RejectUnknownFields fails searches with an UnknownFieldsError.
+	RejectUnknownFields
+)
+
+// UnknownField is a field referenced by a search and not mapped, with the closest
+// mapped field when one is likely meant, e.g. "severity" for "serverity".
+type UnknownField struct {
+	FieldRef
+	Suggestion string
+}
+
+// UnknownFieldsError is returned by searches referencing unknown fields under
+// RejectUnknownFields.
+type UnknownFieldsError struct {
+	Index  []string
+	Fields []UnknownField
+}
+
+func (e *UnknownFieldsError) Error() string {
+	var fields = make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		field := fmt.Sprintf("%s (%s)", f.Field, f.Clause)
+		if f.Suggestion != "" {
+			field += fmt.Sprintf(", did you mean %s?", f.Suggestion)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fmt.Sprintf("unknown fields in %s: %s", strings.Join(e.Index, ","), strings.Join(fields, "; "))
+}
+
+var unknownFields struct {
+	sync.RWMutex
+	policy UnknownFieldPolicy
+	warn   func(ctx context.Context, index []string, fields []UnknownField)
+}
+
+// SetUnknownFieldPolicy sets what SearchIn and MultiSearch do with fields not mapped
+// in the searched indices, as resolved by Mapper, catching typos that would otherwise
+// return no hits. warn is called under WarnUnknownFields and may be nil. Wildcard and
+// metadata fields are not checked.
+func SetUnknownFieldPolicy(policy UnknownFieldPolicy, warn func(ctx context.Context, index []string, fields []UnknownField)) {
+	unknownFields.Lock()
+	defer unknownFields.Unlock()
+
+	unknownFields.policy = policy
+	unknownFields.warn = warn
+}
+
+// UnknownFields returns the fields referenced by the request not mapped in the
+// indices, in the order of FieldRefs.
+func (q SearchRequest) UnknownFields(ctx context.Context, index []string) ([]UnknownField, error) {
+	pattern := strings.Join(index, ",")
+	m := Mapper()
+
+	var unknown []UnknownField
+
+	var seen = make(map[FieldRef]bool)
+
+	for _, ref := range q.FieldRefs() {
+		if strings.HasPrefix(ref.Field, "_") || strings.Contains(ref.Field, "*") || seen[ref] {
+			continue
+		}
+
+		seen[ref] = true
+
+		_, ok, err := m.Field(ctx, pattern, ref.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			unknown = append(unknown, UnknownField{FieldRef: ref})
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+
+	// Every field is only fetched to suggest fixes.
+	fields, err := m.Fields(ctx, pattern, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var names = make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for i := range unknown {
+		unknown[i].Suggestion = closestField(unknown[i].Field, names)
+	}
+
+	return unknown, nil
+}
+
+// checkUnknownFields applies the unknown field policy to the request.
+func (q SearchRequest) checkUnknownFields(ctx context.Context, index []string) error {
+	unknownFields.RLock()
+	policy, warn := unknownFields.policy, unknownFields.warn
+	unknownFields.RUnlock()
+
+	if policy == AllowUnknownFields {
+		return nil
+	}
+
+	unknown, err := q.UnknownFields(ctx, index)
+	if err != nil {
+		// Mappings failing to resolve only fail strict searches.
+		if policy == RejectUnknownFields {
+			return err
+		}
+
+		return nil
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	if policy == RejectUnknownFields {
+		return &UnknownFieldsError{Index: index, Fields: unknown}
+	}
+
+	if warn != nil {
+		warn(ctx, index, unknown)
+	}
+
+	return nil
+}
+
+// closestField returns the name closest to field, within an edit distance of a third
+// of its length, empty when none is.
+func closestField(field string, names []string) string {
+	best, bestDistance := "", len(field)/3+1
+
+	for _, name := range names {
+		d := editDistance(field, name)
+		if d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
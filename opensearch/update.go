@@ -3,7 +3,6 @@ package opensearch
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"strings"
 
@@ -11,11 +10,25 @@ import (
 )
 
 type Update struct {
-	Doc map[string]interface{} `json:"doc"`
+	Doc         map[string]interface{} `json:"doc"`
+	DocAsUpsert bool                   `json:"doc_as_upsert,omitempty"`
 }
 
 // Save updates the document in the index.
 func (h Hit) Save(ctx context.Context) error {
+	err := h.save(ctx)
+
+	recordAudit(ctx, "update", []string{h.Index}, h.ID, nil, "", err)
+
+	return err
+}
+
+func (h Hit) save(ctx context.Context) error {
+	_, err := checkIndexAccess(h.Index)
+	if err != nil {
+		return err
+	}
+
 	j, err := json.Marshal(Update{Doc: h.Source})
 	if err != nil {
 		return err
@@ -27,6 +40,7 @@ func (h Hit) Save(ctx context.Context) error {
 		Index:      h.Index,
 		DocumentID: h.ID,
 		Body:       reader,
+		Routing:    h.Routing,
 	}
 
 	resp, err := req.Do(ctx, client)
@@ -42,7 +56,81 @@ func (h Hit) Save(ctx context.Context) error {
 			return err
 		}
 
-		return fmt.Errorf("search engine status %d, response: %s", resp.StatusCode, body)
+		return statusError(ctx, resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// UpsertDoc creates the document with the given ID or updates it if it exists.
+// If partial is true, doc is merged into the existing document using _update with
+// doc_as_upsert, so fields not present in doc are preserved without a
+// read-modify-write cycle. Otherwise doc replaces the whole document.
+func UpsertDoc(ctx context.Context, index, id string, doc interface{}, partial bool, opts ...DocOption) error {
+	err := upsertDoc(ctx, index, id, doc, partial, opts)
+
+	recordAudit(ctx, "upsert", []string{index}, id, nil, "", err)
+
+	return err
+}
+
+func upsertDoc(ctx context.Context, index, id string, doc interface{}, partial bool, opts []DocOption) error {
+	_, err := checkIndexAccess(index)
+	if err != nil {
+		return err
+	}
+
+	o := newDocOptions(opts)
+
+	var src HitSource
+
+	err = src.SetSource(doc)
+	if err != nil {
+		return err
+	}
+
+	var req opensearchapi.Request
+
+	if partial {
+		j, err := json.Marshal(Update{Doc: src, DocAsUpsert: true})
+		if err != nil {
+			return err
+		}
+
+		req = opensearchapi.UpdateRequest{
+			Index:      index,
+			DocumentID: id,
+			Body:       strings.NewReader(string(j)),
+			Routing:    o.routing,
+		}
+	} else {
+		j, err := json.Marshal(src)
+		if err != nil {
+			return err
+		}
+
+		req = opensearchapi.IndexRequest{
+			Index:      index,
+			DocumentID: id,
+			Body:       strings.NewReader(string(j)),
+			Routing:    o.routing,
+		}
+	}
+
+	resp, err := req.Do(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 && resp.StatusCode != 201 && resp.StatusCode != 202 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		return statusError(ctx, resp.StatusCode, body)
 	}
 
 	return nil
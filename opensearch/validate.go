@@ -0,0 +1,224 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var fullTextClauses = map[string]bool{
+	"match":               true,
+	"match_phrase":        true,
+	"match_phrase_prefix": true,
+	"match_bool_prefix":   true,
+	"multi_match":         true,
+	"query_string":        true,
+	"simple_query_string": true,
+	"intervals":           true,
+	"more_like_this":      true,
+}
+
+var patternClauses = map[string]bool{
+	"prefix":   true,
+	"wildcard": true,
+	"regexp":   true,
+	"fuzzy":    true,
+}
+
+var textTypes = map[string]bool{
+	"text":            true,
+	"match_only_text": true,
+}
+
+var keywordTypes = map[string]bool{
+	"keyword":          true,
+	"constant_keyword": true,
+	"wildcard":         true,
+}
+
+var nonStringTypes = map[string]bool{
+	"long":          true,
+	"integer":       true,
+	"short":         true,
+	"byte":          true,
+	"double":        true,
+	"float":         true,
+	"half_float":    true,
+	"scaled_float":  true,
+	"unsigned_long": true,
+	"date":          true,
+	"date_nanos":    true,
+	"ip":            true,
+	"boolean":       true,
+}
+
+var structuredTypes = map[string]bool{
+	"object":     true,
+	"nested":     true,
+	"binary":     true,
+	"geo_point":  true,
+	"geo_shape":  true,
+	"knn_vector": true,
+	"join":       true,
+}
+
+// ValidateQuery checks whether a clause can be used on a field of the given mapping
+// type. The clause is a query type like "term" or "match", "sort", "collapse", or an
+// aggregation type prefixed with "aggs.". Unknown clauses and types are accepted.
+func ValidateQuery(fieldType, queryType string) error {
+	switch {
+	case queryType == "exists":
+		return nil
+	case queryType == "knn":
+		if fieldType != "knn_vector" {
+			return fmt.Errorf("knn can only be used on knn_vector fields, not on %s fields", fieldType)
+		}
+	case structuredTypes[fieldType]:
+		return fmt.Errorf("%s cannot be used on %s fields", queryType, fieldType)
+	case fullTextClauses[queryType]:
+		return nil
+	case queryType == "term" || queryType == "terms":
+		if textTypes[fieldType] {
+			return fmt.Errorf("%s on analyzed %s field matches individual tokens, not the original value", queryType, fieldType)
+		}
+	case patternClauses[queryType]:
+		if nonStringTypes[fieldType] {
+			return fmt.Errorf("%s can only be used on keyword or text fields, not on %s fields", queryType, fieldType)
+		}
+		if textTypes[fieldType] {
+			return fmt.Errorf("%s on analyzed %s field matches individual tokens, not the original value", queryType, fieldType)
+		}
+	case queryType == "range":
+		if textTypes[fieldType] {
+			return fmt.Errorf("range on analyzed %s field compares individual tokens", fieldType)
+		}
+	case queryType == "sort" || queryType == "collapse" || strings.HasPrefix(queryType, "aggs."):
+		if textTypes[fieldType] {
+			return fmt.Errorf("%s fields are not sortable nor aggregatable", fieldType)
+		}
+		if fieldType == "wildcard" {
+			return fmt.Errorf("wildcard fields are not sortable nor aggregatable unless doc_values is enabled")
+		}
+	}
+
+	return nil
+}
+
+// ValidationIssue is a problem found in a search request by Validate.
+type ValidationIssue struct {
+	Field      string `json:"field"`
+	Clause     string `json:"clause"`
+	Problem    string `json:"problem"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// ValidationReport lists the problems found in a search request.
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// Valid reports whether no problems were found.
+func (r ValidationReport) Valid() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns an error summarizing the issues, or nil if the report is valid.
+func (r ValidationReport) Err() error {
+	if r.Valid() {
+		return nil
+	}
+
+	var problems = make([]string, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		problems = append(problems, fmt.Sprintf("%s (%s): %s", issue.Field, issue.Clause, issue.Problem))
+	}
+
+	return fmt.Errorf("invalid search request: %s", strings.Join(problems, "; "))
+}
+
+// Validate cross-checks every field referenced by the request against the mappings of
+// the given indices, as resolved by Mapper: it reports fields that are not mapped and
+// clauses not suited to the field type, like sorting or aggregating on text fields,
+// suggesting a keyword sub-field when one exists. Wildcard and metadata fields are
+// not checked. The returned error is only set if the mappings could not be fetched.
+func (q SearchRequest) Validate(ctx context.Context, index []string) (ValidationReport, error) {
+	var report = ValidationReport{Issues: make([]ValidationIssue, 0)}
+
+	pattern := strings.Join(index, ",")
+	m := Mapper()
+
+	for _, ref := range q.FieldRefs() {
+		if strings.HasPrefix(ref.Field, "_") || strings.Contains(ref.Field, "*") {
+			continue
+		}
+
+		info, ok, err := m.Field(ctx, pattern, ref.Field)
+		if err != nil {
+			return report, err
+		}
+
+		if !ok {
+			report.Issues = append(report.Issues, ValidationIssue{
+				Field:   ref.Field,
+				Clause:  ref.Clause,
+				Problem: "field is not mapped",
+			})
+			continue
+		}
+
+		err = ValidateQuery(info.Type, ref.Clause)
+		if err == nil {
+			continue
+		}
+
+		issue := ValidationIssue{
+			Field:   ref.Field,
+			Clause:  ref.Clause,
+			Problem: err.Error(),
+		}
+
+		if textTypes[info.Type] {
+			keyword, err := keywordSubField(ctx, m, pattern, ref.Field)
+			if err != nil {
+				return report, err
+			}
+
+			if keyword != "" {
+				issue.Suggestion = fmt.Sprintf("use %s instead", keyword)
+			}
+		}
+
+		report.Issues = append(report.Issues, issue)
+	}
+
+	return report, nil
+}
+
+// keywordSubField returns the first keyword multi-field of field, preferring
+// "<field>.keyword", or an empty string if there is none.
+func keywordSubField(ctx context.Context, m *FieldMapper, pattern, field string) (string, error) {
+	subFields, err := m.Fields(ctx, pattern, field+".")
+	if err != nil {
+		return "", err
+	}
+
+	if info, ok := subFields[field+".keyword"]; ok && keywordTypes[info.Type] {
+		return info.Name, nil
+	}
+
+	var names = make([]string, 0, len(subFields))
+	for name, info := range subFields {
+		if keywordTypes[info.Type] && !strings.Contains(strings.TrimPrefix(name, field+"."), ".") {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	return names[0], nil
+}
@@ -0,0 +1,76 @@
+package opensearch
+
+import (
+	"fmt"
+	"math"
+)
+
+// Float32s converts a []float64 embedding, as decoded from JSON, to the []float32
+// used by k-NN queries.
+func Float32s(v []float64) []float32 {
+	var out = make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(x)
+	}
+
+	return out
+}
+
+// Normalize returns v scaled to unit L2 length, so inner product scores match cosine
+// similarity. A zero vector is returned unchanged.
+func Normalize(v []float32) []float32 {
+	norm := math.Sqrt(dot(v, v))
+	if norm == 0 {
+		return v
+	}
+
+	var out = make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+
+	return out
+}
+
+// Dot returns the dot product of a and b, or an error if their lengths differ.
+func Dot(a, b []float32) (float64, error) {
+	err := sameLength(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	return dot(a, b), nil
+}
+
+// Cosine returns the cosine similarity of a and b, between -1 and 1, or 0 when either
+// is a zero vector. It returns an error if their lengths differ.
+func Cosine(a, b []float32) (float64, error) {
+	err := sameLength(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	na, nb := math.Sqrt(dot(a, a)), math.Sqrt(dot(b, b))
+	if na == 0 || nb == 0 {
+		return 0, nil
+	}
+
+	return dot(a, b) / (na * nb), nil
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return sum
+}
+
+func sameLength(a, b []float32) error {
+	if len(a) != len(b) {
+		return fmt.Errorf("vector lengths differ: %d and %d", len(a), len(b))
+	}
+
+	return nil
+}
@@ -0,0 +1,368 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeliveryError is returned by the HTTP destinations when the destination answers
+// with an unexpected status code.
+type DeliveryError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("destination status %d, response: %s", e.StatusCode, e.Body)
+}
+
+func post(ctx context.Context, client *http.Client, method, url string, headers map[string]string, payload []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &DeliveryError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	return nil
+}
+
+// Webhook posts the payloads to a URL.
+type Webhook struct {
+	URL string
+	// Method is POST by default.
+	Method string
+	// Headers are set on every request, Content-Type being application/json by
+	// default.
+	Headers map[string]string
+	// Client defaults to a client with a 30 seconds timeout.
+	Client *http.Client
+}
+
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+func (w *Webhook) Deliver(ctx context.Context, payload []byte) error {
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range w.Headers {
+		headers[k] = v
+	}
+
+	return post(ctx, w.Client, method, w.URL, headers, payload)
+}
+
+// Slack posts the payloads as the text of messages of an incoming webhook.
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+func (s *Slack) Deliver(ctx context.Context, payload []byte) error {
+	body, err := json.Marshal(map[string]string{"text": string(payload)})
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, s.Client, http.MethodPost, s.WebhookURL, map[string]string{"Content-Type": "application/json"}, body)
+}
+
+// Syslog forwards the payloads as RFC 5424 messages to a syslog collector, over UDP
+// or TCP with octet counting framing.
+type Syslog struct {
+	// Network is "udp", the default, or "tcp".
+	Network string
+	Address string
+	// Facility is the syslog facility, 4 (security) by default, and Severity the
+	// severity of the messages, 4 (warning) by default.
+	Facility int
+	Severity int
+	// AppName is "threatwinds" by default, and Hostname the name of the host.
+	AppName  string
+	Hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (s *Syslog) Name() string {
+	return "syslog"
+}
+
+func (s *Syslog) Deliver(ctx context.Context, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network := s.Network
+	if network == "" {
+		network = "udp"
+	}
+
+	if s.conn == nil {
+		var d net.Dialer
+
+		conn, err := d.DialContext(ctx, network, s.Address)
+		if err != nil {
+			return err
+		}
+
+		s.conn = conn
+	}
+
+	msg := s.format(payload)
+	if network != "udp" {
+		msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	} else {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	}
+
+	_, err := s.conn.Write(msg)
+	if err != nil {
+		// The connection is dialed again on the next delivery.
+		s.conn.Close()
+		s.conn = nil
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *Syslog) format(payload []byte) []byte {
+	facility, severity := s.Facility, s.Severity
+	if facility == 0 {
+		facility = 4
+	}
+
+	if severity == 0 {
+		severity = 4
+	}
+
+	app := s.AppName
+	if app == "" {
+		app = "threatwinds"
+	}
+
+	host := s.Hostname
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	if host == "" {
+		host = "-"
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s %s - - - ", facility*8+severity, time.Now().UTC().Format(time.RFC3339Nano), host, app)
+
+	return append([]byte(header), payload...)
+}
+
+// Close closes the connection to the collector.
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
+
+// Email sends the payloads as plain text emails through an SMTP server.
+type Email struct {
+	// Address is the host and port of the server, e.g. "smtp.example.com:587".
+	Address string
+	Auth    smtp.Auth
+	From    string
+	To      []string
+	// Subject must not contain line breaks, and is encoded as RFC 2047 when it is not
+	// ASCII.
+	Subject string
+}
+
+func (e *Email) Name() string {
+	return "email"
+}
+
+// Deliver sends the payload, upgrading the session with STARTTLS when the server
+// supports it. The session is aborted when ctx is done, and times out after 30
+// seconds when ctx has no deadline.
+func (e *Email) Deliver(ctx context.Context, payload []byte) error {
+	for _, line := range append([]string{e.From}, e.To...) {
+		if strings.ContainsAny(line, "\r\n") {
+			return errors.New("email addresses must not contain line breaks")
+		}
+	}
+
+	if strings.ContainsAny(e.Subject, "\r\n") {
+		return errors.New("email subject must not contain line breaks")
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", e.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.Write(bytes.ReplaceAll(payload, []byte("\n"), []byte("\r\n")))
+
+	host, _, err := net.SplitHostPort(e.Address)
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", e.Address)
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	// Expiring the deadline interrupts the pending reads and writes.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	defer c.Close()
+
+	err = e.send(c, host, msg.Bytes())
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+func (e *Email) send(c *smtp.Client, host string, msg []byte) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err := c.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if e.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+
+		err := c.Auth(e.Auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.Mail(e.From)
+	if err != nil {
+		return err
+	}
+
+	for _, to := range e.To {
+		err = c.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// Kafka publishes the payloads to a topic through Produce, leaving the choice of the
+// Kafka client to the application, e.g. the WriteMessages method of a kafka-go writer.
+type Kafka struct {
+	Topic   string
+	Produce func(ctx context.Context, topic string, value []byte) error
+}
+
+func (k *Kafka) Name() string {
+	return "kafka"
+}
+
+func (k *Kafka) Deliver(ctx context.Context, payload []byte) error {
+	if k.Produce == nil {
+		return fmt.Errorf("kafka output of topic %s has no producer", k.Topic)
+	}
+
+	return k.Produce(ctx, k.Topic, payload)
+}
@@ -0,0 +1,312 @@
+// Package output forwards alerts to destinations outside the platform, like syslog
+// collectors, webhooks, email, Slack or Kafka, completing the input, parse and
+// analysis pipeline of the plugins.
+//
+// A Host renders each alert with the template of every route and queues the payload
+// for its destination. Each destination has its own queue and worker, retrying failed
+// deliveries with exponential backoff, so a slow or unavailable destination doesn't
+// hold back the others. With SpillDir set, payloads exhausting their attempts are kept
+// on disk and delivered once the destination is back.
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/threatwinds/go-sdk/plugins"
+	"github.com/threatwinds/go-sdk/spill"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ErrQueueFull is returned by Forward when the queue of a destination is full and
+// there is no spill queue to keep its payload.
+var ErrQueueFull = errors.New("output queue full")
+
+// Options configures a Host.
+type Options struct {
+	// QueueSize is the number of payloads queued per destination, 1000 by default.
+	QueueSize int
+	// MaxAttempts is the number of deliveries of a payload, 5 by default.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, one second by default, doubling
+	// with each attempt up to MaxBackoff, one minute by default.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// SpillDir keeps the payloads of failed deliveries and full queues on disk, in a
+	// subdirectory per destination, to deliver them every ReplayInterval, 30 seconds
+	// by default.
+	SpillDir       string
+	ReplayInterval time.Duration
+	// OnDrop is called with the payloads that are dropped, if set.
+	OnDrop func(route string, payload []byte, err error)
+}
+
+// Route sends alerts to a destination.
+type Route struct {
+	// Name identifies the route, the name of the output by default. Names must be
+	// unique in a host.
+	Name   string
+	Output plugins.OutputPlugin
+	// Template renders the alerts, executed with the *plugins.Alert, e.g.
+	// templates.Must("slack", templates.Alert) for chat and email destinations.
+	// Alerts are sent as JSON by default.
+	Template *template.Template
+	// Filter selects the alerts sent, all by default.
+	Filter func(*plugins.Alert) bool
+}
+
+// Host forwards alerts to the destinations of its routes. It is safe for concurrent
+// use.
+type Host struct {
+	opts Options
+
+	mu           sync.Mutex
+	destinations []*destination
+	running      bool
+}
+
+type destination struct {
+	route Route
+	queue chan []byte
+	spill *spill.Queue
+}
+
+// NewHost returns a host without routes.
+func NewHost(opts Options) *Host {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Minute
+	}
+
+	if opts.ReplayInterval <= 0 {
+		opts.ReplayInterval = 30 * time.Second
+	}
+
+	return &Host{opts: opts}
+}
+
+// Add adds a route to the host, before Run.
+func (h *Host) Add(r Route) error {
+	if r.Output == nil {
+		return fmt.Errorf("route %s has no output", r.Name)
+	}
+
+	if r.Name == "" {
+		r.Name = r.Output.Name()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.running {
+		return fmt.Errorf("route %s added to a running host", r.Name)
+	}
+
+	for _, d := range h.destinations {
+		if d.route.Name == r.Name {
+			return fmt.Errorf("duplicate route %s", r.Name)
+		}
+	}
+
+	d := &destination{route: r, queue: make(chan []byte, h.opts.QueueSize)}
+
+	if h.opts.SpillDir != "" {
+		q, err := spill.Open(filepath.Join(h.opts.SpillDir, r.Name), spill.Options{})
+		if err != nil {
+			return fmt.Errorf("spill queue of route %s: %w", r.Name, err)
+		}
+
+		d.spill = q
+	}
+
+	h.destinations = append(h.destinations, d)
+
+	return nil
+}
+
+// Render returns the payload of the alert for the route.
+func (r Route) Render(alert *plugins.Alert) ([]byte, error) {
+	if r.Template == nil {
+		return protojson.Marshal(alert)
+	}
+
+	var b bytes.Buffer
+
+	err := r.Template.Execute(&b, alert)
+	if err != nil {
+		return nil, fmt.Errorf("rendering alert %s for route %s: %w", alert.GetId(), r.Name, err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// Forward queues the alert for the destinations of the routes selecting it. Routes
+// failing to render it or with a full queue don't prevent the others from receiving
+// it, and their errors are returned together.
+func (h *Host) Forward(alert *plugins.Alert) error {
+	h.mu.Lock()
+	destinations := h.destinations
+	h.mu.Unlock()
+
+	var errs []error
+
+	for _, d := range destinations {
+		if d.route.Filter != nil && !d.route.Filter(alert) {
+			continue
+		}
+
+		payload, err := d.route.Render(alert)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		select {
+		case d.queue <- payload:
+		default:
+			err = h.keep(d, payload, ErrQueueFull)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("route %s: %w", d.route.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// keep spills a payload that couldn't be delivered, or drops it without a spill
+// queue, returning the reason.
+func (h *Host) keep(d *destination, payload []byte, reason error) error {
+	if d.spill != nil {
+		err := d.spill.Append(payload)
+		if err == nil {
+			return nil
+		}
+
+		reason = fmt.Errorf("%w, spilling: %v", reason, err)
+	}
+
+	if h.opts.OnDrop != nil {
+		h.opts.OnDrop(d.route.Name, payload, reason)
+	}
+
+	return reason
+}
+
+// Run delivers the queued payloads until ctx is done. Payloads still queued are then
+// spilled, or dropped, and the spill queues are closed.
+func (h *Host) Run(ctx context.Context) error {
+	h.mu.Lock()
+	if h.running {
+		h.mu.Unlock()
+		return errors.New("output host already running")
+	}
+
+	h.running = true
+	destinations := h.destinations
+	h.mu.Unlock()
+
+	var wg sync.WaitGroup
+
+	for _, d := range destinations {
+		wg.Add(1)
+
+		go func(d *destination) {
+			defer wg.Done()
+			h.work(ctx, d)
+		}(d)
+	}
+
+	wg.Wait()
+
+	var errs []error
+
+	for _, d := range destinations {
+		if d.spill != nil {
+			errs = append(errs, d.spill.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (h *Host) work(ctx context.Context, d *destination) {
+	ticker := time.NewTicker(h.opts.ReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case payload := <-d.queue:
+					_ = h.keep(d, payload, ctx.Err())
+				default:
+					return
+				}
+			}
+		case payload := <-d.queue:
+			err := h.deliver(ctx, d, payload)
+			if err != nil {
+				_ = h.keep(d, payload, err)
+			}
+		case <-ticker.C:
+			if d.spill != nil {
+				// Replay stops at the first failure, retried at the next tick.
+				_ = d.spill.Replay(func(payload []byte) error {
+					return d.route.Output.Deliver(ctx, payload)
+				})
+			}
+		}
+	}
+}
+
+// deliver sends a payload, retrying with exponential backoff.
+func (h *Host) deliver(ctx context.Context, d *destination, payload []byte) error {
+	backoff := h.opts.Backoff
+
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		err = d.route.Output.Deliver(ctx, payload)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= h.opts.MaxAttempts {
+			return fmt.Errorf("delivery to %s failed after %d attempts: %w", d.route.Name, attempt, err)
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("delivery to %s interrupted: %w", d.route.Name, err)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > h.opts.MaxBackoff {
+			backoff = h.opts.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strconv"
+	"strings"
+
+	"github.com/threatwinds/go-sdk/config"
+)
+
+// SDKVersion is the version of the SDK, checked against the SDK version plugins
+// require.
+const SDKVersion = "1.0.0"
+
+// ManifestSymbol is the name of the *Manifest variable of shared object plugins.
+const ManifestSymbol = "PluginManifest"
+
+// Type is the kind of a plugin, named after the service it implements, or the
+// OutputPlugin interface.
+type Type string
+
+const (
+	TypeInput        Type = "input"
+	TypeParsing      Type = "parsing"
+	TypeAnalysis     Type = "analysis"
+	TypeCorrelation  Type = "correlation"
+	TypeNotification Type = "notification"
+	TypeIntegration  Type = "integration"
+	TypeOutput       Type = "output"
+)
+
+var types = []Type{TypeInput, TypeParsing, TypeAnalysis, TypeCorrelation, TypeNotification, TypeIntegration, TypeOutput}
+
+// Manifest describes a plugin to its host, which validates it before loading the
+// plugin. Shared object plugins export it as the variable named ManifestSymbol:
+//
+//	var PluginManifest = plugins.Manifest{Name: "geoip", Version: "1.2.0", Type: plugins.TypeAnalysis, SDK: "1.0"}
+//
+// Other plugins ship it as JSON, in a sidecar file read by LoadManifest.
+type Manifest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    Type   `json:"type"`
+	// SDK is the SDK version the plugin requires, like "1.2" or "1.2.3": plugins run
+	// with that version and later ones of the same major version.
+	SDK string `json:"sdk"`
+	// ConfigSchema describes the configuration of the plugin, if any, e.g. as returned
+	// by config.SchemaOf.
+	ConfigSchema *config.Schema `json:"config_schema,omitempty"`
+}
+
+// ErrInvalidManifest is returned for manifests missing required values.
+var ErrInvalidManifest = errors.New("invalid plugin manifest")
+
+// IncompatibleError is returned for plugins the host can't run.
+type IncompatibleError struct {
+	Plugin string
+	Reason string
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("plugin %s is incompatible: %s", e.Plugin, e.Reason)
+}
+
+// Validate checks the manifest is complete and that the plugin runs with SDKVersion.
+func (m Manifest) Validate() error {
+	var missing []string
+
+	if m.Name == "" {
+		missing = append(missing, "name")
+	}
+
+	if m.Version == "" {
+		missing = append(missing, "version")
+	}
+
+	if m.Type == "" {
+		missing = append(missing, "type")
+	}
+
+	if m.SDK == "" {
+		missing = append(missing, "sdk")
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("%w: %s required", ErrInvalidManifest, strings.Join(missing, ", "))
+	}
+
+	if _, err := parseVersion(m.Version); err != nil {
+		return fmt.Errorf("%w: version: %v", ErrInvalidManifest, err)
+	}
+
+	if !knownType(m.Type) {
+		return &IncompatibleError{Plugin: m.Name, Reason: fmt.Sprintf("unknown plugin type %q", m.Type)}
+	}
+
+	required, err := parseVersion(m.SDK)
+	if err != nil {
+		return fmt.Errorf("%w: sdk: %v", ErrInvalidManifest, err)
+	}
+
+	current, _ := parseVersion(SDKVersion)
+
+	if required[0] != current[0] || compareVersions(required, current) > 0 {
+		return &IncompatibleError{
+			Plugin: m.Name,
+			Reason: fmt.Sprintf("requires SDK %s, host runs SDK %s", m.SDK, SDKVersion),
+		}
+	}
+
+	return nil
+}
+
+// Require validates the manifest and checks the plugin is of type t, e.g. for a host
+// loading analysis plugins.
+func (m Manifest) Require(t Type) error {
+	err := m.Validate()
+	if err != nil {
+		return err
+	}
+
+	if m.Type != t {
+		return &IncompatibleError{Plugin: m.Name, Reason: fmt.Sprintf("has type %s, %s expected", m.Type, t)}
+	}
+
+	return nil
+}
+
+// ValidateConfig checks the configuration values given to the plugin against its
+// ConfigSchema, accepting any values when it has none.
+func (m Manifest) ValidateConfig(values map[string]interface{}) error {
+	if m.ConfigSchema == nil {
+		return nil
+	}
+
+	err := m.ConfigSchema.Validate(values)
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", m.Name, err)
+	}
+
+	return nil
+}
+
+func knownType(t Type) bool {
+	for _, known := range types {
+		if t == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ManifestPath returns the sidecar manifest of the plugin binary, named after it
+// without extension, e.g. "geoip.json" for "geoip" or "geoip.exe".
+func ManifestPath(binary string) string {
+	return strings.TrimSuffix(binary, filepath.Ext(binary)) + ".json"
+}
+
+// LoadManifest reads and validates the JSON manifest at path.
+func LoadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("plugin manifest: %w", err)
+	}
+
+	var m Manifest
+
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%w %s: %v", ErrInvalidManifest, path, err)
+	}
+
+	return m, m.Validate()
+}
+
+// OpenPlugin opens a shared object plugin after validating the manifest it exports,
+// so that plugins built for another SDK fail with an IncompatibleError rather than
+// when their symbols are used.
+func OpenPlugin(path string) (*plugin.Plugin, Manifest, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		if strings.Contains(err.Error(), "different version of package") {
+			return nil, Manifest{}, &IncompatibleError{
+				Plugin: path,
+				Reason: "built with other versions of the SDK or its dependencies: " + err.Error(),
+			}
+		}
+
+		return nil, Manifest{}, err
+	}
+
+	symbol, err := p.Lookup(ManifestSymbol)
+	if err != nil {
+		return nil, Manifest{}, fmt.Errorf("%w %s: no %s variable", ErrInvalidManifest, path, ManifestSymbol)
+	}
+
+	m, ok := symbol.(*Manifest)
+	if !ok {
+		return nil, Manifest{}, fmt.Errorf("%w %s: %s is a %T, plugins.Manifest expected", ErrInvalidManifest, path, ManifestSymbol, symbol)
+	}
+
+	err = m.Validate()
+	if err != nil {
+		return nil, *m, err
+	}
+
+	return p, *m, nil
+}
+
+// parseVersion parses a "major.minor.patch" version, whose minor and patch numbers
+// are optional, ignoring a "v" prefix and any pre-release or build suffix.
+func parseVersion(v string) ([3]int, error) {
+	var version [3]int
+
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		return version, fmt.Errorf("invalid version %q", v)
+	}
+
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version, fmt.Errorf("invalid version %q", v)
+		}
+
+		version[i] = n
+	}
+
+	return version, nil
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,14 @@
+package plugins
+
+import (
+	"context"
+)
+
+// OutputPlugin delivers the alerts of the platform to a destination outside of it,
+// like a SIEM, a chat channel or a message broker. Payloads are the alerts rendered by
+// the host, which retries failed deliveries, so Deliver makes a single attempt.
+type OutputPlugin interface {
+	// Name identifies the destination, e.g. "webhook".
+	Name() string
+	Deliver(ctx context.Context, payload []byte) error
+}
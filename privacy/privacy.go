@@ -0,0 +1,134 @@
+// Package privacy pseudonymizes and anonymizes event fields, for privacy by design
+// pipelines: before indexing, with ApplyEvent, or on search results, with ApplyHit.
+package privacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/threatwinds/go-sdk/plugins"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Transform returns the replacement of a field value.
+type Transform func(value interface{}) (interface{}, error)
+
+// Rule applies Transform to the dot-notation Field, e.g. "remote.ip" or "log.email".
+// Lists are transformed element by element.
+type Rule struct {
+	Field     string
+	Transform Transform
+}
+
+// Policy is a set of rules applied together.
+type Policy []Rule
+
+// ApplyMap transforms the fields of doc in place. Missing fields are ignored.
+func (p Policy) ApplyMap(doc map[string]interface{}) error {
+	for _, rule := range p {
+		err := apply(doc, strings.Split(rule.Field, "."), rule.Transform)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", rule.Field, err)
+		}
+	}
+
+	return nil
+}
+
+// ApplyHit transforms the fields of the source of a search hit in place.
+func (p Policy) ApplyHit(hit *opensearch.Hit) error {
+	return p.ApplyMap(hit.Source)
+}
+
+// ApplyEvent transforms the fields of an event in place. Fields are named after the
+// JSON representation of the event, e.g. "remote.ip" or "log.userEmail".
+func (p Policy) ApplyEvent(e *plugins.Event) error {
+	j, err := protojson.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var doc map[string]interface{}
+
+	err = json.Unmarshal(j, &doc)
+	if err != nil {
+		return err
+	}
+
+	err = p.ApplyMap(doc)
+	if err != nil {
+		return err
+	}
+
+	j, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return protojson.Unmarshal(j, e)
+}
+
+func apply(node map[string]interface{}, path []string, t Transform) error {
+	// Flattened keys like "source.ip" are matched as well as nested objects.
+	for i := len(path); i > 0; i-- {
+		key := strings.Join(path[:i], ".")
+
+		value, ok := node[key]
+		if !ok {
+			continue
+		}
+
+		if i == len(path) {
+			transformed, err := transformValue(value, t)
+			if err != nil {
+				return err
+			}
+
+			node[key] = transformed
+			return nil
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			return apply(v, path[i:], t)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					err := apply(m, path[i:], t)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func transformValue(value interface{}, t Transform) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return t(value)
+	}
+
+	var out = make([]interface{}, len(list))
+
+	for i, item := range list {
+		transformed, err := transformValue(item, t)
+		if err != nil {
+			return nil, err
+		}
+
+		out[i] = transformed
+	}
+
+	return out, nil
+}
@@ -0,0 +1,129 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Keyring holds the HMAC keys used to pseudonymize values. New values are hashed with
+// the current key, while the previous keys are kept to find the values hashed before
+// a rotation.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	current string
+}
+
+// NewKeyring returns a keyring using key, identified by id, as its current key.
+func NewKeyring(id string, key []byte) *Keyring {
+	return &Keyring{keys: map[string][]byte{id: key}, current: id}
+}
+
+// Rotate adds key, identified by id, and makes it the current key.
+func (k *Keyring) Rotate(id string, key []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[id] = key
+	k.current = id
+}
+
+// Retire removes a previous key. Values hashed with it can no longer be matched.
+func (k *Keyring) Retire(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if id == k.current {
+		return fmt.Errorf("cannot retire the current key %s", id)
+	}
+
+	delete(k.keys, id)
+
+	return nil
+}
+
+// Pseudonym returns the HMAC-SHA256 of value with the current key, prefixed with the
+// key ID, e.g. "2024q3:1f0c...". Equal values get equal pseudonyms until the key is
+// rotated, so pseudonymized fields can still be searched and aggregated.
+func (k *Keyring) Pseudonym(value string) string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return pseudonym(k.current, k.keys[k.current], value)
+}
+
+// Candidates returns the pseudonyms of value under every key, to search the documents
+// pseudonymized before and after rotations, e.g. with a terms query.
+func (k *Keyring) Candidates(value string) []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var candidates = make([]string, 0, len(k.keys))
+	for id, key := range k.keys {
+		candidates = append(candidates, pseudonym(id, key, value))
+	}
+
+	return candidates
+}
+
+func pseudonym(id string, key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+
+	return id + ":" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Hash returns a transform replacing values by their pseudonym in keyring.
+func Hash(keyring *Keyring) Transform {
+	return func(value interface{}) (interface{}, error) {
+		return keyring.Pseudonym(fmt.Sprint(value)), nil
+	}
+}
+
+// TruncateIP returns a transform keeping the first v4Bits of IPv4 addresses and the
+// first v6Bits of IPv6 addresses, zeroing the rest, e.g. 24 and 48 bits turn
+// "192.168.1.77" into "192.168.1.0". Values that are not IP addresses are an error.
+func TruncateIP(v4Bits, v6Bits int) Transform {
+	return func(value interface{}) (interface{}, error) {
+		s := fmt.Sprint(value)
+
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not an IP address", s)
+		}
+
+		if v4 := ip.To4(); v4 != nil {
+			return v4.Mask(net.CIDRMask(v4Bits, 32)).String(), nil
+		}
+
+		return ip.Mask(net.CIDRMask(v6Bits, 128)).String(), nil
+	}
+}
+
+// MaskEmail returns a transform keeping the first character of the local part and
+// the domain of email addresses, e.g. "john.doe@example.com" becomes
+// "j***@example.com". Values without "@" are masked entirely.
+func MaskEmail() Transform {
+	return func(value interface{}) (interface{}, error) {
+		s := fmt.Sprint(value)
+
+		local, domain, ok := strings.Cut(s, "@")
+		if !ok || local == "" {
+			return "***", nil
+		}
+
+		return local[:1] + "***@" + domain, nil
+	}
+}
+
+// Redact returns a transform replacing every value with replacement.
+func Redact(replacement string) Transform {
+	return func(interface{}) (interface{}, error) {
+		return replacement, nil
+	}
+}
@@ -0,0 +1,232 @@
+// Package schedule runs periodic tasks, like feed pulls and retention jobs, on cron
+// schedules with jitter. Runs can be claimed in an index so that, across the replicas
+// of a service, each scheduled run executes once.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the next activation after a given time.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Every is a schedule activating at fixed intervals, aligned on the zero time so
+// that every replica computes the same activations.
+type Every time.Duration
+
+func (e Every) Next(after time.Time) time.Time {
+	d := time.Duration(e)
+	if d <= 0 {
+		return time.Time{}
+	}
+
+	return after.Truncate(d).Add(d)
+}
+
+// cron is a schedule in the standard five fields format, with a bit per allowed
+// minute, hour, day of month, month and day of week.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// anyDay is true when either day field is "*", in which case a day matches if
+	// both fields match, instead of either of them.
+	anyDay   bool
+	location *time.Location
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// Parse parses a schedule: five cron fields, minute, hour, day of month, month and
+// day of week, supporting lists, ranges, steps and names, like "*/15 8-18 * * mon-fri";
+// a macro like "@daily" or "@hourly"; or "@every 10m". Cron schedules are in UTC,
+// unless prefixed with "TZ=<location> ".
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	location := time.UTC
+
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		tz, rest, _ := strings.Cut(spec, " ")
+
+		loc, err := time.LoadLocation(tz[strings.Index(tz, "=")+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+
+		location, spec = loc, strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q", spec)
+		}
+
+		return Every(d), nil
+	}
+
+	if macro, ok := macros[spec]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", spec)
+	}
+
+	c := &cron{location: location, anyDay: fields[2] == "*" || fields[4] == "*"}
+
+	var err error
+
+	for i, f := range []struct {
+		bits     *uint64
+		min, max int
+		names    map[string]int
+	}{
+		{&c.minute, 0, 59, nil},
+		{&c.hour, 0, 23, nil},
+		{&c.dom, 1, 31, nil},
+		{&c.month, 1, 12, monthNames},
+		{&c.dow, 0, 7, dayNames},
+	} {
+		*f.bits, err = parseField(fields[i], f.min, f.max, f.names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+
+	// 7 is also sunday.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	return c, nil
+}
+
+// MustParse is like Parse but panics if the schedule is invalid.
+func MustParse(spec string) Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		var lo, hi int
+
+		if rng == "*" {
+			lo, hi = min, max
+		} else {
+			from, to, isRange := strings.Cut(rng, "-")
+
+			var err error
+
+			lo, err = parseValue(from, min, max, names)
+			if err != nil {
+				return 0, err
+			}
+
+			hi = lo
+
+			if isRange {
+				hi, err = parseValue(to, min, max, names)
+				if err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max
+			}
+
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid value %q, expected %d-%d", s, min, max)
+	}
+
+	return v, nil
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.anyDay {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Next returns the first activation after after, or the zero time if there is none
+// within five years, like for "0 0 30 2 *".
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.In(c.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.location)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.location)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.location)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
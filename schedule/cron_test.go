@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "*/15 8-18 * * mon-fri"},
+		{spec: "0 0 1,15 jan-jun *"},
+		{spec: "30 2 * * 7"},
+		{spec: "@daily"},
+		{spec: "@every 10m"},
+		{spec: "TZ=Europe/Madrid 0 9 * * *"},
+		{spec: "CRON_TZ=America/New_York @hourly"},
+		{spec: "* * * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "0 24 * * *", wantErr: true},
+		{spec: "0 0 0 * *", wantErr: true},
+		{spec: "0 0 * 13 *", wantErr: true},
+		{spec: "0 0 * * 8", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+		{spec: "10-5 * * * *", wantErr: true},
+		{spec: "0 0 * * funday", wantErr: true},
+		{spec: "@every 0s", wantErr: true},
+		{spec: "@every soon", wantErr: true},
+		{spec: "@fortnightly", wantErr: true},
+		{spec: "TZ=Nowhere/Atlantis 0 0 * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+
+	utc := func(s string) time.Time {
+		t.Helper()
+
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return v
+	}
+
+	tests := []struct {
+		name  string
+		spec  string
+		after string
+		want  time.Time
+	}{
+		{name: "next step", spec: "*/15 * * * *", after: "2024-05-01T10:07:30Z", want: utc("2024-05-01T10:15:00Z")},
+		{name: "strictly after", spec: "*/15 * * * *", after: "2024-05-01T10:15:00Z", want: utc("2024-05-01T10:30:00Z")},
+		{name: "next hour in range", spec: "0 8-18 * * *", after: "2024-05-01T18:30:00Z", want: utc("2024-05-02T08:00:00Z")},
+		{name: "weekdays skip weekend", spec: "0 9 * * mon-fri", after: "2024-05-03T10:00:00Z", want: utc("2024-05-06T09:00:00Z")},
+		{name: "7 is sunday", spec: "0 0 * * 7", after: "2024-05-01T00:00:00Z", want: utc("2024-05-05T00:00:00Z")},
+		{name: "month names", spec: "0 0 1 feb *", after: "2024-05-01T00:00:00Z", want: utc("2025-02-01T00:00:00Z")},
+		{name: "leap day", spec: "0 0 29 2 *", after: "2024-03-01T00:00:00Z", want: utc("2028-02-29T00:00:00Z")},
+		{name: "day of month or weekday", spec: "0 0 13 * fri", after: "2024-05-01T00:00:00Z", want: utc("2024-05-03T00:00:00Z")},
+		{name: "day of month and any weekday", spec: "0 0 13 * *", after: "2024-05-01T00:00:00Z", want: utc("2024-05-13T00:00:00Z")},
+		{name: "macro", spec: "@weekly", after: "2024-05-01T00:00:00Z", want: utc("2024-05-05T00:00:00Z")},
+		{name: "time zone", spec: "TZ=Europe/Madrid 0 9 * * *", after: "2024-05-01T08:00:00Z", want: time.Date(2024, 5, 2, 9, 0, 0, 0, madrid)},
+		{name: "every", spec: "@every 10m", after: "2024-05-01T10:07:30Z", want: utc("2024-05-01T10:10:00Z")},
+		{name: "never", spec: "0 0 30 2 *", after: "2024-05-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MustParse(tt.spec).Next(utc(tt.after))
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%s) of %q = %s, want %s", tt.after, tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+)
+
+// Job is a periodic task.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter delays each run by a random duration up to Jitter, spreading the load of
+	// jobs sharing a schedule, like feed pulls hitting the same provider.
+	Jitter time.Duration
+	// Timeout cancels the context of a run, unlimited when zero.
+	Timeout time.Duration
+	Task    func(ctx context.Context) error
+}
+
+// Result is the outcome of a scheduled run. Skipped runs were claimed by another
+// replica.
+type Result struct {
+	Job      string
+	Run      time.Time
+	Started  time.Time
+	Duration time.Duration
+	Skipped  bool
+	Err      error
+}
+
+// Claimer claims the runs of jobs, so that each run executes in a single replica.
+type Claimer interface {
+	// Claim reports whether the run of job scheduled at run was claimed by the
+	// caller, false if it was claimed before.
+	Claim(ctx context.Context, job string, run time.Time) (bool, error)
+}
+
+// Scheduler runs jobs on their schedules. A job doesn't overlap with itself: runs
+// scheduled while it is still running are skipped.
+type Scheduler struct {
+	// Claimer, when set, claims each run before executing it. Without it, every
+	// replica runs every job.
+	Claimer Claimer
+	// OnResult is called after each run, e.g. to log failures.
+	OnResult func(Result)
+
+	mu      sync.Mutex
+	jobs    map[string]Job
+	started bool
+}
+
+// New returns a scheduler claiming runs with claimer, which may be nil.
+func New(claimer Claimer) *Scheduler {
+	return &Scheduler{Claimer: claimer, jobs: make(map[string]Job)}
+}
+
+// Add adds a job. Jobs must be added before Run.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Task == nil {
+		return fmt.Errorf("job requires name, schedule and task")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("scheduler already running")
+	}
+
+	if s.jobs == nil {
+		s.jobs = make(map[string]Job)
+	}
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("job %s already exists", job.Name)
+	}
+
+	s.jobs[job.Name] = job
+
+	return nil
+}
+
+// Run runs the jobs until ctx is done, then waits for the running tasks to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.started = true
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+
+	for _, job := range jobs {
+		wg.Add(1)
+
+		go func(job Job) {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	for {
+		run := job.Schedule.Next(time.Now())
+		if run.IsZero() {
+			return
+		}
+
+		wait := time.Until(run)
+		if job.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(job.Jitter)))
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		result := s.execute(ctx, job, run)
+		if s.OnResult != nil {
+			s.OnResult(result)
+		}
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, job Job, run time.Time) Result {
+	result := Result{Job: job.Name, Run: run, Started: time.Now()}
+
+	if s.Claimer != nil {
+		claimed, err := s.Claimer.Claim(ctx, job.Name, run)
+		if err != nil {
+			result.Err = fmt.Errorf("claim: %w", err)
+			return result
+		}
+
+		if !claimed {
+			result.Skipped = true
+			return result
+		}
+	}
+
+	runCtx := ctx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		runCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	result.Err = job.Task(runCtx)
+	result.Duration = time.Since(result.Started)
+
+	return result
+}
+
+// IndexClaimer claims runs by creating a document per run in an index. Creation fails
+// with a conflict for the replicas claiming a run after the first one. Claims are
+// small and can be expired with a retention policy on the index.
+type IndexClaimer struct {
+	Index string
+	// Owner identifies the replica in the claims, the hostname by default.
+	Owner string
+}
+
+// Claim creates the claim document of the run.
+func (c IndexClaimer) Claim(ctx context.Context, job string, run time.Time) (bool, error) {
+	owner := c.Owner
+	if owner == "" {
+		owner, _ = os.Hostname()
+	}
+
+	id := fmt.Sprintf("%s@%d", job, run.Unix())
+
+	_, err := opensearch.Do(ctx, http.MethodPut, "/"+c.Index+"/_create/"+url.PathEscape(id), nil, map[string]interface{}{
+		"@timestamp": time.Now().UTC(),
+		"job":        job,
+		"run":        run.UTC(),
+		"owner":      owner,
+	})
+
+	var statusErr *opensearch.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,35 @@
+package secrets
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// bearer sends a secret as bearer token with every RPC.
+type bearer struct {
+	provider Provider
+	name     string
+	insecure bool
+}
+
+// BearerToken returns gRPC credentials sending the secret name as bearer token in the
+// authorization metadata of every call, read from p at each call, so rotations apply
+// without reconnecting; wrap p with NewCached to limit the reads. Tokens are only
+// sent over secured transports unless allowInsecure is set, e.g. for local sockets.
+func BearerToken(p Provider, name string, allowInsecure bool) credentials.PerRPCCredentials {
+	return bearer{provider: p, name: name, insecure: allowInsecure}
+}
+
+func (b bearer) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := b.provider.Get(ctx, b.name)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"authorization": "Bearer " + token.Reveal()}, nil
+}
+
+func (b bearer) RequireTransportSecurity() bool {
+	return !b.insecure
+}
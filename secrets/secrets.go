@@ -0,0 +1,159 @@
+// Package secrets reads credentials, like cluster passwords and API keys, from the
+// environment, mounted files or HashiCorp Vault, so that they are resolved where they
+// are used instead of being carried in configuration maps.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a provider has no secret with the requested name.
+var ErrNotFound = errors.New("secret not found")
+
+// Secret is the value of a secret. It prints redacted, so that it doesn't leak
+// through logs or error messages; use Bytes or Reveal to read it.
+type Secret []byte
+
+func (s Secret) String() string {
+	return "[redacted]"
+}
+
+func (s Secret) GoString() string {
+	return "secrets.Secret([redacted])"
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte("[redacted]"), nil
+}
+
+// Bytes returns the value.
+func (s Secret) Bytes() []byte {
+	return []byte(s)
+}
+
+// Reveal returns the value as a string.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// Provider returns secrets by name.
+type Provider interface {
+	Get(ctx context.Context, name string) (Secret, error)
+}
+
+// Env reads secrets from environment variables, named Prefix followed by the name in
+// uppercase, with dots and dashes as underscores.
+type Env struct {
+	Prefix string
+}
+
+func (e Env) Get(_ context.Context, name string) (Secret, error) {
+	variable := e.Prefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_", "/", "_").Replace(name))
+
+	value, ok := os.LookupEnv(variable)
+	if !ok {
+		return nil, fmt.Errorf("%w: environment variable %s", ErrNotFound, variable)
+	}
+
+	return Secret(value), nil
+}
+
+// Files reads secrets from the files in Dir, like the secrets mounted as volumes in
+// containers. Trailing newlines are removed.
+type Files struct {
+	Dir string
+}
+
+func (f Files) Get(_ context.Context, name string) (Secret, error) {
+	if name == "" || strings.Contains(name, "..") {
+		return nil, fmt.Errorf("invalid secret name %q", name)
+	}
+
+	path := filepath.Join(f.Dir, filepath.FromSlash(name))
+
+	value, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: file %s", ErrNotFound, path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Secret(strings.TrimRight(string(value), "\r\n")), nil
+}
+
+// Chain returns the secret of the first provider having it.
+type Chain []Provider
+
+func (c Chain) Get(ctx context.Context, name string) (Secret, error) {
+	for _, p := range c {
+		s, err := p.Get(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		return s, err
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Cached caches the secrets of a provider for a TTL, e.g. to avoid querying Vault on
+// every request.
+type Cached struct {
+	Provider Provider
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cached
+}
+
+type cached struct {
+	secret  Secret
+	expires time.Time
+}
+
+// NewCached returns a Cached provider.
+func NewCached(p Provider, ttl time.Duration) *Cached {
+	return &Cached{Provider: p, TTL: ttl}
+}
+
+func (c *Cached) Get(ctx context.Context, name string) (Secret, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.secret, nil
+	}
+
+	s, err := c.Provider.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]cached)
+	}
+
+	c.entries[name] = cached{secret: s, expires: time.Now().Add(c.TTL)}
+	c.mu.Unlock()
+
+	return s, nil
+}
+
+// Forget removes a secret from the cache, e.g. after it was rejected as outdated.
+func (c *Cached) Forget(name string) {
+	c.mu.Lock()
+	delete(c.entries, name)
+	c.mu.Unlock()
+}
@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Vault reads secrets from a KV version 2 secrets engine of HashiCorp Vault. Names
+// are "path#key", the key defaulting to "value", e.g. "opensearch/admin#password".
+type Vault struct {
+	// Address of the server, e.g. "https://vault:8200".
+	Address string
+	// Token authenticates the requests, read from TokenFile when empty, e.g. a token
+	// written by a Vault agent.
+	Token     string
+	TokenFile string
+	// Mount of the secrets engine, "secret" by default.
+	Mount     string
+	Namespace string
+	// HTTPClient defaults to a client with a 10 seconds timeout.
+	HTTPClient *http.Client
+}
+
+func (v Vault) Get(ctx context.Context, name string) (Secret, error) {
+	path, key, ok := strings.Cut(name, "#")
+	if !ok {
+		key = "value"
+	}
+
+	mount := v.Mount
+	if mount == "" {
+		mount = "secret"
+	}
+
+	token := v.Token
+	if token == "" && v.TokenFile != "" {
+		t, err := Files{}.Get(ctx, v.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("vault token: %w", err)
+		}
+
+		token = t.Reveal()
+	}
+
+	u := strings.TrimSuffix(v.Address, "/") + "/v1/" + strings.Trim(mount, "/") + "/data/" + strings.Trim(path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", token)
+
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	client := v.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: vault path %s", ErrNotFound, path)
+	default:
+		// The body of errors holds messages only, never secret values.
+		return nil, fmt.Errorf("vault status %d, response: %s", resp.StatusCode, body)
+	}
+
+	var kv struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+
+	err = json.Unmarshal(body, &kv)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	value, ok := kv.Data.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: vault key %s of %s", ErrNotFound, key, path)
+	}
+
+	if s, ok := value.(string); ok {
+		return Secret(s), nil
+	}
+
+	j, err := json.Marshal(value)
+
+	return Secret(j), err
+}
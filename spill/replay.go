@@ -0,0 +1,261 @@
+package spill
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/threatwinds/go-sdk/plugins"
+	"google.golang.org/protobuf/proto"
+)
+
+var errCorrupt = errors.New("corrupt record")
+
+// cursorEvery is the number of replayed records between cursor writes.
+const cursorEvery = 1000
+
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [headerSize]byte
+
+	_, err := io.ReadFull(r, header[:])
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errCorrupt
+		}
+
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+
+	var data = make([]byte, length)
+
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errCorrupt
+		}
+
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errCorrupt
+	}
+
+	return data, nil
+}
+
+// Replay calls fn with the queued records in order, removing them from the queue as
+// fn succeeds. It stops at the first error of fn, leaving that record first in the
+// queue, and returns the error. Records appended while replaying are replayed too.
+//
+// Delivery is at least once: after a crash, up to a thousand records already passed
+// to fn may be replayed again, so fn should be idempotent, e.g. indexing events with
+// their IDs.
+func (q *Queue) Replay(fn func(data []byte) error) error {
+	for {
+		q.mu.Lock()
+
+		if q.active == nil {
+			q.mu.Unlock()
+			return os.ErrClosed
+		}
+
+		var current segment
+		var found bool
+
+		for _, s := range q.segments {
+			if s.id >= q.cursor.Segment {
+				current, found = s, true
+				break
+			}
+		}
+
+		last := current.id == q.segments[len(q.segments)-1].id
+		offset := q.offsetIn(current.id)
+
+		q.mu.Unlock()
+
+		if !found || (last && offset >= current.size) {
+			return nil
+		}
+
+		end, err := q.replaySegment(current, offset, fn)
+		if err != nil {
+			return err
+		}
+
+		q.mu.Lock()
+
+		if last {
+			err = q.commit(position{Segment: current.id, Offset: end})
+		} else {
+			err = q.finish(current.id)
+		}
+
+		q.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// replaySegment replays the records of s from offset up to its size when the replay
+// started, and returns the offset reached.
+func (q *Queue) replaySegment(s segment, offset int64, fn func([]byte) error) (int64, error) {
+	f, err := os.Open(q.path(s.id))
+	if err != nil {
+		return offset, err
+	}
+
+	defer f.Close()
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return offset, err
+	}
+
+	r := bufio.NewReader(io.LimitReader(f, s.size-offset))
+
+	var n int
+
+	for offset < s.size {
+		data, err := readRecord(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, errCorrupt) {
+				// Skip the unreadable rest of a sealed segment.
+				return s.size, nil
+			}
+
+			return offset, err
+		}
+
+		err = fn(data)
+		if err != nil {
+			q.mu.Lock()
+			cerr := q.commit(position{Segment: s.id, Offset: offset})
+			q.mu.Unlock()
+
+			return offset, errors.Join(err, cerr)
+		}
+
+		offset += headerSize + int64(len(data))
+
+		n++
+		if n%cursorEvery == 0 {
+			q.mu.Lock()
+			err = q.commit(position{Segment: s.id, Offset: offset})
+			q.mu.Unlock()
+
+			if err != nil {
+				return offset, err
+			}
+		}
+	}
+
+	return offset, nil
+}
+
+// commit persists the replay position, unless its segment was evicted meanwhile.
+// The caller must hold q.mu.
+func (q *Queue) commit(p position) error {
+	if len(q.segments) == 0 || p.Segment < q.segments[0].id {
+		return nil
+	}
+
+	return q.writeCursor(p)
+}
+
+// finish deletes a fully replayed sealed segment. The caller must hold q.mu.
+func (q *Queue) finish(id int64) error {
+	if len(q.segments) == 0 || q.segments[0].id != id {
+		return nil
+	}
+
+	err := os.Remove(q.path(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	q.segments = q.segments[1:]
+
+	return q.writeCursor(position{Segment: q.segments[0].id})
+}
+
+// ReplayEvents is Replay for queues of events appended with AppendEvent.
+func (q *Queue) ReplayEvents(fn func(e *plugins.Event) error) error {
+	return q.Replay(func(data []byte) error {
+		var e plugins.Event
+
+		err := proto.Unmarshal(data, &e)
+		if err != nil {
+			// An event that can't be decoded will never be, so it is dropped.
+			return nil
+		}
+
+		return fn(&e)
+	})
+}
+
+func (q *Queue) readCursor() error {
+	j, err := os.ReadFile(filepath.Join(q.dir, cursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(j, &q.cursor)
+	if err != nil {
+		return fmt.Errorf("invalid spill cursor: %w", err)
+	}
+
+	return nil
+}
+
+// writeCursor atomically replaces the cursor file. The caller must hold q.mu.
+func (q *Queue) writeCursor(p position) error {
+	j, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(q.dir, cursorFile+".tmp")
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(j)
+	if err == nil && !q.opts.NoSync {
+		err = f.Sync()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmp, filepath.Join(q.dir, cursorFile))
+	if err != nil {
+		return err
+	}
+
+	q.cursor = p
+
+	return nil
+}
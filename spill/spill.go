@@ -0,0 +1,406 @@
+// Package spill is a persistent queue on local disk where events can be spilled while
+// the search engine is unavailable, and replayed once it is back, so that maintenance
+// windows and outages don't lose events.
+//
+// The queue is a directory of append-only segment files. Every record is written with
+// its length and CRC32 checksum, and optionally synced to disk, so a crash loses at
+// most the record being written. The replay position is persisted in a cursor file,
+// and fully replayed segments are deleted.
+package spill
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/threatwinds/go-sdk/plugins"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrQueueFull is returned by Append when the queue reached MaxBytes and its eviction
+// policy is RejectNew, or when a record is larger than MaxBytes.
+var ErrQueueFull = errors.New("spill queue full")
+
+// Eviction is what happens when the queue reaches its size cap.
+type Eviction int
+
+const (
+	// DropOldest deletes the oldest segments to make room for new records.
+	DropOldest Eviction = iota
+	// RejectNew rejects new records until the queue is replayed.
+	RejectNew
+)
+
+// Options configures a queue.
+type Options struct {
+	// SegmentSize is the size at which a new segment is started, 64 MiB by default.
+	SegmentSize int64
+	// MaxBytes caps the size of the queue on disk, unlimited when zero.
+	MaxBytes int64
+	Eviction Eviction
+	// NoSync skips the fsync after each append, trading durability for throughput.
+	NoSync bool
+}
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor"
+	headerSize = 8
+)
+
+// Queue is a persistent FIFO queue of records. It is safe for concurrent use.
+type Queue struct {
+	dir  string
+	opts Options
+
+	mu       sync.Mutex
+	segments []segment
+	active   *os.File
+	cursor   position
+	// dropped counts the records evicted by DropOldest since Open.
+	dropped int64
+}
+
+type segment struct {
+	id   int64
+	size int64
+}
+
+type position struct {
+	Segment int64
+	Offset  int64
+}
+
+// Open opens the queue stored in dir, creating it if needed. Records left by a
+// previous process are kept for Replay; a record torn by a crash at the end of the
+// last segment is discarded.
+func Open(dir string, opts Options) (*Queue, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = 64 << 20
+	}
+
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Queue{dir: dir, opts: opts}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		id, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		q.segments = append(q.segments, segment{id: id, size: info.Size()})
+	}
+
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].id < q.segments[j].id })
+
+	err = q.readCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(q.segments) == 0 {
+		err = q.rotate()
+	} else {
+		err = q.openLast()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (q *Queue) path(id int64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// openLast opens the last segment for appending, truncating a torn record at its end.
+func (q *Queue) openLast() error {
+	last := &q.segments[len(q.segments)-1]
+
+	f, err := os.OpenFile(q.path(last.id), os.O_RDWR, 0o640)
+	if err != nil {
+		return err
+	}
+
+	valid, err := validLength(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if valid != last.size {
+		err = f.Truncate(valid)
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		last.size = valid
+	}
+
+	_, err = f.Seek(valid, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	q.active = f
+
+	return nil
+}
+
+// validLength returns the length of the valid records at the start of f.
+func validLength(f *os.File) (int64, error) {
+	r := bufio.NewReader(f)
+
+	var offset int64
+
+	for {
+		data, err := readRecord(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, errCorrupt) {
+				return offset, nil
+			}
+
+			return 0, err
+		}
+
+		offset += headerSize + int64(len(data))
+	}
+}
+
+// rotate closes the active segment and starts a new one. The caller must hold q.mu.
+func (q *Queue) rotate() error {
+	var id int64 = 1
+	if len(q.segments) != 0 {
+		id = q.segments[len(q.segments)-1].id + 1
+	}
+
+	f, err := os.OpenFile(q.path(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if q.active != nil {
+		q.active.Close()
+	}
+
+	q.active = f
+	q.segments = append(q.segments, segment{id: id})
+
+	return nil
+}
+
+// Append adds a record to the queue.
+func (q *Queue) Append(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active == nil {
+		return os.ErrClosed
+	}
+
+	size := headerSize + int64(len(data))
+
+	if q.opts.MaxBytes > 0 {
+		for q.size()+size > q.opts.MaxBytes {
+			if q.opts.Eviction == RejectNew {
+				return ErrQueueFull
+			}
+
+			if len(q.segments) < 2 {
+				// The active segment is never evicted, so it is first closed, unless
+				// it is empty and the record can't fit at all.
+				if q.segments[0].size == 0 {
+					return ErrQueueFull
+				}
+
+				err := q.rotate()
+				if err != nil {
+					return err
+				}
+			}
+
+			err := q.evictOldest()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	last := q.segments[len(q.segments)-1]
+	if last.size > 0 && last.size+size > q.opts.SegmentSize {
+		err := q.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	var record = make([]byte, size)
+
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(data))
+	copy(record[headerSize:], data)
+
+	_, err := q.active.Write(record)
+	if err != nil {
+		return err
+	}
+
+	if !q.opts.NoSync {
+		err = q.active.Sync()
+		if err != nil {
+			return err
+		}
+	}
+
+	q.segments[len(q.segments)-1].size += size
+
+	return nil
+}
+
+// AppendEvent adds an event to the queue.
+func (q *Queue) AppendEvent(e *plugins.Event) error {
+	data, err := proto.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return q.Append(data)
+}
+
+// size returns the bytes not replayed yet. The caller must hold q.mu.
+func (q *Queue) size() int64 {
+	var size int64
+
+	for _, s := range q.segments {
+		size += s.size
+		if s.id == q.cursor.Segment {
+			size -= q.cursor.Offset
+		}
+	}
+
+	return size
+}
+
+// evictOldest deletes the oldest segment, which is never the active one.
+// The caller must hold q.mu.
+func (q *Queue) evictOldest() error {
+	oldest := q.segments[0]
+
+	n, err := q.countRecords(oldest.id, q.offsetIn(oldest.id))
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(q.path(oldest.id))
+	if err != nil {
+		return err
+	}
+
+	q.segments = q.segments[1:]
+	q.dropped += n
+
+	return q.writeCursor(position{Segment: q.segments[0].id})
+}
+
+// offsetIn returns the replay offset within the segment id.
+func (q *Queue) offsetIn(id int64) int64 {
+	if q.cursor.Segment == id {
+		return q.cursor.Offset
+	}
+
+	return 0
+}
+
+func (q *Queue) countRecords(id, offset int64) (int64, error) {
+	f, err := os.Open(q.path(id))
+	if err != nil {
+		return 0, err
+	}
+
+	defer f.Close()
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	r := bufio.NewReader(f)
+
+	var n int64
+
+	for {
+		_, err := readRecord(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, errCorrupt) {
+				return n, nil
+			}
+
+			return n, err
+		}
+
+		n++
+	}
+}
+
+// Dropped returns the number of records evicted by DropOldest since Open.
+func (q *Queue) Dropped() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.dropped
+}
+
+// Size returns the bytes on disk of the records not replayed yet.
+func (q *Queue) Size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.size()
+}
+
+// Close closes the queue. Its records are kept for the next Open.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active == nil {
+		return nil
+	}
+
+	err := q.active.Close()
+	q.active = nil
+
+	return err
+}
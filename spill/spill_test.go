@@ -0,0 +1,79 @@
+package spill
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAppendAtMaxBytes(t *testing.T) {
+	// Records of 8 bytes take 16 on disk, so 4 of them fit in MaxBytes, all within
+	// the single segment of the default size.
+	tests := []struct {
+		name        string
+		eviction    Eviction
+		records     int
+		size        int
+		wantErr     error
+		wantDropped int64
+		wantFirst   string
+	}{
+		{name: "drop oldest within a segment", eviction: DropOldest, records: 6, size: 8, wantDropped: 4, wantFirst: "record-4"},
+		{name: "drop oldest across evictions", eviction: DropOldest, records: 10, size: 8, wantDropped: 8, wantFirst: "record-8"},
+		{name: "reject new", eviction: RejectNew, records: 5, size: 8, wantErr: ErrQueueFull},
+		{name: "record larger than the queue", eviction: DropOldest, records: 1, size: 80, wantErr: ErrQueueFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Open(t.TempDir(), Options{MaxBytes: 64, Eviction: tt.eviction, NoSync: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer q.Close()
+
+			for i := 0; i < tt.records; i++ {
+				data := make([]byte, tt.size)
+				copy(data, fmt.Sprintf("record-%d", i))
+
+				err = q.Append(data)
+				if err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Append() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got := q.Dropped(); got != tt.wantDropped {
+				t.Errorf("Dropped() = %d, want %d", got, tt.wantDropped)
+			}
+
+			if got := q.Size(); got > 64 {
+				t.Errorf("Size() = %d, more than MaxBytes", got)
+			}
+
+			var first string
+
+			err = q.Replay(func(data []byte) error {
+				if first == "" {
+					first = string(data[:len(tt.wantFirst)])
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if first != tt.wantFirst {
+				t.Errorf("first replayed record = %q, want %q", first, tt.wantFirst)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package stix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/threatwinds/go-sdk/plugins"
+	"github.com/threatwinds/go-sdk/tw"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// entityTypes maps the STIX pattern paths of observables to intelligence entity types.
+var entityTypes = map[string]string{
+	"ipv4-addr:value":                "ip",
+	"ipv6-addr:value":                "ip",
+	"domain-name:value":              "domain",
+	"url:value":                      "url",
+	"email-addr:value":               "email",
+	"file:hashes.MD5":                "md5",
+	"file:hashes.'SHA-1'":            "sha1",
+	"file:hashes.'SHA-256'":          "sha256",
+	"file:hashes.SHA1":               "sha1",
+	"file:hashes.SHA256":             "sha256",
+	"file:hashes.'SHA-512'":          "sha512",
+	"x509-certificate:serial_number": "certificate-serial",
+}
+
+// comparison matches the equality comparisons of STIX patterns, e.g.
+// [ipv4-addr:value = '198.51.100.1'].
+var comparison = regexp.MustCompile(`([a-z0-9-]+:[A-Za-z0-9_.'-]+)\s*=\s*'((?:[^'\\]|\\.)*)'`)
+
+// Relationship links two entities of a bundle by their IDs.
+type Relationship struct {
+	SourceID string
+	Relation string
+	TargetID string
+}
+
+// Entities converts a bundle into intelligence entities:
+//
+//   - cyber-observable objects with a value or hashes become entities of the matching
+//     type, e.g. an ipv4-addr becomes an "ip" entity;
+//   - indicators become an entity per observable compared for equality in their
+//     pattern, tagged with the indicator labels; more complex patterns are skipped;
+//   - other domain objects, like malware or threat-actor, become entities of their
+//     STIX type described by their name.
+//
+// Relationships between converted objects are returned too. Relationships with an
+// indicator apply to every entity of its pattern.
+func Entities(b Bundle) ([]tw.Entity, []Relationship) {
+	var entities []tw.Entity
+	var seen = make(map[string]bool)
+	var expanded = make(map[string][]string)
+
+	add := func(e tw.Entity) {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			entities = append(entities, e)
+		}
+	}
+
+	for _, o := range b.Objects {
+		switch o.Type {
+		case "relationship", "sighting", "bundle", "marking-definition", "identity",
+			"observed-data", "report", "note", "opinion", "grouping":
+			continue
+		case "indicator":
+			if o.PatternType != "" && o.PatternType != "stix" {
+				continue
+			}
+
+			for _, m := range comparison.FindAllStringSubmatch(o.Pattern, -1) {
+				e, ok := observableEntity(m[1], unescape(m[2]))
+				if !ok {
+					continue
+				}
+
+				e.Tags = append(e.Tags, o.Labels...)
+				e.Attributes["indicator"] = o.ID
+				if o.Name != "" {
+					e.Attributes["name"] = o.Name
+				}
+
+				if o.Confidence != nil {
+					e.Accuracy = *o.Confidence
+				}
+
+				e.LastSeen = lastSeen(o)
+
+				add(e)
+				expanded[o.ID] = append(expanded[o.ID], e.ID)
+			}
+		default:
+			if e, ok := objectEntity(o); ok {
+				add(e)
+				expanded[o.ID] = []string{e.ID}
+			}
+		}
+	}
+
+	var relationships []Relationship
+
+	for _, o := range b.Objects {
+		if o.Type != "relationship" {
+			continue
+		}
+
+		for _, source := range expanded[o.SourceRef] {
+			for _, target := range expanded[o.TargetRef] {
+				relationships = append(relationships, Relationship{SourceID: source, Relation: o.RelationshipType, TargetID: target})
+			}
+		}
+	}
+
+	return entities, relationships
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(s)
+}
+
+// observableEntity returns the entity of the observable at a STIX pattern path.
+func observableEntity(path, value string) (tw.Entity, bool) {
+	typ, ok := entityTypes[path]
+	if !ok {
+		return tw.Entity{}, false
+	}
+
+	scoType, property, _ := strings.Cut(path, ":")
+
+	var id string
+	if scoType == "file" {
+		algorithm := strings.Trim(strings.TrimPrefix(property, "hashes."), "'")
+		id = observableID("file", "hashes", map[string]string{algorithm: value})
+	} else {
+		id = observableID(scoType, property, value)
+	}
+
+	return tw.Entity{ID: id, Type: typ, Attributes: map[string]interface{}{"value": value}}, true
+}
+
+func objectEntity(o Object) (tw.Entity, bool) {
+	switch {
+	case o.Value != "":
+		e, ok := observableEntity(o.Type+":value", o.Value)
+		if ok {
+			e.ID = o.ID
+		}
+
+		return e, ok
+	case o.Type == "file":
+		for algorithm, hash := range o.Hashes {
+			e, ok := observableEntity("file:hashes."+quotePath(algorithm), hash)
+			if ok {
+				e.ID = o.ID
+				return e, true
+			}
+		}
+
+		return tw.Entity{}, false
+	case o.Name != "":
+		var attributes = map[string]interface{}{"name": o.Name}
+		if o.Description != "" {
+			attributes["description"] = o.Description
+		}
+
+		return tw.Entity{ID: o.ID, Type: o.Type, Attributes: attributes, Tags: o.Labels, LastSeen: lastSeen(o)}, true
+	default:
+		return tw.Entity{}, false
+	}
+}
+
+// quotePath quotes a hash algorithm the way STIX patterns do when it has a dash.
+func quotePath(algorithm string) string {
+	if strings.Contains(algorithm, "-") {
+		return "'" + algorithm + "'"
+	}
+
+	return algorithm
+}
+
+func lastSeen(o Object) time.Time {
+	for _, t := range []*time.Time{o.LastObserved, o.Modified, o.Created} {
+		if t != nil {
+			return *t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Events converts the sightings and observed-data objects of a bundle into events of
+// type "stix", with the observables they reference set on the remote side of the
+// event and every STIX property in the log.
+func Events(b Bundle, dataSource string) ([]*plugins.Event, error) {
+	index := b.Index()
+
+	var events []*plugins.Event
+
+	for _, o := range b.Objects {
+		if o.Type != "sighting" && o.Type != "observed-data" {
+			continue
+		}
+
+		raw, err := json.Marshal(o)
+		if err != nil {
+			return nil, err
+		}
+
+		log, err := structpb.NewStruct(o.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("object %s: %w", o.ID, err)
+		}
+
+		e := &plugins.Event{
+			Id:         uuid.NewSHA1(namespace, []byte(o.ID)).String(),
+			Timestamp:  lastSeen(o).UTC().Format(time.RFC3339Nano),
+			DataType:   "stix",
+			DataSource: dataSource,
+			Raw:        string(raw),
+			Log:        log.Fields,
+			Remote:     &plugins.Side{},
+		}
+
+		refs := o.ObjectRefs
+		if o.Type == "sighting" {
+			refs = append(refs, o.SourceRef)
+			if ref, ok := o.Properties["sighting_of_ref"].(string); ok {
+				refs = append(refs, ref)
+			}
+		}
+
+		for _, ref := range refs {
+			setObservable(e.Remote, index[ref])
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+func setObservable(side *plugins.Side, o Object) {
+	switch o.Type {
+	case "ipv4-addr", "ipv6-addr":
+		side.Ips = append(side.Ips, o.Value)
+	case "domain-name":
+		side.Domains = append(side.Domains, o.Value)
+	case "url":
+		side.Urls = append(side.Urls, o.Value)
+	case "email-addr":
+		side.Emails = append(side.Emails, o.Value)
+	case "file":
+		for algorithm, hash := range o.Hashes {
+			switch strings.ToUpper(strings.ReplaceAll(algorithm, "-", "")) {
+			case "MD5":
+				side.Md5S = append(side.Md5S, hash)
+			case "SHA1":
+				side.Sha1S = append(side.Sha1S, hash)
+			case "SHA256":
+				side.Sha256S = append(side.Sha256S, hash)
+			}
+		}
+	}
+}
+
+// FromHits exports the observables found in search hits as a bundle of observables and
+// indicators. fields maps the source fields to read, dotted for nested objects, to the
+// STIX pattern path of their values, e.g. {"remote.ip": "ipv4-addr:value"}; IPv6
+// values of an ipv4-addr field are exported as ipv6-addr.
+func FromHits(hits []opensearch.Hit, fields map[string]string) Bundle {
+	var objects []Object
+	var seen = make(map[string]bool)
+
+	now := time.Now().UTC()
+
+	for _, hit := range hits {
+		for field, path := range fields {
+			for _, value := range sourceValues(hit.Source, field) {
+				path := path
+				if path == "ipv4-addr:value" && strings.Contains(value, ":") && net.ParseIP(value) != nil {
+					path = "ipv6-addr:value"
+				}
+
+				e, ok := observableEntity(path, value)
+				if !ok || seen[e.ID] {
+					continue
+				}
+
+				seen[e.ID] = true
+
+				scoType, _, _ := strings.Cut(path, ":")
+
+				sco := Object{Type: scoType, SpecVersion: SpecVersion, ID: e.ID}
+				if scoType == "file" {
+					algorithm := strings.Trim(strings.TrimPrefix(path, "file:hashes."), "'")
+					sco.Hashes = map[string]string{algorithm: value}
+				} else {
+					sco.Value = value
+				}
+
+				indicator := Object{
+					Type:        "indicator",
+					SpecVersion: SpecVersion,
+					ID:          "indicator--" + uuid.NewSHA1(namespace, []byte(e.ID)).String(),
+					Created:     &now,
+					Modified:    &now,
+					Name:        value,
+					Pattern:     fmt.Sprintf("[%s = '%s']", path, strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)),
+					PatternType: "stix",
+					ValidFrom:   &now,
+				}
+
+				relationship := Object{
+					Type:             "relationship",
+					SpecVersion:      SpecVersion,
+					ID:               "relationship--" + uuid.NewSHA1(namespace, []byte(indicator.ID+e.ID)).String(),
+					Created:          &now,
+					Modified:         &now,
+					RelationshipType: "based-on",
+					SourceRef:        indicator.ID,
+					TargetRef:        sco.ID,
+				}
+
+				objects = append(objects, sco, indicator, relationship)
+			}
+		}
+	}
+
+	return NewBundle(objects...)
+}
+
+// sourceValues returns the string values of a dotted field of a source, flattening lists.
+func sourceValues(src map[string]interface{}, field string) []string {
+	if v, ok := src[field]; ok {
+		return stringValues(v)
+	}
+
+	for i := strings.IndexByte(field, '.'); i >= 0; i = nextDot(field, i) {
+		if nested, ok := src[field[:i]].(map[string]interface{}); ok {
+			if values := sourceValues(nested, field[i+1:]); len(values) != 0 {
+				return values
+			}
+		}
+	}
+
+	return nil
+}
+
+func nextDot(s string, i int) int {
+	j := strings.IndexByte(s[i+1:], '.')
+	if j < 0 {
+		return -1
+	}
+
+	return i + 1 + j
+}
+
+func stringValues(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, e := range v {
+			out = append(out, stringValues(e)...)
+		}
+		return out
+	default:
+		return nil
+	}
+}
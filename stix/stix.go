@@ -0,0 +1,153 @@
+// Package stix converts STIX 2.1 bundles from intel sharing partners into intelligence
+// entities and events, and exports search results back to STIX bundles.
+package stix
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SpecVersion is the STIX version produced and accepted.
+const SpecVersion = "2.1"
+
+// namespace is the UUIDv5 namespace of the deterministic IDs of STIX cyber-observable
+// objects, set by the STIX 2.1 specification.
+var namespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// Bundle is a STIX bundle.
+type Bundle struct {
+	Type    string   `json:"type"`
+	ID      string   `json:"id"`
+	Objects []Object `json:"objects"`
+}
+
+// Object is a STIX domain, cyber-observable or relationship object. The common and
+// most used properties are decoded in fields, all of them are kept in Properties.
+type Object struct {
+	Type             string            `json:"type"`
+	SpecVersion      string            `json:"spec_version,omitempty"`
+	ID               string            `json:"id"`
+	Created          *time.Time        `json:"created,omitempty"`
+	Modified         *time.Time        `json:"modified,omitempty"`
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Labels           []string          `json:"labels,omitempty"`
+	Confidence       *int              `json:"confidence,omitempty"`
+	Pattern          string            `json:"pattern,omitempty"`
+	PatternType      string            `json:"pattern_type,omitempty"`
+	ValidFrom        *time.Time        `json:"valid_from,omitempty"`
+	ValidUntil       *time.Time        `json:"valid_until,omitempty"`
+	Value            string            `json:"value,omitempty"`
+	Hashes           map[string]string `json:"hashes,omitempty"`
+	RelationshipType string            `json:"relationship_type,omitempty"`
+	SourceRef        string            `json:"source_ref,omitempty"`
+	TargetRef        string            `json:"target_ref,omitempty"`
+	FirstObserved    *time.Time        `json:"first_observed,omitempty"`
+	LastObserved     *time.Time        `json:"last_observed,omitempty"`
+	NumberObserved   int               `json:"number_observed,omitempty"`
+	ObjectRefs       []string          `json:"object_refs,omitempty"`
+
+	Properties map[string]interface{} `json:"-"`
+}
+
+// UnmarshalJSON decodes the known properties and keeps every property in Properties.
+func (o *Object) UnmarshalJSON(data []byte) error {
+	type object Object
+
+	var decoded object
+
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, &decoded.Properties)
+	if err != nil {
+		return err
+	}
+
+	*o = Object(decoded)
+
+	return nil
+}
+
+// MarshalJSON encodes Properties along with the known properties, which take
+// precedence.
+func (o Object) MarshalJSON() ([]byte, error) {
+	type object Object
+
+	known, err := json.Marshal(object(o))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.Properties) == 0 {
+		return known, nil
+	}
+
+	var merged = make(map[string]interface{}, len(o.Properties))
+	for k, v := range o.Properties {
+		merged[k] = v
+	}
+
+	var fields map[string]interface{}
+
+	err = json.Unmarshal(known, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return json.Marshal(merged)
+}
+
+// Parse decodes a STIX 2.1 bundle.
+func Parse(data []byte) (Bundle, error) {
+	var b Bundle
+
+	err := json.Unmarshal(data, &b)
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	if b.Type != "bundle" {
+		return Bundle{}, fmt.Errorf("not a STIX bundle: type is %q", b.Type)
+	}
+
+	for i, o := range b.Objects {
+		if o.Type == "" || o.ID == "" {
+			return Bundle{}, fmt.Errorf("object %d of the bundle has no type or id", i)
+		}
+	}
+
+	return b, nil
+}
+
+// NewBundle returns a bundle with a random ID containing objects.
+func NewBundle(objects ...Object) Bundle {
+	return Bundle{Type: "bundle", ID: "bundle--" + uuid.NewString(), Objects: objects}
+}
+
+// Index returns the objects of the bundle by ID.
+func (b Bundle) Index() map[string]Object {
+	var index = make(map[string]Object, len(b.Objects))
+	for _, o := range b.Objects {
+		index[o.ID] = o
+	}
+
+	return index
+}
+
+// observableID returns the deterministic ID of a cyber-observable object identified by
+// its value, as specified for its type.
+func observableID(typ, key string, value interface{}) string {
+	contributing, _ := json.Marshal(map[string]interface{}{key: value})
+
+	return typ + "--" + uuid.NewSHA1(namespace, contributing).String()
+}
@@ -0,0 +1,182 @@
+package taxii
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/threatwinds/go-sdk/stix"
+)
+
+// Checkpoints persists the date of the last object collected from each collection.
+type Checkpoints interface {
+	Load(key string) (time.Time, error)
+	Save(key string, addedAfter time.Time) error
+}
+
+// FileCheckpoints stores checkpoints in a JSON file.
+type FileCheckpoints struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Load returns the checkpoint of key, or the zero time if there is none.
+func (f *FileCheckpoints) Load(key string) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	all, err := f.read()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return all[key], nil
+}
+
+// Save replaces the checkpoint of key.
+func (f *FileCheckpoints) Save(key string, addedAfter time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	all, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	all[key] = addedAfter
+
+	j, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Path + ".tmp"
+
+	err = os.WriteFile(tmp, j, 0o640)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.Path)
+}
+
+func (f *FileCheckpoints) read() (map[string]time.Time, error) {
+	var all = make(map[string]time.Time)
+
+	j, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, os.MkdirAll(filepath.Dir(f.Path), 0o750)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return all, json.Unmarshal(j, &all)
+}
+
+// Poller collects the new objects of a collection.
+type Poller struct {
+	Client       *Client
+	APIRoot      string
+	CollectionID string
+	Checkpoints  Checkpoints
+	// PageSize is the number of objects requested per page, left to the server when
+	// zero.
+	PageSize int
+	// Since is where collection starts without a checkpoint, e.g. 30 days ago. The
+	// whole collection is fetched when zero.
+	Since time.Time
+}
+
+func (p *Poller) key() string {
+	return p.APIRoot + "|" + p.CollectionID
+}
+
+// Poll passes the objects added to the collection since the checkpoint to fn, page by
+// page, saving the checkpoint after each page fn accepts. It returns the number of
+// objects collected. When fn fails the checkpoint stays at the previous page, which is
+// fetched again by the next Poll.
+func (p *Poller) Poll(ctx context.Context, fn func(objects []stix.Object) error) (int, error) {
+	addedAfter := p.Since
+
+	if p.Checkpoints != nil {
+		checkpoint, err := p.Checkpoints.Load(p.key())
+		if err != nil {
+			return 0, err
+		}
+
+		if !checkpoint.IsZero() {
+			addedAfter = checkpoint
+		}
+	}
+
+	var total int
+	var next string
+
+	for {
+		env, err := p.Client.Objects(ctx, p.APIRoot, p.CollectionID, addedAfter, next, p.PageSize)
+		if err != nil {
+			return total, err
+		}
+
+		if len(env.Objects) != 0 {
+			err = fn(env.Objects)
+			if err != nil {
+				return total, err
+			}
+
+			total += len(env.Objects)
+		}
+
+		if !env.DateAddedLast.IsZero() && p.Checkpoints != nil {
+			err = p.Checkpoints.Save(p.key(), env.DateAddedLast)
+			if err != nil {
+				return total, err
+			}
+		}
+
+		if !env.More {
+			return total, nil
+		}
+
+		// Servers without next pagination continue from the last added date.
+		if env.Next != "" {
+			next = env.Next
+		} else if !env.DateAddedLast.IsZero() && env.DateAddedLast.After(addedAfter) {
+			addedAfter = env.DateAddedLast
+		} else {
+			return total, nil
+		}
+	}
+}
+
+// Run polls every interval until ctx is done, passing each outcome to report, which
+// may be nil. It returns an error only when interval is not positive.
+func (p *Poller) Run(ctx context.Context, interval time.Duration, fn func([]stix.Object) error, report func(int, error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("poll interval must be positive, got %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.Poll(ctx, fn)
+		if report != nil {
+			report(n, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
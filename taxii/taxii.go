@@ -0,0 +1,173 @@
+// Package taxii is a TAXII 2.1 client to pull STIX objects from intel feeds, resuming
+// each collection from a persisted checkpoint.
+package taxii
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threatwinds/go-sdk/stix"
+)
+
+// MediaType is the media type of TAXII 2.1 requests and responses.
+const MediaType = "application/taxii+json;version=2.1"
+
+// StatusError is returned when the server answers with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("TAXII server status %d, response: %s", e.StatusCode, e.Body)
+}
+
+// Client is a TAXII 2.1 client.
+type Client struct {
+	Username string
+	Password string
+	// Header is added to every request, e.g. an API key header.
+	Header http.Header
+	// HTTPClient defaults to a client with a 60 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// Discovery is the response of the discovery endpoint of a server.
+type Discovery struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Default     string   `json:"default,omitempty"`
+	APIRoots    []string `json:"api_roots"`
+}
+
+// Collection is a collection of an API root.
+type Collection struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	CanRead     bool     `json:"can_read"`
+	CanWrite    bool     `json:"can_write"`
+	MediaTypes  []string `json:"media_types,omitempty"`
+}
+
+// Envelope is a page of objects of a collection.
+type Envelope struct {
+	More    bool          `json:"more"`
+	Next    string        `json:"next,omitempty"`
+	Objects []stix.Object `json:"objects"`
+
+	// DateAddedLast is the date the last object of the page was added to the
+	// collection, from the X-TAXII-Date-Added-Last header.
+	DateAddedLast time.Time `json:"-"`
+}
+
+func (c *Client) get(ctx context.Context, u string, params url.Values, out interface{}) (http.Header, error) {
+	if len(params) != 0 {
+		sep := "?"
+		if strings.Contains(u, "?") {
+			sep = "&"
+		}
+
+		u += sep + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Accept", MediaType)
+
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: body}
+	}
+
+	return resp.Header, json.Unmarshal(body, out)
+}
+
+// Discover returns the API roots of the server, from its discovery URL, e.g.
+// "https://feed.example.com/taxii2/".
+func (c *Client) Discover(ctx context.Context, discoveryURL string) (Discovery, error) {
+	var d Discovery
+
+	_, err := c.get(ctx, discoveryURL, nil, &d)
+
+	return d, err
+}
+
+// Collections returns the collections of an API root.
+func (c *Client) Collections(ctx context.Context, apiRoot string) ([]Collection, error) {
+	var result struct {
+		Collections []Collection `json:"collections"`
+	}
+
+	_, err := c.get(ctx, strings.TrimSuffix(apiRoot, "/")+"/collections/", nil, &result)
+
+	return result.Collections, err
+}
+
+// Objects returns a page of up to limit objects of a collection, added after
+// addedAfter when it is not zero. next continues a previous page; limit is left to
+// the server when zero.
+func (c *Client) Objects(ctx context.Context, apiRoot, collectionID string, addedAfter time.Time, next string, limit int) (Envelope, error) {
+	var params = url.Values{}
+
+	if !addedAfter.IsZero() {
+		params.Set("added_after", addedAfter.UTC().Format(time.RFC3339Nano))
+	}
+
+	if next != "" {
+		params.Set("next", next)
+	}
+
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	u := strings.TrimSuffix(apiRoot, "/") + "/collections/" + url.PathEscape(collectionID) + "/objects/"
+
+	var env Envelope
+
+	header, err := c.get(ctx, u, params, &env)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	if last := header.Get("X-TAXII-Date-Added-Last"); last != "" {
+		env.DateAddedLast, _ = time.Parse(time.RFC3339Nano, last)
+	}
+
+	return env, nil
+}
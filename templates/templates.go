@@ -0,0 +1,327 @@
+// Package templates renders alerts, events and search hits into human-readable
+// notifications with text/template and helper functions shared by the output plugins
+// and alerting, so that every channel formats severities, times and fields alike:
+//
+//	[{{severity .Severity | upper}}] {{.Name}} at {{formatTime "2006-01-02 15:04 MST" .Timestamp}}
+//	Target: {{field "target.ip" . | default "unknown"}}
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/threatwinds/go-sdk/opensearch"
+	"github.com/tidwall/gjson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Alert is a plain text notification of a *plugins.Alert.
+const Alert = `[{{severity .Severity | upper}}] {{.Name}}
+Time: {{formatTime "2006-01-02 15:04:05 MST" .Timestamp}}
+Category: {{.Category | default "-"}}{{with .Technique}} ({{.}}){{end}}
+{{- with .Adversary}}{{with .Ip}}
+Adversary: {{.}}{{end}}{{end}}
+{{- with .Target}}{{with .Ip}}
+Target: {{.}}{{end}}{{end}}
+{{with .Description}}
+{{.}}{{end}}
+`
+
+// New parses a template with the helper functions of Funcs.
+func New(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(Funcs()).Parse(text)
+}
+
+// Must is like New but panics on errors, for templates known at compile time.
+func Must(name, text string) *template.Template {
+	return template.Must(New(name, text))
+}
+
+// Funcs returns the helper functions of the templates:
+//
+//   - severity normalizes a severity name or score into "low", "medium", "high" or
+//     "critical", and severityRank returns it from 1 to 4, 0 when unknown.
+//   - formatTime formats a time.Time, an RFC 3339 string or epoch milliseconds with a
+//     layout, in UTC, and formatTimeIn in the named location.
+//   - since returns the time elapsed since a time, rounded to the second.
+//   - field returns the value at a dotted path, like "target.ip", of an alert, event,
+//     search hit source or map, or nil.
+//   - json encodes a value as JSON.
+//   - default returns its first argument when the second one is empty.
+//   - upper, lower, join, truncate and indent transform strings.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"severity":     Severity,
+		"severityRank": SeverityRank,
+		"formatTime":   formatTime,
+		"formatTimeIn": formatTimeIn,
+		"since":        since,
+		"field":        Field,
+		"json":         toJSON,
+		"default":      defaultValue,
+		"upper":        strings.ToUpper,
+		"lower":        strings.ToLower,
+		"join":         join,
+		"truncate":     truncate,
+		"indent":       indent,
+	}
+}
+
+var severities = []string{"low", "medium", "high", "critical"}
+
+// Severity normalizes a severity, a name like "HIGH" or "informational", or a score
+// from 0 to 10 as an impact score, into "low", "medium", "high" or "critical". It
+// returns the name lowercase when it isn't known.
+func Severity(v interface{}) string {
+	var score float64
+
+	switch s := v.(type) {
+	case string:
+		name := strings.ToLower(strings.TrimSpace(s))
+
+		switch name {
+		case "info", "informational", "low":
+			return "low"
+		case "moderate", "medium":
+			return "medium"
+		case "high":
+			return "high"
+		case "critical", "severe":
+			return "critical"
+		}
+
+		n, err := strconv.ParseFloat(name, 64)
+		if err != nil {
+			return name
+		}
+
+		score = n
+	default:
+		n, ok := number(v)
+		if !ok {
+			return ""
+		}
+
+		score = n
+	}
+
+	switch {
+	case score >= 9:
+		return "critical"
+	case score >= 7:
+		return "high"
+	case score >= 4:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// SeverityRank returns the rank of a severity, from 1 for low to 4 for critical, or 0
+// when it isn't known, e.g. to sort notifications.
+func SeverityRank(v interface{}) int {
+	s := Severity(v)
+
+	for i, name := range severities {
+		if s == name {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// toTime converts a time.Time, an RFC 3339 string or epoch milliseconds into a time.
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q", t)
+		}
+
+		return parsed, nil
+	default:
+		if ms, ok := number(v); ok {
+			return time.UnixMilli(int64(ms)), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %v", v)
+}
+
+func formatTime(layout string, v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+
+	return t.UTC().Format(layout), nil
+}
+
+func formatTimeIn(layout, location string, v interface{}) (string, error) {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+
+	return t.In(loc).Format(layout), nil
+}
+
+func since(v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+
+	return time.Since(t).Round(time.Second).String(), nil
+}
+
+// Field returns the value at the dotted path of an alert, an event, a search hit,
+// whose source is read, a map or any value encoded as a JSON object, or nil when
+// there is none.
+func Field(path string, v interface{}) interface{} {
+	var doc []byte
+	var err error
+
+	switch d := v.(type) {
+	case proto.Message:
+		doc, err = protojson.Marshal(d)
+	case opensearch.Hit:
+		doc, err = json.Marshal(d.Source)
+	case *opensearch.Hit:
+		doc, err = json.Marshal(d.Source)
+	case []byte:
+		doc = d
+	case string:
+		doc = []byte(d)
+	default:
+		doc, err = json.Marshal(d)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	result := gjson.GetBytes(doc, path)
+	if !result.Exists() {
+		return nil
+	}
+
+	return result.Value()
+}
+
+func toJSON(v interface{}) (string, error) {
+	var j []byte
+	var err error
+
+	if m, ok := v.(proto.Message); ok {
+		j, err = protojson.Marshal(m)
+	} else {
+		j, err = json.Marshal(v)
+	}
+
+	return string(j), err
+}
+
+func defaultValue(def, v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return def
+	case string:
+		if t == "" {
+			return def
+		}
+	case []interface{}:
+		if len(t) == 0 {
+			return def
+		}
+	case []string:
+		if len(t) == 0 {
+			return def
+		}
+	case float64:
+		if t == 0 || math.IsNaN(t) {
+			return def
+		}
+	case int:
+		if t == 0 {
+			return def
+		}
+	}
+
+	return v
+}
+
+func join(sep string, v interface{}) string {
+	switch items := v.(type) {
+	case []string:
+		return strings.Join(items, sep)
+	case []interface{}:
+		var s = make([]string, len(items))
+		for i, item := range items {
+			s[i] = fmt.Sprint(item)
+		}
+
+		return strings.Join(s, sep)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// truncate shortens s to n runes, ending with an ellipsis when shortened.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= n || n <= 0 {
+		return s
+	}
+
+	if n == 1 {
+		return "…"
+	}
+
+	return string(runes[:n-1]) + "…"
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
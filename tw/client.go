@@ -0,0 +1,219 @@
+// Package tw is a client of the ThreatWinds intelligence API, for enrichment plugins
+// to look indicators up. Lookups are cached and rate limited on the client side, so
+// that enriching bursts of events doesn't exceed the API quota.
+package tw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/threatwinds/go-sdk/secrets"
+)
+
+// DefaultBaseURL is the base URL of the ThreatWinds API.
+const DefaultBaseURL = "https://apis.threatwinds.com"
+
+const (
+	entityPath       = "/api/search/v1/entity"
+	searchPath       = "/api/search/v1/search"
+	associationsPath = "/api/search/v1/entity/%s/associations"
+)
+
+// APIError is returned when the API answers with an unexpected status code.
+type APIError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("intel API status %d, response: %s", e.StatusCode, e.Body)
+}
+
+// Options configures a Client.
+type Options struct {
+	// BaseURL defaults to DefaultBaseURL.
+	BaseURL string
+	// RatePerSecond limits the requests sent, 10 by default, with bursts of Burst.
+	RatePerSecond float64
+	Burst         int
+	// CacheTTL is how long lookups are cached, 10 minutes by default. Indicators not
+	// found are cached too. A negative value disables the cache.
+	CacheTTL time.Duration
+	// CacheSize bounds the cached lookups, 10000 by default.
+	CacheSize int
+	// HTTPClient defaults to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// Client queries the ThreatWinds intelligence API. It is safe for concurrent use.
+type Client struct {
+	baseURL   string
+	apiKey    string
+	apiSecret string
+	http      *http.Client
+	limiter   *limiter
+	cache     *cache
+}
+
+// NewClient returns a client authenticated with the given API key and secret.
+func NewClient(apiKey, apiSecret string, opts Options) *Client {
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+
+	if opts.RatePerSecond <= 0 {
+		opts.RatePerSecond = 10
+	}
+
+	if opts.Burst <= 0 {
+		opts.Burst = 1
+	}
+
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = 10 * time.Minute
+	}
+
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = 10000
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	c := &Client{
+		baseURL:   opts.BaseURL,
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		http:      opts.HTTPClient,
+		limiter:   newLimiter(opts.RatePerSecond, opts.Burst),
+	}
+
+	if opts.CacheTTL > 0 {
+		c.cache = newCache(opts.CacheTTL, opts.CacheSize)
+	}
+
+	return c
+}
+
+// NewClientWithSecrets returns a client authenticated with the API key and secret read
+// from p under the given secret names.
+func NewClientWithSecrets(ctx context.Context, p secrets.Provider, keySecret, secretSecret string, opts Options) (*Client, error) {
+	key, err := p.Get(ctx, keySecret)
+	if err != nil {
+		return nil, fmt.Errorf("intel API key: %w", err)
+	}
+
+	secret, err := p.Get(ctx, secretSecret)
+	if err != nil {
+		return nil, fmt.Errorf("intel API secret: %w", err)
+	}
+
+	return NewClient(key.Reveal(), secret.Reveal(), opts), nil
+}
+
+// do sends a request and decodes the JSON response into out. Requests rejected with
+// 429 are retried once after the delay requested by the API.
+func (c *Client) do(ctx context.Context, method, path string, params url.Values, body, out interface{}) error {
+	var payload []byte
+
+	if body != nil {
+		var err error
+
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := c.baseURL + path
+	if len(params) != 0 {
+		u += "?" + params.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.limiter.wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("api-key", c.apiKey)
+		req.Header.Set("api-secret", c.apiSecret)
+		req.Header.Set("Accept", "application/json")
+
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			err = sleep(ctx, retryAfter(resp.Header.Get("Retry-After")))
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return &APIError{StatusCode: resp.StatusCode, Body: respBody}
+		}
+
+		if out == nil {
+			return nil
+		}
+
+		return json.Unmarshal(respBody, out)
+	}
+}
+
+func retryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+
+	return time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
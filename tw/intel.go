@@ -0,0 +1,168 @@
+package tw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Indicator is an observable to look up, e.g. {Type: "ip", Value: "203.0.113.7"}.
+type Indicator struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Entity is a ThreatWinds intelligence entity.
+type Entity struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Reputation int                    `json:"reputation"`
+	Accuracy   int                    `json:"accuracy"`
+	Tags       []string               `json:"tags,omitempty"`
+	LastSeen   time.Time              `json:"lastSeen"`
+}
+
+// Association links an entity to another one, e.g. a domain resolving to an IP.
+type Association struct {
+	Relation string `json:"relation"`
+	Entity   Entity `json:"entity"`
+}
+
+type entityPage struct {
+	Items []Entity `json:"items"`
+	Total int64    `json:"total"`
+}
+
+// Lookup returns the entity of an indicator. The boolean result is false when the
+// intelligence has no entity for it.
+func (c *Client) Lookup(ctx context.Context, ind Indicator) (Entity, bool, error) {
+	key := "entity/" + ind.Type + "/" + ind.Value
+
+	if cached, ok := c.cache.get(key); ok {
+		e, found := cached.(*Entity)
+		if !found {
+			return Entity{}, false, nil
+		}
+
+		return *e, true, nil
+	}
+
+	var e Entity
+
+	err := c.do(ctx, http.MethodGet, entityPath, url.Values{"type": {ind.Type}, "value": {ind.Value}}, nil, &e)
+
+	var apiErr *APIError
+
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		c.cache.set(key, nil)
+		return Entity{}, false, nil
+	case err != nil:
+		return Entity{}, false, err
+	}
+
+	c.cache.set(key, &e)
+
+	return e, true, nil
+}
+
+// LookupBatch looks several indicators up, with at most concurrency requests in
+// flight, and returns the entities found. Cached indicators are not requested.
+func (c *Client) LookupBatch(ctx context.Context, indicators []Indicator, concurrency int) (map[Indicator]Entity, error) {
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	var found = make(map[Indicator]Entity, len(indicators))
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	var sem = make(chan struct{}, concurrency)
+	var requested = make(map[Indicator]bool, len(indicators))
+
+	for _, ind := range indicators {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+
+		if requested[ind] || failed {
+			continue
+		}
+
+		requested[ind] = true
+
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(ind Indicator) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			e, ok, err := c.Lookup(ctx, ind)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("looking %s %s up: %w", ind.Type, ind.Value, err)
+				}
+			case ok:
+				found[ind] = e
+			}
+		}(ind)
+	}
+
+	wg.Wait()
+
+	return found, firstErr
+}
+
+// Search returns the entities matching a free text query, e.g. a domain suffix or a
+// malware family name, optionally restricted to entityType.
+func (c *Client) Search(ctx context.Context, query, entityType string, page, size int) ([]Entity, int64, error) {
+	var params = url.Values{"query": {query}, "page": {fmt.Sprint(page)}, "size": {fmt.Sprint(size)}}
+
+	if entityType != "" {
+		params.Set("type", entityType)
+	}
+
+	var result entityPage
+
+	err := c.do(ctx, http.MethodGet, searchPath, params, nil, &result)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result.Items, result.Total, nil
+}
+
+// Associations returns the entities associated with the entity with the given ID.
+func (c *Client) Associations(ctx context.Context, entityID string) ([]Association, error) {
+	key := "associations/" + entityID
+
+	if cached, ok := c.cache.get(key); ok {
+		return cached.([]Association), nil
+	}
+
+	var result struct {
+		Items []Association `json:"items"`
+	}
+
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf(associationsPath, url.PathEscape(entityID)), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.set(key, result.Items)
+
+	return result.Items, nil
+}